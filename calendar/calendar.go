@@ -0,0 +1,268 @@
+//Package calendar provides the trade calendar shared by the Shanghai and Shenzhen stock exchanges.
+//Dates are strings in global.DateFormat. Dates not covered by the calendar are presumed trading days
+//from Monday to Friday.
+package calendar
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/carusyte/stock/global"
+	"github.com/carusyte/stock/model"
+)
+
+var (
+	log   = global.Log
+	dbmap = global.Dbmap
+	deft  *Calendar
+	mu    sync.Mutex
+)
+
+//kinds of calendar days
+const (
+	Trading = "trading"
+	Weekend = "weekend"
+	Holiday = "holiday"
+	//Makeup days are weekend days officially adjusted to workdays, on which the exchanges are still closed
+	Makeup = "makeup"
+)
+
+//maxGap is the maximum number of consecutive non-trading days searched for the next or previous trading day.
+const maxGap = 60
+
+//Calendar answers trading day queries in memory.
+type Calendar struct {
+	open map[string]bool
+	//sorted trading days covered
+	tds         []string
+	first, last string
+}
+
+//New creates a calendar of the given days.
+func New(days []*model.TradeCalendar) *Calendar {
+	c := &Calendar{open: make(map[string]bool, len(days))}
+	for _, d := range days {
+		c.open[d.Date] = d.Open
+		if d.Open {
+			c.tds = append(c.tds, d.Date)
+		}
+		if c.first == "" || d.Date < c.first {
+			c.first = d.Date
+		}
+		if d.Date > c.last {
+			c.last = d.Date
+		}
+	}
+	sort.Strings(c.tds)
+	return c
+}
+
+//Default returns the calendar loaded from tradecal table, which is cached until Reload is called.
+func Default() *Calendar {
+	mu.Lock()
+	defer mu.Unlock()
+	if deft == nil {
+		deft = load()
+	}
+	return deft
+}
+
+//Reload discards the cached calendar so that it's loaded again on next query.
+func Reload() {
+	mu.Lock()
+	defer mu.Unlock()
+	deft = nil
+}
+
+func load() *Calendar {
+	var days []*model.TradeCalendar
+	_, e := dbmap.Select(&days, "select * from tradecal where calendarDate is not null order by calendarDate")
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("failed to load trade calendar: %+v", e)
+	}
+	for _, d := range days {
+		d.Kind = kindOf(d.Date, d.Open)
+	}
+	if len(days) == 0 {
+		log.Warn("trade calendar is empty, weekdays are presumed trading days")
+	}
+	return New(days)
+}
+
+//Covers reports whether the date is within the range of the calendar.
+func (c *Calendar) Covers(date string) bool {
+	return c.first != "" && date >= c.first && date <= c.last
+}
+
+//IsTradingDay reports whether the exchanges are open on the date.
+func (c *Calendar) IsTradingDay(date string) bool {
+	if c.Covers(date) {
+		return c.open[date]
+	}
+	t, e := time.Parse(global.DateFormat, date)
+	if e != nil {
+		return false
+	}
+	return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+}
+
+//NextTD returns the first trading day after the date, or empty string if the date is invalid.
+func (c *Calendar) NextTD(date string) string {
+	if date >= c.first && date < c.last {
+		if i := sort.SearchStrings(c.tds, date+"~"); i < len(c.tds) {
+			return c.tds[i]
+		}
+	}
+	return c.step(date, 1)
+}
+
+//PrevTD returns the last trading day before the date, or empty string if the date is invalid.
+func (c *Calendar) PrevTD(date string) string {
+	if date > c.first && date <= c.last {
+		if i := sort.SearchStrings(c.tds, date); i > 0 {
+			return c.tds[i-1]
+		}
+	}
+	return c.step(date, -1)
+}
+
+func (c *Calendar) step(date string, dir int) string {
+	t, e := time.Parse(global.DateFormat, date)
+	if e != nil {
+		return ""
+	}
+	for i := 0; i < maxGap; i++ {
+		t = t.AddDate(0, 0, dir)
+		if d := t.Format(global.DateFormat); c.IsTradingDay(d) {
+			return d
+		}
+	}
+	return ""
+}
+
+//TDBetween returns the trading days between from and to, inclusive.
+func (c *Calendar) TDBetween(from, to string) (tds []string) {
+	t, e := time.Parse(global.DateFormat, from)
+	if e != nil {
+		return
+	}
+	for d := from; d <= to; d = t.Format(global.DateFormat) {
+		if c.IsTradingDay(d) {
+			tds = append(tds, d)
+		}
+		t = t.AddDate(0, 0, 1)
+	}
+	return
+}
+
+//WeekStart returns the first trading day of the ISO week of the date, or empty string if there is none.
+func (c *Calendar) WeekStart(date string) string {
+	mon, sun := weekBounds(date)
+	return c.first1(mon, sun)
+}
+
+//WeekEnd returns the last trading day of the ISO week of the date, or empty string if there is none.
+func (c *Calendar) WeekEnd(date string) string {
+	mon, sun := weekBounds(date)
+	return c.last1(mon, sun)
+}
+
+//MonthStart returns the first trading day of the month of the date, or empty string if there is none.
+func (c *Calendar) MonthStart(date string) string {
+	first, last := monthBounds(date)
+	return c.first1(first, last)
+}
+
+//MonthEnd returns the last trading day of the month of the date, or empty string if there is none.
+func (c *Calendar) MonthEnd(date string) string {
+	first, last := monthBounds(date)
+	return c.last1(first, last)
+}
+
+func (c *Calendar) first1(from, to string) string {
+	if from == "" {
+		return ""
+	}
+	if c.IsTradingDay(from) {
+		return from
+	}
+	if d := c.NextTD(from); d != "" && d <= to {
+		return d
+	}
+	return ""
+}
+
+func (c *Calendar) last1(from, to string) string {
+	if to == "" {
+		return ""
+	}
+	if c.IsTradingDay(to) {
+		return to
+	}
+	if d := c.PrevTD(to); d != "" && d >= from {
+		return d
+	}
+	return ""
+}
+
+//weekBounds returns the Monday and Sunday of the ISO week of the date.
+func weekBounds(date string) (mon, sun string) {
+	t, e := time.Parse(global.DateFormat, date)
+	if e != nil {
+		return
+	}
+	wd := (int(t.Weekday()) + 6) % 7
+	m := t.AddDate(0, 0, -wd)
+	return m.Format(global.DateFormat), m.AddDate(0, 0, 6).Format(global.DateFormat)
+}
+
+//monthBounds returns the first and last days of the month of the date.
+func monthBounds(date string) (first, last string) {
+	t, e := time.Parse(global.DateFormat, date)
+	if e != nil {
+		return
+	}
+	f := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return f.Format(global.DateFormat), f.AddDate(0, 1, -1).Format(global.DateFormat)
+}
+
+//IsTradingDay reports whether the exchanges are open on the date, according to the default calendar.
+func IsTradingDay(date string) bool {
+	return Default().IsTradingDay(date)
+}
+
+//NextTD returns the first trading day after the date, according to the default calendar.
+func NextTD(date string) string {
+	return Default().NextTD(date)
+}
+
+//PrevTD returns the last trading day before the date, according to the default calendar.
+func PrevTD(date string) string {
+	return Default().PrevTD(date)
+}
+
+//TDBetween returns the trading days between from and to inclusive, according to the default calendar.
+func TDBetween(from, to string) []string {
+	return Default().TDBetween(from, to)
+}
+
+//WeekStart returns the first trading day of the ISO week of the date, according to the default calendar.
+func WeekStart(date string) string {
+	return Default().WeekStart(date)
+}
+
+//WeekEnd returns the last trading day of the ISO week of the date, according to the default calendar.
+func WeekEnd(date string) string {
+	return Default().WeekEnd(date)
+}
+
+//MonthStart returns the first trading day of the month of the date, according to the default calendar.
+func MonthStart(date string) string {
+	return Default().MonthStart(date)
+}
+
+//MonthEnd returns the last trading day of the month of the date, according to the default calendar.
+func MonthEnd(date string) string {
+	return Default().MonthEnd(date)
+}
@@ -0,0 +1,146 @@
+package calendar
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/carusyte/stock/conf"
+)
+
+//testCalendar covers 2020-09-28 to 2020-10-11, with National Day holidays from 10-01 to 10-08
+//and the make-up day on 10-10.
+func testCalendar() *Calendar {
+	tds := []string{"2020-09-28", "2020-09-29", "2020-09-30", "2020-10-09"}
+	return New(Derive("2020-09-28", "2020-10-11", tds))
+}
+
+func TestDerive(t *testing.T) {
+	md := conf.Args.DataSource.Calendar.MakeupDays
+	conf.Args.DataSource.Calendar.MakeupDays = []string{"2020-10-10"}
+	defer func() { conf.Args.DataSource.Calendar.MakeupDays = md }()
+	days := Derive("2020-09-30", "2020-10-11", []string{"2020-09-30", "2020-10-09"})
+	if len(days) != 12 {
+		t.Fatalf("want 12 days, got %d", len(days))
+	}
+	for _, c := range []struct {
+		i    int
+		kind string
+	}{{0, Trading}, {1, Holiday}, {3, Weekend}, {9, Trading}, {10, Makeup}, {11, Weekend}} {
+		if d := days[c.i]; d.Kind != c.kind || d.Open != (c.kind == Trading) {
+			t.Errorf("%s: want %s, got %+v", d.Date, c.kind, d)
+		}
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	c := testCalendar()
+	for d, want := range map[string]bool{
+		"2020-09-30": true,
+		"2020-10-01": false,
+		"2020-10-05": false,
+		"2020-10-09": true,
+		//beyond the calendar
+		"2020-10-12": true,
+		"2020-09-27": false,
+	} {
+		if got := c.IsTradingDay(d); got != want {
+			t.Errorf("IsTradingDay(%s): want %v, got %v", d, want, got)
+		}
+	}
+	for _, x := range [][3]string{
+		{"2020-09-30", "2020-10-09", "2020-09-29"},
+		{"2020-10-03", "2020-10-09", "2020-09-30"},
+		{"2020-10-09", "2020-10-12", "2020-09-30"},
+		{"2020-09-28", "2020-09-29", "2020-09-25"},
+	} {
+		if got := c.NextTD(x[0]); got != x[1] {
+			t.Errorf("NextTD(%s): want %s, got %s", x[0], x[1], got)
+		}
+		if got := c.PrevTD(x[0]); got != x[2] {
+			t.Errorf("PrevTD(%s): want %s, got %s", x[0], x[2], got)
+		}
+	}
+	want := []string{"2020-09-29", "2020-09-30", "2020-10-09", "2020-10-12"}
+	if got := c.TDBetween("2020-09-29", "2020-10-12"); !reflect.DeepEqual(got, want) {
+		t.Errorf("TDBetween: want %v, got %v", want, got)
+	}
+	for _, x := range [][3]string{
+		//date, week start, week end
+		{"2020-09-30", "2020-09-28", "2020-09-30"},
+		{"2020-10-08", "2020-10-09", "2020-10-09"},
+	} {
+		if got := c.WeekStart(x[0]); got != x[1] {
+			t.Errorf("WeekStart(%s): want %s, got %s", x[0], x[1], got)
+		}
+		if got := c.WeekEnd(x[0]); got != x[2] {
+			t.Errorf("WeekEnd(%s): want %s, got %s", x[0], x[2], got)
+		}
+	}
+	if got := c.MonthStart("2020-10-05"); got != "2020-10-09" {
+		t.Errorf("MonthStart: want 2020-10-09, got %s", got)
+	}
+	if got := c.MonthEnd("2020-09-05"); got != "2020-09-30" {
+		t.Errorf("MonthEnd: want 2020-09-30, got %s", got)
+	}
+
+	//week fully closed
+	c = New(Derive("2020-01-20", "2020-02-09", []string{"2020-01-20", "2020-01-21", "2020-01-22", "2020-01-23",
+		"2020-02-03"}))
+	if got := c.WeekStart("2020-01-29"); got != "" {
+		t.Errorf("WeekStart of closed week: want none, got %s", got)
+	}
+	if got := c.NextTD("2020-01-23"); got != "2020-02-03" {
+		t.Errorf("NextTD over Spring Festival: want 2020-02-03, got %s", got)
+	}
+}
+
+func TestParseSZSE(t *testing.T) {
+	days, e := parseSZSE([]byte(`{"data":[{"zrxh":5,"jybz":"0","jyrq":"2020-10-01"},` +
+		`{"zrxh":6,"jybz":"1","jyrq":"2020-10-09"},{"zrxh":7,"jybz":"0","jyrq":"2020-10-10"}],"nowdate":"2020-10-17"}`))
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if len(days) != 3 {
+		t.Fatalf("want 3 days, got %d", len(days))
+	}
+	if days[0].Kind != Holiday || days[1].Kind != Trading || !days[1].Open || days[2].Kind == Trading {
+		t.Errorf("unexpected days: %+v %+v %+v", days[0], days[1], days[2])
+	}
+	if _, e = parseSZSE([]byte(`<html>`)); e == nil {
+		t.Error("want error for invalid response")
+	}
+}
+
+func TestMonthsToFetch(t *testing.T) {
+	now := time.Date(2020, 10, 17, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		known string
+		want  []string
+	}{
+		{"2020-10-16", []string{"2020-10", "2020-11", "2020-12"}},
+		{"2020-10-31", []string{"2020-11", "2020-12"}},
+		{"2020-12-31", nil},
+		{"", []string{"2020-10", "2020-11", "2020-12"}},
+	} {
+		if got := monthsToFetch(c.known, now); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: want %v, got %v", c.known, c.want, got)
+		}
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	md := conf.Args.DataSource.Calendar.MakeupDays
+	conf.Args.DataSource.Calendar.MakeupDays = []string{"2020-10-10"}
+	defer func() { conf.Args.DataSource.Calendar.MakeupDays = md }()
+	for d, want := range map[string]string{
+		"2020-10-09": Trading,
+		"2020-10-08": Holiday,
+		"2020-10-10": Makeup,
+		"2020-10-11": Weekend,
+	} {
+		if got := kindOf(d, want == Trading); got != want {
+			t.Errorf("%s: want %s, got %s", d, want, got)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package calendar
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/global"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+//szseMonth is the response of the monthly calendar API of SZSE.
+type szseMonth struct {
+	Data []struct {
+		//交易日期
+		Jyrq string `json:"jyrq"`
+		//交易标志，1为交易日
+		Jybz string `json:"jybz"`
+	} `json:"data"`
+}
+
+//Update derives the historical calendar from the daily klines of the configured index, and fetches
+//the calendar of the rest of the year from SZSE unless it's already known. Historical days already
+//in the table are kept.
+func Update() (e error) {
+	now := time.Now()
+	today := now.Format(global.DateFormat)
+	var latest, known sql.NullString
+	if e = dbmap.SelectOne(&latest, "select max(calendarDate) from tradecal where calendarDate <= ?", today); e != nil {
+		return errors.WithStack(e)
+	}
+	if e = dbmap.SelectOne(&known, "select max(calendarDate) from tradecal"); e != nil {
+		return errors.WithStack(e)
+	}
+	var dates []string
+	code := conf.Args.DataSource.Calendar.Index
+	_, e = dbmap.Select(&dates, "select date from index_d_n where code = ? and date > ? order by date",
+		code, latest.String)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		return errors.Wrapf(e, "failed to query daily klines of %s", code)
+	}
+	var days []*model.TradeCalendar
+	//days up to cutoff are known
+	cutoff := latest.String
+	if len(dates) > 0 {
+		from := dates[0]
+		if latest.Valid {
+			t, e := time.Parse(global.DateFormat, latest.String)
+			if e != nil {
+				return errors.WithStack(e)
+			}
+			from = t.AddDate(0, 0, 1).Format(global.DateFormat)
+		}
+		cutoff = dates[len(dates)-1]
+		days = Derive(from, cutoff, dates)
+		log.Printf("%d calendar days derived from klines of %s", len(days), code)
+	}
+	if known.String > cutoff {
+		cutoff = known.String
+	}
+	for _, m := range monthsToFetch(cutoff, now) {
+		fetched, e := fetchSZSE(m)
+		if e != nil {
+			log.Warnf("failed to fetch trade calendar of %s from SZSE: %+v", m, e)
+			break
+		}
+		for _, d := range fetched {
+			if d.Date > cutoff {
+				days = append(days, d)
+			}
+		}
+	}
+	if e = save(days); e != nil {
+		return e
+	}
+	Reload()
+	return nil
+}
+
+//monthsToFetch returns the months, in the format of 2006-01, after the date known through the end
+//of the year of now, which is usually published ahead of time.
+func monthsToFetch(known string, now time.Time) (months []string) {
+	m := now
+	if t, e := time.Parse(global.DateFormat, known); e == nil {
+		m = t.AddDate(0, 0, 1)
+	}
+	m = time.Date(m.Year(), m.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(now.Year(), 12, 1, 0, 0, 0, 0, time.UTC)
+	for ; !m.After(end); m = m.AddDate(0, 1, 0) {
+		months = append(months, m.Format("2006-01"))
+	}
+	return
+}
+
+//Derive builds the calendar days between from and to inclusive, given the trading days within.
+//Closed weekdays are holidays.
+func Derive(from, to string, tds []string) (days []*model.TradeCalendar) {
+	open := make(map[string]bool, len(tds))
+	for _, d := range tds {
+		open[d] = true
+	}
+	t, e := time.Parse(global.DateFormat, from)
+	if e != nil {
+		return
+	}
+	for d := from; d <= to; d = t.Format(global.DateFormat) {
+		days = append(days, newDay(d, t.Weekday(), open[d]))
+		t = t.AddDate(0, 0, 1)
+	}
+	return
+}
+
+func newDay(date string, wd time.Weekday, open bool) *model.TradeCalendar {
+	idx, _ := strconv.ParseInt(strings.Replace(date, "-", "", -1), 10, 64)
+	return &model.TradeCalendar{
+		Index: sql.NullInt64{Int64: idx, Valid: true},
+		Date:  date,
+		Open:  open,
+		Kind:  kind(date, wd, open),
+	}
+}
+
+//kindOf returns the kind of the date.
+func kindOf(date string, open bool) string {
+	t, e := time.Parse(global.DateFormat, date)
+	if e != nil {
+		return Holiday
+	}
+	return kind(date, t.Weekday(), open)
+}
+
+func kind(date string, wd time.Weekday, open bool) string {
+	if open {
+		return Trading
+	}
+	if wd != time.Saturday && wd != time.Sunday {
+		return Holiday
+	}
+	for _, m := range conf.Args.DataSource.Calendar.MakeupDays {
+		if m == date {
+			return Makeup
+		}
+	}
+	return Weekend
+}
+
+//fetchSZSE fetches the calendar of the month, in the format of 2006-01, from SZSE.
+func fetchSZSE(month string) (days []*model.TradeCalendar, e error) {
+	url := fmt.Sprintf(`http://www.szse.cn/api/report/exchange/onepersistenthour/monthList?month=%s`, month)
+	body, e := util.HttpGetBytes(url)
+	if e != nil {
+		return nil, e
+	}
+	return parseSZSE(body)
+}
+
+func parseSZSE(body []byte) (days []*model.TradeCalendar, e error) {
+	m := new(szseMonth)
+	if e = json.Unmarshal(body, m); e != nil {
+		return nil, errors.Wrapf(e, "invalid SZSE calendar: %s", body)
+	}
+	for _, d := range m.Data {
+		t, e := time.Parse(global.DateFormat, d.Jyrq)
+		if e != nil {
+			return nil, errors.Wrapf(e, "invalid SZSE calendar date: %s", d.Jyrq)
+		}
+		days = append(days, newDay(d.Jyrq, t.Weekday(), d.Jybz == "1"))
+	}
+	return
+}
+
+//save replaces the days in the table. The days are in chronological order.
+func save(days []*model.TradeCalendar) (e error) {
+	if len(days) == 0 {
+		return nil
+	}
+	tran, e := dbmap.Begin()
+	if e != nil {
+		return errors.WithStack(e)
+	}
+	_, e = tran.Exec("delete from tradecal where calendarDate between ? and ?", days[0].Date, days[len(days)-1].Date)
+	if e != nil {
+		tran.Rollback()
+		return errors.Wrap(e, "failed to delete trade calendar")
+	}
+	d, t := util.TimeStr()
+	batch := 500
+	for i := 0; i < len(days); i += batch {
+		end := i + batch
+		if end > len(days) {
+			end = len(days)
+		}
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]interface{}, 0, (end-i)*5)
+		for _, day := range days[i:end] {
+			valueStrings = append(valueStrings, "(?,?,?,?,?)")
+			valueArgs = append(valueArgs, day.Index, day.Date, day.Open, d, t)
+		}
+		stmt := fmt.Sprintf("insert into tradecal (`index`,calendarDate,isOpen,udate,utime) values %s",
+			strings.Join(valueStrings, ","))
+		if _, e = tran.Exec(stmt, valueArgs...); e != nil {
+			tran.Rollback()
+			return errors.Wrap(e, "failed to save trade calendar")
+		}
+	}
+	if e = tran.Commit(); e != nil {
+		return errors.WithStack(e)
+	}
+	log.Printf("%d trade calendar days saved", len(days))
+	return nil
+}
@@ -54,27 +54,6 @@ func TestGorpSelectStr(t *testing.T) {
 	log.Printf("%s", str)
 }
 
-func TestSelectNullInt(t *testing.T) {
-	mxid, mxiw, mxim := -1, -1, -1
-	code := "603988"
-	mxidn, err := dbMap.SelectNullInt("select max(klid) from indicator_d where code=?", code)
-	checkErr(err, "failed to query max klid in indicator_d for "+code)
-	mxiwn, err := dbMap.SelectNullInt("select max(klid) from indicator_w where code=?", code)
-	checkErr(err, "failed to query max klid in indicator_w for "+code)
-	mximn, err := dbMap.SelectNullInt("select max(klid) from indicator_m where code=?", code)
-	checkErr(err, "failed to query max klid in indicator_m for "+code)
-	if mxidn.Valid {
-		mxid = int(mxidn.Int64)
-	}
-	if mxiwn.Valid {
-		mxiw = int(mxiwn.Int64)
-	}
-	if mximn.Valid {
-		mxim = int(mximn.Int64)
-	}
-	log.Printf("%d, %d, %d", mxid, mxiw, mxim)
-}
-
 func TestGorpSelectOne(t *testing.T) {
 	var k *model.KlineW
 	e := dbMap.SelectOne(&k, "select * from kline_w where code = ? limit 1", "610104")
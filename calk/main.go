@@ -196,72 +196,13 @@ func caljob(wg *sync.WaitGroup, s model.Stock) {
 		klw.Close, klm.Close = k.Close, k.Close
 	}
 
-	//TODO refactor due to model structure update
-	// mxid, mxiw, mxim := getMaxIdcDates(s.Code)
-
-	// kdj := subidc(indc.DeftKDJ(q), mxid)
-	// kdjw := subidcw(indc.DeftKDJ_W(qw), mxiw)
-	// kdjm := subidcm(indc.DeftKDJ_M(qm), mxim)
-
-	// batchInsert(s.Code, klinesw, klinesm, kdj, kdjw, kdjm)
+	//indicators are calculated by getd into the per-indicator tables
+	// batchInsert(s.Code, klinesw, klinesm)
 
 	log.Printf("%s complete in %f s: dy: %d, wk: %d, mo: %d\n", s.Code, time.Since(start).Seconds(),
 		len(klines), len(klinesw), len(klinesm))
 }
 
-func getMaxIdcDates(code string) (mxid, mxiw, mxim int) {
-	mxid, mxiw, mxim = -1, -1, -1
-	mxidn, err := dbmap.SelectNullInt("select max(klid) from indicator_d where code=?", code)
-	checkErr(err, "failed to query max klid in indicator_d for "+code)
-	mxiwn, err := dbmap.SelectNullInt("select max(klid) from indicator_w where code=?", code)
-	checkErr(err, "failed to query max klid in indicator_w for "+code)
-	mximn, err := dbmap.SelectNullInt("select max(klid) from indicator_m where code=?", code)
-	checkErr(err, "failed to query max klid in indicator_m for "+code)
-	if mxidn.Valid {
-		mxid = int(mxidn.Int64)
-	}
-	if mxiwn.Valid {
-		mxiw = int(mxiwn.Int64)
-	}
-	if mximn.Valid {
-		mxim = int(mximn.Int64)
-	}
-	return
-}
-
-func subidc(q []*model.Indicator, klid int) (ret []*model.Indicator) {
-	ret = make([]*model.Indicator, 0)
-	for i, qe := range q {
-		if qe.Klid > klid {
-			ret = q[i:]
-			return
-		}
-	}
-	return
-}
-
-func subidcw(q []*model.IndicatorW, klid int) (ret []*model.IndicatorW) {
-	ret = make([]*model.IndicatorW, 0)
-	for i, qe := range q {
-		if qe.Klid > klid {
-			ret = q[i:]
-			return
-		}
-	}
-	return
-}
-
-func subidcm(q []*model.IndicatorM, klid int) (ret []*model.IndicatorM) {
-	ret = make([]*model.IndicatorM, 0)
-	for i, qe := range q {
-		if qe.Klid > klid {
-			ret = q[i:]
-			return
-		}
-	}
-	return
-}
-
 // Fetch all klines, latest kline_w and kline_m. Nil will be return if there's no such record.
 func getKlines(s model.Stock) ([]*model.Kline, *model.KlineW, *model.KlineM) {
 	mxw, mxm := getMaxDates(s.Code)
@@ -284,17 +225,11 @@ func purgeOld() {
 	checkErr(err, "failed to query last 7 trade date")
 	_, err = dbmap.Exec("delete from kline_w where date >= ?", lst7)
 	checkErr(err, "failed to purge kline_w")
-	_, err = dbmap.Exec("delete from indicator_d where date >= ?", lst7)
-	checkErr(err, "failed to purge indicator_d")
-	_, err = dbmap.Exec("delete from indicator_w where date >= ?", lst7)
-	checkErr(err, "failed to purge indicator_w")
 
 	lstm, err := dbmap.SelectStr(lastNTD, 32)
 	checkErr(err, "failed to query last 32 trade date")
 	_, err = dbmap.Exec("delete from kline_m where date >= ?", lstm)
 	checkErr(err, "failed to purge kline_m")
-	_, err = dbmap.Exec("delete from indicator_m where date >= ?", lstm)
-	checkErr(err, "failed to purge indicator_m")
 }
 
 func newKlinew() *model.KlineW {
@@ -311,15 +246,10 @@ func newKlinem() *model.KlineM {
 	return klm
 }
 
-func batchInsert(code string, klinesw []*model.KlineW, klinesm []*model.KlineM,
-	indc []*model.Indicator, indcw []*model.IndicatorW, indcm []*model.IndicatorM) {
+func batchInsert(code string, klinesw []*model.KlineW, klinesm []*model.KlineM) {
 	// cklw := binsKlw(klinesw)
 	// cklm := binsKlm(klinesm)
-	// cindc := getd.binsIndc(kdjw, "indicator_d")
-	// cindw := getd.binsIndc(kdjw, "indicator_w")
-	// cindm := getd.binsIndc(kdjw, "indicator_m")
-	// log.Printf("%s saved to database, wk[%d], mo[%d], ind[%d], indw[%d], indm[%d]", code, cklw, cklm,
-	// 	cindc, cindw, cindm)
+	// log.Printf("%s saved to database, wk[%d], mo[%d]", code, cklw, cklm)
 }
 
 func binsKlm(klinesm []*model.KlineM) (c int) {
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/carusyte/stock/getd"
+	"github.com/carusyte/stock/indc"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/score"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fmlCodes  []string
+	fmlCycle  string
+	fmlRtype  string
+	fmlBars   int
+	fmlRows   int
+	fmlScreen bool
+	fmlLimit  int
+)
+
+func init() {
+	formulaCmd.Flags().StringSliceVarP(&fmlCodes, "code", "c", nil,
+		"specify the stocks to evaluate. All stocks will be screened if not specified.")
+	formulaCmd.Flags().StringVar(&fmlCycle, "cycle", string(model.DAY),
+		"specify the kline cycle. Valid cycles include: D, W, M, M1, M5, M15, M30, M60, M120")
+	formulaCmd.Flags().StringVar(&fmlRtype, "rtype", string(model.Forward),
+		"specify the reinstatement type. Valid types include: forward, backward, none")
+	formulaCmd.Flags().IntVarP(&fmlBars, "bars", "b", 500,
+		"specify the number of latest bars to evaluate on. All bars will be used if not positive.")
+	formulaCmd.Flags().IntVarP(&fmlRows, "rows", "r", 10,
+		"specify the number of latest rows to print for each stock.")
+	formulaCmd.Flags().BoolVarP(&fmlScreen, "screen", "s", false,
+		"screen stocks by the last output of the formula instead of printing the series.")
+	formulaCmd.Flags().IntVarP(&fmlLimit, "limit", "l", -1,
+		"specify the maximum number of screened stocks to print.")
+	rootCmd.AddCommand(formulaCmd)
+}
+
+var formulaCmd = &cobra.Command{
+	Use:   "formula <formula>",
+	Short: "Evaluate indicator formula in TDX/THS syntax.",
+	Long: `Evaluate indicator formula in TDX/THS syntax, e.g.
+	stock formula -c 000001 "DIF:EMA(C,12)-EMA(C,26); DEA:EMA(DIF,9)"
+	stock formula -s "CROSS(MA(C,5),MA(C,10)) AND V>MA(V,5)*2"`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fml, e := indc.ParseFormula(args[0])
+		if e != nil {
+			log.Panicf("%+v", e)
+		}
+		if fmlScreen || len(fmlCodes) == 0 {
+			start := time.Now()
+			f := &score.Formula{
+				Text:      args[0],
+				Cycle:     model.CYTP(strings.ToUpper(fmlCycle)),
+				Reinstate: model.Rtype(strings.ToLower(fmlRtype)),
+				Bars:      fmlBars,
+				Screen:    true,
+			}
+			log.Printf("\n%+v", f.Get(fmlCodes, fmlLimit, true))
+			log.Printf("Time Cost: %v", time.Since(start).Seconds())
+			return
+		}
+		qry := getd.TrDataQry{
+			Cycle:     model.CYTP(strings.ToUpper(fmlCycle)),
+			Reinstate: model.Rtype(strings.ToLower(fmlRtype)),
+			Basic:     true,
+		}
+		for _, c := range fmlCodes {
+			td := getd.GetTrDataDB(c, qry, fmlBars, false)
+			printFormula(td, fml.Eval(td))
+		}
+	},
+}
+
+//printFormula prints the latest rows of the formula outputs as a table.
+func printFormula(td *model.TradeData, r *indc.FormulaResult) {
+	fmt.Printf("%s [%s, %s]\n", td.Code, td.Cycle, td.Reinstatement)
+	tw := tablewriter.NewWriter(os.Stdout)
+	tw.SetHeader(append([]string{"DATE", "CLOSE"}, r.Names...))
+	bg := len(td.Base) - fmlRows
+	if bg < 0 || fmlRows <= 0 {
+		bg = 0
+	}
+	for i := bg; i < len(td.Base); i++ {
+		row := []string{td.Base[i].Date, fmt.Sprintf("%.2f", td.Base[i].Close)}
+		for _, s := range r.Series {
+			row = append(row, fmt.Sprintf("%.3f", s[i]))
+		}
+		tw.Append(row)
+	}
+	tw.Render()
+}
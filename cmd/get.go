@@ -1,20 +1,35 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/carusyte/stock/getd"
 	"github.com/spf13/cobra"
 )
 
+var (
+	getOnly   []string
+	getResume bool
+)
+
 func init() {
+	getCmd.Flags().StringSliceVar(&getOnly, "only", nil,
+		fmt.Sprintf("specify the stages to run. All stages will be run if not specified. Valid stages include: %s",
+			strings.Join(getd.StageNames(), ", ")))
+	getCmd.Flags().BoolVar(&getResume, "resume", false,
+		"resume the previous run, skipping the stocks already completed by each stage.")
 	rootCmd.AddCommand(getCmd)
 }
 
 var getCmd = &cobra.Command{
 	Use:   "get",
 	Short: "Get relevant stock data",
+	Long: `Get relevant stock data through the stages of the data pipeline, e.g.
+	stock get --only klines,indicators --resume`,
 	Run: func(cmd *cobra.Command, args []string) {
 		defer shutdownHook()
-		getd.GetV2()
+		getd.RunPipeline(getOnly, getResume)
 	},
 }
 
@@ -14,12 +14,15 @@ import (
 )
 
 var (
-	scorer string
+	scorer  string
+	formula string
 )
 
 func init() {
 	scoreCmd.Flags().StringVarP(&scorer, "scorer", "s", "",
 		"specify the scorer to score the stocks.")
+	scoreCmd.Flags().StringVarP(&formula, "formula", "f", "",
+		"specify the formula in TDX/THS syntax for the formula scorer.")
 	scoreCmd.MarkFlagRequired("scorer")
 	rootCmd.AddCommand(scoreCmd)
 }
@@ -42,6 +45,10 @@ var scoreCmd = &cobra.Command{
 			hidBlueKdjSt()
 		case "bluekdjv":
 			blueKdjv()
+		case "formula":
+			formulaScore()
+		case "dragontiger":
+			dragonTiger()
 		default:
 			log.Panicf("unsupported scorer: %s", s)
 		}
@@ -91,6 +98,20 @@ func empirical() {
 	log.Printf("Time Cost: %v", time.Since(start).Seconds())
 }
 
+func formulaScore() {
+	start := time.Now()
+	r := (&score.Formula{Text: formula}).Get(nil, -1, true)
+	log.Printf("\n%+v", r)
+	log.Printf("Time Cost: %v", time.Since(start).Seconds())
+}
+
+func dragonTiger() {
+	start := time.Now()
+	r := new(score.DragonTiger).Get(nil, -1, true)
+	log.Printf("\n%+v", r)
+	log.Printf("Time Cost: %v", time.Since(start).Seconds())
+}
+
 func blue() {
 	r := new(score.BlueChip).Get(nil, -1, true)
 	log.Printf("\n%+v", r)
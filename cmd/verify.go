@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/getd"
+	"github.com/carusyte/stock/model"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	vrfCodes  []string
+	vrfCycles []string
+	vrfRtypes []string
+	vrfBars   int
+	vrfRows   int
+	vrfRepair string
+	vrfReport bool
+)
+
+func init() {
+	verifyCmd.Flags().StringSliceVarP(&vrfCodes, "code", "c", nil,
+		"specify the stocks to verify. All stocks will be verified if not specified.")
+	verifyCmd.Flags().StringSliceVar(&vrfCycles, "cycle", []string{"D", "W", "M"},
+		"specify the kline cycles to verify.")
+	verifyCmd.Flags().StringSliceVar(&vrfRtypes, "rtype", []string{string(model.Forward), string(model.Backward)},
+		"specify the reinstatement types to verify. Valid types include: forward, backward, none")
+	verifyCmd.Flags().IntVarP(&vrfBars, "bars", "b", 0,
+		"specify the number of latest bars to verify. Defaults to DataSource.Reconcile.Bars in config.")
+	verifyCmd.Flags().IntVarP(&vrfRows, "rows", "r", 50,
+		"specify the maximum number of discrepancies to print in detail. All will be printed if not positive.")
+	verifyCmd.Flags().StringVar(&vrfRepair, "repair", "",
+		"repair the affected ranges of master klines. Valid options include: "+
+			"refetch (download from master source), patch (copy from validate source)")
+	verifyCmd.Flags().BoolVar(&vrfReport, "report", false,
+		"print the discrepancies saved by the previous verification without comparing again.")
+	rootCmd.AddCommand(verifyCmd)
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Reconcile master klines against the validate source.",
+	Long: `Reconcile master klines against the validate source field by field, save the discrepancies
+and print a report, e.g.
+	stock verify -c 000001,600000 --cycle D
+	stock verify --report --repair refetch`,
+	Run: func(cmd *cobra.Command, args []string) {
+		defer shutdownHook()
+		if vrfRepair != "" && vrfRepair != "refetch" && vrfRepair != "patch" {
+			log.Panicf("unsupported repair option: %s", vrfRepair)
+		}
+		var cycles []model.CYTP
+		for _, c := range vrfCycles {
+			cycles = append(cycles, model.CYTP(strings.ToUpper(c)))
+		}
+		var rtypes []model.Rtype
+		for _, r := range vrfRtypes {
+			rtypes = append(rtypes, model.Rtype(strings.ToLower(r)))
+		}
+		bars := vrfBars
+		if bars <= 0 {
+			bars = conf.Args.DataSource.Reconcile.Bars
+		}
+		var discs []*model.KlineDiscrepancy
+		if vrfReport {
+			discs = getd.LoadDiscrepancies(vrfCodes, cycles, rtypes)
+		} else {
+			stks := new(model.Stocks)
+			if len(vrfCodes) > 0 {
+				stks.Add(getd.StocksDbByCode(vrfCodes...)...)
+			} else {
+				stks.Add(getd.StocksDb()...)
+			}
+			discs = getd.ReconcileKlines(stks, cycles, rtypes, bars)
+		}
+		printDiscrepancies(discs)
+		if vrfRepair == "" || len(discs) == 0 {
+			return
+		}
+		rstks := getd.RepairDiscrepancies(discs, vrfRepair == "patch")
+		if rstks.Size() == 0 {
+			return
+		}
+		log.Printf("verifying %d repaired stocks", rstks.Size())
+		printDiscrepancies(getd.ReconcileKlines(rstks, cycles, rtypes, bars))
+	},
+}
+
+//printDiscrepancies prints the summary by kind and the details of the discrepancies.
+func printDiscrepancies(discs []*model.KlineDiscrepancy) {
+	cnt := make(map[string]int)
+	codes := make(map[string]map[string]bool)
+	for _, x := range discs {
+		cnt[x.Kind]++
+		if codes[x.Kind] == nil {
+			codes[x.Kind] = make(map[string]bool)
+		}
+		codes[x.Kind][x.Code] = true
+	}
+	var kinds []string
+	for k := range cnt {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	fmt.Printf("%d kline discrepancies found\n", len(discs))
+	tw := tablewriter.NewWriter(os.Stdout)
+	tw.SetHeader([]string{"KIND", "COUNT", "STOCKS"})
+	for _, k := range kinds {
+		tw.Append([]string{k, fmt.Sprint(cnt[k]), fmt.Sprint(len(codes[k]))})
+	}
+	tw.Render()
+	if len(discs) == 0 {
+		return
+	}
+	tw = tablewriter.NewWriter(os.Stdout)
+	tw.SetHeader([]string{"CODE", "CYCLE", "RTYPE", "DATE", "KIND", "FIELDS", "RATIO", "DEV"})
+	for i, x := range discs {
+		if vrfRows > 0 && i >= vrfRows {
+			break
+		}
+		ratio, dev := "", ""
+		if x.Ratio.Valid {
+			ratio = fmt.Sprintf("%.4f", x.Ratio.Float64)
+		}
+		if x.Dev.Valid {
+			dev = fmt.Sprintf("%.2f%%", x.Dev.Float64*100)
+		}
+		tw.Append([]string{x.Code, x.Cycle, x.Rtype, x.Date, x.Kind, x.Fields, ratio, dev})
+	}
+	tw.Render()
+	if vrfRows > 0 && len(discs) > vrfRows {
+		fmt.Printf("%d more discrepancies omitted\n", len(discs)-vrfRows)
+	}
+}
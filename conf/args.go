@@ -40,6 +40,15 @@ const (
 	TencentCSRC string = "tencent.csrc"
 	TencentTC   string = "tencent.tc"
 	WHT         string = "wht"
+	LocalFile   string = "local"
+)
+
+//HTTP cassette modes
+const (
+	//CassetteRecord saves HTTP responses to cassettes while accessing the network
+	CassetteRecord string = "record"
+	//CassetteReplay serves HTTP responses from recorded cassettes without network access
+	CassetteReplay string = "replay"
 )
 
 //Arguments arguments struct type
@@ -74,6 +83,15 @@ type Arguments struct {
 		UserAgents                 string  `mapstructure:"user_agents"`
 		UserAgentLifespan          int     `mapstructure:"user_agent_lifespan"`
 		HTTPTimeout                int     `mapstructure:"http_timeout"`
+		Cassette                   struct {
+			//Mode is either record or replay. The network is accessed directly if empty.
+			Mode string `mapstructure:"mode"`
+			//Path is the directory of the cassette files
+			Path string `mapstructure:"path"`
+			//IgnoreParams are the volatile query parameters excluded from cassette keys, e.g. cache busting
+			//timestamps. Parameters selecting the data, such as the begin of a kline query, must be kept.
+			IgnoreParams []string `mapstructure:"ignore_params"`
+		}
 	}
 	GCS struct {
 		Connection  int    `mapstructure:"connection"`
@@ -107,11 +125,27 @@ type Arguments struct {
 		SkipKlines            bool      `mapstructure:"skip_klines"`
 		SkipFsStats           bool      `mapstructure:"skip_fs_stats"`
 		SkipIndices           bool      `mapstructure:"skip_indices"`
+		SkipCalendar          bool      `mapstructure:"skip_calendar"`
 		SkipBasicsUpdate      bool      `mapstructure:"skip_basics_update"`
 		SkipIndexCalculation  bool      `mapstructure:"skip_index_calculation"`
 		SkipFinMark           bool      `mapstructure:"skip_fin_mark"`
+		SkipNorthbound        bool      `mapstructure:"skip_northbound"`
+		SkipMargin            bool      `mapstructure:"skip_margin"`
+		SkipDragonTiger       bool      `mapstructure:"skip_dragon_tiger"`
+		SkipHolders           bool      `mapstructure:"skip_holders"`
+		SkipFinStmt           bool      `mapstructure:"skip_fin_stmt"`
+		SkipAnnouncements     bool      `mapstructure:"skip_announcements"`
+		SkipLockup            bool      `mapstructure:"skip_lockup"`
+		SkipFunds             bool      `mapstructure:"skip_funds"`
+		SkipCBonds            bool      `mapstructure:"skip_cbonds"`
+		DragonTigerSince      string    `mapstructure:"dragon_tiger_since"`
+		AnnounceSince         string    `mapstructure:"announce_since"`
+		LockupSince           string    `mapstructure:"lockup_since"`
+		CBondDiscountRate     float64   `mapstructure:"cbond_discount_rate"`
 		SampleKdjFeature      bool      `mapstructure:"sample_kdj_feature"`
 		IndicatorSource       string    `mapstructure:"indicator_source"`
+		Indicators            []string  `mapstructure:"indicators"`
+		MinuteKlines          []string  `mapstructure:"minute_klines"`
 		LimitPriceDayLr       []float64 `mapstructure:"limit_price_day_lr"`
 		FeatureScaling        string    `mapstructure:"feature_scaling"`
 		Validate              struct {
@@ -137,6 +171,35 @@ type Arguments struct {
 		WHT struct {
 			URL string `mapstructure:"url"`
 		}
+		Local struct {
+			//Path is the root directory of local kline files
+			Path string `mapstructure:"path"`
+		}
+		Reinstate struct {
+			//Local derives forward and backward reinstated klines from the non-reinstated ones and xdxr
+			//instead of downloading them
+			Local bool `mapstructure:"local"`
+			//Verify compares the locally reinstated klines with those from the validate source
+			Verify bool `mapstructure:"verify"`
+			//VerifyBars is the number of latest bars to verify
+			VerifyBars int `mapstructure:"verify_bars"`
+			//Tolerance is the maximum relative price deviation allowed in verification
+			Tolerance float64 `mapstructure:"tolerance"`
+		}
+		Calendar struct {
+			//Index is the code of the index whose daily klines derive the historical trade calendar
+			Index string `mapstructure:"index"`
+			//MakeupDays are the weekend days officially adjusted to workdays, on which the exchanges are closed
+			MakeupDays []string `mapstructure:"makeup_days"`
+		}
+		Reconcile struct {
+			//Bars is the number of latest bars to reconcile
+			Bars int `mapstructure:"bars"`
+			//PriceTolerance is the maximum relative deviation allowed for prices
+			PriceTolerance float64 `mapstructure:"price_tolerance"`
+			//VolumeTolerance is the maximum relative deviation allowed for volume, amount and xrate
+			VolumeTolerance float64 `mapstructure:"volume_tolerance"`
+		}
 	}
 	Scorer struct {
 		RunScorer            bool     `mapstructure:"run_scorer"`
@@ -230,9 +293,18 @@ func setDefaults() {
 	Args.Kdjv.SampleSizeMin = 5
 	Args.Kdjv.StatsRetroSpan = 600
 	Args.Network.HTTPTimeout = 60
+	Args.Network.Cassette.Path = "cassettes"
+	Args.Network.Cassette.IgnoreParams = []string{"_"}
 	Args.DataSource.Kline = THS
 	Args.DataSource.Index = TENCENT
 	Args.DataSource.Industry = TencentCSRC
+	Args.DataSource.Reinstate.VerifyBars = 250
+	Args.DataSource.Reinstate.Tolerance = 0.01
+	Args.DataSource.Calendar.Index = "sh000001"
+	Args.DataSource.CBondDiscountRate = 3
+	Args.DataSource.Reconcile.Bars = 250
+	Args.DataSource.Reconcile.PriceTolerance = 0.005
+	Args.DataSource.Reconcile.VolumeTolerance = 0.05
 	Args.Scorer.FetchData = true
 	Args.Scorer.BlueWeight = 0.8
 	Args.Scorer.KdjStWeight = 0.67
@@ -0,0 +1,272 @@
+package getd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/global"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+//emAnnURL is the url template of the announcement list of eastmoney, taking the page size, page number and
+//stock code. Announcements are sorted by date descending.
+const emAnnURL = `http://np-anotice-stock.eastmoney.com/api/security/ann?sr=-1&page_size=%d&page_index=%d` +
+	`&ann_type=A&client_source=web&stock_list=%s&f_node=0&s_node=0`
+
+const annPageSize = 100
+
+//emAnnList is the response of the announcement list of eastmoney.
+type emAnnList struct {
+	Data *struct {
+		TotalHits int `json:"total_hits"`
+		List      []*struct {
+			ArtCode    string `json:"art_code"`
+			Title      string `json:"title"`
+			NoticeDate string `json:"notice_date"`
+			Columns    []*struct {
+				Name string `json:"column_name"`
+			} `json:"columns"`
+		} `json:"list"`
+	} `json:"data"`
+}
+
+//annRule tags the announcements whose titles contain any of the keywords but none of the excludes.
+//An exclude matches the titles containing all of its words.
+type annRule struct {
+	event    string
+	keywords []string
+	excludes [][]string
+}
+
+//annRules are matched in order, the first match wins.
+var annRules = []annRule{
+	{model.EventPledgeRelease, []string{"解除质押", "解质押", "质押解除"}, nil},
+	{model.EventPledge, []string{"质押"}, [][]string{{"股票质押式回购"}}},
+	{model.EventLockupExpiry, []string{"限售股上市流通", "限售股份上市流通", "解除限售", "解禁"}, nil},
+	{model.EventBuyback, []string{"回购"}, [][]string{{"回购注销", "限制性"}, {"质押式回购"}, {"逆回购"}}},
+	{model.EventReduction, []string{"减持"}, nil},
+	{model.EventIncrease, []string{"增持"}, nil},
+	{model.EventPreAnnounce, []string{"业绩预告", "业绩快报", "业绩预增", "业绩预减", "业绩预亏", "预盈", "扭亏"}, nil},
+	{model.EventRestructure, []string{"重大资产重组", "重大资产购买", "重大资产出售", "发行股份购买资产", "重组"}, nil},
+	{model.EventDividend, []string{"权益分派", "分红派息", "利润分配实施"}, nil},
+	{model.EventSuspension, []string{"停牌", "复牌"}, nil},
+}
+
+//TagAnnouncement maps the announcement title to the event type, or returns empty if no rule matches.
+func TagAnnouncement(title string) string {
+	for _, r := range annRules {
+		excluded := false
+		for _, ex := range r.excludes {
+			if containsAll(title, ex) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded && containsAny(title, r.keywords) {
+			return r.event
+		}
+	}
+	return ""
+}
+
+func containsAll(s string, subs []string) bool {
+	for _, sub := range subs {
+		if !strings.Contains(s, sub) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+//GetAnnouncements indexes the announcements of the stocks since the latest ones in database, or since
+//the configured date on the first run, and tags them with event types.
+//Returns the stocks successfully updated.
+func GetAnnouncements(stocks *model.Stocks) (rstks *model.Stocks) {
+	log.Println("getting announcements...")
+	var wg sync.WaitGroup
+	chstk := make(chan *model.Stock, global.JobCapacity)
+	chrstk := make(chan *model.Stock, global.JobCapacity)
+	rstks = new(model.Stocks)
+	wgr := collect(rstks, chrstk)
+	for i := 0; i < conf.Args.Concurrency; i++ {
+		wg.Add(1)
+		go getAnnouncements(chstk, &wg, chrstk)
+	}
+	for _, s := range stocks.List {
+		chstk <- s
+	}
+	close(chstk)
+	wg.Wait()
+	close(chrstk)
+	wgr.Wait()
+	log.Printf("%d announcements updated", rstks.Size())
+	if stocks.Size() != rstks.Size() {
+		same, skp := stocks.Diff(rstks)
+		if !same {
+			log.Printf("Failed: %+v", skp)
+		}
+	}
+	return
+}
+
+func getAnnouncements(chstk chan *model.Stock, wg *sync.WaitGroup, chrstk chan *model.Stock) {
+	defer wg.Done()
+	RETRIES := conf.Args.DataSource.KlineFailureRetry
+	for stock := range chstk {
+		wait := 1000
+		for rtCount := 0; rtCount <= RETRIES; rtCount++ {
+			ok, r := doGetAnnouncements(stock.Code)
+			if ok {
+				chrstk <- stock
+				break
+			} else if r {
+				log.Printf("%s retrying %d...", stock.Code, rtCount+1)
+				time.Sleep(time.Millisecond * time.Duration(wait+rand.Intn(wait)))
+				continue
+			} else {
+				log.Printf("%s retried %d, giving up. restart the program to recover", stock.Code, rtCount+1)
+				break
+			}
+		}
+	}
+}
+
+func doGetAnnouncements(code string) (ok, retry bool) {
+	latest, e := dbmap.SelectNullStr("select max(date) from announcement where code = ?", code)
+	if e != nil {
+		log.Printf("%s failed to query latest announcement: %+v", code, e)
+		return false, true
+	}
+	// announcements of the latest date are refetched as more may come later in the day
+	since := conf.Args.DataSource.AnnounceSince
+	if latest.Valid {
+		since = latest.String
+	}
+	var anns []*model.Announcement
+	for page, pages := 1, 1; page <= pages; page++ {
+		body, e := util.HttpGetBytes(fmt.Sprintf(emAnnURL, annPageSize, page, code))
+		if e != nil {
+			log.Printf("%s failed to get announcements: %+v", code, e)
+			return false, true
+		}
+		var ps []*model.Announcement
+		if ps, pages, e = parseAnnouncements(code, body); e != nil {
+			log.Printf("%s %+v", code, e)
+			return false, true
+		}
+		for _, a := range ps {
+			if a.Date < since {
+				pages = 0
+				break
+			}
+			anns = append(anns, a)
+		}
+	}
+	if e = saveAnnouncements(anns); e != nil {
+		log.Printf("%s %+v", code, e)
+		return false, true
+	}
+	return true, false
+}
+
+//parseAnnouncements converts the announcement list page and returns the total number of pages.
+func parseAnnouncements(code string, body []byte) (anns []*model.Announcement, pages int, e error) {
+	r := new(emAnnList)
+	if e = json.Unmarshal(body, r); e != nil {
+		return nil, 0, errors.Wrapf(e, "invalid announcement list: %s", body)
+	}
+	if r.Data == nil {
+		return nil, 0, nil
+	}
+	pages = (r.Data.TotalHits + annPageSize - 1) / annPageSize
+	for _, it := range r.Data.List {
+		date, e := emDate(it.NoticeDate)
+		if e != nil {
+			return nil, 0, errors.Wrapf(e, "invalid announcement date of %s", it.ArtCode)
+		}
+		var cats []string
+		for _, c := range it.Columns {
+			cats = append(cats, c.Name)
+		}
+		cat := strings.Join(cats, ",")
+		ev := TagAnnouncement(it.Title)
+		anns = append(anns, &model.Announcement{
+			Code:     code,
+			ArtCode:  it.ArtCode,
+			Date:     date,
+			Title:    it.Title,
+			Category: sql.NullString{String: cat, Valid: cat != ""},
+			Event:    sql.NullString{String: ev, Valid: ev != ""},
+		})
+	}
+	return anns, pages, nil
+}
+
+func saveAnnouncements(anns []*model.Announcement) (e error) {
+	d, t := util.TimeStr()
+	batch := 500
+	for i := 0; i < len(anns); i += batch {
+		end := i + batch
+		if end > len(anns) {
+			end = len(anns)
+		}
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]interface{}, 0, (end-i)*8)
+		for _, a := range anns[i:end] {
+			valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?)")
+			valueArgs = append(valueArgs, a.Code, a.ArtCode, a.Date, a.Title, a.Category, a.Event, d, t)
+		}
+		stmt := fmt.Sprintf("insert into announcement (code,art_code,date,title,category,event,udate,utime) "+
+			"values %s on duplicate key update date=values(date),title=values(title),category=values(category),"+
+			"event=values(event),udate=values(udate),utime=values(utime)", strings.Join(valueStrings, ","))
+		if _, e = dbmap.Exec(stmt, valueArgs...); e != nil {
+			return errors.Wrap(e, "failed to save announcements")
+		}
+	}
+	return nil
+}
+
+//Announcements loads the announcements between the dates inclusive in chronological order. Empty dates
+//are unbounded. Only the announcements of the event type are loaded if specified, and only those of the
+//stocks if any is specified.
+func Announcements(event, since, until string, codes ...string) (anns []*model.Announcement) {
+	qry := "select * from announcement where 1=1"
+	var args []interface{}
+	if event != "" {
+		qry += " and event = ?"
+		args = append(args, event)
+	}
+	if since != "" {
+		qry += " and date >= ?"
+		args = append(args, since)
+	}
+	if until != "" {
+		qry += " and date <= ?"
+		args = append(args, until)
+	}
+	if len(codes) > 0 {
+		qry += fmt.Sprintf(" and code in (%s)", util.Join(codes, ",", true))
+	}
+	_, e := dbmap.Select(&anns, qry+" order by date, code, art_code", args...)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("failed to load announcements: %+v", e)
+	}
+	return
+}
@@ -0,0 +1,53 @@
+package getd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+)
+
+func TestTagAnnouncement(t *testing.T) {
+	tests := map[string]string{
+		"关于控股股东部分股份解除质押的公告":     model.EventPledgeRelease,
+		"关于控股股东股份质押的公告":         model.EventPledge,
+		"关于首次公开发行限售股上市流通的公告":    model.EventLockupExpiry,
+		"关于以集中竞价交易方式回购股份的回购报告书": model.EventBuyback,
+		"关于回购注销限制性股票的公告":        "",
+		"关于回购注销部分限制性股票的公告":      "",
+		"关于持股5%以上股东减持股份计划的公告":   model.EventReduction,
+		"2020年前三季度业绩预告":         model.EventPreAnnounce,
+		"关于筹划重大资产重组的停牌公告":       model.EventRestructure,
+		"2019年年度权益分派实施公告":       model.EventDividend,
+		"第七届董事会第十次会议决议公告":       "",
+	}
+	for title, want := range tests {
+		if got := TagAnnouncement(title); got != want {
+			t.Errorf("%s: want %q, got %q", title, want, got)
+		}
+	}
+}
+
+func TestParseAnnouncements(t *testing.T) {
+	defer useCassettes(t)()
+	body, e := util.HttpGetBytes(fmt.Sprintf(emAnnURL, annPageSize, 1, "600000"))
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	anns, pages, e := parseAnnouncements("600000", body)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if pages != 3 || len(anns) != 2 {
+		t.Fatalf("want 2 announcements of 3 pages, got %d of %d", len(anns), pages)
+	}
+	a := anns[0]
+	if a.Code != "600000" || a.Date != "2020-10-16" || a.Event.String != model.EventReduction ||
+		a.Category.String != "股东/实际控制人股份减持,减持计划" {
+		t.Errorf("unexpected announcement: %+v", a)
+	}
+	if anns[1].Event.Valid || anns[1].Category.Valid {
+		t.Errorf("want null event and category: %+v", anns[1])
+	}
+}
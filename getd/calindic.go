@@ -58,244 +58,203 @@ func CalcIndics(stocks *model.Stocks) (rstks *model.Stocks) {
 
 func doCalcIndices(chstk chan *model.Stock, wg *sync.WaitGroup, chrstk chan *model.Stock) {
 	defer wg.Done()
+	specs := enabledIndics()
 	for stock := range chstk {
-		code := stock.Code
-		var offd, offw, offm int64 = 10, 5, 5
-		lx := latestUFRXdxr(code)
-		if lx != nil {
-			offd, offw, offm = -1, -1, -1
+		// price history of reinstated klines may be revised by pending xdxr, recalculate everything
+		full := latestUFRXdxr(stock.Code) != nil
+		purgeKdjFeatDat(stock.Code)
+		for _, c := range append([]model.CYTP{model.DAY, model.WEEK, model.MONTH}, MinuteCycles()...) {
+			if c.Intraday() && (len(stock.Source) != 0 || len(stock.Type) != 0) {
+				//intraday klines are not available for indices and funds
+				continue
+			}
+			calcCycle(stock, c, specs, full)
 		}
-		purgeKdjFeatDat(code)
-		calcDay(stock, offd)
-		calcWeek(stock, offw)
-		calcMonth(stock, offm)
 		chrstk <- stock
 	}
 }
 
-func calcWeek(stk *model.Stock, offset int64) {
-	var (
-		err  error
-		code = stk.Code
-	)
-	tab := "index_w_n"
-	if len(stk.Source) == 0 { // non index
-		switch model.Rtype(conf.Args.DataSource.IndicatorSource) {
-		case model.Forward:
-			tab = "kline_w_f"
-		case model.Backward:
-			tab = "kline_w_b"
-		case model.None:
-			tab = "kline_w_n"
-		default:
-			panic("undefined reinstatement type:" + conf.Args.DataSource.IndicatorSource)
-		}
+//enabledIndics returns the indicator specs enabled by configuration, or all registered
+//indicators if none is configured.
+func enabledIndics() []*indc.Spec {
+	specs, e := indc.Specs(conf.Args.DataSource.Indicators...)
+	if e != nil {
+		log.Panicf("invalid indicator configuration: %+v", e)
 	}
+	return specs
+}
 
-	var qw []*model.TradeDataBasic
-	_, err = dbmap.Select(&qw, fmt.Sprintf("select code,date,klid,open,high,close,low,volume,amount,xrate "+
-		"from %s where code = ? order by klid", tab), code)
-	util.CheckErr(err, fmt.Sprintf("Failed to query %s for %s", tab, code))
+//IndicTable returns the name of the table storing the specified indicator for the given cycle.
+func IndicTable(name string, cycle model.CYTP) string {
+	return fmt.Sprintf("indicator_%s_%s", strings.ToLower(string(cycle)), name)
+}
 
-	indicators := indc.DeftKDJ(qw)
-	macd := indc.DeftMACD(qw)
-	for i, idc := range indicators {
-		idc.MACD = macd[i].MACD
-		idc.MACD_diff = macd[i].MACD_diff
-		idc.MACD_dea = macd[i].MACD_dea
+//indicSrcTable resolves the kline table from which indicators are calculated. Raw indicators are
+//calculated from the non-reinstated klines.
+func indicSrcTable(stk *model.Stock, cycle model.CYTP, raw bool) string {
+	c := strings.ToLower(string(cycle))
+	if len(stk.Source) != 0 { // index
+		return fmt.Sprintf("index_%s_n", c)
 	}
-	rsi := indc.DeftRSI(qw)
-	for i, idc := range indicators {
-		idc.RSI1 = rsi[i].RSI1
-		idc.RSI2 = rsi[i].RSI2
-		idc.RSI3 = rsi[i].RSI3
+	base := model.KlineMaster
+	if stk.Type == model.FundETF || stk.Type == model.FundLOF {
+		base = model.FundKline
 	}
-	bias := indc.DeftBIAS(qw)
-	for i, idc := range indicators {
-		idc.BIAS1 = bias[i].BIAS1
-		idc.BIAS2 = bias[i].BIAS2
-		idc.BIAS3 = bias[i].BIAS3
+	if raw {
+		return fmt.Sprintf("%s_%s_n", base, c)
 	}
-	boll := indc.DeftBOLL(qw)
-	for i, idc := range indicators {
-		idc.BOLL_lower = boll[i].BOLL_lower
-		idc.BOLL_lower_c = boll[i].BOLL_lower_c
-		idc.BOLL_lower_h = boll[i].BOLL_lower_h
-		idc.BOLL_lower_l = boll[i].BOLL_lower_l
-		idc.BOLL_lower_o = boll[i].BOLL_lower_o
-		idc.BOLL_mid = boll[i].BOLL_mid
-		idc.BOLL_mid_c = boll[i].BOLL_mid_c
-		idc.BOLL_mid_h = boll[i].BOLL_mid_h
-		idc.BOLL_mid_l = boll[i].BOLL_mid_l
-		idc.BOLL_mid_o = boll[i].BOLL_mid_o
-		idc.BOLL_upper = boll[i].BOLL_upper
-		idc.BOLL_upper_c = boll[i].BOLL_upper_c
-		idc.BOLL_upper_h = boll[i].BOLL_upper_h
-		idc.BOLL_upper_l = boll[i].BOLL_upper_l
-		idc.BOLL_upper_o = boll[i].BOLL_upper_o
-	}
-
-	binsIndc(indicators, "indicator_w")
-
-	if conf.Args.DataSource.SampleKdjFeature {
-		panic("function not refactored yet")
-		// SmpKdjFeat(code, model.WEEK, 5.0, 2.0, 2)
+	switch model.Rtype(conf.Args.DataSource.IndicatorSource) {
+	case model.Forward:
+		return fmt.Sprintf("%s_%s_f", base, c)
+	case model.Backward:
+		return fmt.Sprintf("%s_%s_b", base, c)
+	case model.None:
+		return fmt.Sprintf("%s_%s_n", base, c)
+	default:
+		panic("undefined reinstatement type:" + conf.Args.DataSource.IndicatorSource)
 	}
 }
 
-func calcMonth(stk *model.Stock, offset int64) {
-	var (
-		err  error
-		code = stk.Code
-	)
-
-	tab := "index_m_n"
-	if len(stk.Source) == 0 { // non index
-		switch model.Rtype(conf.Args.DataSource.IndicatorSource) {
-		case model.Forward:
-			tab = "kline_m_f"
-		case model.Backward:
-			tab = "kline_m_b"
-		case model.None:
-			tab = "kline_m_n"
-		default:
-			panic("undefined reinstatement type:" + conf.Args.DataSource.IndicatorSource)
-		}
-	}
-
-	var qm []*model.TradeDataBasic
-	_, err = dbmap.Select(&qm, fmt.Sprintf("select code,date,klid,open,high,close,low,volume,amount,xrate "+
-		"from %s where code = ? order by klid", tab), code)
-	util.CheckErr(err, fmt.Sprintf("Failed to query %s for %s", tab, code))
-
-	indicators := indc.DeftKDJ(qm)
-	macd := indc.DeftMACD(qm)
-	for i, idc := range indicators {
-		idc.MACD = macd[i].MACD
-		idc.MACD_diff = macd[i].MACD_diff
-		idc.MACD_dea = macd[i].MACD_dea
+//calcCycle calculates indicators of the given cycle, continuing from the persisted states
+//unless full recalculation is requested or the states are no longer valid.
+func calcCycle(stk *model.Stock, cycle model.CYTP, specs []*indc.Spec, full bool) {
+	sts := make(map[string]*indc.State)
+	if !full {
+		sts = loadIndicStates(stk.Code, cycle)
 	}
-	rsi := indc.DeftRSI(qm)
-	for i, idc := range indicators {
-		idc.RSI1 = rsi[i].RSI1
-		idc.RSI2 = rsi[i].RSI2
-		idc.RSI3 = rsi[i].RSI3
-	}
-	bias := indc.DeftBIAS(qm)
-	for i, idc := range indicators {
-		idc.BIAS1 = bias[i].BIAS1
-		idc.BIAS2 = bias[i].BIAS2
-		idc.BIAS3 = bias[i].BIAS3
+	var tabs []string
+	groups := make(map[string][]*indc.Spec)
+	for _, s := range specs {
+		tab := indicSrcTable(stk, cycle, s.Raw)
+		if _, ok := groups[tab]; !ok {
+			tabs = append(tabs, tab)
+		}
+		groups[tab] = append(groups[tab], s)
 	}
-	boll := indc.DeftBOLL(qm)
-	for i, idc := range indicators {
-		idc.BOLL_lower = boll[i].BOLL_lower
-		idc.BOLL_lower_c = boll[i].BOLL_lower_c
-		idc.BOLL_lower_h = boll[i].BOLL_lower_h
-		idc.BOLL_lower_l = boll[i].BOLL_lower_l
-		idc.BOLL_lower_o = boll[i].BOLL_lower_o
-		idc.BOLL_mid = boll[i].BOLL_mid
-		idc.BOLL_mid_c = boll[i].BOLL_mid_c
-		idc.BOLL_mid_h = boll[i].BOLL_mid_h
-		idc.BOLL_mid_l = boll[i].BOLL_mid_l
-		idc.BOLL_mid_o = boll[i].BOLL_mid_o
-		idc.BOLL_upper = boll[i].BOLL_upper
-		idc.BOLL_upper_c = boll[i].BOLL_upper_c
-		idc.BOLL_upper_h = boll[i].BOLL_upper_h
-		idc.BOLL_upper_l = boll[i].BOLL_upper_l
-		idc.BOLL_upper_o = boll[i].BOLL_upper_o
+	for _, tab := range tabs {
+		calcCycleSrc(stk.Code, cycle, tab, groups[tab], sts)
 	}
 
-	binsIndc(indicators, "indicator_m")
-
 	if conf.Args.DataSource.SampleKdjFeature {
 		panic("function not refactored yet")
-		// SmpKdjFeat(code, model.MONTH, 5.0, 2.0, 2)
+		// SmpKdjFeat(code, cycle, 5.0, 2.0, 2)
 	}
 }
 
-func calcDay(stk *model.Stock, offset int64) {
-	var (
-		err  error
-		code = stk.Code
-	)
+//calcCycleSrc calculates the indicators from the given kline table.
+func calcCycleSrc(code string, cycle model.CYTP, tab string, specs []*indc.Spec, sts map[string]*indc.State) {
+	// query from the earliest last consumed bar, which is required to validate the state
+	from := -1
+	for i, s := range specs {
+		st, ok := sts[s.Name]
+		if !ok || st.Count == 0 {
+			from = -1
+			break
+		}
+		if i == 0 || st.Klid < from {
+			from = st.Klid
+		}
+	}
+	q := queryIndicSrc(tab, code, from)
+	var qall []*model.TradeDataBasic
+	if from < 0 {
+		qall = q
+	}
 
-	tab := "index_d_n"
-	if len(stk.Source) == 0 { //non index
-		switch model.Rtype(conf.Args.DataSource.IndicatorSource) {
-		case model.Forward:
-			tab = "kline_d_f"
-		case model.Backward:
-			tab = "kline_d_b"
-		case model.None:
-			tab = "kline_d_n"
-		default:
-			panic("undefined reinstatement type:" + conf.Args.DataSource.IndicatorSource)
+	for _, s := range specs {
+		st, ok := sts[s.Name]
+		var bars []*model.TradeDataBasic
+		if ok && st.Count > 0 {
+			idx := -1
+			for i, b := range q {
+				if b.Klid == st.Klid {
+					idx = i
+					break
+				}
+			}
+			if idx >= 0 && st.Valid(s, q[idx]) {
+				bars = q[idx+1:]
+			} else {
+				log.Debugf("%s %s state for %s invalidated, recalculating", code, s.Name, cycle)
+				ok = false
+			}
 		}
+		if !ok || st.Count == 0 {
+			if qall == nil {
+				qall = queryIndicSrc(tab, code, -1)
+			}
+			st = s.NewState()
+			bars = qall
+		}
+		if len(bars) == 0 {
+			continue
+		}
+		// the latest bars may be revised by subsequent fetching (e.g. current week or month),
+		// keep the persisted state behind them so they will be recalculated next time.
+		cut := int(math.Max(0, float64(len(bars)-IndicStateLag)))
+		vals := s.Update(st, bars[:cut])
+		if cut > 0 {
+			saveIndicState(code, cycle, s.Name, st)
+		}
+		vals = append(vals, s.Update(st.Clone(), bars[cut:])...)
+		binsIndc(s, cycle, bars, vals)
 	}
+}
 
-	var qd []*model.TradeDataBasic
-	_, err = dbmap.Select(&qd, fmt.Sprintf("select code,date,klid,open,high,close,low,volume,amount,xrate from "+
-		"%s where code = ? order by klid", tab), code)
+//queryIndicSrc queries trade data from the given table with klid greater than or equal to
+//the specified one, or all trade data if klid is negative.
+func queryIndicSrc(tab, code string, klid int) (q []*model.TradeDataBasic) {
+	_, err := dbmap.Select(&q, fmt.Sprintf("select code,date,klid,open,high,close,low,volume,amount,xrate from "+
+		"%s where code = ? and klid >= ? order by klid", tab), code, klid)
 	util.CheckErr(err, fmt.Sprintf("Failed to query %s for %s", tab, code))
+	return
+}
 
-	indicators := indc.DeftKDJ(qd)
-	macd := indc.DeftMACD(qd)
-	for i, idc := range indicators {
-		idc.MACD = macd[i].MACD
-		idc.MACD_diff = macd[i].MACD_diff
-		idc.MACD_dea = macd[i].MACD_dea
-	}
-	rsi := indc.DeftRSI(qd)
-	for i, idc := range indicators {
-		idc.RSI1 = rsi[i].RSI1
-		idc.RSI2 = rsi[i].RSI2
-		idc.RSI3 = rsi[i].RSI3
+var indicTables sync.Map
+
+//ensureIndicTable creates the storage table for the indicator if it does not exist yet.
+func ensureIndicTable(spec *indc.Spec, cycle model.CYTP) string {
+	table := IndicTable(spec.Name, cycle)
+	if _, ok := indicTables.Load(table); ok {
+		return table
 	}
-	bias := indc.DeftBIAS(qd)
-	for i, idc := range indicators {
-		idc.BIAS1 = bias[i].BIAS1
-		idc.BIAS2 = bias[i].BIAS2
-		idc.BIAS3 = bias[i].BIAS3
+	cols := make([]string, len(spec.Cols))
+	for i, c := range spec.Cols {
+		cols[i] = fmt.Sprintf("`%s` double DEFAULT NULL,", c)
 	}
-	boll := indc.DeftBOLL(qd)
-	for i, idc := range indicators {
-		idc.BOLL_lower = boll[i].BOLL_lower
-		idc.BOLL_lower_c = boll[i].BOLL_lower_c
-		idc.BOLL_lower_h = boll[i].BOLL_lower_h
-		idc.BOLL_lower_l = boll[i].BOLL_lower_l
-		idc.BOLL_lower_o = boll[i].BOLL_lower_o
-		idc.BOLL_mid = boll[i].BOLL_mid
-		idc.BOLL_mid_c = boll[i].BOLL_mid_c
-		idc.BOLL_mid_h = boll[i].BOLL_mid_h
-		idc.BOLL_mid_l = boll[i].BOLL_mid_l
-		idc.BOLL_mid_o = boll[i].BOLL_mid_o
-		idc.BOLL_upper = boll[i].BOLL_upper
-		idc.BOLL_upper_c = boll[i].BOLL_upper_c
-		idc.BOLL_upper_h = boll[i].BOLL_upper_h
-		idc.BOLL_upper_l = boll[i].BOLL_upper_l
-		idc.BOLL_upper_o = boll[i].BOLL_upper_o
+	stmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` ("+
+		"`Code` varchar(8) NOT NULL,"+
+		"`Date` varchar(20) NOT NULL,"+
+		"`Klid` int(11) NOT NULL,"+
+		"%s"+
+		"`udate` varchar(10) DEFAULT NULL COMMENT '更新日期',"+
+		"`utime` varchar(8) DEFAULT NULL COMMENT '更新时间',"+
+		"PRIMARY KEY (`Code`,`Klid`)"+
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci", table, strings.Join(cols, ""))
+	if _, e := dbmap.Exec(stmt); e != nil {
+		log.Panicf("failed to create table %s: %+v", table, e)
 	}
+	indicTables.Store(table, true)
+	return table
+}
 
-	binsIndc(indicators, "indicator_d")
-
-	if conf.Args.DataSource.SampleKdjFeature {
-		panic("function not refactored yet")
-		// SmpKdjFeat(code, model.DAY, 5.0, 2.0, 2)
+//indicDate returns the date of the indicator row, which keeps the time of intraday bars.
+func indicDate(cycle model.CYTP, date string) string {
+	if cycle.Intraday() || len(date) <= 10 {
+		return date
 	}
+	return date[:10]
 }
 
-func binsIndc(indc []*model.Indicator, table string) (c int) {
-	if len(indc) == 0 {
+func binsIndc(spec *indc.Spec, cycle model.CYTP, src []*model.TradeDataBasic, vals [][]float64) (c int) {
+	if len(src) == 0 {
 		return
 	}
+	table := ensureIndicTable(spec, cycle)
 	retry := conf.Args.DeadlockRetry
 	rt := 0
-	sklid := indc[0].Klid
-	if len(indc) > 5 {
-		sklid = indc[len(indc)-5].Klid
-	}
-	code := indc[0].Code
+	sklid := src[0].Klid
+	code := src[0].Code
 	var e error
 	for ; rt < retry; rt++ {
 		stmt := fmt.Sprintf("delete from %s where code = ? and klid >= ?", table)
@@ -314,85 +273,47 @@ func binsIndc(indc []*model.Indicator, table string) (c int) {
 		log.Panicf("%s failed to delete %s where klid > %d", code, table, sklid)
 	}
 	batchSize := 200
-	for idx := 0; idx < len(indc); idx += batchSize {
-		end := int(math.Min(float64(len(indc)), float64(idx+batchSize)))
-		c += insertIndicMiniBatch(indc[idx:end], table)
+	for idx := 0; idx < len(src); idx += batchSize {
+		end := int(math.Min(float64(len(src)), float64(idx+batchSize)))
+		c += insertIndicMiniBatch(spec, cycle, table, src[idx:end], vals[idx:end])
 	}
 	return
 }
 
-func insertIndicMiniBatch(indc []*model.Indicator, table string) (c int) {
-	numFields := 32
+func insertIndicMiniBatch(spec *indc.Spec, cycle model.CYTP, table string, src []*model.TradeDataBasic,
+	vals [][]float64) (c int) {
+	numFields := len(spec.Cols) + 5
 	holders := make([]string, numFields)
 	for i := range holders {
 		holders[i] = "?"
 	}
 	holderString := fmt.Sprintf("(%s)", strings.Join(holders, ","))
-	valueStrings := make([]string, 0, len(indc))
-	valueArgs := make([]interface{}, 0, len(indc)*numFields)
-	code := indc[0].Code
+	valueStrings := make([]string, 0, len(src))
+	valueArgs := make([]interface{}, 0, len(src)*numFields)
+	code := src[0].Code
 	var e error
-	for _, i := range indc {
-		d, t := util.TimeStr()
-		i.Udate.Valid = true
-		i.Utime.Valid = true
-		i.Udate.String = d
-		i.Utime.String = t
+	d, t := util.TimeStr()
+	for i, s := range src {
 		valueStrings = append(valueStrings, holderString)
-		valueArgs = append(valueArgs, i.Code)
-		valueArgs = append(valueArgs, i.Date)
-		valueArgs = append(valueArgs, i.Klid)
-		valueArgs = append(valueArgs, i.KDJ_K)
-		valueArgs = append(valueArgs, i.KDJ_D)
-		valueArgs = append(valueArgs, i.KDJ_J)
-		valueArgs = append(valueArgs, i.MACD)
-		valueArgs = append(valueArgs, i.MACD_diff)
-		valueArgs = append(valueArgs, i.MACD_dea)
-		valueArgs = append(valueArgs, i.RSI1)
-		valueArgs = append(valueArgs, i.RSI2)
-		valueArgs = append(valueArgs, i.RSI3)
-		valueArgs = append(valueArgs, i.BIAS1)
-		valueArgs = append(valueArgs, i.BIAS2)
-		valueArgs = append(valueArgs, i.BIAS3)
-		valueArgs = append(valueArgs, i.BOLL_lower)
-		valueArgs = append(valueArgs, i.BOLL_lower_c)
-		valueArgs = append(valueArgs, i.BOLL_lower_h)
-		valueArgs = append(valueArgs, i.BOLL_lower_l)
-		valueArgs = append(valueArgs, i.BOLL_lower_o)
-		valueArgs = append(valueArgs, i.BOLL_mid)
-		valueArgs = append(valueArgs, i.BOLL_mid_c)
-		valueArgs = append(valueArgs, i.BOLL_mid_h)
-		valueArgs = append(valueArgs, i.BOLL_mid_l)
-		valueArgs = append(valueArgs, i.BOLL_mid_o)
-		valueArgs = append(valueArgs, i.BOLL_upper)
-		valueArgs = append(valueArgs, i.BOLL_upper_c)
-		valueArgs = append(valueArgs, i.BOLL_upper_h)
-		valueArgs = append(valueArgs, i.BOLL_upper_l)
-		valueArgs = append(valueArgs, i.BOLL_upper_o)
-		valueArgs = append(valueArgs, i.Udate)
-		valueArgs = append(valueArgs, i.Utime)
+		valueArgs = append(valueArgs, s.Code)
+		valueArgs = append(valueArgs, indicDate(cycle, s.Date))
+		valueArgs = append(valueArgs, s.Klid)
+		for _, v := range vals[i] {
+			valueArgs = append(valueArgs, v)
+		}
+		valueArgs = append(valueArgs, d)
+		valueArgs = append(valueArgs, t)
 	}
 
+	upds := make([]string, len(spec.Cols))
+	for i, col := range spec.Cols {
+		upds[i] = fmt.Sprintf("%[1]s=values(%[1]s)", col)
+	}
 	retry := conf.Args.DeadlockRetry
 	rt := 0
-	stmt := fmt.Sprintf("INSERT INTO %s (code,date,klid,kdj_k,kdj_d,kdj_j,macd,macd_diff,macd_dea,"+
-		"rsi1,rsi2,rsi3,bias1,bias2,bias3,"+
-		"boll_lower,boll_lower_c,boll_lower_h,boll_lower_l,boll_lower_o,"+
-		"boll_mid,boll_mid_c,boll_mid_h,boll_mid_l,boll_mid_o,"+
-		"boll_upper,boll_upper_c,boll_upper_h,boll_upper_l,boll_upper_o,"+
-		"udate,utime) VALUES %s on "+
-		"duplicate key update date=values(date),kdj_k=values(kdj_k),kdj_d=values(kdj_d),kdj_j=values"+
-		"(kdj_j),macd=values(macd),macd_diff=values(macd_diff),macd_dea=values(macd_dea),"+
-		"rsi1=values(rsi1),rsi2=values(rsi2),rsi3=values(rsi3),"+
-		"bias1=values(bias1),bias2=values(bias2),bias3=values(bias3),"+
-		"boll_lower=values(boll_lower),boll_lower_c=values(boll_lower_c),boll_lower_h=values(boll_lower_h),"+
-		"boll_lower_l=values(boll_lower_l),boll_lower_o=values(boll_lower_o),"+
-		"boll_mid=values(boll_mid),boll_mid_c=values(boll_mid_c),boll_mid_h=values(boll_mid_h),"+
-		"boll_mid_l=values(boll_mid_l),boll_mid_o=values(boll_mid_o),"+
-		"boll_upper=values(boll_upper),boll_upper_c=values(boll_upper_c),boll_upper_h=values(boll_upper_h),"+
-		"boll_upper_l=values(boll_upper_l),boll_upper_o=values(boll_upper_o),"+
-		"udate=values(udate),utime=values(utime)",
-		table, strings.Join(valueStrings, ","))
+	stmt := fmt.Sprintf("INSERT INTO %s (code,date,klid,%s,udate,utime) VALUES %s on "+
+		"duplicate key update date=values(date),%s,udate=values(udate),utime=values(utime)",
+		table, strings.Join(spec.Cols, ","), strings.Join(valueStrings, ","), strings.Join(upds, ","))
 	for ; rt < retry; rt++ {
 		_, e = dbmap.Exec(stmt, valueArgs...)
 		if e != nil {
@@ -403,7 +324,7 @@ func insertIndicMiniBatch(indc []*model.Indicator, table string) (c int) {
 				log.Panicf("%s failed to overwrite %s: %+v", code, table, e)
 			}
 		}
-		return len(indc)
+		return len(src)
 	}
 	log.Panicf("%s failed to overwrite %s: %+v", code, table, e)
 	return
@@ -29,3 +29,18 @@ func TestCalIndicators4Indices(t *testing.T) {
 	}
 	CalcIndics(allstk)
 }
+
+func TestIndicDate(t *testing.T) {
+	for _, c := range []struct {
+		cycle      model.CYTP
+		date, want string
+	}{
+		{model.DAY, "2020-01-02", "2020-01-02"},
+		{model.DAY, "2020-01-02 00:00:00", "2020-01-02"},
+		{model.M30, "2020-01-02 10:00", "2020-01-02 10:00"},
+	} {
+		if d := indicDate(c.cycle, c.date); d != c.want {
+			t.Errorf("%s %s: want %s, got %s", c.cycle, c.date, c.want, d)
+		}
+	}
+}
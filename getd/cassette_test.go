@@ -0,0 +1,173 @@
+package getd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+)
+
+//useCassettes serves HTTP requests from the cassettes under testdata until the returned
+//function is called. Set STOCK_CASSETTE=record to access the network and record the responses
+//instead, e.g. to refresh the cassettes after a site changes its layout:
+//
+//	STOCK_CASSETTE=record go test -run 'Replay|Read10jqk|ReadFinPage|Parse' ./getd
+//
+//The values asserted by the tests should be updated to the recorded responses afterwards.
+func useCassettes(t *testing.T) func() {
+	c := conf.Args.Network.Cassette
+	conf.Args.Network.Cassette.Mode = conf.CassetteReplay
+	if os.Getenv("STOCK_CASSETTE") == conf.CassetteRecord {
+		conf.Args.Network.Cassette.Mode = conf.CassetteRecord
+	}
+	conf.Args.Network.Cassette.Path = "testdata/cassettes"
+	conf.Args.Network.Cassette.IgnoreParams = []string{"_"}
+	util.ResetCassettes()
+	return func() {
+		conf.Args.Network.Cassette = c
+	}
+}
+
+func assertFloat(t *testing.T, name string, want, got float64) {
+	t.Helper()
+	if math.Abs(want-got) > 1e-6 {
+		t.Errorf("%s: want %v, got %v", name, want, got)
+	}
+}
+
+func TestReplayXQKline(t *testing.T) {
+	defer useCassettes(t)()
+	//XQ timestamps are at the midnight of Beijing time, and the 3 klines before 2020-01-23 are requested
+	loc := time.Local
+	time.Local = time.FixedZone("CST", 8*3600)
+	defer func() { time.Local = loc }()
+
+	url := `https://stock.xueqiu.com/v5/stock/chart/kline.json?` +
+		`symbol=SZ000585&begin=1579708800000&period=day&type=before&count=-3&indicator=kline`
+	res, e := util.HTTPGet(url, nil, nil)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	defer res.Body.Close()
+	data, e := ioutil.ReadAll(res.Body)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	xqk := &model.XQKline{Code: "000585"}
+	if e = json.Unmarshal(data, xqk); e != nil {
+		t.Fatalf("%+v", e)
+	}
+	ks := xqk.GetData(false)
+	if len(ks) != 3 {
+		t.Fatalf("want 3 klines, got %d", len(ks))
+	}
+	for i, d := range []string{"2020-01-20", "2020-01-21", "2020-01-22"} {
+		if ks[i].Date != d {
+			t.Errorf("#%d date: want %s, got %s", i, d, ks[i].Date)
+		}
+	}
+	k := ks[1]
+	assertFloat(t, "open", 2.37, k.Open)
+	assertFloat(t, "high", 2.38, k.High)
+	assertFloat(t, "low", 2.30, k.Low)
+	assertFloat(t, "close", 2.31, k.Close)
+	assertFloat(t, "volume", 6012300, k.Volume.Float64)
+	assertFloat(t, "xrate", 0.69, k.Xrate.Float64)
+	assertFloat(t, "amount", 14000000, k.Amount.Float64)
+}
+
+func TestReplayEMKline(t *testing.T) {
+	defer useCassettes(t)()
+	url := `http://pdfm.eastmoney.com/EM_UBG_PDTI_Fast/api/js?&rtntype=5&id=0005852&type=k&authorityType=`
+	res, e := util.HTTPGet(url, nil, nil)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	defer res.Body.Close()
+	data, e := ioutil.ReadAll(res.Body)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	emk := &model.EMKline{Code: "000585"}
+	//strip parentheses
+	if e = json.Unmarshal(data[1:len(data)-1], emk); e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if len(emk.Data) != 2 {
+		t.Fatalf("want 2 klines, got %d", len(emk.Data))
+	}
+	k := emk.DataMap["2020-01-20"]
+	if k == nil {
+		t.Fatalf("kline of 2020-01-20 not found: %+v", emk.Dates)
+	}
+	assertFloat(t, "open", 2.35, k.Open)
+	assertFloat(t, "close", 2.37, k.Close)
+	assertFloat(t, "high", 2.39, k.High)
+	assertFloat(t, "low", 2.33, k.Low)
+	assertFloat(t, "volume", 5236100, k.Volume.Float64)
+	assertFloat(t, "amount", 12345600, k.Amount.Float64)
+	assertFloat(t, "varate", 2.56, k.Varate.Float64)
+	assertFloat(t, "xrate", 0.60, k.Xrate.Float64)
+}
+
+func TestRead10jqkBonus(t *testing.T) {
+	defer useCassettes(t)()
+	stk := &model.Stock{Code: "000585", Name: "东北电气"}
+	url := `http://basic.10jqka.com.cn/000585/bonus.html`
+	res, e := util.HTTPGetResponse(url, nil, false, true, true)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	defer res.Body.Close()
+	xdxrs, ok, _ := read10jqkBonus(stk, url, res.Body)
+	if !ok {
+		t.Fatal("failed to parse bonus page")
+	}
+	if len(xdxrs) != 2 {
+		t.Fatalf("want 2 xdxr records, got %d", len(xdxrs))
+	}
+	x := xdxrs[0]
+	if x.Idx != 1 || x.ReportYear.String != "2018年报" || x.XdxrDate.String != "2019-06-17" {
+		t.Errorf("unexpected xdxr: %+v", x)
+	}
+	assertFloat(t, "shares allot", 3, x.SharesAllot.Float64)
+	assertFloat(t, "shares cvt", 2, x.SharesCvt.Float64)
+	assertFloat(t, "divi", 1.5, x.Divi.Float64)
+	x = xdxrs[1]
+	if x.Idx != 0 || x.XdxrDate.Valid || x.Divi.Valid {
+		t.Errorf("unexpected xdxr: %+v", x)
+	}
+}
+
+func TestReadFinPage(t *testing.T) {
+	defer useCassettes(t)()
+	url := `http://basic.10jqka.com.cn/000585/finance.html`
+	res, e := util.HTTPGetResponse(url, nil, false, true, true)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	defer res.Body.Close()
+	fins, ok, _ := readFinPage(url, "000585", res.Body)
+	if !ok {
+		t.Fatal("failed to parse finance page")
+	}
+	if len(fins) != 3 {
+		t.Fatalf("want 3 finance records, got %d", len(fins))
+	}
+	f := fins[0]
+	if f.Code != "000585" || f.Year != "2019-12-31" {
+		t.Errorf("unexpected finance: %+v", f)
+	}
+	assertFloat(t, "eps", 0.52, f.Eps.Float64)
+	assertFloat(t, "np", 5.2, f.Np.Float64)
+	assertFloat(t, "navps", 4.5, f.Navps.Float64)
+	assertFloat(t, "roe", 12.1, f.Roe.Float64)
+	assertFloat(t, "eps yoy", 30, f.EpsYoy.Float64)
+	assertFloat(t, "ocfps yoy", 100./3., f.OcfpsYoy.Float64)
+}
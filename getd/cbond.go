@@ -0,0 +1,602 @@
+package getd
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/global"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+//cbConvPriceRefresh is the number of days before the latest recorded conversion price change from which the
+//changes are fetched again.
+const cbConvPriceRefresh = 30
+
+var (
+	//cbRateRegex matches the coupon rates in the interest rate explanation, e.g. 第一年0.30%、第二年0.50%
+	cbRateRegex = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*[%％]`)
+	//cbRedeemRegex matches the redemption price at maturity, e.g. 面值的110% or 108元
+	cbRedeemRegex = regexp.MustCompile(`(\d{3}(?:\.\d+)?)\s*(?:[%％]|元)`)
+)
+
+//emCBond is a row of the convertible bond list of eastmoney.
+type emCBond struct {
+	Code          string   `json:"SECURITY_CODE"`
+	SecuCode      string   `json:"SECUCODE"`
+	Name          string   `json:"SECURITY_NAME_ABBR"`
+	StockCode     string   `json:"CONVERT_STOCK_CODE"`
+	ValueDate     *string  `json:"VALUE_DATE"`
+	ListDate      *string  `json:"LISTING_DATE"`
+	Maturity      *string  `json:"EXPIRE_DATE"`
+	DelistDate    *string  `json:"DELIST_DATE"`
+	IssueSize     *float64 `json:"ACTUAL_ISSUE_SCALE"`
+	InitConvPrice *float64 `json:"INITIAL_TRANSFER_PRICE"`
+	ConvPrice     *float64 `json:"TRANSFER_PRICE"`
+	RateExplain   string   `json:"INTEREST_RATE_EXPLAIN"`
+	CallClause    string   `json:"REDEEM_CLAUSE"`
+	PutClause     string   `json:"RESALE_CLAUSE"`
+	CallTrigger   *float64 `json:"REDEEM_TRIG_PRICE"`
+	PutTrigger    *float64 `json:"RESALE_TRIG_PRICE"`
+}
+
+//emConvPrice is a row of the conversion price changes of the convertible bonds of eastmoney.
+type emConvPrice struct {
+	Code string `json:"SECURITY_CODE"`
+	//effective date of the new price
+	Date      string   `json:"CHANGE_DATE"`
+	ConvPrice *float64 `json:"CONVERT_PRICE"`
+}
+
+//cbondClose is the close of the convertible bond joined with the close of the underlying stock.
+type cbondClose struct {
+	Date       string
+	Close      float64
+	StockClose sql.NullFloat64 `db:"stock_close"`
+}
+
+//CBondStocks loads the listed convertible bonds as stocks, with the Type field set.
+func CBondStocks() *model.Stocks {
+	var cbs []*model.CBond
+	_, e := dbmap.Select(&cbs, "select * from cbond where list_date is not null and delist_date is null "+
+		"order by code")
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("failed to query convertible bond list: %+v", e)
+	}
+	log.Printf("%d convertible bonds queried from db", len(cbs))
+	stks := new(model.Stocks)
+	for _, cb := range cbs {
+		stks.Add(&model.Stock{
+			Code:   cb.Code,
+			Name:   cb.Name,
+			Market: sql.NullString{String: cb.Market, Valid: true},
+			Type:   model.BondCB,
+		})
+	}
+	return stks
+}
+
+//GetCBonds fetches the convertible bond list and the conversion price changes. Returns the listed
+//convertible bonds if successful, or none otherwise.
+func GetCBonds() *model.Stocks {
+	log.Println("getting convertible bond list...")
+	if e := getCBonds(); e != nil {
+		log.Warnf("failed to get convertible bond list: %+v", e)
+		return new(model.Stocks)
+	}
+	return CBondStocks()
+}
+
+func getCBonds() (e error) {
+	var cbs []*model.CBond
+	for page, pages := 1, 1; page <= pages; page++ {
+		body, e := fetchEmReport("RPT_BOND_CB_LIST", "SECURITY_CODE", "", page)
+		if e != nil {
+			return errors.Wrap(e, "failed to get convertible bond list")
+		}
+		var ps []*model.CBond
+		if ps, pages, e = parseCBond(body); e != nil {
+			return e
+		}
+		cbs = append(cbs, ps...)
+	}
+	log.Printf("%d convertible bonds fetched", len(cbs))
+	if e = saveCBond(cbs); e != nil {
+		return e
+	}
+	var hist []*model.CBondConvPrice
+	_, e = dbmap.Select(&hist, "select * from cbond_conv_price")
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		return errors.Wrap(e, "failed to query conversion prices")
+	}
+	since := ""
+	for _, h := range hist {
+		if h.Date > since {
+			since = h.Date
+		}
+	}
+	if since != "" {
+		//resets may be announced after they become effective
+		d, e := time.Parse(global.DateFormat, since)
+		if e != nil {
+			return errors.Wrapf(e, "invalid conversion price date: %s", since)
+		}
+		since = d.AddDate(0, 0, -cbConvPriceRefresh).Format(global.DateFormat)
+	}
+	chgs, e := getConvPriceChanges(since)
+	if e != nil {
+		return e
+	}
+	cps := newConvPrices(cbs, hist, chgs)
+	log.Printf("%d conversion price changes found", len(cps))
+	return saveConvPrice(cps)
+}
+
+//getConvPriceChanges fetches the conversion price changes of the convertible bonds effective since the date,
+//or all history if the date is empty.
+func getConvPriceChanges(since string) (cps []*model.CBondConvPrice, e error) {
+	filter := ""
+	if since != "" {
+		filter = fmt.Sprintf("(CHANGE_DATE>='%s')", since)
+	}
+	for page, pages := 1, 1; page <= pages; page++ {
+		body, e := fetchEmReport("RPT_BOND_CB_CLZGJ", "CHANGE_DATE", filter, page)
+		if e != nil {
+			return nil, errors.Wrap(e, "failed to get conversion price changes")
+		}
+		var ps []*model.CBondConvPrice
+		if ps, pages, e = parseConvPriceChange(body); e != nil {
+			return nil, e
+		}
+		cps = append(cps, ps...)
+	}
+	return
+}
+
+func parseConvPriceChange(body []byte) (cps []*model.CBondConvPrice, pages int, e error) {
+	var rows []*emConvPrice
+	if pages, e = parseEmReport(body, &rows); e != nil {
+		return nil, 0, e
+	}
+	for _, r := range rows {
+		if r.ConvPrice == nil {
+			continue
+		}
+		date, e := emDate(r.Date)
+		if e != nil {
+			return nil, 0, errors.Wrapf(e, "invalid conversion price change of %s", r.Code)
+		}
+		cps = append(cps, &model.CBondConvPrice{Code: r.Code, Date: date, ConvPrice: *r.ConvPrice})
+	}
+	return cps, pages, nil
+}
+
+func parseCBond(body []byte) (cbs []*model.CBond, pages int, e error) {
+	var rows []*emCBond
+	if pages, e = parseEmReport(body, &rows); e != nil {
+		return nil, 0, e
+	}
+	date := func(s *string) (d sql.NullString, e error) {
+		if s == nil || *s == "" {
+			return
+		}
+		d.String, e = emDate(*s)
+		d.Valid = e == nil
+		return
+	}
+	text := func(s string) sql.NullString {
+		s = strings.TrimSpace(s)
+		return sql.NullString{String: s, Valid: s != ""}
+	}
+	for _, r := range rows {
+		mkt := ""
+		if i := strings.LastIndex(r.SecuCode, "."); i >= 0 {
+			mkt = r.SecuCode[i+1:]
+		}
+		if mkt != model.MarketSH && mkt != model.MarketSZ {
+			log.Warnf("%s unsupported market of convertible bond: %s", r.Code, r.SecuCode)
+			continue
+		}
+		cb := &model.CBond{
+			Code:          r.Code,
+			Market:        mkt,
+			Name:          r.Name,
+			StockCode:     r.StockCode,
+			IssueSize:     emNullFloat(r.IssueSize),
+			InitConvPrice: emNullFloat(r.InitConvPrice),
+			ConvPrice:     emNullFloat(r.ConvPrice),
+			CallTrigger:   emNullFloat(r.CallTrigger),
+			PutTrigger:    emNullFloat(r.PutTrigger),
+			CallClause:    text(r.CallClause),
+			PutClause:     text(r.PutClause),
+		}
+		for _, d := range []struct {
+			src *string
+			dst *sql.NullString
+		}{
+			{r.ValueDate, &cb.ValueDate},
+			{r.ListDate, &cb.ListDate},
+			{r.Maturity, &cb.Maturity},
+			{r.DelistDate, &cb.DelistDate},
+		} {
+			if *d.dst, e = date(d.src); e != nil {
+				return nil, 0, errors.Wrapf(e, "invalid date of convertible bond %s", r.Code)
+			}
+		}
+		cps := parseCoupons(r.RateExplain)
+		if len(cps) > 0 {
+			strs := make([]string, len(cps))
+			for i, c := range cps {
+				strs[i] = strconv.FormatFloat(c, 'f', -1, 64)
+			}
+			cb.Coupons = sql.NullString{String: strings.Join(strs, ","), Valid: true}
+			cb.RedeemPrice = parseRedeemPrice(r.CallClause, cps[len(cps)-1])
+		}
+		cbs = append(cbs, cb)
+	}
+	return cbs, pages, nil
+}
+
+//parseCoupons returns the annual coupon rates (%) in the interest rate explanation in order.
+func parseCoupons(explain string) (cps []float64) {
+	for _, m := range cbRateRegex.FindAllStringSubmatch(explain, -1) {
+		f, e := strconv.ParseFloat(m[1], 64)
+		if e != nil {
+			return nil
+		}
+		cps = append(cps, f)
+	}
+	return
+}
+
+//parseRedeemPrice returns the redemption price at maturity in the redemption clause, including the last
+//coupon which is added if the clause states otherwise.
+func parseRedeemPrice(clause string, lastCoupon float64) (p sql.NullFloat64) {
+	i := strings.Index(clause, "到期赎回")
+	if i < 0 {
+		if i = strings.Index(clause, "期满后"); i < 0 {
+			return
+		}
+	}
+	clause = clause[i:]
+	m := cbRedeemRegex.FindStringSubmatchIndex(clause)
+	if m == nil {
+		return
+	}
+	f, e := strconv.ParseFloat(clause[m[2]:m[3]], 64)
+	if e != nil || f < 100 || f > 150 {
+		return
+	}
+	//the statement of the last coupon follows the price closely
+	tail := clause[m[1]:]
+	if len(tail) > 60 {
+		tail = tail[:60]
+	}
+	if strings.Contains(tail, "不含最后一期") {
+		f += lastCoupon
+	}
+	return sql.NullFloat64{Float64: f, Valid: true}
+}
+
+//newConvPrices returns the conversion prices of the bonds not yet in the history, or changed since. The
+//initial price is taken as effective from the value date if a bond has no history at all.
+func newConvPrices(cbs []*model.CBond, hist, chgs []*model.CBondConvPrice) (cps []*model.CBondConvPrice) {
+	hmap := make(map[string]map[string]float64)
+	for _, h := range hist {
+		if hmap[h.Code] == nil {
+			hmap[h.Code] = make(map[string]float64)
+		}
+		hmap[h.Code][h.Date] = h.ConvPrice
+	}
+	listed := make(map[string]bool, len(cbs))
+	for _, cb := range cbs {
+		listed[cb.Code] = true
+		if _, ok := hmap[cb.Code]; ok || !cb.InitConvPrice.Valid {
+			continue
+		}
+		since := cb.ValueDate
+		if !since.Valid {
+			since = cb.ListDate
+		}
+		if !since.Valid {
+			continue
+		}
+		cps = append(cps, &model.CBondConvPrice{Code: cb.Code, Date: since.String, ConvPrice: cb.InitConvPrice.Float64})
+	}
+	for _, c := range chgs {
+		if !listed[c.Code] {
+			continue
+		}
+		if p, ok := hmap[c.Code][c.Date]; ok && math.Abs(p-c.ConvPrice) < 1e-6 {
+			continue
+		}
+		cps = append(cps, c)
+	}
+	return
+}
+
+func saveCBond(cbs []*model.CBond) (e error) {
+	d, t := util.TimeStr()
+	batch := 500
+	for i := 0; i < len(cbs); i += batch {
+		end := i + batch
+		if end > len(cbs) {
+			end = len(cbs)
+		}
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]interface{}, 0, (end-i)*19)
+		for _, c := range cbs[i:end] {
+			valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)")
+			valueArgs = append(valueArgs, c.Code, c.Market, c.Name, c.StockCode, c.ValueDate, c.ListDate,
+				c.Maturity, c.DelistDate, c.IssueSize, c.InitConvPrice, c.ConvPrice, c.Coupons, c.RedeemPrice,
+				c.CallTrigger, c.PutTrigger, c.CallClause, c.PutClause, d, t)
+		}
+		stmt := fmt.Sprintf("insert into cbond (code,market,name,stock_code,value_date,list_date,maturity,"+
+			"delist_date,issue_size,init_conv_price,conv_price,coupons,redeem_price,call_trigger,put_trigger,"+
+			"call_clause,put_clause,udate,utime) values %s on duplicate key update market=values(market),"+
+			"name=values(name),stock_code=values(stock_code),value_date=values(value_date),"+
+			"list_date=values(list_date),maturity=values(maturity),delist_date=values(delist_date),"+
+			"issue_size=values(issue_size),init_conv_price=values(init_conv_price),conv_price=values(conv_price),"+
+			"coupons=values(coupons),redeem_price=values(redeem_price),call_trigger=values(call_trigger),"+
+			"put_trigger=values(put_trigger),call_clause=values(call_clause),put_clause=values(put_clause),"+
+			"udate=values(udate),utime=values(utime)", strings.Join(valueStrings, ","))
+		if _, e = dbmap.Exec(stmt, valueArgs...); e != nil {
+			return errors.Wrap(e, "failed to save convertible bonds")
+		}
+	}
+	return nil
+}
+
+//saveConvPrice saves the conversion prices and drops the values of the bonds since the prices are effective,
+//so that they are recalculated with the new prices.
+func saveConvPrice(cps []*model.CBondConvPrice) (e error) {
+	if len(cps) == 0 {
+		return nil
+	}
+	tran, e := dbmap.Begin()
+	if e != nil {
+		return errors.WithStack(e)
+	}
+	d, t := util.TimeStr()
+	batch := 500
+	for i := 0; i < len(cps); i += batch {
+		end := i + batch
+		if end > len(cps) {
+			end = len(cps)
+		}
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]interface{}, 0, (end-i)*5)
+		for _, c := range cps[i:end] {
+			valueStrings = append(valueStrings, "(?,?,?,?,?)")
+			valueArgs = append(valueArgs, c.Code, c.Date, c.ConvPrice, d, t)
+		}
+		stmt := fmt.Sprintf("insert into cbond_conv_price (code,date,conv_price,udate,utime) values %s "+
+			"on duplicate key update conv_price=values(conv_price),udate=values(udate),utime=values(utime)",
+			strings.Join(valueStrings, ","))
+		if _, e = tran.Exec(stmt, valueArgs...); e != nil {
+			tran.Rollback()
+			return errors.Wrap(e, "failed to save conversion prices")
+		}
+	}
+	for _, c := range cps {
+		if _, e = tran.Exec("delete from cbond_value where code = ? and date >= ?", c.Code, c.Date); e != nil {
+			tran.Rollback()
+			return errors.Wrapf(e, "%s failed to delete values since %s", c.Code, c.Date)
+		}
+	}
+	return errors.WithStack(tran.Commit())
+}
+
+//GetCBondKlines fetches the non-reinstated daily klines of the convertible bonds. Returns the bonds
+//successfully fetched.
+func GetCBondKlines(stks *model.Stocks) *model.Stocks {
+	stks = GetKlinesV2(stks, FetchRequest{
+		RemoteSource: model.DataSource(conf.Args.DataSource.Kline),
+		LocalSource:  model.ConvBond,
+		Reinstate:    model.None,
+		Cycle:        model.DAY,
+	})
+	FreeFetcherResources()
+	return stks
+}
+
+//CalcCBondValues calculates the daily conversion values, premiums, pure-bond values and double-low scores
+//of the convertible bonds since the latest ones in database, ignoring the stocks that are not convertible
+//bonds. Returns the bonds successfully calculated.
+func CalcCBondValues(stks *model.Stocks) (rstks *model.Stocks) {
+	log.Println("calculating convertible bond values...")
+	rstks = new(model.Stocks)
+	for _, s := range stks.List {
+		if s.Type != model.BondCB {
+			continue
+		}
+		if e := calcCBondValues(s.Code, conf.Args.DataSource.CBondDiscountRate); e != nil {
+			log.Printf("%s %+v", s.Code, e)
+			continue
+		}
+		rstks.Add(s)
+	}
+	log.Printf("values of %d convertible bonds calculated", rstks.Size())
+	return
+}
+
+func calcCBondValues(code string, rate float64) (e error) {
+	cb := new(model.CBond)
+	if e = dbmap.SelectOne(cb, "select * from cbond where code = ?", code); e != nil {
+		return errors.Wrap(e, "failed to load convertible bond")
+	}
+	latest, e := dbmap.SelectNullStr("select max(date) from cbond_value where code = ?", code)
+	if e != nil {
+		return errors.Wrap(e, "failed to query latest value")
+	}
+	//the latest values are recalculated as the underlying klines may not be available at the time, and so
+	//are the earlier ones whose underlying klines are available by now
+	missing, e := dbmap.SelectNullStr("select min(v.date) from cbond_value v inner join kline_d_n k "+
+		"on k.code = ? and k.date = v.date where v.code = ? and v.stock_close is null", cb.StockCode, code)
+	if e != nil {
+		return errors.Wrap(e, "failed to query values missing the underlying close")
+	}
+	if missing.Valid && missing.String < latest.String {
+		latest = missing
+	}
+	var rows []*cbondClose
+	_, e = dbmap.Select(&rows, "select b.date, b.close, k.close stock_close from cb_d_n b left join kline_d_n k "+
+		"on k.code = ? and k.date = b.date where b.code = ? and b.date >= ? and b.close is not null order by b.date",
+		cb.StockCode, code, latest.String)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		return errors.Wrap(e, "failed to query klines")
+	}
+	vs := calCBondValues(cb, ConvPrices(code), rows, rate)
+	return saveCBondValues(vs)
+}
+
+//calCBondValues calculates the values of the convertible bond on each of the closes.
+func calCBondValues(cb *model.CBond, cps []*model.CBondConvPrice, rows []*cbondClose, rate float64) (
+	vs []*model.CBondValue) {
+	for _, r := range rows {
+		v := &model.CBondValue{
+			Code:       cb.Code,
+			Date:       r.Date,
+			Close:      r.Close,
+			StockClose: r.StockClose,
+			ConvPrice:  convPriceAt(cb, cps, r.Date),
+			BondValue:  cbondPureValue(cb, r.Date, rate),
+		}
+		if v.ConvPrice.Valid && v.ConvPrice.Float64 > 0 && r.StockClose.Valid {
+			cv := 100 / v.ConvPrice.Float64 * r.StockClose.Float64
+			v.ConvValue = sql.NullFloat64{Float64: cv, Valid: true}
+			if cv > 0 {
+				p := (r.Close/cv - 1) * 100
+				v.ConvPremium = sql.NullFloat64{Float64: p, Valid: true}
+				v.DoubleLow = sql.NullFloat64{Float64: r.Close + p, Valid: true}
+			}
+		}
+		if v.BondValue.Valid && v.BondValue.Float64 > 0 {
+			v.BondPremium = sql.NullFloat64{Float64: (r.Close/v.BondValue.Float64 - 1) * 100, Valid: true}
+		}
+		vs = append(vs, v)
+	}
+	return
+}
+
+//convPriceAt returns the conversion price effective on the date from the reset history in chronological
+//order, or the earliest one before the history begins, or the latest price of the bond if there's no history.
+func convPriceAt(cb *model.CBond, cps []*model.CBondConvPrice, date string) (p sql.NullFloat64) {
+	if len(cps) == 0 {
+		return cb.ConvPrice
+	}
+	p = sql.NullFloat64{Float64: cps[0].ConvPrice, Valid: true}
+	for _, c := range cps {
+		if c.Date > date {
+			break
+		}
+		p.Float64 = c.ConvPrice
+	}
+	return
+}
+
+//cbondPureValue discounts the remaining coupons and the redemption price at maturity of the convertible
+//bond at the annual rate (%). Coupons are paid on the anniversaries of the value date, and the last one is
+//included in the redemption price, or added to the par value if the price is unknown.
+func cbondPureValue(cb *model.CBond, date string, rate float64) (v sql.NullFloat64) {
+	if !cb.ValueDate.Valid || !cb.Maturity.Valid || !cb.Coupons.Valid {
+		return
+	}
+	var cps []float64
+	for _, s := range strings.Split(cb.Coupons.String, ",") {
+		c, e := strconv.ParseFloat(s, 64)
+		if e != nil {
+			log.Warnf("%s invalid coupons: %s", cb.Code, cb.Coupons.String)
+			return
+		}
+		cps = append(cps, c)
+	}
+	vd, e := time.Parse(global.DateFormat, cb.ValueDate.String)
+	if e != nil {
+		return
+	}
+	md, e := time.Parse(global.DateFormat, cb.Maturity.String)
+	if e != nil {
+		return
+	}
+	d, e := time.Parse(global.DateFormat, date)
+	if e != nil || !d.Before(md) {
+		return
+	}
+	for i := 1; i <= len(cps); i++ {
+		pay, cf := vd.AddDate(i, 0, 0), cps[i-1]
+		if i == len(cps) {
+			pay = md
+			if cb.RedeemPrice.Valid {
+				cf = cb.RedeemPrice.Float64
+			} else {
+				cf += 100
+			}
+		}
+		if !pay.After(d) {
+			continue
+		}
+		t := pay.Sub(d).Hours() / 24 / 365
+		v.Float64 += cf / math.Pow(1+rate/100, t)
+	}
+	v.Valid = true
+	return
+}
+
+func saveCBondValues(vs []*model.CBondValue) (e error) {
+	d, t := util.TimeStr()
+	batch := 500
+	for i := 0; i < len(vs); i += batch {
+		end := i + batch
+		if end > len(vs) {
+			end = len(vs)
+		}
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]interface{}, 0, (end-i)*12)
+		for _, v := range vs[i:end] {
+			valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?,?,?,?,?)")
+			valueArgs = append(valueArgs, v.Code, v.Date, v.Close, v.StockClose, v.ConvPrice, v.ConvValue,
+				v.ConvPremium, v.BondValue, v.BondPremium, v.DoubleLow, d, t)
+		}
+		stmt := fmt.Sprintf("insert into cbond_value (code,date,close,stock_close,conv_price,conv_value,"+
+			"conv_premium,bond_value,bond_premium,double_low,udate,utime) values %s on duplicate key update "+
+			"close=values(close),stock_close=values(stock_close),conv_price=values(conv_price),"+
+			"conv_value=values(conv_value),conv_premium=values(conv_premium),bond_value=values(bond_value),"+
+			"bond_premium=values(bond_premium),double_low=values(double_low),udate=values(udate),"+
+			"utime=values(utime)", strings.Join(valueStrings, ","))
+		if _, e = dbmap.Exec(stmt, valueArgs...); e != nil {
+			return errors.Wrap(e, "failed to save convertible bond values")
+		}
+	}
+	return nil
+}
+
+//ConvPrices loads the conversion price reset history of the convertible bond in chronological order.
+func ConvPrices(code string) (cps []*model.CBondConvPrice) {
+	_, e := dbmap.Select(&cps, "select * from cbond_conv_price where code = ? order by date", code)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("%s failed to load conversion prices: %+v", code, e)
+	}
+	return
+}
+
+//CBondValues loads the values of the convertible bonds on the date in ascending order of the double-low
+//scores, those without scores last. All bonds are loaded if none is specified.
+func CBondValues(date string, codes ...string) (vs []*model.CBondValue) {
+	qry := "select * from cbond_value where date = ?"
+	if len(codes) > 0 {
+		qry += fmt.Sprintf(" and code in (%s)", util.Join(codes, ",", true))
+	}
+	_, e := dbmap.Select(&vs, qry+" order by double_low is null, double_low, code", date)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("failed to load convertible bond values: %+v", e)
+	}
+	return
+}
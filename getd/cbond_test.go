@@ -0,0 +1,151 @@
+package getd
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+
+	"github.com/carusyte/stock/model"
+)
+
+func TestParseCBond(t *testing.T) {
+	defer useCassettes(t)()
+	body, e := fetchEmReport("RPT_BOND_CB_LIST", "SECURITY_CODE", "", 1)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	cbs, pages, e := parseCBond(body)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if pages != 2 {
+		t.Errorf("want 2 pages, got %d", pages)
+	}
+	if len(cbs) != 1 {
+		t.Fatalf("want 1 bond, got %d", len(cbs))
+	}
+	cb := cbs[0]
+	if cb.Code != "113050" || cb.Market != model.MarketSH || cb.StockCode != "601009" ||
+		cb.ValueDate.String != "2021-06-15" || cb.Maturity.String != "2027-06-14" || cb.DelistDate.Valid ||
+		cb.ConvPrice.Float64 != 10.1 || cb.PutTrigger.Valid || cb.PutClause.Valid {
+		t.Errorf("unexpected bond: %+v", cb)
+	}
+	if cb.Coupons.String != "0.2,0.4,0.7,1.2,1.7,2" {
+		t.Errorf("unexpected coupons: %s", cb.Coupons.String)
+	}
+	if math.Abs(cb.RedeemPrice.Float64-113) > 1e-9 {
+		t.Errorf("want redemption price 113, got %+v", cb.RedeemPrice)
+	}
+}
+
+func TestParseConvPriceChange(t *testing.T) {
+	defer useCassettes(t)()
+	body, e := fetchEmReport("RPT_BOND_CB_CLZGJ", "CHANGE_DATE", "(CHANGE_DATE>='2021-01-01')", 1)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	cps, pages, e := parseConvPriceChange(body)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if pages != 1 || len(cps) != 1 {
+		t.Fatalf("want 1 change of 1 page, got %d of %d", len(cps), pages)
+	}
+	if c := cps[0]; c.Code != "113050" || c.Date != "2021-12-27" || c.ConvPrice != 9.76 {
+		t.Errorf("unexpected conversion price change: %+v", c)
+	}
+}
+
+func TestParseRedeemPrice(t *testing.T) {
+	for clause, want := range map[string]sql.NullFloat64{
+		"到期赎回：期满后五个交易日内，公司将按债券面值的110%（含最后一期利息）赎回":         {Float64: 110, Valid: true},
+		"在本次发行的可转债期满后五个交易日内，公司将以108元（含最后一期利息）的价格赎回":       {Float64: 108, Valid: true},
+		"有条件赎回条款：当公司股票连续三十个交易日中至少十五个交易日的收盘价格不低于转股价格的130%": {},
+	} {
+		if p := parseRedeemPrice(clause, 2); p != want {
+			t.Errorf("%s: want %+v, got %+v", clause, want, p)
+		}
+	}
+}
+
+func TestNewConvPrices(t *testing.T) {
+	valid := func(f float64) sql.NullFloat64 { return sql.NullFloat64{Float64: f, Valid: true} }
+	date := func(d string) sql.NullString { return sql.NullString{String: d, Valid: true} }
+	cbs := []*model.CBond{
+		{Code: "A", ValueDate: date("2020-01-01"), InitConvPrice: valid(10), ConvPrice: valid(9)},
+		{Code: "B", ListDate: date("2020-02-01"), InitConvPrice: valid(10), ConvPrice: valid(10)},
+		{Code: "C", InitConvPrice: valid(10), ConvPrice: valid(8)},
+		{Code: "D", InitConvPrice: valid(10), ConvPrice: valid(10)},
+	}
+	hist := []*model.CBondConvPrice{
+		{Code: "C", Date: "2020-01-01", ConvPrice: 10},
+		{Code: "C", Date: "2020-12-01", ConvPrice: 8.5},
+	}
+	chgs := []*model.CBondConvPrice{
+		{Code: "A", Date: "2020-12-15", ConvPrice: 9},
+		{Code: "C", Date: "2020-12-01", ConvPrice: 8.5},
+		{Code: "C", Date: "2021-01-04", ConvPrice: 8},
+		{Code: "E", Date: "2021-01-04", ConvPrice: 5},
+	}
+	cps := newConvPrices(cbs, hist, chgs)
+	want := []model.CBondConvPrice{
+		{Code: "A", Date: "2020-01-01", ConvPrice: 10},
+		{Code: "B", Date: "2020-02-01", ConvPrice: 10},
+		{Code: "A", Date: "2020-12-15", ConvPrice: 9},
+		{Code: "C", Date: "2021-01-04", ConvPrice: 8},
+	}
+	if len(cps) != len(want) {
+		t.Fatalf("want %d conversion prices, got %d", len(want), len(cps))
+	}
+	for i, w := range want {
+		if *cps[i] != w {
+			t.Errorf("want %+v, got %+v", w, *cps[i])
+		}
+	}
+}
+
+func TestCalCBondValues(t *testing.T) {
+	cb := &model.CBond{
+		Code:        "113050",
+		ValueDate:   sql.NullString{String: "2020-01-01", Valid: true},
+		Maturity:    sql.NullString{String: "2022-01-01", Valid: true},
+		Coupons:     sql.NullString{String: "1,2", Valid: true},
+		RedeemPrice: sql.NullFloat64{Float64: 110, Valid: true},
+	}
+	cps := []*model.CBondConvPrice{
+		{Code: "113050", Date: "2020-01-01", ConvPrice: 10},
+		{Code: "113050", Date: "2021-01-01", ConvPrice: 8},
+	}
+	vs := calCBondValues(cb, cps, []*cbondClose{
+		{Date: "2020-07-01", Close: 120, StockClose: sql.NullFloat64{Float64: 11, Valid: true}},
+		{Date: "2021-01-01", Close: 130, StockClose: sql.NullFloat64{Float64: 10, Valid: true}},
+		{Date: "2021-01-04", Close: 125},
+	}, 0)
+	if len(vs) != 3 {
+		t.Fatalf("want 3 values, got %d", len(vs))
+	}
+	near := func(n sql.NullFloat64, f float64) bool { return n.Valid && math.Abs(n.Float64-f) < 1e-9 }
+	v := vs[0]
+	if !near(v.ConvPrice, 10) || !near(v.ConvValue, 110) || !near(v.ConvPremium, (120./110-1)*100) ||
+		!near(v.DoubleLow, 120+(120./110-1)*100) {
+		t.Errorf("unexpected conversion value: %+v", v)
+	}
+	//both the coupon and the redemption price remain at zero discount rate
+	if !near(v.BondValue, 111) || !near(v.BondPremium, (120./111-1)*100) {
+		t.Errorf("unexpected bond value: %+v", v)
+	}
+	v = vs[1]
+	if !near(v.ConvPrice, 8) || !near(v.ConvValue, 125) || !near(v.BondValue, 110) {
+		t.Errorf("unexpected value after reset: %+v", v)
+	}
+	v = vs[2]
+	if v.ConvValue.Valid || v.DoubleLow.Valid || !near(v.BondValue, 110) {
+		t.Errorf("unexpected value without underlying close: %+v", v)
+	}
+	if b := cbondPureValue(cb, "2021-01-01", 10); !near(b, 110/1.1) {
+		t.Errorf("want discounted bond value %f, got %+v", 110/1.1, b)
+	}
+	if b := cbondPureValue(cb, "2022-01-01", 0); b.Valid {
+		t.Errorf("want no bond value at maturity, got %+v", b)
+	}
+}
@@ -7,10 +7,8 @@ import (
 	"math/rand"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/carusyte/stock/conf"
-	"github.com/carusyte/stock/global"
 	"github.com/carusyte/stock/model"
 	"github.com/carusyte/stock/util"
 	"github.com/pkg/errors"
@@ -70,6 +68,8 @@ func (f *EmKlineFetcher) fetchKline(stk *model.Stock, fr FetchRequest, incr bool
 		period = "wk"
 	case model.MONTH:
 		period = "mk"
+	case model.M60, model.M30, model.M15, model.M5:
+		period = fmt.Sprintf("m%dk", cycle.Minutes())
 	default:
 		log.Panicf("unsupported cycle: %+v", cycle)
 	}
@@ -175,12 +175,12 @@ func fixEMKfromDB(k *model.EMKline, fr FetchRequest, ctr model.Rtype) bool {
 	if ltb == nil {
 		return false
 	}
-	dbDate, e := time.Parse(global.DateFormat, ltb.Date)
+	dbDate, e := parseKlineDate(ltb.Date)
 	if e != nil {
 		log.Panicf("%s invalid time format from db: %s, %+v", k.Code, ltb.Date, e)
 	}
 	kd := k.Dates[len(k.Dates)-1]
-	kDate, e := time.Parse(global.DateFormat, kd)
+	kDate, e := parseKlineDate(kd)
 	if e != nil {
 		log.Panicf("%s invalid time format from remote: %s, %+v", k.Code, kd, e)
 	}
@@ -0,0 +1,345 @@
+package getd
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"encoding/csv"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/carusyte/stock/calendar"
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/model"
+	"github.com/pkg/errors"
+)
+
+//LocalKlineFetcher fetches kline data from local files under the directory configured by
+//datasource.local.path. Two kinds of files are supported:
+//
+//CSV files at <path>/csv/<cycle>/<rtype>/<code>.csv, e.g. csv/d/f/600000.csv for daily forward
+//reinstated klines of 600000. <cycle> is one of d, w, m, m1, m5, m15, m30, m60, m120 and <rtype>
+//is one of n (none), f (forward), b (backward). The first line is the header, naming the columns
+//in any order among: date, open, high, low, close, volume (shares), amount (yuan), xrate (%).
+//Dates are in the format of 2006-01-02, or 2006-01-02 15:04 for intraday klines.
+//
+//Tongdaxin binary files at <path>/vipdoc/<market>/lday/<market><code>.day, e.g.
+//vipdoc/sh/lday/sh600000.day. They provide non-reinstated daily klines, from which weekly and
+//monthly klines are aggregated. CSV files take precedence if both are available.
+type LocalKlineFetcher struct{}
+
+//fetchKline from local files for the given stock.
+func (f *LocalKlineFetcher) fetchKline(stk *model.Stock, fr FetchRequest, incr bool) (
+	tdmap map[FetchRequest]*model.TradeData, lkmap map[FetchRequest]int, suc, retry bool) {
+
+	tdmap = make(map[FetchRequest]*model.TradeData)
+	lkmap = make(map[FetchRequest]int)
+	lkmap[fr] = -1
+	code := stk.Code
+	tabs := resolveTableNames(fr)
+
+	data, e := readLocalKline(stk, fr.Cycle, fr.Reinstate)
+	if e != nil {
+		log.Warnf("%s %+v failed to read local kline: %+v", code, tabs, e)
+		return tdmap, lkmap, false, false
+	}
+	if incr {
+		data, lkmap[fr] = sliceFromLatest(code, fr, data)
+	} else {
+		log.Printf("%s %+v data will be fully refreshed", code, tabs)
+	}
+
+	tdmap[fr] = &model.TradeData{
+		Code:          code,
+		Source:        fr.LocalSource,
+		Cycle:         fr.Cycle,
+		Reinstatement: fr.Reinstate,
+		Base:          data,
+	}
+	return tdmap, lkmap, true, false
+}
+
+//sliceFromLatest slices the chronological klines from the latest ones in database for incremental update,
+//returning the klid to start from, or -1 with the klines intact if they should be fully refreshed.
+func sliceFromLatest(code string, fr FetchRequest, data []*model.TradeDataBasic) (
+	[]*model.TradeDataBasic, int) {
+	tabs := resolveTableNames(fr)
+	ldy := getLatestTradeDataBasic(code, fr.LocalSource, fr.Cycle, fr.Reinstate, 5+1) //plus one offset for pre-close, varate calculation
+	if ldy == nil {
+		log.Printf("%s latest %+v data not found, will be fully refreshed", code, tabs)
+		return data, -1
+	}
+	if i := sort.Search(len(data), func(i int) bool {
+		return data[i].Date >= ldy.Date
+	}); i < len(data) && data[i].Date == ldy.Date {
+		//continue from the stored klid rather than the position in the local file
+		data = data[i:]
+		for j, d := range data {
+			d.Klid = ldy.Klid + j
+		}
+		return data, ldy.Klid
+	}
+	log.Printf("%s %+v data doesn't contain latest date %s, will be fully refreshed", code, tabs, ldy.Date)
+	return data, -1
+}
+
+//readLocalKline reads klines of the specified cycle and reinstatement type from local files,
+//in chronological order.
+func readLocalKline(stk *model.Stock, cycle model.CYTP, rtype model.Rtype) (
+	data []*model.TradeDataBasic, e error) {
+	root := conf.Args.DataSource.Local.Path
+	if root == "" {
+		return nil, errors.New("datasource.local.path is not configured")
+	}
+	cc, ok := cycleTabCode(cycle)
+	if !ok {
+		return nil, errors.Errorf("unsupported cycle: %v", cycle)
+	}
+	rc := ""
+	switch rtype {
+	case model.None:
+		rc = "n"
+	case model.Forward:
+		rc = "f"
+	case model.Backward:
+		rc = "b"
+	default:
+		return nil, errors.Errorf("unsupported reinstatement type: %v", rtype)
+	}
+	csvFile := filepath.Join(root, "csv", cc, rc, stk.Code+".csv")
+	if _, e = os.Stat(csvFile); e == nil {
+		return readKlineCSVFile(stk.Code, csvFile)
+	}
+	if rtype != model.None || cycle.Intraday() {
+		return nil, errors.Errorf("%s not found, tdx day file only provides non-reinstated daily data", csvFile)
+	}
+	dayFile, e := tdxDayFile(root, stk)
+	if e != nil {
+		return nil, e
+	}
+	fd, e := os.Open(dayFile)
+	if e != nil {
+		return nil, errors.Wrapf(e, "no local kline file available for %s", stk.Code)
+	}
+	defer fd.Close()
+	if data, e = parseTdxDay(stk.Code, tdxPriceScale(stk), fd); e != nil {
+		return nil, errors.Wrapf(e, "failed to parse %s", dayFile)
+	}
+	switch cycle {
+	case model.WEEK:
+		data, e = aggregateKline(data, weekKey(tradeCal()))
+	case model.MONTH:
+		data, e = aggregateKline(data, monthKey(tradeCal()))
+	}
+	return
+}
+
+//tdxDayFile returns the path of the .day file of the stock. Without the market of the stock, the
+//file is looked up under each market, since the code alone is ambiguous, e.g. sh000001 is the
+//Shanghai composite index while sz000001 is a stock.
+func tdxDayFile(root string, stk *model.Stock) (string, error) {
+	path := func(mkt string) string {
+		return filepath.Join(root, "vipdoc", mkt, "lday", mkt+stk.Code+".day")
+	}
+	if mkt := strings.ToLower(stk.Market.String); mkt != "" {
+		return path(mkt), nil
+	}
+	var found []string
+	for _, mkt := range []string{"sh", "sz", "bj"} {
+		if _, e := os.Stat(path(mkt)); e == nil {
+			found = append(found, path(mkt))
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", errors.Errorf("no local kline file available for %s", stk.Code)
+	case 1:
+		return found[0], nil
+	}
+	return "", errors.Errorf("market of %s is unknown and ambiguous among %+v", stk.Code, found)
+}
+
+//tdxPriceScale returns the divisor of the prices in the .day file. Funds and bonds are quoted to
+//3 decimals, and stocks and indices to 2.
+func tdxPriceScale(stk *model.Stock) float64 {
+	if stk.Type != "" {
+		return 1000.
+	}
+	return 100.
+}
+
+//tdxDayRecord is the 32-byte record layout of the Tongdaxin .day file, in little endian.
+type tdxDayRecord struct {
+	Date                   uint32 //YYYYMMDD
+	Open, High, Low, Close uint32 //price in 0.01 yuan, or 0.001 yuan for funds and bonds
+	Amount                 float32
+	Volume                 uint32
+	Reserved               uint32
+}
+
+//parseTdxDay parses the Tongdaxin .day binary data, dividing the prices by scale.
+func parseTdxDay(code string, scale float64, r io.Reader) (data []*model.TradeDataBasic, e error) {
+	for {
+		var rec tdxDayRecord
+		if e = binary.Read(r, binary.LittleEndian, &rec); e != nil {
+			if e == io.EOF {
+				return data, nil
+			}
+			return nil, errors.Wrapf(e, "invalid record #%d", len(data))
+		}
+		d := strconv.Itoa(int(rec.Date))
+		if len(d) != 8 {
+			return nil, errors.Errorf("invalid date in record #%d: %d", len(data), rec.Date)
+		}
+		data = append(data, &model.TradeDataBasic{
+			Code:   code,
+			Date:   d[:4] + "-" + d[4:6] + "-" + d[6:],
+			Klid:   len(data),
+			Open:   float64(rec.Open) / scale,
+			High:   float64(rec.High) / scale,
+			Low:    float64(rec.Low) / scale,
+			Close:  float64(rec.Close) / scale,
+			Volume: sql.NullFloat64{Float64: float64(rec.Volume), Valid: true},
+			Amount: sql.NullFloat64{Float64: float64(rec.Amount), Valid: true},
+		})
+	}
+}
+
+func readKlineCSVFile(code, file string) (data []*model.TradeDataBasic, e error) {
+	fd, e := os.Open(file)
+	if e != nil {
+		return nil, errors.WithStack(e)
+	}
+	defer fd.Close()
+	if data, e = parseKlineCSV(code, fd); e != nil {
+		return nil, errors.Wrapf(e, "failed to parse %s", file)
+	}
+	return
+}
+
+//parseKlineCSV parses kline data in the CSV layout documented in LocalKlineFetcher.
+//Records are sorted by date.
+func parseKlineCSV(code string, r io.Reader) (data []*model.TradeDataBasic, e error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, e := cr.Read()
+	if e != nil {
+		return nil, errors.Wrap(e, "failed to read header")
+	}
+	cols := make(map[string]int)
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, c := range []string{"date", "open", "high", "low", "close"} {
+		if _, ok := cols[c]; !ok {
+			return nil, errors.Errorf("missing column '%s' in header: %+v", c, header)
+		}
+	}
+	for ln := 2; ; ln++ {
+		rec, e := cr.Read()
+		if e == io.EOF {
+			break
+		} else if e != nil {
+			return nil, errors.Wrapf(e, "failed to read line %d", ln)
+		}
+		b := &model.TradeDataBasic{Code: code, Date: strings.TrimSpace(rec[cols["date"]])}
+		if _, e = parseKlineDate(b.Date); e != nil {
+			return nil, errors.Wrapf(e, "invalid date at line %d", ln)
+		}
+		for c, p := range map[string]*float64{
+			"open": &b.Open, "high": &b.High, "low": &b.Low, "close": &b.Close,
+		} {
+			if *p, e = strconv.ParseFloat(strings.TrimSpace(rec[cols[c]]), 64); e != nil {
+				return nil, errors.Wrapf(e, "invalid %s at line %d", c, ln)
+			}
+		}
+		for c, p := range map[string]*sql.NullFloat64{
+			"volume": &b.Volume, "amount": &b.Amount, "xrate": &b.Xrate,
+		} {
+			i, ok := cols[c]
+			if !ok || strings.TrimSpace(rec[i]) == "" {
+				continue
+			}
+			v, e := strconv.ParseFloat(strings.TrimSpace(rec[i]), 64)
+			if e != nil {
+				return nil, errors.Wrapf(e, "invalid %s at line %d", c, ln)
+			}
+			*p = sql.NullFloat64{Float64: v, Valid: true}
+		}
+		data = append(data, b)
+	}
+	sort.SliceStable(data, func(i, j int) bool {
+		return data[i].Date < data[j].Date
+	})
+	for i, b := range data {
+		b.Klid = i
+	}
+	return
+}
+
+//tradeCal returns the trade calendar used to group daily klines into trading weeks and months.
+var tradeCal = calendar.Default
+
+//weekKey returns the key function grouping dates by trading week, i.e. the last trading day of the
+//week in the calendar.
+func weekKey(cal *calendar.Calendar) func(string) (string, error) {
+	return func(d string) (string, error) {
+		if k := cal.WeekEnd(d); k != "" {
+			return k, nil
+		}
+		return "", errors.Errorf("no trading day in the week of %s", d)
+	}
+}
+
+//monthKey returns the key function grouping dates by trading month, i.e. the last trading day of
+//the month in the calendar.
+func monthKey(cal *calendar.Calendar) func(string) (string, error) {
+	return func(d string) (string, error) {
+		if k := cal.MonthEnd(d); k != "" {
+			return k, nil
+		}
+		return "", errors.Errorf("no trading day in the month of %s", d)
+	}
+}
+
+//aggregateKline merges daily klines into longer cycles grouped by the key of the date.
+//The date of the merged kline is the last trading day within the group.
+func aggregateKline(daily []*model.TradeDataBasic, key func(date string) (string, error)) (
+	data []*model.TradeDataBasic, e error) {
+	var (
+		cur  *model.TradeDataBasic
+		ckey string
+	)
+	for _, d := range daily {
+		k, e := key(d.Date)
+		if e != nil {
+			return nil, e
+		}
+		if cur == nil || k != ckey {
+			c := *d
+			cur, ckey = &c, k
+			cur.Klid = len(data)
+			data = append(data, cur)
+			continue
+		}
+		cur.Date = d.Date
+		cur.High = math.Max(cur.High, d.High)
+		cur.Low = math.Min(cur.Low, d.Low)
+		cur.Close = d.Close
+		cur.Volume = addNullFloat(cur.Volume, d.Volume)
+		cur.Amount = addNullFloat(cur.Amount, d.Amount)
+		cur.Xrate = addNullFloat(cur.Xrate, d.Xrate)
+	}
+	return
+}
+
+func addNullFloat(a, b sql.NullFloat64) sql.NullFloat64 {
+	if !b.Valid {
+		return a
+	}
+	return sql.NullFloat64{Float64: a.Float64 + b.Float64, Valid: true}
+}
@@ -0,0 +1,145 @@
+package getd
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/carusyte/stock/calendar"
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/model"
+)
+
+func TestParseTdxDay(t *testing.T) {
+	var buf bytes.Buffer
+	recs := []tdxDayRecord{
+		{Date: 20200102, Open: 1020, High: 1050, Low: 1010, Close: 1040, Amount: 1.04e6, Volume: 100000},
+		{Date: 20200103, Open: 1040, High: 1060, Low: 1000, Close: 1005, Amount: 2.01e6, Volume: 200000},
+	}
+	for _, r := range recs {
+		if e := binary.Write(&buf, binary.LittleEndian, r); e != nil {
+			t.Fatal(e)
+		}
+	}
+	data, e := parseTdxDay("600000", 100, &buf)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(data) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(data))
+	}
+	d := data[1]
+	if d.Date != "2020-01-03" || d.Open != 10.4 || d.High != 10.6 || d.Low != 10 || d.Close != 10.05 ||
+		d.Volume.Float64 != 200000 || d.Amount.Float64 != 2.01e6 || d.Klid != 1 {
+		t.Errorf("unexpected record: %+v", d)
+	}
+	if _, e = parseTdxDay("600000", 100, bytes.NewReader(make([]byte, 20))); e == nil {
+		t.Error("expected error for truncated record")
+	}
+}
+
+func TestParseKlineCSV(t *testing.T) {
+	data, e := parseKlineCSV("000001", strings.NewReader(
+		"date,open,close,high,low,volume,amount\n"+
+			"2020-01-03,10.4,10.05,10.6,10,200000,\n"+
+			"2020-01-02,10.2,10.4,10.5,10.1,100000,1040000\n"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(data) != 2 || data[0].Date != "2020-01-02" || data[1].Klid != 1 {
+		t.Fatalf("records not sorted by date: %+v", data)
+	}
+	if data[1].Close != 10.05 || data[1].Amount.Valid || !data[1].Volume.Valid {
+		t.Errorf("unexpected record: %+v", data[1])
+	}
+	if _, e = parseKlineCSV("000001", strings.NewReader("date,open,high\n2020-01-02,1,1\n")); e == nil {
+		t.Error("expected error for missing columns")
+	}
+}
+
+func TestLocalKlineFetcher(t *testing.T) {
+	dir, e := ioutil.TempDir("", "stock_local")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	var buf bytes.Buffer
+	//Thursday, Friday and the Monday after
+	for i, dt := range []uint32{20200102, 20200103, 20200106} {
+		r := tdxDayRecord{Date: dt, Open: 1000, High: 1100 + uint32(i), Low: 900 - uint32(i), Close: 1000 + uint32(i),
+			Amount: 1000, Volume: 100}
+		binary.Write(&buf, binary.LittleEndian, r)
+	}
+	lday := filepath.Join(dir, "vipdoc", "sh", "lday")
+	if e = os.MkdirAll(lday, 0755); e != nil {
+		t.Fatal(e)
+	}
+	if e = ioutil.WriteFile(filepath.Join(lday, "sh600000.day"), buf.Bytes(), 0644); e != nil {
+		t.Fatal(e)
+	}
+	conf.Args.DataSource.Local.Path = dir
+	tradeCal = func() *calendar.Calendar { return calendar.New(nil) }
+	defer func() { tradeCal = calendar.Default }()
+	stk := &model.Stock{Code: "600000"}
+	fr := FetchRequest{RemoteSource: model.Local, LocalSource: model.KlineMaster, Cycle: model.WEEK, Reinstate: model.None}
+	tdmap, lkmap, suc, _ := new(LocalKlineFetcher).fetchKline(stk, fr, false)
+	if !suc || lkmap[fr] != -1 {
+		t.Fatalf("failed to fetch local kline: %v, %+v", suc, lkmap)
+	}
+	w := tdmap[fr].Base
+	if len(w) != 2 {
+		t.Fatalf("expected 2 weekly klines, got %d", len(w))
+	}
+	if w[0].Date != "2020-01-03" || w[0].High != 11.01 || w[0].Low != 8.99 || w[0].Close != 10.01 ||
+		w[0].Volume.Float64 != 200 {
+		t.Errorf("unexpected weekly kline: %+v", w[0])
+	}
+	fr.Reinstate = model.Forward
+	if _, _, suc, _ = new(LocalKlineFetcher).fetchKline(stk, fr, false); suc {
+		t.Error("forward reinstated kline should not be available from day files")
+	}
+
+	//the code also exists in sz, so the market can't be told from the files
+	if e = os.MkdirAll(filepath.Join(dir, "vipdoc", "sz", "lday"), 0755); e != nil {
+		t.Fatal(e)
+	}
+	if e = ioutil.WriteFile(filepath.Join(dir, "vipdoc", "sz", "lday", "sz600000.day"), buf.Bytes(), 0644); e != nil {
+		t.Fatal(e)
+	}
+	fr.Reinstate = model.None
+	if _, _, suc, _ = new(LocalKlineFetcher).fetchKline(stk, fr, false); suc {
+		t.Error("ambiguous market should fail")
+	}
+	//funds are quoted to 3 decimals
+	stk = &model.Stock{Code: "600000", Market: sql.NullString{String: "SZ", Valid: true}, Type: model.FundETF}
+	tdmap, _, suc, _ = new(LocalKlineFetcher).fetchKline(stk, fr, false)
+	if !suc || tdmap[fr].Base[0].High != 1.101 {
+		t.Errorf("unexpected fund kline: %v, %+v", suc, tdmap[fr])
+	}
+}
+
+func TestWeekKey(t *testing.T) {
+	//2020-10-01 to 2020-10-08 were national day holidays
+	cal := calendar.New(calendar.Derive("2020-09-28", "2020-10-11",
+		[]string{"2020-09-28", "2020-09-29", "2020-09-30", "2020-10-09"}))
+	daily := []*model.TradeDataBasic{
+		{Date: "2020-09-29", Open: 1, High: 2, Low: 1, Close: 2},
+		{Date: "2020-09-30", Open: 2, High: 3, Low: 2, Close: 3},
+		{Date: "2020-10-09", Open: 3, High: 4, Low: 3, Close: 4},
+	}
+	w, e := aggregateKline(daily, weekKey(cal))
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if len(w) != 2 || w[0].Date != "2020-09-30" || w[1].Date != "2020-10-09" || w[1].Klid != 1 {
+		t.Errorf("unexpected weekly klines: %+v, %+v", w[0], w[1])
+	}
+	if k, _ := weekKey(cal)("2020-10-01"); k != "2020-09-30" {
+		t.Errorf("holiday should fall in the trading week ended on 2020-09-30, got %s", k)
+	}
+}
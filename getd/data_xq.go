@@ -64,6 +64,8 @@ func (f *XqKlineFetcher) fetchKline(stk *model.Stock, fr FetchRequest, incr bool
 		period = "week"
 	case model.MONTH:
 		period = "month"
+	case model.M120, model.M60, model.M30, model.M15, model.M5, model.M1:
+		period = fmt.Sprintf("%dm", cycle.Minutes())
 	default:
 		log.Panicf("unsupported cycle: %+v", fr.Cycle)
 	}
@@ -107,14 +109,15 @@ func (f *XqKlineFetcher) fetchKline(stk *model.Stock, fr FetchRequest, incr bool
 	multiGet := false
 	if lkmap[fr] == -1 {
 		count = int(math.Round(-.75*(time.Since(startDate).Hours()/24.) - float64(rand.Intn(1000))))
+		count *= barsPerDay(cycle)
 		multiGet = true
 	} else {
-		ltime, e := time.Parse(global.DateFormat, ldate)
+		ltime, e := parseKlineDate(ldate)
 		if e != nil {
 			log.Warnf("%s %+v failed to parse date value '%s': %+v", stk.Code, tabs, ldate, e)
 			return tdmap, lkmap, false, false
 		}
-		count = -1 * (int(time.Since(ltime).Hours()/24) + 2)
+		count = -1 * (int(time.Since(ltime).Hours()/24) + 2) * barsPerDay(cycle)
 	}
 
 	xqk, e := tryXQKline(code, symbol, period, xdrType, count, multiGet)
@@ -179,6 +182,12 @@ func (f *XqKlineFetcher) fixData(stk *model.Stock, k *model.XQKline, fr FetchReq
 	if len(k.MissingData) == 0 && len(k.MissingAmount) == 0 {
 		return
 	}
+	if fr.Cycle.Intraday() {
+		//validate klines are not available for all intraday cycles
+		log.Warnf("%s %+v unable to fix missing data for the following dates: %+v",
+			k.Code, resolveTableNames(fr), append(k.MissingAmount, k.MissingData...))
+		return
+	}
 
 	vsrc := model.DataSource(conf.Args.DataSource.Validate.Source)
 	vcode, found := f.mapCode(k.Code, conf.Args.DataSource.Validate.Source)
@@ -321,7 +330,7 @@ func tryXQCookie() (cookies []*http.Cookie, px *util.Proxy, headers map[string]s
 }
 
 func tryXQKline(code, symbol, period, xdrType string, count int, multiGet bool) (xqk *model.XQKline, e error) {
-	xqk = &model.XQKline{Code: code}
+	xqk = &model.XQKline{Code: code, Intraday: strings.HasSuffix(period, "m")}
 	//symbol = SH600104
 	//begin = 1579589390096
 	//period = day/week/month/60m/120m...
@@ -384,12 +393,16 @@ func tryXQKline(code, symbol, period, xdrType string, count int, multiGet bool)
 	for multiGet && xqk.NumAdded == count && ckTimeout < 2 {
 		data := xqk.GetData(false)
 		var startDate time.Time
-		startDate, e = time.Parse(global.DateFormat, data[0].Date)
+		startDate, e = parseKlineDate(data[0].Date)
 		if e != nil {
 			log.Warnf("%s failed to parse date %s: %+v", code, data[0].Date, e)
 			return
 		}
-		begin = util.UnixMilliseconds(startDate.AddDate(0, 0, -1))
+		if xqk.Intraday {
+			begin = util.UnixMilliseconds(startDate.Add(-time.Minute))
+		} else {
+			begin = util.UnixMilliseconds(startDate.AddDate(0, 0, -1))
+		}
 		url = fmt.Sprintf(urlt, symbol, begin, period, xdrType, count)
 		if e = repeat.Repeat(
 			repeat.FnWithCounter(genop(url, hd, px, ck)),
@@ -411,6 +424,44 @@ func tryXQKline(code, symbol, period, xdrType string, count int, multiGet bool)
 	return
 }
 
+//fetchXqShares fetches the share changes of the stock from xueqiu.com, latest first.
+func fetchXqShares(stock *model.Stock, px *util.Proxy, headers map[string]string, cookies []*http.Cookie) (
+	xqshare *model.XqSharesChg, ok, retry bool) {
+	// get share info from xueqiu.com
+	// https://xueqiu.com/snowman/S/SH601598/detail#/GBJG
+	// https://stock.xueqiu.com/v5/stock/f10/cn/shareschg.json?symbol=SH601598&count=100&extend=true
+	url := fmt.Sprintf(`https://stock.xueqiu.com/v5/stock/f10/cn/shareschg.json?symbol=%s%s&count=1000&extend=true`, stock.Market.String, stock.Code)
+	res, e := util.HTTPGet(url, headers, px, cookies...)
+	if e != nil {
+		log.Printf("%s, http failed %s", stock.Code, url)
+		return nil, false, true
+	}
+	defer res.Body.Close()
+	xqshare = new(model.XqSharesChg)
+	var body []byte
+	if body, e = ioutil.ReadAll(res.Body); e != nil {
+		log.Printf("[%s,%s] failed to read from response body, retrying...", stock.Code,
+			stock.Name)
+		util.UpdateProxyScore(px, false)
+		return nil, false, true
+	}
+	util.UpdateProxyScore(px, true)
+	if strings.Contains(string(body), `"error_code": "400016"`) {
+		log.Warnf("%s cookie timeout: %+v", stock.Code, string(body))
+		return nil, false, true
+	} else if e = json.Unmarshal(body, xqshare); e != nil {
+		log.Printf("[%s,%s] failed to parse json body, retrying...", stock.Code,
+			stock.Name)
+		return nil, false, true
+	}
+	if xqshare.ErrorCode != 0 {
+		log.Printf("[%s,%s] failed from xueqiu.com:[%d, %s] retrying...", stock.Code,
+			stock.Name, xqshare.ErrorCode, xqshare.ErrorDesc)
+		return nil, false, true
+	}
+	return xqshare, true, false
+}
+
 func xqCookie() (cookies []*http.Cookie, px *util.Proxy, headers map[string]string, e error) {
 	homePage := `https://xueqiu.com/`
 	var uagent string
@@ -460,37 +511,9 @@ func xqCookie() (cookies []*http.Cookie, px *util.Proxy, headers map[string]stri
 }
 
 func xqShares(stock *model.Stock, px *util.Proxy, headers map[string]string, cookies []*http.Cookie) (ok, retry bool) {
-	// get share info from xueqiu.com
-	// https://xueqiu.com/snowman/S/SH601598/detail#/GBJG
-	// https://stock.xueqiu.com/v5/stock/f10/cn/shareschg.json?symbol=SH601598&count=100&extend=true
-	url := fmt.Sprintf(`https://stock.xueqiu.com/v5/stock/f10/cn/shareschg.json?symbol=%s%s&count=1000&extend=true`, stock.Market.String, stock.Code)
-	res, e := util.HTTPGet(url, headers, px, cookies...)
-	if e != nil {
-		log.Printf("%s, http failed %s", stock.Code, url)
-		return false, true
-	}
-	defer res.Body.Close()
-	var xqshare model.XqSharesChg
-	var body []byte
-	if body, e = ioutil.ReadAll(res.Body); e != nil {
-		log.Printf("[%s,%s] failed to read from response body, retrying...", stock.Code,
-			stock.Name)
-		util.UpdateProxyScore(px, false)
-		return false, true
-	}
-	util.UpdateProxyScore(px, true)
-	if strings.Contains(string(body), `"error_code": "400016"`) {
-		log.Warnf("%s cookie timeout: %+v", stock.Code, string(body))
-		return false, true
-	} else if e = json.Unmarshal(body, &xqshare); e != nil {
-		log.Printf("[%s,%s] failed to parse json body, retrying...", stock.Code,
-			stock.Name)
-		return false, true
-	}
-	if xqshare.ErrorCode != 0 {
-		log.Printf("[%s,%s] failed from xueqiu.com:[%d, %s] retrying...", stock.Code,
-			stock.Name, xqshare.ErrorCode, xqshare.ErrorDesc)
-		return false, true
+	xqshare, ok, retry := fetchXqShares(stock, px, headers, cookies)
+	if !ok {
+		return
 	} else if len(xqshare.Data.Items) == 0 {
 		log.Printf("[%s,%s] no share info from xueqiu.com", stock.Code, stock.Name)
 		return true, false
@@ -0,0 +1,339 @@
+package getd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+const (
+	//SeatBuy marks the top buy seats of a Dragon-Tiger list entry
+	SeatBuy = "B"
+	//SeatSell marks the top sell seats of a Dragon-Tiger list entry
+	SeatSell = "S"
+	//topSeats is the number of seats disclosed on each side
+	topSeats = 5
+)
+
+//seatProfileDays are the numbers of trading days after the appearances by which the seats are profiled.
+var seatProfileDays = []int{1, 3, 5, 10}
+
+//emDragonTiger is a row of the daily Dragon-Tiger list report of eastmoney.
+type emDragonTiger struct {
+	Code       string   `json:"SECURITY_CODE"`
+	Date       string   `json:"TRADE_DATE"`
+	Reason     string   `json:"EXPLANATION"`
+	Close      *float64 `json:"CLOSE_PRICE"`
+	ChangeRate *float64 `json:"CHANGE_RATE"`
+	NetBuy     *float64 `json:"BILLBOARD_NET_AMT"`
+	Buy        *float64 `json:"BILLBOARD_BUY_AMT"`
+	Sell       *float64 `json:"BILLBOARD_SELL_AMT"`
+	Amount     *float64 `json:"BILLBOARD_DEAL_AMT"`
+}
+
+//emDragonTigerSeat is a row of the daily Dragon-Tiger seat reports of eastmoney.
+type emDragonTigerSeat struct {
+	Code     string   `json:"SECURITY_CODE"`
+	Date     string   `json:"TRADE_DATE"`
+	Reason   string   `json:"EXPLANATION"`
+	SeatCode string   `json:"OPERATEDEPT_CODE"`
+	SeatName string   `json:"OPERATEDEPT_NAME"`
+	Buy      *float64 `json:"BUY"`
+	Sell     *float64 `json:"SELL"`
+	NetBuy   *float64 `json:"NET"`
+}
+
+//GetDragonTiger fetches the Dragon-Tiger lists along with the top seats since the latest ones in database,
+//then updates the seat profiles. Returns the stocks as is if successful, or none otherwise.
+func GetDragonTiger(stks *model.Stocks) *model.Stocks {
+	log.Println("getting dragon-tiger lists...")
+	if e := getDragonTiger(); e != nil {
+		log.Warnf("failed to get dragon-tiger lists: %+v", e)
+		return new(model.Stocks)
+	}
+	if e := updateSeatProfiles(); e != nil {
+		log.Warnf("failed to update seat profiles: %+v", e)
+		return new(model.Stocks)
+	}
+	return stks
+}
+
+func getDragonTiger() (e error) {
+	latest, e := dbmap.SelectNullStr("select max(date) from dragon_tiger")
+	if e != nil {
+		return errors.WithStack(e)
+	}
+	filter := fmt.Sprintf(`(TRADE_DATE>='%s')`, conf.Args.DataSource.DragonTigerSince)
+	if latest.Valid {
+		//the list of a day may be published in parts, refetches the latest date and upserts
+		filter = fmt.Sprintf(`(TRADE_DATE>='%s')`, latest.String)
+	}
+	dmap := make(map[string][]*model.DragonTiger)
+	for page, pages := 1, 1; page <= pages; page++ {
+		body, e := fetchEmReport("RPT_DAILYBILLBOARD_DETAILSNEW", "TRADE_DATE", filter, page)
+		if e != nil {
+			return e
+		}
+		var ps []*model.DragonTiger
+		if ps, pages, e = parseDragonTiger(body); e != nil {
+			return e
+		}
+		for _, dt := range ps {
+			dmap[dt.Date] = append(dmap[dt.Date], dt)
+		}
+	}
+	dates := make([]string, 0, len(dmap))
+	for d := range dmap {
+		dates = append(dates, d)
+	}
+	//saves in chronological order so that the latest date in database is complete
+	sort.Strings(dates)
+	for _, date := range dates {
+		var seats []*model.DragonTigerSeat
+		smap := make(map[string]*model.Seat)
+		for side, report := range map[string]string{
+			SeatBuy:  "RPT_BILLBOARD_DAILYDETAILSBUY",
+			SeatSell: "RPT_BILLBOARD_DAILYDETAILSSELL",
+		} {
+			sortCol := "BUY"
+			if side == SeatSell {
+				sortCol = "SELL"
+			}
+			filter := fmt.Sprintf(`(TRADE_DATE='%s')`, date)
+			var ss []*model.DragonTigerSeat
+			for page, pages := 1, 1; page <= pages; page++ {
+				body, e := fetchEmReport(report, sortCol, filter, page)
+				if e != nil {
+					return e
+				}
+				var ps []*model.DragonTigerSeat
+				if ps, pages, e = parseDragonTigerSeats(body, side, smap); e != nil {
+					return e
+				}
+				ss = append(ss, ps...)
+			}
+			seats = append(seats, rankSeats(ss)...)
+		}
+		if e = saveDragonTiger(dmap[date], seats, smap); e != nil {
+			return errors.Wrapf(e, "failed to save dragon-tiger list of %s", date)
+		}
+		log.Printf("dragon-tiger list of %s updated: %d entries, %d seats", date, len(dmap[date]), len(seats))
+	}
+	return nil
+}
+
+func parseDragonTiger(body []byte) (dts []*model.DragonTiger, pages int, e error) {
+	var rows []*emDragonTiger
+	if pages, e = parseEmReport(body, &rows); e != nil {
+		return nil, 0, e
+	}
+	for _, r := range rows {
+		date, e := emDate(r.Date)
+		if e != nil {
+			return nil, 0, errors.Wrapf(e, "invalid dragon-tiger list date of %s", r.Code)
+		}
+		dts = append(dts, &model.DragonTiger{
+			Code:       r.Code,
+			Date:       date,
+			Reason:     r.Reason,
+			Close:      emNullFloat(r.Close),
+			ChangeRate: emNullFloat(r.ChangeRate),
+			NetBuy:     emNullFloat(r.NetBuy),
+			Buy:        emNullFloat(r.Buy),
+			Sell:       emNullFloat(r.Sell),
+			Amount:     emNullFloat(r.Amount),
+		})
+	}
+	return dts, pages, nil
+}
+
+//parseDragonTigerSeats parses the seats of the side in the order of the report, and collects the seats
+//into smap keyed by seat code. Seats without code, such as institutions, are identified by their names.
+func parseDragonTigerSeats(body []byte, side string, smap map[string]*model.Seat) (
+	ss []*model.DragonTigerSeat, pages int, e error) {
+	var rows []*emDragonTigerSeat
+	if pages, e = parseEmReport(body, &rows); e != nil {
+		return nil, 0, e
+	}
+	for _, r := range rows {
+		date, e := emDate(r.Date)
+		if e != nil {
+			return nil, 0, errors.Wrapf(e, "invalid dragon-tiger seat date of %s", r.Code)
+		}
+		code := r.SeatCode
+		if code == "" {
+			code = r.SeatName
+		}
+		if code == "" {
+			continue
+		}
+		smap[code] = &model.Seat{Code: code, Name: r.SeatName}
+		ss = append(ss, &model.DragonTigerSeat{
+			Code:     r.Code,
+			Date:     date,
+			Reason:   r.Reason,
+			Side:     side,
+			SeatCode: code,
+			Buy:      emNullFloat(r.Buy),
+			Sell:     emNullFloat(r.Sell),
+			NetBuy:   emNullFloat(r.NetBuy),
+		})
+	}
+	return ss, pages, nil
+}
+
+//rankSeats ranks the seats of each list entry in their order, keeping the top ones.
+func rankSeats(ss []*model.DragonTigerSeat) (ranked []*model.DragonTigerSeat) {
+	ranks := make(map[string]int)
+	for _, s := range ss {
+		k := s.Code + "|" + s.Reason
+		if ranks[k] >= topSeats {
+			continue
+		}
+		ranks[k]++
+		s.Rank = ranks[k]
+		ranked = append(ranked, s)
+	}
+	return
+}
+
+func saveDragonTiger(dts []*model.DragonTiger, seats []*model.DragonTigerSeat, smap map[string]*model.Seat) (
+	e error) {
+	d, t := util.TimeStr()
+	tran, e := dbmap.Begin()
+	if e != nil {
+		return errors.WithStack(e)
+	}
+	exec := func(stmt string, args []interface{}) error {
+		if _, e := tran.Exec(stmt, args...); e != nil {
+			tran.Rollback()
+			return errors.WithStack(e)
+		}
+		return nil
+	}
+	valueStrings := make([]string, 0, len(dts))
+	valueArgs := make([]interface{}, 0, len(dts)*11)
+	for _, dt := range dts {
+		valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?,?,?,?)")
+		valueArgs = append(valueArgs, dt.Code, dt.Date, dt.Reason, dt.Close, dt.ChangeRate, dt.NetBuy, dt.Buy,
+			dt.Sell, dt.Amount, d, t)
+	}
+	if len(dts) > 0 {
+		if e = exec(fmt.Sprintf("insert into dragon_tiger (code,date,reason,close,change_rate,net_buy,buy,sell,"+
+			"amount,udate,utime) values %s on duplicate key update close=values(close),"+
+			"change_rate=values(change_rate),net_buy=values(net_buy),buy=values(buy),sell=values(sell),"+
+			"amount=values(amount),udate=values(udate),utime=values(utime)",
+			strings.Join(valueStrings, ",")), valueArgs); e != nil {
+			return
+		}
+	}
+	batch := 500
+	for i := 0; i < len(seats); i += batch {
+		end := i + batch
+		if end > len(seats) {
+			end = len(seats)
+		}
+		valueStrings = make([]string, 0, end-i)
+		valueArgs = make([]interface{}, 0, (end-i)*11)
+		for _, s := range seats[i:end] {
+			valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?,?,?,?)")
+			valueArgs = append(valueArgs, s.Code, s.Date, s.Reason, s.Side, s.Rank, s.SeatCode, s.Buy, s.Sell,
+				s.NetBuy, d, t)
+		}
+		if e = exec(fmt.Sprintf("insert into dragon_tiger_seat (code,date,reason,side,seat_rank,seat_code,buy,"+
+			"sell,net_buy,udate,utime) values %s on duplicate key update seat_code=values(seat_code),"+
+			"buy=values(buy),sell=values(sell),net_buy=values(net_buy),udate=values(udate),utime=values(utime)",
+			strings.Join(valueStrings, ",")), valueArgs); e != nil {
+			return
+		}
+	}
+	if len(smap) > 0 {
+		valueStrings = make([]string, 0, len(smap))
+		valueArgs = make([]interface{}, 0, len(smap)*4)
+		for _, s := range smap {
+			valueStrings = append(valueStrings, "(?,?,?,?)")
+			valueArgs = append(valueArgs, s.Code, s.Name, d, t)
+		}
+		if e = exec(fmt.Sprintf("insert into seat (code,name,udate,utime) values %s on duplicate key update "+
+			"name=values(name),udate=values(udate),utime=values(utime)", strings.Join(valueStrings, ",")),
+			valueArgs); e != nil {
+			return
+		}
+	}
+	return errors.WithStack(tran.Commit())
+}
+
+//updateSeatProfiles recalculates the returns of the stocks after the seats net buying on the lists,
+//based on the forward reinstated daily klines.
+func updateSeatProfiles() (e error) {
+	sqlt, e := dot.Raw("UPDATE_SEAT_PROFILE")
+	if e != nil {
+		return errors.WithStack(e)
+	}
+	for _, days := range seatProfileDays {
+		if _, e = dbmap.Exec(fmt.Sprintf(sqlt, days)); e != nil {
+			return errors.Wrapf(e, "failed to update %d-day seat profiles", days)
+		}
+	}
+	log.Printf("seat profiles updated for %v trading days", seatProfileDays)
+	return nil
+}
+
+//LatestDragonTiger loads the Dragon-Tiger list entries of the stocks, or all stocks if none is specified,
+//on the latest list date in database.
+func LatestDragonTiger(codes ...string) (date string, dts []*model.DragonTiger) {
+	latest, e := dbmap.SelectNullStr("select max(date) from dragon_tiger")
+	if e != nil {
+		log.Panicf("failed to query latest dragon-tiger list: %+v", e)
+	}
+	if !latest.Valid {
+		return
+	}
+	date = latest.String
+	qry := "select * from dragon_tiger where date = ?"
+	if len(codes) > 0 {
+		qry += fmt.Sprintf(" and code in (%s)", util.Join(codes, ",", true))
+	}
+	_, e = dbmap.Select(&dts, qry+" order by code, reason", date)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("failed to load dragon-tiger list of %s: %+v", date, e)
+	}
+	return
+}
+
+//DragonTigerSeats loads the top seats of the side on the Dragon-Tiger lists of the stocks on the date.
+func DragonTigerSeats(date, side string, codes ...string) (ss []*model.DragonTigerSeat) {
+	qry := "select * from dragon_tiger_seat where date = ? and side = ?"
+	if len(codes) > 0 {
+		qry += fmt.Sprintf(" and code in (%s)", util.Join(codes, ",", true))
+	}
+	_, e := dbmap.Select(&ss, qry+" order by code, reason, seat_rank", date, side)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("failed to load dragon-tiger seats of %s: %+v", date, e)
+	}
+	return
+}
+
+//SeatProfiles loads the profiles of the seats by the number of trading days after appearance, keyed by
+//seat code.
+func SeatProfiles(days int, seats ...string) map[string]*model.SeatProfile {
+	qry := "select * from seat_profile where days = ?"
+	if len(seats) > 0 {
+		qry += fmt.Sprintf(" and seat_code in (%s)", util.Join(seats, ",", true))
+	}
+	var ps []*model.SeatProfile
+	_, e := dbmap.Select(&ps, qry, days)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("failed to load %d-day seat profiles: %+v", days, e)
+	}
+	m := make(map[string]*model.SeatProfile, len(ps))
+	for _, p := range ps {
+		m[p.SeatCode] = p
+	}
+	return m
+}
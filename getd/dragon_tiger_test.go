@@ -0,0 +1,57 @@
+package getd
+
+import (
+	"testing"
+
+	"github.com/carusyte/stock/model"
+)
+
+func TestParseDragonTiger(t *testing.T) {
+	dts, pages, e := parseDragonTiger([]byte(`{"result":{"pages":1,"data":[` +
+		`{"SECURITY_CODE":"600000","TRADE_DATE":"2020-10-16 00:00:00","EXPLANATION":"日涨幅偏离值达到7%的前5只证券",` +
+		`"CLOSE_PRICE":10.02,"CHANGE_RATE":10.01,"BILLBOARD_NET_AMT":12345678.9,"BILLBOARD_BUY_AMT":null}]}}`))
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if pages != 1 || len(dts) != 1 {
+		t.Fatalf("want 1 entry of 1 page, got %d of %d", len(dts), pages)
+	}
+	if dt := dts[0]; dt.Date != "2020-10-16" || dt.Reason == "" || dt.NetBuy.Float64 != 12345678.9 || dt.Buy.Valid {
+		t.Errorf("unexpected entry: %+v", dt)
+	}
+}
+
+func TestParseDragonTigerSeats(t *testing.T) {
+	smap := make(map[string]*model.Seat)
+	ss, _, e := parseDragonTigerSeats([]byte(`{"result":{"pages":1,"data":[`+
+		`{"SECURITY_CODE":"600000","TRADE_DATE":"2020-10-16 00:00:00","EXPLANATION":"r1",`+
+		`"OPERATEDEPT_CODE":"10001","OPERATEDEPT_NAME":"某证券某营业部","BUY":300,"SELL":0,"NET":300},`+
+		`{"SECURITY_CODE":"600000","TRADE_DATE":"2020-10-16 00:00:00","EXPLANATION":"r1",`+
+		`"OPERATEDEPT_CODE":"","OPERATEDEPT_NAME":"机构专用","BUY":200,"SELL":50,"NET":150},`+
+		`{"SECURITY_CODE":"600000","TRADE_DATE":"2020-10-16 00:00:00","EXPLANATION":"r1",`+
+		`"OPERATEDEPT_CODE":"","OPERATEDEPT_NAME":"","BUY":100}]}}`), SeatBuy, smap)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if len(ss) != 2 || ss[1].SeatCode != "机构专用" || ss[0].Side != SeatBuy {
+		t.Fatalf("unexpected seats: %+v", ss)
+	}
+	if len(smap) != 2 || smap["10001"].Name != "某证券某营业部" {
+		t.Errorf("unexpected seat dimensions: %+v", smap)
+	}
+}
+
+func TestRankSeats(t *testing.T) {
+	var ss []*model.DragonTigerSeat
+	for i := 0; i < 7; i++ {
+		ss = append(ss, &model.DragonTigerSeat{Code: "600000", Reason: "r1"})
+	}
+	ss = append(ss, &model.DragonTigerSeat{Code: "600000", Reason: "r2"})
+	ranked := rankSeats(ss)
+	if len(ranked) != 6 {
+		t.Fatalf("want 6 ranked seats, got %d", len(ranked))
+	}
+	if ranked[4].Rank != 5 || ranked[5].Reason != "r2" || ranked[5].Rank != 1 {
+		t.Errorf("unexpected ranks: %+v, %+v", ranked[4], ranked[5])
+	}
+}
@@ -0,0 +1,371 @@
+package getd
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/global"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+//emStmtReports are the eastmoney F10 reports of the financial statements of general companies.
+//Banks, insurers and brokers report in other formats and are not covered.
+var emStmtReports = map[string]string{
+	model.StmtBalance:  "RPT_F10_FINANCE_GBALANCE",
+	model.StmtIncome:   "RPT_F10_FINANCE_GINCOME",
+	model.StmtCashFlow: "RPT_F10_FINANCE_GCASHFLOW",
+}
+
+//stmtItems maps the source fields of each statement to the canonical items.
+var stmtItems = map[string]map[string]string{
+	model.StmtBalance: {
+		"MONETARYFUNDS":           "cash",
+		"NOTE_RECE":               "notes_rece",
+		"ACCOUNTS_RECE":           "accounts_rece",
+		"PREPAYMENT":              "prepayment",
+		"OTHER_RECE":              "other_rece",
+		"INVENTORY":               "inventory",
+		"CONTRACT_ASSET":          "contract_assets",
+		"TOTAL_CURRENT_ASSETS":    "total_cur_assets",
+		"LONG_EQUITY_INVEST":      "lt_equity_invest",
+		"INVEST_REALESTATE":       "invest_realestate",
+		"FIXED_ASSET":             "fixed_assets",
+		"CIP":                     "cip",
+		"INTANGIBLE_ASSET":        "intangible_assets",
+		"DEVELOP_EXPENSE":         "dev_exp",
+		"GOODWILL":                "goodwill",
+		"LONG_PREPAID_EXPENSE":    "lt_prepaid_exp",
+		"DEFER_TAX_ASSET":         "deferred_tax_assets",
+		"TOTAL_NONCURRENT_ASSETS": "total_noncur_assets",
+		"TOTAL_ASSETS":            "total_assets",
+		"SHORT_LOAN":              "st_loan",
+		"NOTE_PAYABLE":            "notes_payable",
+		"ACCOUNTS_PAYABLE":        "accounts_payable",
+		"ADVANCE_RECEIVABLES":     "advance_rece",
+		"CONTRACT_LIAB":           "contract_liab",
+		"STAFF_SALARY_PAYABLE":    "salary_payable",
+		"TAX_PAYABLE":             "tax_payable",
+		"NONCURRENT_LIAB_1YEAR":   "noncur_liab_1y",
+		"TOTAL_CURRENT_LIAB":      "total_cur_liab",
+		"LONG_LOAN":               "lt_loan",
+		"BOND_PAYABLE":            "bonds_payable",
+		"LEASE_LIAB":              "lease_liab",
+		"TOTAL_NONCURRENT_LIAB":   "total_noncur_liab",
+		"TOTAL_LIABILITIES":       "total_liab",
+		"SHARE_CAPITAL":           "share_capital",
+		"CAPITAL_RESERVE":         "capital_reserve",
+		"TREASURY_SHARES":         "treasury_shares",
+		"SURPLUS_RESERVE":         "surplus_reserve",
+		"UNASSIGN_RPOFIT":         "retained_earnings",
+		"TOTAL_PARENT_EQUITY":     "parent_equity",
+		"MINORITY_EQUITY":         "minority_equity",
+		"TOTAL_EQUITY":            "total_equity",
+		"TOTAL_LIAB_EQUITY":       "total_liab_equity",
+	},
+	model.StmtIncome: {
+		"TOTAL_OPERATE_INCOME":     "total_revenue",
+		"OPERATE_INCOME":           "revenue",
+		"TOTAL_OPERATE_COST":       "total_op_cost",
+		"OPERATE_COST":             "cost_of_revenue",
+		"OPERATE_TAX_ADD":          "taxes_surcharges",
+		"SALE_EXPENSE":             "selling_exp",
+		"MANAGE_EXPENSE":           "admin_exp",
+		"RESEARCH_EXPENSE":         "rd_exp",
+		"FINANCE_EXPENSE":          "fin_exp",
+		"FE_INTEREST_EXPENSE":      "interest_exp",
+		"FE_INTEREST_INCOME":       "interest_income",
+		"OTHER_INCOME":             "other_income",
+		"INVEST_INCOME":            "invest_income",
+		"FAIRVALUE_CHANGE_INCOME":  "fv_chg_income",
+		"CREDIT_IMPAIRMENT_INCOME": "credit_impairment",
+		"ASSET_IMPAIRMENT_INCOME":  "asset_impairment",
+		"ASSET_DISPOSAL_INCOME":    "asset_disposal_income",
+		"OPERATE_PROFIT":           "op_profit",
+		"NONBUSINESS_INCOME":       "nonop_income",
+		"NONBUSINESS_EXPENSE":      "nonop_exp",
+		"TOTAL_PROFIT":             "total_profit",
+		"INCOME_TAX":               "income_tax",
+		"NETPROFIT":                "net_profit",
+		"PARENT_NETPROFIT":         "parent_np",
+		"MINORITY_INTEREST":        "minority_np",
+		"DEDUCT_PARENT_NETPROFIT":  "parent_np_adn",
+		"BASIC_EPS":                "basic_eps",
+		"DILUTED_EPS":              "diluted_eps",
+		"TOTAL_COMPRE_INCOME":      "total_compre_income",
+	},
+	model.StmtCashFlow: {
+		"SALES_SERVICES":         "cash_from_sales",
+		"RECEIVE_TAX_REFUND":     "tax_refund",
+		"TOTAL_OPERATE_INFLOW":   "op_inflow",
+		"BUY_SERVICES":           "cash_for_purchases",
+		"PAY_STAFF_CASH":         "cash_for_staff",
+		"PAY_ALL_TAX":            "taxes_paid",
+		"TOTAL_OPERATE_OUTFLOW":  "op_outflow",
+		"NETCASH_OPERATE":        "net_cf_op",
+		"WITHDRAW_INVEST":        "invest_withdrawn",
+		"RECEIVE_INVEST_INCOME":  "invest_income_received",
+		"DISPOSAL_LONG_ASSET":    "lt_assets_disposed",
+		"TOTAL_INVEST_INFLOW":    "invest_inflow",
+		"CONSTRUCT_LONG_ASSET":   "capex",
+		"INVEST_PAY_CASH":        "invest_paid",
+		"TOTAL_INVEST_OUTFLOW":   "invest_outflow",
+		"NETCASH_INVEST":         "net_cf_invest",
+		"ACCEPT_INVEST_CASH":     "invest_accepted",
+		"RECEIVE_LOAN_CASH":      "loans_received",
+		"TOTAL_FINANCE_INFLOW":   "fin_inflow",
+		"PAY_DEBT_CASH":          "debt_repaid",
+		"ASSIGN_DIVIDEND_PORFIT": "dividends_paid",
+		"TOTAL_FINANCE_OUTFLOW":  "fin_outflow",
+		"NETCASH_FINANCE":        "net_cf_fin",
+		"CCE_ADD":                "net_cash_chg",
+		"BEGIN_CCE":              "begin_cash",
+		"END_CCE":                "end_cash",
+		"ASSET_IMPAIRMENT":       "asset_impairment",
+		"FA_IR_DEPR":             "depreciation",
+		"IA_AMORTIZE":            "amortization",
+	},
+}
+
+var (
+	//unmappedStmtField counts the numeric source fields of each statement with no canonical item, keyed by
+	//statement and field, e.g. BS.OTHER_CURRENT_ASSET.
+	unmappedStmtField = make(map[string]int)
+	unmappedStmtLock  sync.Mutex
+)
+
+//finStmtRefresh is the number of the latest periods in database fetched again on every run, so that
+//restatements are picked up.
+const finStmtRefresh = 8
+
+//GetFinStmt fetches the balance sheets, income statements and cash flow statements of the stocks since the
+//latest reports in database, re-fetching the last finStmtRefresh periods, and saves the mapped line items
+//in long format.
+//Returns the stocks successfully updated.
+func GetFinStmt(stocks *model.Stocks) (rstks *model.Stocks) {
+	log.Println("getting financial statements...")
+	var wg sync.WaitGroup
+	chstk := make(chan *model.Stock, global.JobCapacity)
+	chrstk := make(chan *model.Stock, global.JobCapacity)
+	rstks = new(model.Stocks)
+	wgr := collect(rstks, chrstk)
+	for i := 0; i < conf.Args.Concurrency; i++ {
+		wg.Add(1)
+		go getFinStmt(chstk, &wg, chrstk)
+	}
+	for _, s := range stocks.List {
+		chstk <- s
+	}
+	close(chstk)
+	wg.Wait()
+	close(chrstk)
+	wgr.Wait()
+	log.Printf("%d financial statements updated", rstks.Size())
+	if stocks.Size() != rstks.Size() {
+		same, skp := stocks.Diff(rstks)
+		if !same {
+			log.Printf("Failed: %+v", skp)
+		}
+	}
+	unmappedStmtLock.Lock()
+	if len(unmappedStmtField) > 0 {
+		log.Printf("%d unmapped statement fields: %+v", len(unmappedStmtField), unmappedStmtField)
+	}
+	unmappedStmtLock.Unlock()
+	return
+}
+
+func getFinStmt(chstk chan *model.Stock, wg *sync.WaitGroup, chrstk chan *model.Stock) {
+	defer wg.Done()
+	RETRIES := conf.Args.DataSource.KlineFailureRetry
+	for stock := range chstk {
+		wait := 1000
+		for rtCount := 0; rtCount <= RETRIES; rtCount++ {
+			ok, r := doGetFinStmt(stock)
+			if ok {
+				chrstk <- stock
+				break
+			} else if r {
+				log.Printf("%s retrying %d...", stock.Code, rtCount+1)
+				time.Sleep(time.Millisecond * time.Duration(wait+rand.Intn(wait)))
+				continue
+			} else {
+				log.Printf("%s retried %d, giving up. restart the program to recover", stock.Code, rtCount+1)
+				break
+			}
+		}
+	}
+}
+
+func doGetFinStmt(stock *model.Stock) (ok, retry bool) {
+	code := stock.Code
+	for _, stmt := range []string{model.StmtBalance, model.StmtIncome, model.StmtCashFlow} {
+		since, e := dbmap.SelectNullStr("select min(year) from (select distinct year from fin_stmt "+
+			"where code = ? and statement = ? order by year desc limit ?) t", code, stmt, finStmtRefresh)
+		if e != nil {
+			log.Printf("%s failed to query latest %s statement: %+v", code, stmt, e)
+			return false, true
+		}
+		filter := fmt.Sprintf(`(SECUCODE="%s.%s")`, code, stock.Market.String)
+		var fss []*model.FinStmt
+		for page, pages := 1, 1; page <= pages; page++ {
+			body, e := fetchEmReport(emStmtReports[stmt], "REPORT_DATE", filter, page)
+			if e != nil {
+				log.Printf("%s failed to get %s statement: %+v", code, stmt, e)
+				return false, true
+			}
+			var ps []*model.FinStmt
+			if ps, pages, e = parseFinStmt(body, stmt); e != nil {
+				log.Printf("%s %+v", code, e)
+				return false, true
+			}
+			for _, f := range ps {
+				if f.Year < since.String {
+					pages = 0
+					break
+				}
+				fss = append(fss, f)
+			}
+		}
+		if e = saveFinStmt(code, stmt, fss); e != nil {
+			log.Printf("%s %+v", code, e)
+			return false, true
+		}
+	}
+	return true, false
+}
+
+//parseFinStmt converts the statement report rows to line items, in the order of the rows and then the
+//items. Fields of year-on-year ratios and non-numeric fields are ignored. Free cash flow is derived as
+//the operating cash flow less the capital expenditure.
+func parseFinStmt(body []byte, stmt string) (fss []*model.FinStmt, pages int, e error) {
+	var rows []map[string]interface{}
+	if pages, e = parseEmReport(body, &rows); e != nil {
+		return nil, 0, e
+	}
+	mapping := stmtItems[stmt]
+	for _, r := range rows {
+		code, _ := r["SECURITY_CODE"].(string)
+		dt, _ := r["REPORT_DATE"].(string)
+		year, e := emDate(dt)
+		if e != nil {
+			return nil, 0, errors.Wrapf(e, "invalid %s statement date of %s", stmt, code)
+		}
+		items := make(map[string]float64)
+		for k, v := range r {
+			f, ok := v.(float64)
+			if !ok || strings.HasSuffix(k, "_YOY") {
+				continue
+			}
+			item, ok := mapping[k]
+			if !ok {
+				unmappedStmt(stmt, k)
+				continue
+			}
+			items[item] = f
+		}
+		if stmt == model.StmtCashFlow {
+			op, ok1 := items["net_cf_op"]
+			capex, ok2 := items["capex"]
+			if ok1 && ok2 {
+				items["fcf"] = op - capex
+			}
+		}
+		keys := make([]string, 0, len(items))
+		for k := range items {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fss = append(fss, &model.FinStmt{
+				Code:      code,
+				Year:      year,
+				Period:    finPeriod(year),
+				Statement: stmt,
+				Item:      k,
+				Value:     items[k],
+			})
+		}
+	}
+	return fss, pages, nil
+}
+
+//unmappedStmt records the source field with no canonical item, logging it on first sight.
+func unmappedStmt(stmt, field string) {
+	key := stmt + "." + field
+	unmappedStmtLock.Lock()
+	defer unmappedStmtLock.Unlock()
+	if _, ok := unmappedStmtField[key]; !ok {
+		log.Debugf("unmapped statement field: %s", key)
+	}
+	unmappedStmtField[key]++
+}
+
+//saveFinStmt replaces the line items of the statement of the periods fetched, dropping the items no longer
+//reported by a restatement.
+func saveFinStmt(code, statement string, fss []*model.FinStmt) (e error) {
+	if len(fss) == 0 {
+		return nil
+	}
+	years := make([]string, 0, 16)
+	for _, f := range fss {
+		if len(years) == 0 || years[len(years)-1] != f.Year {
+			years = append(years, f.Year)
+		}
+	}
+	tran, e := dbmap.Begin()
+	if e != nil {
+		return errors.Wrap(e, "failed to start transaction")
+	}
+	if _, e = tran.Exec(fmt.Sprintf("delete from fin_stmt where code = ? and statement = ? and year in (%s)",
+		util.Join(years, ",", true)), code, statement); e != nil {
+		tran.Rollback()
+		return errors.Wrap(e, "failed to delete refreshed financial statements")
+	}
+	d, t := util.TimeStr()
+	batch := 500
+	for i := 0; i < len(fss); i += batch {
+		end := i + batch
+		if end > len(fss) {
+			end = len(fss)
+		}
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]interface{}, 0, (end-i)*8)
+		for _, f := range fss[i:end] {
+			valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?)")
+			valueArgs = append(valueArgs, f.Code, f.Year, f.Period, f.Statement, f.Item, f.Value, d, t)
+		}
+		stmt := fmt.Sprintf("insert into fin_stmt (code,year,period,statement,item,value,udate,utime) values %s "+
+			"on duplicate key update period=values(period),value=values(value),udate=values(udate),"+
+			"utime=values(utime)", strings.Join(valueStrings, ","))
+		if _, e = tran.Exec(stmt, valueArgs...); e != nil {
+			tran.Rollback()
+			return errors.Wrap(e, "failed to save financial statements")
+		}
+	}
+	if e = tran.Commit(); e != nil {
+		return errors.Wrap(e, "failed to commit financial statements")
+	}
+	return nil
+}
+
+//FinStmts loads the line items of the stock in chronological order. All items are loaded if none is
+//specified.
+func FinStmts(code string, items ...string) (fss []*model.FinStmt) {
+	qry := "select * from fin_stmt where code = ?"
+	args := []interface{}{code}
+	if len(items) > 0 {
+		qry += fmt.Sprintf(" and item in (%s)", util.Join(items, ",", true))
+	}
+	_, e := dbmap.Select(&fss, qry+" order by year, statement, item", args...)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("%s failed to load financial statements: %+v", code, e)
+	}
+	return
+}
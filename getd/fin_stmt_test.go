@@ -0,0 +1,47 @@
+package getd
+
+import (
+	"testing"
+
+	"github.com/carusyte/stock/model"
+)
+
+func TestParseFinStmt(t *testing.T) {
+	fss, pages, e := parseFinStmt([]byte(`{"result":{"pages":3,"data":[{"SECUCODE":"600000.SH",`+
+		`"SECURITY_CODE":"600000","REPORT_DATE":"2020-09-30 00:00:00","REPORT_TYPE":"三季报",`+
+		`"NETCASH_OPERATE":1500.5,"CONSTRUCT_LONG_ASSET":500.5,"NETCASH_OPERATE_YOY":12.3,`+
+		`"END_CCE":null,"TEST_UNMAPPED_FIELD":1}]},"success":true}`), model.StmtCashFlow)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if pages != 3 {
+		t.Errorf("want 3 pages, got %d", pages)
+	}
+	items := make(map[string]float64)
+	for _, f := range fss {
+		if f.Code != "600000" || f.Year != "2020-09-30" || f.Period != model.PeriodQ3 ||
+			f.Statement != model.StmtCashFlow {
+			t.Errorf("unexpected line item: %+v", f)
+		}
+		items[f.Item] = f.Value
+	}
+	want := map[string]float64{"net_cf_op": 1500.5, "capex": 500.5, "fcf": 1000}
+	if len(items) != len(want) {
+		t.Errorf("want items %v, got %v", want, items)
+	}
+	for k, v := range want {
+		if items[k] != v {
+			t.Errorf("want %s = %f, got %f", k, v, items[k])
+		}
+	}
+	unmappedStmtLock.Lock()
+	n := unmappedStmtField[model.StmtCashFlow+".TEST_UNMAPPED_FIELD"]
+	unmappedStmtLock.Unlock()
+	if n == 0 {
+		t.Error("want unmapped field recorded")
+	}
+	if _, _, e = parseFinStmt([]byte(`{"result":{"pages":1,"data":[{"REPORT_DATE":"2020"}]}}`),
+		model.StmtBalance); e == nil {
+		t.Error("want error for invalid report date")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
 	"math/rand"
 	"net/http"
@@ -127,7 +128,6 @@ func parse10jqkBonus(stock *model.Stock) (ok, retry bool) {
 	urlt := `http://basic.10jqka.com.cn/%s/bonus.html`
 	url := fmt.Sprintf(urlt, stock.Code)
 
-	var xdxrs []*model.Xdxr
 	// Load the URL
 	res, e := util.HTTPGetResponse(url, nil, false, true, true)
 	if e != nil {
@@ -136,33 +136,42 @@ func parse10jqkBonus(stock *model.Stock) (ok, retry bool) {
 	}
 	defer res.Body.Close()
 
+	xdxrs, ok, retry := read10jqkBonus(stock, url, res.Body)
+	if ok && len(xdxrs) > 0 {
+		saveXdxrs(xdxrs)
+	}
+	return
+}
+
+//read10jqkBonus parses the GBK encoded bonus page of 10jqka.
+func read10jqkBonus(stock *model.Stock, url string, body io.Reader) (xdxrs []*model.Xdxr, ok, retry bool) {
 	// Convert the designated charset HTML to utf-8 encoded HTML.
-	utfBody := transform.NewReader(res.Body, simplifiedchinese.GBK.NewDecoder())
+	utfBody := transform.NewReader(body, simplifiedchinese.GBK.NewDecoder())
 
 	// parse body using goquery
 	doc, e := goquery.NewDocumentFromReader(utfBody)
 	if e != nil {
 		log.Printf("[%s,%s] failed to read from response body, retrying...", stock.Code,
 			stock.Name)
-		return false, true
+		return nil, false, true
 	}
 
 	if strings.Contains(doc.Text(), conf.Args.DataSource.THS.FailureKeyword) {
 		log.Printf("%s encounter authorization block, retrying: %s", stock.Code, url)
-		return false, true
+		return nil, false, true
 	}
 
 	numXdxr := strings.TrimSpace(doc.Find("#bonuslist div.bd.pt5.pagination div strong").Text())
 	if len(numXdxr) == 0 {
 		log.Warnf("%s possible erroneous page encountered."+
 			" unable to find xdxr counts in page %s", stock.Code, url)
-		return false, true
+		return nil, false, true
 	}
 
 	//if table doesn't exist and historical xdxr record is 0, return normally
 	if doc.Find("#bonus_table").Size() == 0 && numXdxr == "0" {
 		log.Printf("%s no xdxr data found at %s", stock.Code, url)
-		return true, false
+		return nil, true, false
 	}
 
 	//parse column index
@@ -261,7 +270,7 @@ func parse10jqkBonus(stock *model.Stock) (ok, retry bool) {
 	// no records found, possible errornous page encounter
 	if len(xdxrs) == 0 {
 		log.Printf("%s no xdxr data found at %s", stock.Code, url)
-		return false, true
+		return nil, false, true
 	}
 
 	// reverse order
@@ -269,9 +278,7 @@ func parse10jqkBonus(stock *model.Stock) (ok, retry bool) {
 		xdxrs[i].Idx = j
 	}
 
-	saveXdxrs(xdxrs)
-
-	return true, false
+	return xdxrs, true, false
 }
 
 // calculates dyr and dpr based on non-reinstated master kline data.
@@ -336,7 +343,7 @@ func stockDyrDpr(code string) {
 
 			// calculates dpr
 			eps, e := dbmap.SelectNullFloat("select eps from finance where code = ? "+
-				"and year < ? and year like '%-12-31' order by year desc limit 1", x.Code, date)
+				"and year < ? and period = 'FY' order by year desc limit 1", x.Code, date)
 			if e != nil {
 				log.Printf("failed to query eps for %s before %s", x.Code, date)
 			} else {
@@ -744,10 +751,11 @@ func saveFinPredict(code string, fpMap map[string]*model.FinPredict) bool {
 	}
 	rt = 0
 	//update to database
+	d, _ := util.TimeStr()
 	valueStrings := make([]string, 0, len(fpMap))
-	valueArgs := make([]interface{}, 0, len(fpMap)*14)
+	valueArgs := make([]interface{}, 0, len(fpMap)*18)
 	for _, fp := range fpMap {
-		valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 		valueArgs = append(valueArgs, fp.Code)
 		valueArgs = append(valueArgs, fp.Year)
 		valueArgs = append(valueArgs, fp.EpsNum)
@@ -755,22 +763,27 @@ func saveFinPredict(code string, fpMap map[string]*model.FinPredict) bool {
 		valueArgs = append(valueArgs, fp.EpsAvg)
 		valueArgs = append(valueArgs, fp.EpsMax)
 		valueArgs = append(valueArgs, fp.EpsIndAvg)
+		valueArgs = append(valueArgs, fp.EpsUpRt)
+		valueArgs = append(valueArgs, fp.EpsDnRt)
 		valueArgs = append(valueArgs, fp.NpNum)
 		valueArgs = append(valueArgs, fp.NpMin)
 		valueArgs = append(valueArgs, fp.NpAvg)
 		valueArgs = append(valueArgs, fp.NpMax)
 		valueArgs = append(valueArgs, fp.NpIndAvg)
+		valueArgs = append(valueArgs, fp.NpUpRt)
+		valueArgs = append(valueArgs, fp.NpDnRt)
 		valueArgs = append(valueArgs, fp.Udate)
 		valueArgs = append(valueArgs, fp.Utime)
 	}
-	stmt := fmt.Sprintf("INSERT INTO fin_predict (code,year,eps_num,eps_min,eps_avg,eps_max,eps_ind_avg,np_num,"+
-		"np_min,np_avg,np_max,np_ind_avg,udate,utime) VALUES"+
-		" %s"+
-		" on duplicate key update eps_num=values(eps_num),eps_min=values(eps_min),eps_avg=values(eps_avg),"+
-		"eps_max=values(eps_max),eps_ind_avg=values(eps_ind_avg),"+
-		"np_num=values(np_num),np_min=values(np_min),np_avg=values(np_avg),np_max=values(np_max),"+
-		"np_ind_avg=values(np_ind_avg),udate=values(udate),utime=values(utime)",
-		strings.Join(valueStrings, ","))
+	cols := "eps_num,eps_min,eps_avg,eps_max,eps_ind_avg,eps_up_rt,eps_dn_rt,np_num,np_min,np_avg,np_max," +
+		"np_ind_avg,np_up_rt,np_dn_rt,udate,utime"
+	upd := " on duplicate key update eps_num=values(eps_num),eps_min=values(eps_min),eps_avg=values(eps_avg)," +
+		"eps_max=values(eps_max),eps_ind_avg=values(eps_ind_avg),eps_up_rt=values(eps_up_rt)," +
+		"eps_dn_rt=values(eps_dn_rt),np_num=values(np_num),np_min=values(np_min),np_avg=values(np_avg)," +
+		"np_max=values(np_max),np_ind_avg=values(np_ind_avg),np_up_rt=values(np_up_rt)," +
+		"np_dn_rt=values(np_dn_rt),udate=values(udate),utime=values(utime)"
+	stmt := fmt.Sprintf("INSERT INTO fin_predict (code,year,%s) VALUES %s%s", cols,
+		strings.Join(valueStrings, ","), upd)
 	for ; rt < retry; rt++ {
 		_, e := global.Dbmap.Exec(stmt, valueArgs...)
 		if e != nil {
@@ -786,9 +799,116 @@ func saveFinPredict(code string, fpMap map[string]*model.FinPredict) bool {
 	if rt >= retry {
 		log.Panicf("%s failed to bulk update fin_predict, too much deadlock", code)
 	}
+	// keep the snapshot of the day for the revision history. the revision ratios stay null as 10jqka does not
+	// provide them, in which case the breadth is derived from the consecutive snapshots.
+	hvs := make([]string, 0, len(fpMap))
+	hargs := make([]interface{}, 0, len(fpMap)*19)
+	for i := 0; i < len(valueArgs); i += 18 {
+		hvs = append(hvs, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		hargs = append(hargs, valueArgs[i], valueArgs[i+1], d)
+		hargs = append(hargs, valueArgs[i+2:i+18]...)
+	}
+	stmt = fmt.Sprintf("INSERT INTO fin_predict_hist (code,year,date,%s) VALUES %s%s", cols,
+		strings.Join(hvs, ","), upd)
+	for rt = 0; rt < retry; rt++ {
+		_, e := global.Dbmap.Exec(stmt, hargs...)
+		if e != nil {
+			fmt.Println(e)
+			if strings.Contains(e.Error(), "Deadlock") {
+				continue
+			} else {
+				log.Panicf("%s failed to bulk update fin_predict_hist\n%+v", code, e)
+			}
+		}
+		break
+	}
+	if rt >= retry {
+		log.Panicf("%s failed to bulk update fin_predict_hist, too much deadlock", code)
+	}
 	return true
 }
 
+//FinPredictRevs loads the prediction snapshots of the stock, and derives the revisions of the consensus
+//for each predicted year, keyed by year.
+func FinPredictRevs(code string) (revs map[string]*model.FinPredictRev) {
+	var hist []*model.FinPredictHist
+	_, e := dbmap.Select(&hist, "select * from fin_predict_hist where code = ? order by year, date", code)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("%s failed to load prediction snapshots: %+v", code, e)
+	}
+	revs = make(map[string]*model.FinPredictRev)
+	for i, j := 0, 0; i < len(hist); i = j {
+		for j = i + 1; j < len(hist) && hist[j].Year == hist[i].Year; j++ {
+		}
+		if rev := calFinPredictRev(hist[i:j]); rev != nil {
+			revs[rev.Year] = rev
+		}
+	}
+	return
+}
+
+//calFinPredictRev derives the revision of the consensus from the snapshots of a year in chronological
+//order. The change over n months compares the latest snapshot with the last one at least n months earlier,
+//and is null if the history is shorter. The breadth is the difference between the up and down revision
+//ratios of the institutions if available, otherwise that between the raises and cuts of the consensus EPS
+//in the past 3 months relative to their sum.
+func calFinPredictRev(hist []*model.FinPredictHist) (rev *model.FinPredictRev) {
+	if len(hist) == 0 {
+		return nil
+	}
+	last := hist[len(hist)-1]
+	rev = &model.FinPredictRev{Code: last.Code, Year: last.Year, Date: last.Date, EpsAvg: last.EpsAvg}
+	ld, e := time.Parse(global.DateFormat, last.Date)
+	if e != nil {
+		log.Warnf("%s invalid prediction snapshot date: %s", last.Code, last.Date)
+		return
+	}
+	//base returns the last snapshot at least n months before the latest one
+	base := func(n int) *model.FinPredictHist {
+		target := ld.AddDate(0, -n, 0).Format(global.DateFormat)
+		for i := len(hist) - 1; i >= 0; i-- {
+			if hist[i].Date <= target {
+				return hist[i]
+			}
+		}
+		return nil
+	}
+	chg := func(cur, prev sql.NullFloat64) sql.NullFloat64 {
+		if !cur.Valid || !prev.Valid || prev.Float64 == 0 {
+			return sql.NullFloat64{}
+		}
+		return sql.NullFloat64{Float64: (cur.Float64 - prev.Float64) / math.Abs(prev.Float64) * 100, Valid: true}
+	}
+	if b := base(1); b != nil {
+		rev.EpsChg1M = chg(last.EpsAvg, b.EpsAvg)
+	}
+	if b := base(3); b != nil {
+		rev.EpsChg3M = chg(last.EpsAvg, b.EpsAvg)
+		rev.NpChg3M = chg(last.NpAvg, b.NpAvg)
+	}
+	if b := base(6); b != nil {
+		rev.EpsChg6M = chg(last.EpsAvg, b.EpsAvg)
+	}
+	since := ld.AddDate(0, -3, 0).Format(global.DateFormat)
+	for i := 1; i < len(hist); i++ {
+		p, c := hist[i-1], hist[i]
+		if c.Date <= since || !p.EpsAvg.Valid || !c.EpsAvg.Valid {
+			continue
+		}
+		if c.EpsAvg.Float64 > p.EpsAvg.Float64 {
+			rev.EpsUps++
+		} else if c.EpsAvg.Float64 < p.EpsAvg.Float64 {
+			rev.EpsDowns++
+		}
+	}
+	if last.EpsUpRt.Valid && last.EpsDnRt.Valid {
+		rev.Breadth = sql.NullFloat64{Float64: last.EpsUpRt.Float64 - last.EpsDnRt.Float64, Valid: true}
+	} else if n := rev.EpsUps + rev.EpsDowns; n > 0 {
+		rev.Breadth = sql.NullFloat64{Float64: float64(rev.EpsUps-rev.EpsDowns) / float64(n), Valid: true}
+	}
+	return
+}
+
 func newFinPredict(code string) *model.FinPredict {
 	fp := new(model.FinPredict)
 	fp.Code = code
@@ -830,45 +950,17 @@ func parseFinancePage(chstk chan *model.Stock, wg *sync.WaitGroup, chrstk chan *
 }
 
 func doParseFinPage(url string, code string) (ok, retry bool) {
-	var (
-		res *http.Response
-		doc *goquery.Document
-		e   error
-	)
 	// Load the URL
-	res, e = util.HTTPGetResponse(url, nil, false, true, true)
+	res, e := util.HTTPGetResponse(url, nil, false, true, true)
 	if e != nil {
 		log.Printf("%s, http failed %s", code, url)
 		return false, true
 	}
 	defer res.Body.Close()
-	// Convert the designated charset HTML to utf-8 encoded HTML.
-	utfBody := transform.NewReader(res.Body, simplifiedchinese.GBK.NewDecoder())
-	// parse body using goquery
-	doc, e = goquery.NewDocumentFromReader(utfBody)
-	if e != nil {
-		log.Printf("%s failed to read from response body, retrying...", code)
-		return false, true
-	}
-
-	if strings.Contains(doc.Text(), conf.Args.DataSource.THS.FailureKeyword) {
-		log.Printf("%s encounter authorization block, retrying: %s", code, url)
-		return false, true
-	}
-
-	fr := &model.FinReport{Code: code, UnmappedField: unmappedField}
-	jsonStr := doc.Find("#main").Text()
-	if e = json.Unmarshal([]byte(jsonStr), fr); e != nil {
-		jsonStr = doc.Find(".main").Text()
-		fr = &model.FinReport{Code: code, UnmappedField: unmappedField}
-		if e = json.Unmarshal([]byte(jsonStr), fr); e != nil {
-			log.Printf("%s failed to parse json, retrying...\n%s", code, url)
-			return false, true
-		}
+	fins, ok, retry := readFinPage(url, code, res.Body)
+	if !ok {
+		return
 	}
-	fr.SetCode(code)
-	fins := fr.Items
-	fins = organize(fins)
 	//update to database
 	if len(fins) > 0 {
 		valueStrings := make([]string, 0, len(fins))
@@ -876,7 +968,7 @@ func doParseFinPage(url string, code string) (ok, retry bool) {
 		ud, ut := util.TimeStr()
 		for _, f := range fins {
 			valueStrings = append(valueStrings, "(?, ?, ?, ?, round(?,2), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, "+
-				"round(?,2), ?, round(?,2), ?, ?, round(?,2), ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+				"round(?,2), ?, round(?,2), ?, ?, round(?,2), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 			valueArgs = append(valueArgs, f.Code)
 			valueArgs = append(valueArgs, f.Dar)
 			valueArgs = append(valueArgs, f.Crps)
@@ -907,12 +999,22 @@ func doParseFinPage(url string, code string) (ok, retry bool) {
 			valueArgs = append(valueArgs, f.QuickRatio)
 			valueArgs = append(valueArgs, f.ConsQuickRatio)
 			valueArgs = append(valueArgs, f.EquityRatio)
+			valueArgs = append(valueArgs, f.Period)
+			valueArgs = append(valueArgs, f.EpsQ)
+			valueArgs = append(valueArgs, f.NpQ)
+			valueArgs = append(valueArgs, f.NpAdnQ)
+			valueArgs = append(valueArgs, f.GrQ)
+			valueArgs = append(valueArgs, f.EpsTtm)
+			valueArgs = append(valueArgs, f.NpTtm)
+			valueArgs = append(valueArgs, f.GrTtm)
+			valueArgs = append(valueArgs, f.RoeTtm)
 			valueArgs = append(valueArgs, ud)
 			valueArgs = append(valueArgs, ut)
 		}
 		stmt := fmt.Sprintf("INSERT INTO finance (code,dar,crps,eps,eps_yoy,gpm,busi_cycle,gr,gr_yoy,itr,navps,np,np_adn,"+
 			"np_adn_yoy,npm,np_yoy,ocfps,ocfps_yoy,roe,roe_yoy,roe_dlt,udpps,udpps_yoy,year,"+
-			"inv_turnover_days,ar_turnover_days,cur_ratio,quick_ratio,cons_quick_ratio,equity_ratio,udate,utime) VALUES"+
+			"inv_turnover_days,ar_turnover_days,cur_ratio,quick_ratio,cons_quick_ratio,equity_ratio,period,eps_q,np_q,"+
+			"np_adn_q,gr_q,eps_ttm,np_ttm,gr_ttm,roe_ttm,udate,utime) VALUES"+
 			" %s"+
 			" on duplicate key update dar=values(dar),crps=values(crps),eps=values(eps),eps_yoy=values(eps_yoy),"+
 			"gpm=values(gpm),busi_cycle=values(busi_cycle),"+
@@ -924,7 +1026,9 @@ func doParseFinPage(url string, code string) (ok, retry bool) {
 			"inv_turnover_days=values(inv_turnover_days),ar_turnover_days=values(ar_turnover_days),"+
 			"cur_ratio=values(cur_ratio),quick_ratio=values(quick_ratio),"+
 			"cons_quick_ratio=values(cons_quick_ratio),equity_ratio=values(equity_ratio),"+
-			"udate=values(udate),utime=values(utime)",
+			"period=values(period),eps_q=values(eps_q),np_q=values(np_q),np_adn_q=values(np_adn_q),"+
+			"gr_q=values(gr_q),eps_ttm=values(eps_ttm),np_ttm=values(np_ttm),gr_ttm=values(gr_ttm),"+
+			"roe_ttm=values(roe_ttm),udate=values(udate),utime=values(utime)",
 			strings.Join(valueStrings, ","))
 		_, err := global.Dbmap.Exec(stmt, valueArgs...)
 		util.CheckErr(err, code+": failed to bulk update finance")
@@ -932,6 +1036,36 @@ func doParseFinPage(url string, code string) (ok, retry bool) {
 	return true, false
 }
 
+//readFinPage parses the GBK encoded finance page of 10jqka.
+func readFinPage(url, code string, body io.Reader) (fins []*model.Finance, ok, retry bool) {
+	// Convert the designated charset HTML to utf-8 encoded HTML.
+	utfBody := transform.NewReader(body, simplifiedchinese.GBK.NewDecoder())
+	// parse body using goquery
+	doc, e := goquery.NewDocumentFromReader(utfBody)
+	if e != nil {
+		log.Printf("%s failed to read from response body, retrying...", code)
+		return nil, false, true
+	}
+
+	if strings.Contains(doc.Text(), conf.Args.DataSource.THS.FailureKeyword) {
+		log.Printf("%s encounter authorization block, retrying: %s", code, url)
+		return nil, false, true
+	}
+
+	fr := &model.FinReport{Code: code, UnmappedField: unmappedField}
+	jsonStr := doc.Find("#main").Text()
+	if e = json.Unmarshal([]byte(jsonStr), fr); e != nil {
+		jsonStr = doc.Find(".main").Text()
+		fr = &model.FinReport{Code: code, UnmappedField: unmappedField}
+		if e = json.Unmarshal([]byte(jsonStr), fr); e != nil {
+			log.Printf("%s failed to parse json, retrying...\n%s", code, url)
+			return nil, false, true
+		}
+	}
+	fr.SetCode(code)
+	return organize(fr.Items), true, false
+}
+
 //Supplement data such as EpsYoy, OcfpsYoy, RoeYoy, UdppsYoy etc.
 func organize(fins []*model.Finance) []*model.Finance {
 	for i := 0; i < len(fins); i++ {
@@ -988,9 +1122,100 @@ func organize(fins []*model.Finance) []*model.Finance {
 			}
 		}
 	}
+	deriveFinance(fins)
 	return fins
 }
 
+//prevPeriodEnd is the end of the previous report period in the same fiscal year.
+var prevPeriodEnd = map[string]string{
+	model.PeriodH1: "-03-31",
+	model.PeriodQ3: "-06-30",
+	model.PeriodFY: "-09-30",
+}
+
+//finPeriod returns the period type of the report by the month of its end date, or empty if unknown.
+func finPeriod(year string) string {
+	if len(year) < 7 {
+		return ""
+	}
+	switch year[5:7] {
+	case "03":
+		return model.PeriodQ1
+	case "06":
+		return model.PeriodH1
+	case "09":
+		return model.PeriodQ3
+	case "12":
+		return model.PeriodFY
+	}
+	return ""
+}
+
+//finEquity estimates the shareholders' equity (亿) at the end of the report period, by NAVPS times the shares
+//implied by the net profit over EPS.
+func finEquity(f *model.Finance) (eq float64, ok bool) {
+	if f == nil || !f.Navps.Valid || !f.Np.Valid || !f.Eps.Valid || f.Eps.Float64 == 0 {
+		return 0, false
+	}
+	return f.Navps.Float64 * f.Np.Float64 / f.Eps.Float64, true
+}
+
+//deriveFinance sets the period types, derives the single-quarter values from the cumulative ones
+//of the same fiscal year, and the TTM values by adding the last annual report to the cumulative ones
+//less those of the same period last year. TTM ROE is the TTM net profit over the average equity at the end
+//of the period and of the same period last year.
+func deriveFinance(fins []*model.Finance) {
+	fmap := make(map[string]*model.Finance, len(fins))
+	for _, f := range fins {
+		fmap[f.Year] = f
+	}
+	diff := func(c, p sql.NullFloat64) sql.NullFloat64 {
+		if !c.Valid || !p.Valid {
+			return sql.NullFloat64{}
+		}
+		return sql.NullFloat64{Float64: c.Float64 - p.Float64, Valid: true}
+	}
+	ttm := func(c, fy, lp sql.NullFloat64) sql.NullFloat64 {
+		if !c.Valid || !fy.Valid || !lp.Valid {
+			return sql.NullFloat64{}
+		}
+		return sql.NullFloat64{Float64: c.Float64 + fy.Float64 - lp.Float64, Valid: true}
+	}
+	for _, f := range fins {
+		p := finPeriod(f.Year)
+		f.Period = sql.NullString{String: p, Valid: p != ""}
+		f.EpsQ, f.NpQ, f.NpAdnQ, f.GrQ = sql.NullFloat64{}, sql.NullFloat64{}, sql.NullFloat64{}, sql.NullFloat64{}
+		f.EpsTtm, f.NpTtm, f.GrTtm, f.RoeTtm = sql.NullFloat64{}, sql.NullFloat64{}, sql.NullFloat64{},
+			sql.NullFloat64{}
+		switch p {
+		case "":
+			continue
+		case model.PeriodQ1:
+			f.EpsQ, f.NpQ, f.NpAdnQ, f.GrQ = f.Eps, f.Np, f.NpAdn, f.Gr
+		default:
+			if pf, ok := fmap[f.Year[:4]+prevPeriodEnd[p]]; ok {
+				f.EpsQ, f.NpQ, f.NpAdnQ, f.GrQ = diff(f.Eps, pf.Eps), diff(f.Np, pf.Np), diff(f.NpAdn, pf.NpAdn),
+					diff(f.Gr, pf.Gr)
+			}
+		}
+		y, e := strconv.Atoi(f.Year[:4])
+		if e != nil {
+			continue
+		}
+		fy, lp := fmap[fmt.Sprintf("%d-12-31", y-1)], fmap[fmt.Sprintf("%d%s", y-1, f.Year[4:])]
+		if p == model.PeriodFY {
+			f.EpsTtm, f.NpTtm, f.GrTtm = f.Eps, f.Np, f.Gr
+		} else if fy != nil && lp != nil {
+			f.EpsTtm, f.NpTtm, f.GrTtm = ttm(f.Eps, fy.Eps, lp.Eps), ttm(f.Np, fy.Np, lp.Np), ttm(f.Gr, fy.Gr, lp.Gr)
+		}
+		eq, ok1 := finEquity(f)
+		leq, ok2 := finEquity(lp)
+		if f.NpTtm.Valid && ok1 && ok2 && eq+leq > 0 {
+			f.RoeTtm = sql.NullFloat64{Float64: f.NpTtm.Float64 / ((eq + leq) / 2.) * 100., Valid: true}
+		}
+	}
+}
+
 func findByYear(fins []*model.Finance, year string) *model.Finance {
 	for _, f := range fins {
 		if f.Year == year {
@@ -1,6 +1,8 @@
 package getd
 
 import (
+	"database/sql"
+	"math"
 	"reflect"
 	"testing"
 
@@ -66,6 +68,64 @@ func TestGetAllPerfPrediction(t *testing.T) {
 	GetFinPrediction(allstk)
 }
 
+func TestDeriveFinance(t *testing.T) {
+	nf := func(f float64) sql.NullFloat64 { return sql.NullFloat64{Float64: f, Valid: true} }
+	fins := []*model.Finance{
+		{Code: "600000", Year: "2020-06-30", Eps: nf(0.5), Np: nf(50), Roe: nf(6), Navps: nf(6)},
+		{Code: "600000", Year: "2020-03-31", Eps: nf(0.3), Np: nf(30), Roe: nf(3)},
+		{Code: "600000", Year: "2019-12-31", Eps: nf(1.0), Np: nf(100), Roe: nf(12)},
+		{Code: "600000", Year: "2019-06-30", Eps: nf(0.4), Np: nf(40), Roe: nf(5), Navps: nf(5)},
+	}
+	deriveFinance(fins)
+	h1, q1, fy, lh1 := fins[0], fins[1], fins[2], fins[3]
+	if h1.Period.String != model.PeriodH1 || q1.Period.String != model.PeriodQ1 ||
+		fy.Period.String != model.PeriodFY {
+		t.Fatalf("unexpected periods: %v %v %v", h1.Period, q1.Period, fy.Period)
+	}
+	if q1.NpQ.Float64 != 30 || h1.NpQ.Float64 != 20 || lh1.NpQ.Valid {
+		t.Errorf("unexpected single-quarter np: %v %v %v", q1.NpQ, h1.NpQ, lh1.NpQ)
+	}
+	if h1.NpTtm.Float64 != 110 || math.Abs(h1.RoeTtm.Float64-20) > 1e-9 || fy.EpsTtm.Float64 != 1.0 {
+		t.Errorf("unexpected ttm: %v %v %v", h1.NpTtm, h1.RoeTtm, fy.EpsTtm)
+	}
+	if q1.NpTtm.Valid || h1.GrTtm.Valid || fy.RoeTtm.Valid {
+		t.Errorf("want null ttm for missing reports: %v %v %v", q1.NpTtm, h1.GrTtm, fy.RoeTtm)
+	}
+}
+
+func TestCalFinPredictRev(t *testing.T) {
+	snap := func(date string, eps float64) *model.FinPredictHist {
+		h := &model.FinPredictHist{Date: date}
+		h.Code, h.Year = "600000", "2021"
+		h.EpsAvg = sql.NullFloat64{Float64: eps, Valid: true}
+		return h
+	}
+	hist := []*model.FinPredictHist{
+		snap("2020-03-01", 0.8),
+		snap("2020-06-15", 1.0),
+		snap("2020-08-01", 1.1),
+		snap("2020-09-01", 1.05),
+		snap("2020-10-16", 1.2),
+	}
+	rev := calFinPredictRev(hist)
+	if rev.Date != "2020-10-16" || rev.EpsAvg.Float64 != 1.2 {
+		t.Fatalf("unexpected latest snapshot: %+v", rev)
+	}
+	if math.Abs(rev.EpsChg1M.Float64-(1.2-1.05)/1.05*100) > 1e-9 {
+		t.Errorf("unexpected 1 month change: %v", rev.EpsChg1M)
+	}
+	if math.Abs(rev.EpsChg3M.Float64-20) > 1e-9 || math.Abs(rev.EpsChg6M.Float64-50) > 1e-9 {
+		t.Errorf("unexpected 3/6 month changes: %v %v", rev.EpsChg3M, rev.EpsChg6M)
+	}
+	if rev.EpsUps != 2 || rev.EpsDowns != 1 || math.Abs(rev.Breadth.Float64-1./3.) > 1e-9 {
+		t.Errorf("unexpected revision breadth: %d %d %v", rev.EpsUps, rev.EpsDowns, rev.Breadth)
+	}
+	rev = calFinPredictRev(hist[4:])
+	if rev.EpsChg1M.Valid || rev.Breadth.Valid {
+		t.Errorf("want null revision for a single snapshot: %+v", rev)
+	}
+}
+
 func TestSubSlice(t *testing.T) {
 	s := []int{1, 2, 3, 4, 5}
 	t.Errorf("slice: %+v", s)
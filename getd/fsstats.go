@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/model"
 )
 
 //CollectFsStats updates feature scaling stats.
@@ -25,8 +26,11 @@ func CollectFsStats() {
 		defer w.Done()
 		for m := range ch {
 			t, f := m["tab"], m["field"]
-			usql := fmt.Sprintf(sqlt, t, f)
-			_, e = dbmap.Exec(usql)
+			usql, ok := m["sql"]
+			if !ok {
+				usql = fmt.Sprintf(sqlt, t, f)
+			}
+			_, e := dbmap.Exec(usql)
 			if e != nil {
 				log.Printf("failed to update fs_stats for [%s.%s]: %+v", t, f, e)
 				continue
@@ -74,18 +78,26 @@ func CollectFsStats() {
 			"vol5", "vol10", "vol20", "vol30", "vol60", "vol120", "vol200", "vol250",
 		},
 	)
-	// indicators
-	feed(ch,
-		[]string{"indicator_d", "indicator_w", "indicator_m"},
-		[]string{"KDJ_K", "KDJ_D", "KDJ_J",
-			"MACD", "MACD_diff", "MACD_dea",
-			"RSI1", "RSI2", "RSI3",
-			"BIAS1", "BIAS2", "BIAS3",
-			"BOLL_lower", "BOLL_lower_o", "BOLL_lower_h", "BOLL_lower_l", "BOLL_lower_c",
-			"BOLL_mid", "BOLL_mid_o", "BOLL_mid_h", "BOLL_mid_l", "BOLL_mid_c",
-			"BOLL_upper", "BOLL_upper_o", "BOLL_upper_h", "BOLL_upper_l", "BOLL_upper_c",
-		},
-	)
+	// margin trading log returns
+	feed(ch, []string{"margin_lr"}, MarginLrCols)
+	// indicators, excluding the warm-up period of each stock
+	isqlt, e := dot.Raw("COLLECT_INDICATOR_STANDARDIZATION_STATS")
+	if e != nil {
+		log.Printf("failed to get indicator fs_stats sql %+v", e)
+	} else {
+		for _, spec := range enabledIndics() {
+			for _, c := range []model.CYTP{model.DAY, model.WEEK, model.MONTH} {
+				t := IndicTable(spec.Name, c)
+				for _, f := range spec.Cols {
+					ch <- map[string]string{
+						"tab":   t,
+						"field": f,
+						"sql":   fmt.Sprintf(isqlt, t, f, spec.WarmUp),
+					}
+				}
+			}
+		}
+	}
 
 	close(ch)
 	wg.Wait()
@@ -3,6 +3,9 @@ package getd
 import (
 	"fmt"
 	"testing"
+
+	"github.com/carusyte/stock/indc"
+	"github.com/carusyte/stock/model"
 )
 
 func TestCollectIndicatorStats(t *testing.T) {
@@ -12,15 +15,11 @@ func TestCollectIndicatorStats(t *testing.T) {
 		log.Printf("failed to get fs_stats sql %+v", e)
 		return
 	}
-	tabs := []string{"indicator_d"}
-	fields := []string{
-		"BOLL_lower", "BOLL_lower_o", "BOLL_lower_h", "BOLL_lower_l", "BOLL_lower_c",
-		"BOLL_mid", "BOLL_mid_o", "BOLL_mid_h", "BOLL_mid_l", "BOLL_mid_c",
-		"BOLL_upper", "BOLL_upper_o", "BOLL_upper_h", "BOLL_upper_l", "BOLL_upper_c",
-	}
+	spec, _ := indc.Lookup("boll")
+	tabs := []string{IndicTable(spec.Name, model.DAY)}
 	for _, t := range tabs {
-		for _, f := range fields {
-			usql := fmt.Sprintf(sqlt, t, f)
+		for _, f := range spec.Cols {
+			usql := fmt.Sprintf(sqlt, t, f, spec.WarmUp)
 			_, e = dbmap.Exec(usql)
 			if e != nil {
 				log.Printf("failed to update fs_stats for field %s: %+v", f, e)
@@ -0,0 +1,461 @@
+package getd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/global"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+//emFundNavURL is the url template of the NAV history of eastmoney, taking the fund code, page number and
+//page size. NAVs are sorted by date descending.
+const emFundNavURL = `http://api.fund.eastmoney.com/f10/lsjz?fundCode=%s&pageIndex=%d&pageSize=%d`
+
+//emFundHoldingURL is the url template of the latest stock holdings of a fund on eastmoney.
+const emFundHoldingURL = `https://fundmobapi.eastmoney.com/FundMNewApi/FundMNInverstPosition?FCODE=%s` +
+	`&deviceid=Wap&plat=Wap&product=EFund&version=2.0.0`
+
+//emFundListURL is the url template of the securities of the boards on eastmoney quotes, taking the page
+//number, page size and board filter.
+const emFundListURL = `http://push2.eastmoney.com/api/qt/clist/get?pn=%d&pz=%d&po=0&np=1&fid=f12&fs=%s` +
+	`&fields=f12,f13,f14`
+
+const (
+	fundNavPageSize  = 100
+	fundListPageSize = 500
+)
+
+//emFundBoards are the board filters of the fund types on eastmoney quotes.
+var emFundBoards = map[string]string{
+	model.FundETF: "b:MK0021,b:MK0022,b:MK0023,b:MK0024",
+	model.FundLOF: "b:MK0404,b:MK0405,b:MK0406,b:MK0407",
+}
+
+//emFundList is the response of the securities of the boards on eastmoney quotes.
+type emFundList struct {
+	//null if there's no data
+	Data *struct {
+		Total int `json:"total"`
+		Diff  []*struct {
+			Code string `json:"f12"`
+			//1 for Shanghai, 0 for Shenzhen
+			Market int    `json:"f13"`
+			Name   string `json:"f14"`
+		} `json:"diff"`
+	} `json:"data"`
+}
+
+//emFundNav is the response of the NAV history of eastmoney.
+type emFundNav struct {
+	Data *struct {
+		List []*struct {
+			Date   string `json:"FSRQ"`
+			Nav    string `json:"DWJZ"`
+			AccNav string `json:"LJJZ"`
+			NavChg string `json:"JZZZL"`
+		} `json:"LSJZList"`
+	} `json:"Data"`
+	TotalCount int `json:"TotalCount"`
+}
+
+//emFundHolding is the response of the latest stock holdings of a fund on eastmoney.
+type emFundHolding struct {
+	Datas *struct {
+		Stocks []*struct {
+			Code  string `json:"GPDM"`
+			Name  string `json:"GPJC"`
+			Ratio string `json:"JZBL"`
+		} `json:"fundStocks"`
+	} `json:"Datas"`
+	//end date of the reporting period
+	Expansion string `json:"Expansion"`
+}
+
+//FundStocks loads the funds in the fund table as stocks with the Type field set, so that their klines are
+//fetched and indicators calculated along with the stocks.
+func FundStocks() *model.Stocks {
+	var fs []*model.Fund
+	_, e := dbmap.Select(&fs, "select * from fund order by code")
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("failed to query fund list: %+v", e)
+	}
+	log.Printf("%d funds queried from db", len(fs))
+	return fundStocks(fs)
+}
+
+func fundStocks(fs []*model.Fund) *model.Stocks {
+	stks := new(model.Stocks)
+	for _, f := range fs {
+		stks.Add(&model.Stock{
+			Code:   f.Code,
+			Name:   f.Name,
+			Market: sql.NullString{String: f.Market, Valid: true},
+			Type:   f.Type,
+		})
+	}
+	return stks
+}
+
+//GetFundList fetches the listed ETFs and LOFs into the fund table. Returns the funds in the table, which is
+//kept as it was if the list fails to update.
+func GetFundList() *model.Stocks {
+	log.Println("getting fund list...")
+	if e := getFundList(); e != nil {
+		log.Warnf("failed to get fund list: %+v", e)
+	}
+	return FundStocks()
+}
+
+func getFundList() (e error) {
+	var fs []*model.Fund
+	for _, t := range []string{model.FundETF, model.FundLOF} {
+		for page, pages := 1, 1; page <= pages; page++ {
+			body, e := util.HttpGetBytes(fmt.Sprintf(emFundListURL, page, fundListPageSize, emFundBoards[t]))
+			if e != nil {
+				return errors.Wrapf(e, "failed to get %s list", t)
+			}
+			var ps []*model.Fund
+			if ps, pages, e = parseFundList(t, body); e != nil {
+				return e
+			}
+			fs = append(fs, ps...)
+		}
+	}
+	log.Printf("%d funds fetched", len(fs))
+	return saveFund(fs)
+}
+
+//parseFundList converts the page of the funds of the type and returns the total number of pages.
+func parseFundList(typ string, body []byte) (fs []*model.Fund, pages int, e error) {
+	r := new(emFundList)
+	if e = json.Unmarshal(body, r); e != nil {
+		return nil, 0, errors.Wrapf(e, "invalid %s list: %s", typ, body)
+	}
+	if r.Data == nil {
+		return nil, 0, nil
+	}
+	pages = (r.Data.Total + fundListPageSize - 1) / fundListPageSize
+	for _, it := range r.Data.Diff {
+		f := &model.Fund{Code: it.Code, Name: it.Name, Type: typ}
+		switch it.Market {
+		case 1:
+			f.Market = model.MarketSH
+		case 0:
+			f.Market = model.MarketSZ
+		default:
+			log.Warnf("%s unknown market of %s: %d", it.Code, typ, it.Market)
+			continue
+		}
+		fs = append(fs, f)
+	}
+	return fs, pages, nil
+}
+
+//saveFund replaces the fund table with the funds, so that the delisted ones are dropped. An empty list is
+//taken as a failed fetch and leaves the table intact.
+func saveFund(fs []*model.Fund) (e error) {
+	if len(fs) == 0 {
+		return nil
+	}
+	tran, e := dbmap.Begin()
+	if e != nil {
+		return errors.WithStack(e)
+	}
+	if _, e = tran.Exec("delete from fund"); e != nil {
+		tran.Rollback()
+		return errors.Wrap(e, "failed to delete fund list")
+	}
+	d, t := util.TimeStr()
+	batch := 500
+	for i := 0; i < len(fs); i += batch {
+		end := i + batch
+		if end > len(fs) {
+			end = len(fs)
+		}
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]interface{}, 0, (end-i)*6)
+		for _, f := range fs[i:end] {
+			valueStrings = append(valueStrings, "(?,?,?,?,?,?)")
+			valueArgs = append(valueArgs, f.Code, f.Market, f.Name, f.Type, d, t)
+		}
+		stmt := fmt.Sprintf("insert into fund (code,market,name,type,udate,utime) values %s "+
+			"on duplicate key update market=values(market),name=values(name),type=values(type),"+
+			"udate=values(udate),utime=values(utime)", strings.Join(valueStrings, ","))
+		if _, e = tran.Exec(stmt, valueArgs...); e != nil {
+			tran.Rollback()
+			return errors.Wrap(e, "failed to save fund list")
+		}
+	}
+	return errors.WithStack(tran.Commit())
+}
+
+//GetFunds fetches the NAV history of the funds since the latest in database, and the latest stock holdings
+//of the ETFs. Returns the funds successfully updated.
+func GetFunds(stocks *model.Stocks) (rstks *model.Stocks) {
+	log.Println("getting fund NAV and holdings...")
+	var wg sync.WaitGroup
+	chstk := make(chan *model.Stock, global.JobCapacity)
+	chrstk := make(chan *model.Stock, global.JobCapacity)
+	rstks = new(model.Stocks)
+	wgr := collect(rstks, chrstk)
+	for i := 0; i < conf.Args.Concurrency; i++ {
+		wg.Add(1)
+		go getFunds(chstk, &wg, chrstk)
+	}
+	for _, s := range stocks.List {
+		chstk <- s
+	}
+	close(chstk)
+	wg.Wait()
+	close(chrstk)
+	wgr.Wait()
+	log.Printf("%d funds updated", rstks.Size())
+	if stocks.Size() != rstks.Size() {
+		same, skp := stocks.Diff(rstks)
+		if !same {
+			log.Printf("Failed: %+v", skp)
+		}
+	}
+	return
+}
+
+func getFunds(chstk chan *model.Stock, wg *sync.WaitGroup, chrstk chan *model.Stock) {
+	defer wg.Done()
+	RETRIES := conf.Args.DataSource.KlineFailureRetry
+	for stock := range chstk {
+		wait := 1000
+		for rtCount := 0; rtCount <= RETRIES; rtCount++ {
+			ok, r := doGetFund(stock)
+			if ok {
+				chrstk <- stock
+				break
+			} else if r {
+				log.Printf("%s retrying %d...", stock.Code, rtCount+1)
+				time.Sleep(time.Millisecond * time.Duration(wait+rand.Intn(wait)))
+				continue
+			} else {
+				log.Printf("%s retried %d, giving up. restart the program to recover", stock.Code, rtCount+1)
+				break
+			}
+		}
+	}
+}
+
+func doGetFund(stock *model.Stock) (ok, retry bool) {
+	code := stock.Code
+	latest, e := dbmap.SelectNullStr("select max(date) from fund_nav where code = ?", code)
+	if e != nil {
+		log.Printf("%s failed to query latest NAV: %+v", code, e)
+		return false, true
+	}
+	headers := map[string]string{"Referer": "http://fundf10.eastmoney.com/"}
+	var navs []*model.FundNav
+	for page, pages := 1, 1; page <= pages; page++ {
+		body, e := util.HttpGetBytesUsingHeaders(fmt.Sprintf(emFundNavURL, code, page, fundNavPageSize), headers)
+		if e != nil {
+			log.Printf("%s failed to get NAV: %+v", code, e)
+			return false, true
+		}
+		var ps []*model.FundNav
+		if ps, pages, e = parseFundNav(code, body); e != nil {
+			log.Printf("%s %+v", code, e)
+			return false, true
+		}
+		for _, n := range ps {
+			if n.Date <= latest.String {
+				pages = 0
+				break
+			}
+			navs = append(navs, n)
+		}
+	}
+	if e = saveFundNav(navs); e != nil {
+		log.Printf("%s %+v", code, e)
+		return false, true
+	}
+	if stock.Type != model.FundETF {
+		return true, false
+	}
+	body, e := util.HttpGetBytes(fmt.Sprintf(emFundHoldingURL, code))
+	if e != nil {
+		log.Printf("%s failed to get holdings: %+v", code, e)
+		return false, true
+	}
+	fhs, e := parseFundHolding(code, body)
+	if e != nil {
+		log.Printf("%s %+v", code, e)
+		return false, true
+	}
+	if e = saveFundHolding(fhs); e != nil {
+		log.Printf("%s %+v", code, e)
+		return false, true
+	}
+	return true, false
+}
+
+//parseFundNav converts the NAV history page and returns the total number of pages.
+func parseFundNav(code string, body []byte) (navs []*model.FundNav, pages int, e error) {
+	r := new(emFundNav)
+	if e = json.Unmarshal(body, r); e != nil {
+		return nil, 0, errors.Wrapf(e, "invalid NAV history: %s", body)
+	}
+	if r.Data == nil {
+		return nil, 0, nil
+	}
+	pages = (r.TotalCount + fundNavPageSize - 1) / fundNavPageSize
+	for _, it := range r.Data.List {
+		nav := util.Str2Fnull(it.Nav)
+		if !nav.Valid {
+			continue
+		}
+		navs = append(navs, &model.FundNav{
+			Code:   code,
+			Date:   it.Date,
+			Nav:    nav.Float64,
+			AccNav: util.Str2Fnull(it.AccNav),
+			NavChg: util.Str2Fnull(it.NavChg),
+		})
+	}
+	return navs, pages, nil
+}
+
+//parseFundHolding converts the latest stock holdings of the fund.
+func parseFundHolding(code string, body []byte) (fhs []*model.FundHolding, e error) {
+	r := new(emFundHolding)
+	if e = json.Unmarshal(body, r); e != nil {
+		return nil, errors.Wrapf(e, "invalid fund holdings: %s", body)
+	}
+	if r.Datas == nil || len(r.Datas.Stocks) == 0 {
+		return nil, nil
+	}
+	date, e := emDate(r.Expansion)
+	if e != nil {
+		return nil, errors.Wrap(e, "invalid fund holding date")
+	}
+	for _, s := range r.Datas.Stocks {
+		fhs = append(fhs, &model.FundHolding{
+			Code:      code,
+			Date:      date,
+			StockCode: s.Code,
+			StockName: s.Name,
+			Ratio:     util.Str2Fnull(s.Ratio),
+		})
+	}
+	return fhs, nil
+}
+
+func saveFundNav(navs []*model.FundNav) (e error) {
+	d, t := util.TimeStr()
+	batch := 500
+	for i := 0; i < len(navs); i += batch {
+		end := i + batch
+		if end > len(navs) {
+			end = len(navs)
+		}
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]interface{}, 0, (end-i)*7)
+		for _, n := range navs[i:end] {
+			valueStrings = append(valueStrings, "(?,?,?,?,?,?,?)")
+			valueArgs = append(valueArgs, n.Code, n.Date, n.Nav, n.AccNav, n.NavChg, d, t)
+		}
+		stmt := fmt.Sprintf("insert into fund_nav (code,date,nav,acc_nav,nav_chg,udate,utime) values %s "+
+			"on duplicate key update nav=values(nav),acc_nav=values(acc_nav),nav_chg=values(nav_chg),"+
+			"udate=values(udate),utime=values(utime)", strings.Join(valueStrings, ","))
+		if _, e = dbmap.Exec(stmt, valueArgs...); e != nil {
+			return errors.Wrap(e, "failed to save fund NAV")
+		}
+	}
+	return nil
+}
+
+func saveFundHolding(fhs []*model.FundHolding) (e error) {
+	if len(fhs) == 0 {
+		return nil
+	}
+	d, t := util.TimeStr()
+	valueStrings := make([]string, 0, len(fhs))
+	valueArgs := make([]interface{}, 0, len(fhs)*7)
+	for _, h := range fhs {
+		valueStrings = append(valueStrings, "(?,?,?,?,?,?,?)")
+		valueArgs = append(valueArgs, h.Code, h.Date, h.StockCode, h.StockName, h.Ratio, d, t)
+	}
+	stmt := fmt.Sprintf("insert into fund_holding (code,date,stock_code,stock_name,ratio,udate,utime) values %s "+
+		"on duplicate key update stock_name=values(stock_name),ratio=values(ratio),udate=values(udate),"+
+		"utime=values(utime)", strings.Join(valueStrings, ","))
+	if _, e = dbmap.Exec(stmt, valueArgs...); e != nil {
+		return errors.Wrap(e, "failed to save fund holdings")
+	}
+	return nil
+}
+
+//GetFundKlines fetches the non-reinstated, backward and forward reinstated klines of the funds into the
+//fund kline tables, then updates the premium or discount of the close to NAV. Returns the funds successfully
+//fetched.
+func GetFundKlines(stks *model.Stocks) *model.Stocks {
+	src := model.DataSource(conf.Args.DataSource.Kline)
+	var frs []FetchRequest
+	for _, r := range []model.Rtype{model.None, model.Backward, model.Forward} {
+		for _, c := range []model.CYTP{model.DAY, model.WEEK, model.MONTH} {
+			frs = append(frs, FetchRequest{
+				RemoteSource: src,
+				LocalSource:  model.FundKline,
+				Reinstate:    r,
+				Cycle:        c,
+			})
+		}
+	}
+	stks = GetKlinesV2(stks, frs...)
+	FreeFetcherResources()
+	if stks.Size() == 0 {
+		return stks
+	}
+	d, t := util.TimeStr()
+	_, e := dbmap.Exec(fmt.Sprintf("update fund_nav n inner join fund_d_n k on k.code = n.code and "+
+		"k.date = n.date set n.close = k.close, n.premium = round((k.close / n.nav - 1) * 100, 4), "+
+		"n.udate = ?, n.utime = ? where n.code in (%s) and n.premium is null and n.nav > 0",
+		util.Join(stks.Codes, ",", true)), d, t)
+	if e != nil {
+		log.Panicf("failed to update fund premium: %+v", e)
+	}
+	return stks
+}
+
+//FundNavs loads the NAV history of the fund since the date in chronological order, or all history if the
+//date is empty.
+func FundNavs(code, since string) (navs []*model.FundNav) {
+	_, e := dbmap.Select(&navs, "select * from fund_nav where code = ? and date >= ? order by date", code, since)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("%s failed to load fund NAV: %+v", code, e)
+	}
+	return
+}
+
+//FundHoldings loads the stock holdings of the fund at the end of the reporting period, or the latest period
+//if the date is empty, in descending order of the ratio.
+func FundHoldings(code, date string) (fhs []*model.FundHolding) {
+	if date == "" {
+		latest, e := dbmap.SelectNullStr("select max(date) from fund_holding where code = ?", code)
+		if e != nil {
+			log.Panicf("%s failed to query latest fund holdings: %+v", code, e)
+		}
+		if !latest.Valid {
+			return
+		}
+		date = latest.String
+	}
+	_, e := dbmap.Select(&fhs, "select * from fund_holding where code = ? and date = ? order by ratio desc",
+		code, date)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("%s failed to load fund holdings: %+v", code, e)
+	}
+	return
+}
@@ -0,0 +1,91 @@
+package getd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+)
+
+func TestParseFundList(t *testing.T) {
+	defer useCassettes(t)()
+	body, e := util.HttpGetBytes(fmt.Sprintf(emFundListURL, 1, fundListPageSize, emFundBoards[model.FundLOF]))
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	fs, pages, e := parseFundList(model.FundLOF, body)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if pages != 2 || len(fs) != 2 {
+		t.Fatalf("want 2 funds of 2 pages, got %d of %d", len(fs), pages)
+	}
+	if f := fs[1]; f.Code != "501000" || f.Market != model.MarketSH || f.Name != "国金鑫新LOF" ||
+		f.Type != model.FundLOF {
+		t.Errorf("unexpected fund: %+v", f)
+	}
+	//the fund prices of Tongdaxin day files are scaled by the type
+	if stk := fundStocks(fs).List[0]; stk.Market.String != model.MarketSZ || tdxPriceScale(stk) != 1000 {
+		t.Errorf("unexpected fund stock: %+v", stk)
+	}
+}
+
+func TestParseFundNav(t *testing.T) {
+	defer useCassettes(t)()
+	body, e := util.HttpGetBytesUsingHeaders(fmt.Sprintf(emFundNavURL, "510300", 1, fundNavPageSize),
+		map[string]string{"Referer": "http://fundf10.eastmoney.com/"})
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	navs, pages, e := parseFundNav("510300", body)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if pages != 3 {
+		t.Errorf("want 3 pages, got %d", pages)
+	}
+	if len(navs) != 2 {
+		t.Fatalf("want 2 NAVs, got %d", len(navs))
+	}
+	n := navs[0]
+	if n.Code != "510300" || n.Date != "2020-09-30" || n.Nav != 4.687 || n.AccNav.Float64 != 1.7921 ||
+		n.NavChg.Float64 != -0.12 {
+		t.Errorf("unexpected NAV: %+v", n)
+	}
+	if navs[1].NavChg.Valid {
+		t.Errorf("want null NAV change, got %+v", navs[1].NavChg)
+	}
+	if _, _, e = parseFundNav("510300", []byte(`<html>`)); e == nil {
+		t.Error("want error for invalid response")
+	}
+}
+
+func TestParseFundHolding(t *testing.T) {
+	defer useCassettes(t)()
+	body, e := util.HttpGetBytes(fmt.Sprintf(emFundHoldingURL, "510300"))
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	fhs, e := parseFundHolding("510300", body)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if len(fhs) != 2 {
+		t.Fatalf("want 2 holdings, got %d", len(fhs))
+	}
+	h := fhs[0]
+	if h.Code != "510300" || h.Date != "2020-09-30" || h.StockCode != "600519" || h.StockName != "贵州茅台" ||
+		h.Ratio.Float64 != 5.43 {
+		t.Errorf("unexpected holding: %+v", h)
+	}
+	if fhs[1].Ratio.Valid {
+		t.Errorf("want null ratio, got %+v", fhs[1].Ratio)
+	}
+	if body, e = util.HttpGetBytes(fmt.Sprintf(emFundHoldingURL, "161005")); e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if fhs, e = parseFundHolding("161005", body); e != nil || len(fhs) != 0 {
+		t.Errorf("want no holdings, got %+v, %+v", fhs, e)
+	}
+}
@@ -4,161 +4,312 @@ import (
 	"math"
 	"time"
 
+	"github.com/carusyte/stock/calendar"
 	"github.com/carusyte/stock/conf"
 	"github.com/carusyte/stock/model"
 )
 
-//GetV2 gets miscellaneous stock info.
+//GetV2 gets miscellaneous stock info through all stages of the data pipeline.
 func GetV2() {
-	var allstks, stks *model.Stocks
+	RunPipeline(nil, false)
+}
+
+//RunPipeline runs the specified stages of the data pipeline, or all stages if only is empty.
+//Stages not specified pass their stocks through to their dependents. If resume is set, stocks
+//already checkpointed by the previous run of a stage are not processed again.
+func RunPipeline(only []string, resume bool) {
 	start := time.Now()
 	defer StopWatch("GETD_TOTAL", start)
-	if !conf.Args.DataSource.SkipStocks {
-		allstks = GetStockInfo()
-		StopWatch("STOCK_LIST", start)
-	} else {
-		log.Printf("skipped stock data from web")
-		allstks = new(model.Stocks)
-		stks := StocksDb()
-		log.Printf("%d stocks queried from db", len(stks))
-		allstks.Add(stks...)
+	p, e := NewPipeline(dataStages()...)
+	if e != nil {
+		log.Panicf("%+v", e)
 	}
-
-	//every step hereafter returns only the stocks successfully processed
-	if !conf.Args.DataSource.SkipFinance {
-		stgfi := time.Now()
-		stks = GetFinance(allstks)
-		StopWatch("GET_FINANCE", stgfi)
-	} else {
-		log.Printf("skipped finance data from web")
-		stks = allstks
+	sel, e := p.Select(only...)
+	if e != nil {
+		log.Panicf("%+v", e)
 	}
+	outs := p.Run(sel, resume)
+	rptFailed(unionStocks(outs[StageStocks], outs[StageIndices]), outs[StageFinMark])
+}
 
-	if !conf.Args.DataSource.SkipFinancePrediction {
-		fipr := time.Now()
-		stks = GetFinPrediction(stks)
-		StopWatch("GET_FIN_PREDICT", fipr)
-	} else {
-		log.Printf("skipped financial prediction data from web")
-	}
+//stages of the data pipeline
+const (
+	StageStocks       = "stocks"
+	StageFinance      = "finance"
+	StageFinPredict   = "fin_predict"
+	StageXdxr         = "xdxr"
+	StageKlineVld     = "kline_vld"
+	StageKlinePre     = "kline_pre"
+	StageKlineLimit   = "kline_limit"
+	StageStatusHist   = "status_hist"
+	StageKlines       = "klines"
+	StageKlinePost    = "kline_post"
+	StageMinuteKlines = "minute_klines"
+	StageIndices      = "indices"
+	StageCalendar     = "calendar"
+	StageBasics       = "basics"
+	StageIndicators   = "indicators"
+	StageFsStats      = "fs_stats"
+	StageFinMark      = "fin_mark"
+	StageNorthbound   = "northbound"
+	StageMargin       = "margin"
+	StageDragonTiger  = "dragon_tiger"
+	StageHolders      = "holders"
+	StageFinStmt      = "fin_stmt"
+	StageAnnounce     = "announcements"
+	StageLockup       = "lockup"
+	StageFundList     = "fund_list"
+	StageFunds        = "funds"
+	StageFundKlines   = "fund_klines"
+	StageCBonds       = "cbonds"
+	StageCBondKlines  = "cbond_klines"
+	StageCBondValues  = "cbond_values"
+)
 
-	if !conf.Args.DataSource.SkipXdxr {
-		// Validate Kline process already fetches XDXR info
-		if conf.Args.DataSource.SkipKlineVld {
-			stgx := time.Now()
-			stks = GetXDXRs(stks)
-			StopWatch("GET_XDXR", stgx)
-		}
-	} else {
-		log.Printf("skipped xdxr data from web")
+//StageNames returns the names of the data pipeline stages in execution order.
+func StageNames() []string {
+	p, e := NewPipeline(dataStages()...)
+	if e != nil {
+		log.Panicf("%+v", e)
 	}
+	return p.Stages()
+}
 
-	stks = getKlineVld(stks)
-
-	src := model.DataSource(conf.Args.DataSource.Kline)
-	frs := make([]FetchRequest, 3)
+//dataStages declares the stages of the data pipeline. Every stage returns only the stocks
+//successfully processed.
+func dataStages() []*Stage {
+	ds := &conf.Args.DataSource
+	src := model.DataSource(ds.Kline)
 	cs := []model.CYTP{model.DAY, model.WEEK, model.MONTH}
-	postProcess := false
-	if !conf.Args.DataSource.SkipKlinePre {
-		begin := time.Now()
-		for i := range frs {
-			frs[i] = FetchRequest{
-				RemoteSource: src,
-				LocalSource:  model.KlineMaster,
-				Reinstate:    model.None,
-				Cycle:        cs[i],
-			}
-		}
-		stks = GetKlinesV2(stks, frs...)
-		StopWatch("GET_KLINES_PRE", begin)
-		postProcess = true
-	} else {
-		log.Printf("skipped kline-pre data from web (non-reinstated)")
-	}
-
-	if !conf.Args.DataSource.SkipKlines {
-		begin := time.Now()
-		frs = make([]FetchRequest, 6)
-		for i := range frs {
-			csi := int(math.Mod(float64(i), 3))
-			r := model.Backward
-			if i > 2 {
-				r = model.Forward
-			}
-			frs[i] = FetchRequest{
-				RemoteSource: src,
-				LocalSource:  model.KlineMaster,
-				Reinstate:    r,
-				Cycle:        cs[csi],
-			}
-		}
-		stks = GetKlinesV2(stks, frs...)
-		StopWatch("GET_MASTER_KLINES", begin)
-		postProcess = true
-	} else {
-		log.Printf("skipped klines data from web (backward & forward reinstated)")
+	return []*Stage{
+		{
+			Name:   StageStocks,
+			Global: true,
+			Skip:   func() bool { return ds.SkipStocks },
+			Source: func() *model.Stocks {
+				stks := new(model.Stocks)
+				stks.Add(StocksDb()...)
+				log.Printf("%d stocks queried from db", stks.Size())
+				return stks
+			},
+			Run: func(*model.Stocks) *model.Stocks { return GetStockInfo() },
+		}, {
+			Name: StageFinance,
+			Deps: []string{StageStocks},
+			Skip: func() bool { return ds.SkipFinance },
+			Run:  GetFinance,
+		}, {
+			Name: StageFinStmt,
+			Deps: []string{StageStocks},
+			Skip: func() bool { return ds.SkipFinStmt },
+			Run:  GetFinStmt,
+		}, {
+			Name: StageFinPredict,
+			Deps: []string{StageStocks},
+			Skip: func() bool { return ds.SkipFinancePrediction },
+			Run:  GetFinPrediction,
+		}, {
+			Name: StageNorthbound,
+			Deps: []string{StageStocks},
+			Skip: func() bool { return ds.SkipNorthbound },
+			Run:  GetNorthbound,
+		}, {
+			Name: StageMargin,
+			Deps: []string{StageStocks},
+			Skip: func() bool { return ds.SkipMargin },
+			Run:  GetMargin,
+		}, {
+			Name: StageHolders,
+			Deps: []string{StageStocks},
+			Skip: func() bool { return ds.SkipHolders },
+			Run:  GetHolders,
+		}, {
+			Name: StageLockup,
+			//the float ratios are complemented by the share changes saved by the holders stage
+			Deps:   []string{StageStocks, StageHolders},
+			Global: true,
+			Skip:   func() bool { return ds.SkipLockup },
+			Run:    GetLockup,
+		}, {
+			Name: StageAnnounce,
+			Deps: []string{StageStocks},
+			Skip: func() bool { return ds.SkipAnnouncements },
+			Run:  GetAnnouncements,
+		}, {
+			Name: StageXdxr,
+			Deps: []string{StageStocks},
+			// Validate Kline process already fetches XDXR info, whereas local reinstatement
+			// requires the latest ones in advance
+			Skip: func() bool { return ds.SkipXdxr || !ds.SkipKlineVld && !ds.Reinstate.Local },
+			Run:  GetXDXRs,
+		}, {
+			Name: StageKlineVld,
+			Deps: []string{StageXdxr},
+			Skip: func() bool { return ds.SkipKlineVld },
+			Run:  getKlineVld,
+		}, {
+			Name: StageKlinePre,
+			Deps: []string{StageKlineVld},
+			Skip: func() bool { return ds.SkipKlinePre },
+			Run: func(stks *model.Stocks) *model.Stocks {
+				frs := make([]FetchRequest, 3)
+				for i := range frs {
+					frs[i] = FetchRequest{
+						RemoteSource: src,
+						LocalSource:  model.KlineMaster,
+						Reinstate:    model.None,
+						Cycle:        cs[i],
+					}
+				}
+				stks = GetKlinesV2(stks, frs...)
+				FreeFetcherResources()
+				return stks
+			},
+		}, {
+			Name: StageStatusHist,
+			Deps: []string{StageKlinePre},
+			Auto: true,
+			Skip: func() bool { return ds.SkipKlinePre },
+			Run:  BackfillStatus,
+		}, {
+			Name: StageKlineLimit,
+			Deps: []string{StageStatusHist},
+			Auto: true,
+			Skip: func() bool { return ds.SkipKlinePre },
+			Run:  UpdateLimits,
+		}, {
+			Name: StageKlines,
+			Deps: []string{StageKlinePre},
+			Skip: func() bool { return ds.SkipKlines },
+			Run: func(stks *model.Stocks) *model.Stocks {
+				rsrc := src
+				if ds.Reinstate.Local {
+					rsrc = model.Reinstated
+				}
+				frs := make([]FetchRequest, 6)
+				for i := range frs {
+					csi := int(math.Mod(float64(i), 3))
+					r := model.Backward
+					if i > 2 {
+						r = model.Forward
+					}
+					frs[i] = FetchRequest{
+						RemoteSource: rsrc,
+						LocalSource:  model.KlineMaster,
+						Reinstate:    r,
+						Cycle:        cs[csi],
+					}
+				}
+				stks = GetKlinesV2(stks, frs...)
+				FreeFetcherResources()
+				if ds.Reinstate.Local && ds.Reinstate.Verify {
+					stks = VerifyReinstatement(stks)
+				}
+				return stks
+			},
+		}, {
+			Name: StageKlinePost,
+			Deps: []string{StageKlines},
+			Auto: true,
+			Skip: func() bool { return ds.SkipKlinePre && ds.SkipKlines },
+			Run:  KlinePostProcess,
+		}, {
+			Name: StageMinuteKlines,
+			Deps: []string{StageKlinePost},
+			Skip: func() bool { return len(MinuteCycles()) == 0 },
+			Run: func(stks *model.Stocks) *model.Stocks {
+				stks = GetMinuteKlines(stks, MinuteCycles()...)
+				FreeFetcherResources()
+				return stks
+			},
+		}, {
+			Name:   StageIndices,
+			Skip:   func() bool { return ds.SkipIndices },
+			Source: IndexStocks,
+			Run:    GetIndexKlines,
+		}, {
+			Name:   StageFundList,
+			Global: true,
+			Skip:   func() bool { return ds.SkipFunds },
+			Source: FundStocks,
+			Run:    func(*model.Stocks) *model.Stocks { return GetFundList() },
+		}, {
+			Name: StageFunds,
+			Deps: []string{StageFundList},
+			Skip: func() bool { return ds.SkipFunds },
+			Run:  GetFunds,
+		}, {
+			Name: StageFundKlines,
+			Deps: []string{StageFunds},
+			Skip: func() bool { return ds.SkipFunds || ds.SkipKlines },
+			Run:  GetFundKlines,
+		}, {
+			Name:   StageCBonds,
+			Global: true,
+			Skip:   func() bool { return ds.SkipCBonds },
+			Source: CBondStocks,
+			Run:    func(*model.Stocks) *model.Stocks { return GetCBonds() },
+		}, {
+			Name: StageCBondKlines,
+			Deps: []string{StageCBonds},
+			Skip: func() bool { return ds.SkipCBonds || ds.SkipKlines },
+			Run:  GetCBondKlines,
+		}, {
+			//underlying prices are joined from the non-reinstated daily klines of the stocks
+			Name:   StageCBondValues,
+			Deps:   []string{StageCBondKlines, StageKlinePre},
+			Union:  true,
+			Global: true,
+			Skip:   func() bool { return ds.SkipCBonds },
+			Run:    CalcCBondValues,
+		}, {
+			Name:   StageCalendar,
+			Deps:   []string{StageIndices},
+			Global: true,
+			Skip:   func() bool { return ds.SkipCalendar },
+			Run: func(stks *model.Stocks) *model.Stocks {
+				if e := calendar.Update(); e != nil {
+					log.Panicf("failed to update trade calendar: %+v", e)
+				}
+				return stks
+			},
+		}, {
+			Name: StageBasics,
+			Deps: []string{StageFinance, StageFinPredict, StageMinuteKlines},
+			Skip: func() bool { return ds.SkipBasicsUpdate },
+			Run:  updBasics,
+		}, {
+			Name:  StageIndicators,
+			Deps:  []string{StageBasics, StageIndices, StageFundKlines},
+			Union: true,
+			Skip:  func() bool { return ds.SkipIndexCalculation },
+			Run:   CalcIndics,
+		}, {
+			Name:   StageFsStats,
+			Deps:   []string{StageIndicators},
+			Global: true,
+			Skip:   func() bool { return ds.SkipFsStats },
+			Run: func(stks *model.Stocks) *model.Stocks {
+				CollectFsStats()
+				return stks
+			},
+		}, {
+			Name: StageFinMark,
+			Deps: []string{StageFsStats},
+			Skip: func() bool { return ds.SkipFinMark },
+			Run:  finMark,
+		}, {
+			//seat profiles are based on the forward reinstated klines
+			Name:   StageDragonTiger,
+			Deps:   []string{StageKlinePost},
+			Global: true,
+			Skip:   func() bool { return ds.SkipDragonTiger },
+			Run:    GetDragonTiger,
+		},
 	}
-
-	FreeFetcherResources()
-	if postProcess {
-		stks = KlinePostProcess(stks)
-	}
-
-	var allIdx, sucIdx []*model.IdxLst
-	if !conf.Args.DataSource.SkipIndices {
-		stidx := time.Now()
-		allIdx, sucIdx = GetIndicesV2()
-		StopWatch("GET_INDICES", stidx)
-		for _, idx := range allIdx {
-			allstks.Add(&model.Stock{Code: idx.Code, Name: idx.Name})
-		}
-	} else {
-		log.Printf("skipped index data from web")
-	}
-
-	if !conf.Args.DataSource.SkipBasicsUpdate {
-		updb := time.Now()
-		stks = updBasics(stks)
-		StopWatch("UPD_BASICS", updb)
-	} else {
-		log.Printf("skipped updating basics table")
-	}
-
-	// Add indices pending to be calculated
-	for _, idx := range sucIdx {
-		stks.Add(&model.Stock{Code: idx.Code, Name: idx.Name, Source: idx.Src})
-	}
-	if !conf.Args.DataSource.SkipIndexCalculation {
-		stci := time.Now()
-		stks = CalcIndics(stks)
-		StopWatch("CALC_INDICS", stci)
-	} else {
-		log.Printf("skipped index calculation")
-	}
-
-	if !conf.Args.DataSource.SkipFsStats {
-		stfss := time.Now()
-		CollectFsStats()
-		StopWatch("FS_STATS", stfss)
-	} else {
-		log.Printf("skipped feature scaling stats")
-	}
-
-	if !conf.Args.DataSource.SkipFinMark {
-		finMark(stks)
-	} else {
-		log.Printf("skipped updating fin mark")
-	}
-
-	rptFailed(allstks, stks)
 }
 
 func getKlineVld(stks *model.Stocks) *model.Stocks {
-	if conf.Args.DataSource.SkipKlineVld {
-		log.Printf("skipped kline-vld data from web")
-		return stks
-	}
-
 	vsrc := model.DataSource(conf.Args.DataSource.Validate.Source)
 	cs := []model.CYTP{model.DAY, model.WEEK, model.MONTH}
 	var frs []FetchRequest
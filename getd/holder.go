@@ -0,0 +1,502 @@
+package getd
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/global"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+//statuses of the top holders compared with the previous period
+const (
+	HolderNew  = "new"
+	HolderInc  = "inc"
+	HolderDec  = "dec"
+	HolderSame = "same"
+)
+
+//emHolderNum is a row of the shareholder count report of eastmoney.
+type emHolderNum struct {
+	Code       string   `json:"SECURITY_CODE"`
+	EndDate    string   `json:"END_DATE"`
+	NoticeDate *string  `json:"HOLD_NOTICE_DATE"`
+	Holders    *float64 `json:"HOLDER_NUM"`
+	AvgShares  *float64 `json:"AVG_HOLD_NUM"`
+}
+
+//emTopHolder is a row of the top 10 holders or top 10 float holders report of eastmoney.
+type emTopHolder struct {
+	Code    string   `json:"SECURITY_CODE"`
+	EndDate string   `json:"END_DATE"`
+	Rank    int      `json:"HOLDER_RANK"`
+	Name    string   `json:"HOLDER_NAME"`
+	Shares  *float64 `json:"HOLD_NUM"`
+	//ratio to total shares for top 10 holders
+	Ratio *float64 `json:"HOLD_NUM_RATIO"`
+	//ratio to float shares for top 10 float holders
+	FreeRatio *float64 `json:"FREE_HOLDNUM_RATIO"`
+	ShareType string   `json:"SHARES_TYPE"`
+}
+
+//GetHolders fetches the shareholder counts and the top 10 holders of the stocks since the latest ones
+//in database, as well as the share changes, then updates the chip concentration metrics.
+//Returns the stocks successfully updated.
+func GetHolders(stocks *model.Stocks) (rstks *model.Stocks) {
+	log.Println("getting shareholders...")
+	var wg sync.WaitGroup
+	chstk := make(chan *model.Stock, global.JobCapacity)
+	chrstk := make(chan *model.Stock, global.JobCapacity)
+	rstks = new(model.Stocks)
+	wgr := collect(rstks, chrstk)
+	for i := 0; i < conf.Args.Concurrency; i++ {
+		wg.Add(1)
+		go getHolders(chstk, &wg, chrstk)
+	}
+	for _, s := range stocks.List {
+		chstk <- s
+	}
+	close(chstk)
+	wg.Wait()
+	close(chrstk)
+	wgr.Wait()
+	log.Printf("%d shareholders updated", rstks.Size())
+	if stocks.Size() != rstks.Size() {
+		same, skp := stocks.Diff(rstks)
+		if !same {
+			log.Printf("Failed: %+v", skp)
+		}
+	}
+	return
+}
+
+func getHolders(chstk chan *model.Stock, wg *sync.WaitGroup, chrstk chan *model.Stock) {
+	defer wg.Done()
+	RETRIES := conf.Args.DataSource.KlineFailureRetry
+	for stock := range chstk {
+		wait := 1000
+		for rtCount := 0; rtCount <= RETRIES; rtCount++ {
+			cookies, px, headers, e := xqCookie()
+			if e != nil {
+				log.Warnf("%s failed to get XQ cookies: %+v, retrying %d...", stock.Code, e, rtCount+1)
+				continue
+			}
+			ok, r := doGetHolders(stock, px, headers, cookies)
+			if ok {
+				chrstk <- stock
+				break
+			} else if r {
+				log.Printf("%s retrying %d...", stock.Code, rtCount+1)
+				time.Sleep(time.Millisecond * time.Duration(wait+rand.Intn(wait)))
+				continue
+			} else {
+				log.Printf("%s retried %d, giving up. restart the program to recover", stock.Code, rtCount+1)
+				break
+			}
+		}
+	}
+}
+
+func doGetHolders(stock *model.Stock, px *util.Proxy, headers map[string]string, cookies []*http.Cookie) (
+	ok, retry bool) {
+	code := stock.Code
+	xqshare, ok, retry := fetchXqShares(stock, px, headers, cookies)
+	if !ok {
+		return
+	}
+	chgs := parseXqShareChg(code, xqshare)
+	if e := saveShareChg(chgs); e != nil {
+		log.Printf("%s %+v", code, e)
+		return false, true
+	}
+	for _, float := range []bool{false, true} {
+		if e := getTopHolders(stock, float); e != nil {
+			log.Printf("%s %+v", code, e)
+			return false, true
+		}
+	}
+	hns, e := getHolderNum(code)
+	if e != nil {
+		log.Printf("%s %+v", code, e)
+		return false, true
+	}
+	top10 := make(map[string]float64)
+	var sums []*struct {
+		Date  string
+		Ratio float64
+	}
+	_, e = dbmap.Select(&sums, "select date, sum(ratio) ratio from top_holder where code = ? and is_float = 1 "+
+		"and ratio is not null group by date", code)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Printf("%s failed to query top 10 float holders: %+v", code, e)
+		return false, true
+	}
+	for _, s := range sums {
+		top10[s.Date] = s.Ratio
+	}
+	holderMetrics(hns, top10, chgs)
+	if e = saveHolderNum(hns); e != nil {
+		log.Printf("%s %+v", code, e)
+		return false, true
+	}
+	return true, false
+}
+
+//parseXqShareChg converts the share changes of xueqiu.com in chronological order.
+func parseXqShareChg(code string, xqshare *model.XqSharesChg) (chgs []*model.ShareChg) {
+	for _, it := range xqshare.Data.Items {
+		if it.ChgDate == nil {
+			continue
+		}
+		c := &model.ShareChg{
+			Code:         code,
+			Date:         time.Unix(int64(*it.ChgDate/1000), 0).Format(global.DateFormat),
+			TotalShares:  emNullFloat(it.TotalShare),
+			FloatAShares: emNullFloat(it.FloatAShare),
+			LimitAShares: emNullFloat(it.LimitAShare),
+		}
+		if it.ChgReason != nil {
+			c.Reason = sql.NullString{String: *it.ChgReason, Valid: true}
+		}
+		chgs = append(chgs, c)
+	}
+	sort.Slice(chgs, func(i, j int) bool { return chgs[i].Date < chgs[j].Date })
+	return
+}
+
+func saveShareChg(chgs []*model.ShareChg) (e error) {
+	if len(chgs) == 0 {
+		return nil
+	}
+	d, t := util.TimeStr()
+	valueStrings := make([]string, 0, len(chgs))
+	valueArgs := make([]interface{}, 0, len(chgs)*8)
+	for _, c := range chgs {
+		valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?)")
+		valueArgs = append(valueArgs, c.Code, c.Date, c.TotalShares, c.FloatAShares, c.LimitAShares, c.Reason, d, t)
+	}
+	stmt := fmt.Sprintf("insert into share_chg (code,date,total_shares,float_a_shares,limit_a_shares,reason,"+
+		"udate,utime) values %s on duplicate key update total_shares=values(total_shares),"+
+		"float_a_shares=values(float_a_shares),limit_a_shares=values(limit_a_shares),reason=values(reason),"+
+		"udate=values(udate),utime=values(utime)", strings.Join(valueStrings, ","))
+	if _, e = dbmap.Exec(stmt, valueArgs...); e != nil {
+		return errors.Wrap(e, "failed to save share changes")
+	}
+	return nil
+}
+
+//getHolderNum fetches the shareholder counts since the latest one in database, and returns the whole
+//history in chronological order.
+func getHolderNum(code string) (hns []*model.HolderNum, e error) {
+	latest, e := dbmap.SelectNullStr("select max(date) from holder_num where code = ?", code)
+	if e != nil {
+		return nil, errors.WithStack(e)
+	}
+	filter := fmt.Sprintf(`(SECURITY_CODE="%s")`, code)
+	var fetched []*model.HolderNum
+	for page, pages := 1, 1; page <= pages; page++ {
+		body, e := fetchEmReport("RPT_HOLDERNUM_DET", "END_DATE", filter, page)
+		if e != nil {
+			return nil, errors.Wrap(e, "failed to get shareholder count")
+		}
+		var ps []*model.HolderNum
+		if ps, pages, e = parseHolderNum(body); e != nil {
+			return nil, e
+		}
+		for _, h := range ps {
+			if h.Date <= latest.String {
+				pages = 0
+				break
+			}
+			fetched = append(fetched, h)
+		}
+	}
+	_, e = dbmap.Select(&hns, "select * from holder_num where code = ? order by date", code)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		return nil, errors.Wrap(e, "failed to query shareholder count")
+	}
+	hns = append(hns, fetched...)
+	sort.Slice(hns, func(i, j int) bool { return hns[i].Date < hns[j].Date })
+	return hns, nil
+}
+
+func parseHolderNum(body []byte) (hns []*model.HolderNum, pages int, e error) {
+	var rows []*emHolderNum
+	if pages, e = parseEmReport(body, &rows); e != nil {
+		return nil, 0, e
+	}
+	for _, r := range rows {
+		date, e := emDate(r.EndDate)
+		if e != nil {
+			return nil, 0, errors.Wrapf(e, "invalid shareholder count date of %s", r.Code)
+		}
+		if r.Holders == nil {
+			continue
+		}
+		h := &model.HolderNum{
+			Code:      r.Code,
+			Date:      date,
+			Holders:   int64(*r.Holders),
+			AvgShares: emNullFloat(r.AvgShares),
+		}
+		if r.NoticeDate != nil {
+			if nd, e := emDate(*r.NoticeDate); e == nil {
+				h.NoticeDate = sql.NullString{String: nd, Valid: true}
+			}
+		}
+		hns = append(hns, h)
+	}
+	return hns, pages, nil
+}
+
+//holderMetrics derives the chip concentration metrics of the shareholder counts in chronological order,
+//given the sums of the top 10 float holder ratios keyed by date, and the share changes in chronological
+//order.
+func holderMetrics(hns []*model.HolderNum, top10 map[string]float64, chgs []*model.ShareChg) {
+	var prev *model.HolderNum
+	c := -1
+	for _, h := range hns {
+		for c+1 < len(chgs) && chgs[c+1].Date <= h.Date {
+			c++
+		}
+		h.HoldersChg, h.FloatPerHolder, h.Top10Float, h.Top10FloatChg =
+			sql.NullFloat64{}, sql.NullFloat64{}, sql.NullFloat64{}, sql.NullFloat64{}
+		//float shares as of the latest change on or before the period end
+		for i := c; i >= 0 && h.Holders > 0; i-- {
+			if chgs[i].FloatAShares.Valid {
+				h.FloatPerHolder = sql.NullFloat64{Float64: chgs[i].FloatAShares.Float64 / float64(h.Holders),
+					Valid: true}
+				break
+			}
+		}
+		if r, ok := top10[h.Date]; ok {
+			h.Top10Float = sql.NullFloat64{Float64: r, Valid: true}
+		}
+		if prev != nil {
+			if prev.Holders > 0 {
+				h.HoldersChg = sql.NullFloat64{
+					Float64: float64(h.Holders-prev.Holders) / float64(prev.Holders) * 100,
+					Valid:   true,
+				}
+			}
+			if h.Top10Float.Valid && prev.Top10Float.Valid {
+				h.Top10FloatChg = sql.NullFloat64{Float64: h.Top10Float.Float64 - prev.Top10Float.Float64,
+					Valid: true}
+			}
+		}
+		prev = h
+	}
+}
+
+func saveHolderNum(hns []*model.HolderNum) (e error) {
+	if len(hns) == 0 {
+		return nil
+	}
+	d, t := util.TimeStr()
+	valueStrings := make([]string, 0, len(hns))
+	valueArgs := make([]interface{}, 0, len(hns)*11)
+	for _, h := range hns {
+		valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?,?,?,?)")
+		valueArgs = append(valueArgs, h.Code, h.Date, h.NoticeDate, h.Holders, h.AvgShares, h.HoldersChg,
+			h.FloatPerHolder, h.Top10Float, h.Top10FloatChg, d, t)
+	}
+	stmt := fmt.Sprintf("insert into holder_num (code,date,notice_date,holders,avg_shares,holders_chg,"+
+		"float_per_holder,top10_float,top10_float_chg,udate,utime) values %s on duplicate key update "+
+		"notice_date=values(notice_date),holders=values(holders),avg_shares=values(avg_shares),"+
+		"holders_chg=values(holders_chg),float_per_holder=values(float_per_holder),"+
+		"top10_float=values(top10_float),top10_float_chg=values(top10_float_chg),"+
+		"udate=values(udate),utime=values(utime)", strings.Join(valueStrings, ","))
+	if _, e = dbmap.Exec(stmt, valueArgs...); e != nil {
+		return errors.Wrap(e, "failed to save shareholder count")
+	}
+	return nil
+}
+
+//getTopHolders fetches the top 10 holders, or the top 10 float holders, since the latest period in
+//database, and compares each period with the previous one.
+func getTopHolders(stock *model.Stock, float bool) (e error) {
+	code := stock.Code
+	latest, e := dbmap.SelectNullStr("select max(date) from top_holder where code = ? and is_float = ?",
+		code, float)
+	if e != nil {
+		return errors.WithStack(e)
+	}
+	report := "RPT_F10_EH_HOLDERS"
+	if float {
+		report = "RPT_F10_EH_FREEHOLDERS"
+	}
+	filter := fmt.Sprintf(`(SECUCODE="%s.%s")`, code, stock.Market.String)
+	var ths []*model.TopHolder
+	for page, pages := 1, 1; page <= pages; page++ {
+		body, e := fetchEmReport(report, "END_DATE", filter, page)
+		if e != nil {
+			return errors.Wrap(e, "failed to get top holders")
+		}
+		var ps []*model.TopHolder
+		if ps, pages, e = parseTopHolders(body, float); e != nil {
+			return e
+		}
+		for _, h := range ps {
+			if h.Date <= latest.String {
+				pages = 0
+				break
+			}
+			ths = append(ths, h)
+		}
+	}
+	if len(ths) == 0 {
+		return nil
+	}
+	var prev []*model.TopHolder
+	if latest.Valid {
+		prev = TopHolders(code, latest.String, float)
+	}
+	periods := rankTopHolders(ths)
+	ths = ths[:0]
+	for _, p := range periods {
+		diffTopHolders(prev, p)
+		ths = append(ths, p...)
+		prev = p
+	}
+	return saveTopHolders(ths)
+}
+
+func parseTopHolders(body []byte, float bool) (ths []*model.TopHolder, pages int, e error) {
+	var rows []*emTopHolder
+	if pages, e = parseEmReport(body, &rows); e != nil {
+		return nil, 0, e
+	}
+	for _, r := range rows {
+		date, e := emDate(r.EndDate)
+		if e != nil {
+			return nil, 0, errors.Wrapf(e, "invalid top holder date of %s", r.Code)
+		}
+		if r.Shares == nil || r.Name == "" {
+			continue
+		}
+		h := &model.TopHolder{
+			Code:      r.Code,
+			Date:      date,
+			Float:     float,
+			Rank:      r.Rank,
+			Name:      r.Name,
+			Shares:    *r.Shares,
+			Ratio:     emNullFloat(r.Ratio),
+			ShareType: sql.NullString{String: r.ShareType, Valid: r.ShareType != ""},
+		}
+		if float {
+			h.Ratio = emNullFloat(r.FreeRatio)
+		}
+		ths = append(ths, h)
+	}
+	return ths, pages, nil
+}
+
+//rankTopHolders groups the holders by period in chronological order, and ranks the holders of each
+//period consecutively, as tied holders share the same rank in the reports.
+func rankTopHolders(ths []*model.TopHolder) (periods [][]*model.TopHolder) {
+	sort.SliceStable(ths, func(i, j int) bool {
+		if ths[i].Date != ths[j].Date {
+			return ths[i].Date < ths[j].Date
+		}
+		if ths[i].Rank != ths[j].Rank {
+			return ths[i].Rank < ths[j].Rank
+		}
+		return ths[i].Shares > ths[j].Shares
+	})
+	for i, h := range ths {
+		if i == 0 || h.Date != ths[i-1].Date {
+			periods = append(periods, nil)
+		}
+		p := len(periods) - 1
+		periods[p] = append(periods[p], h)
+		h.Rank = len(periods[p])
+	}
+	return
+}
+
+//diffTopHolders sets the changes of the holders compared with the previous period. All holders are new
+//if there's no previous period.
+func diffTopHolders(prev, cur []*model.TopHolder) {
+	pmap := make(map[string]float64, len(prev))
+	for _, p := range prev {
+		pmap[p.Name] += p.Shares
+	}
+	for _, h := range cur {
+		p, ok := pmap[h.Name]
+		if !ok {
+			h.Chg = sql.NullFloat64{}
+			h.Status = HolderNew
+			continue
+		}
+		h.Chg = sql.NullFloat64{Float64: h.Shares - p, Valid: true}
+		switch {
+		case h.Shares > p:
+			h.Status = HolderInc
+		case h.Shares < p:
+			h.Status = HolderDec
+		default:
+			h.Status = HolderSame
+		}
+	}
+}
+
+func saveTopHolders(ths []*model.TopHolder) (e error) {
+	d, t := util.TimeStr()
+	batch := 500
+	for i := 0; i < len(ths); i += batch {
+		end := i + batch
+		if end > len(ths) {
+			end = len(ths)
+		}
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]interface{}, 0, (end-i)*12)
+		for _, h := range ths[i:end] {
+			valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?,?,?,?,?)")
+			valueArgs = append(valueArgs, h.Code, h.Date, h.Float, h.Rank, h.Name, h.Shares, h.Ratio, h.ShareType,
+				h.Chg, h.Status, d, t)
+		}
+		stmt := fmt.Sprintf("insert into top_holder (code,date,is_float,holder_rank,name,shares,ratio,share_type,"+
+			"chg,status,udate,utime) values %s on duplicate key update name=values(name),shares=values(shares),"+
+			"ratio=values(ratio),share_type=values(share_type),chg=values(chg),status=values(status),"+
+			"udate=values(udate),utime=values(utime)", strings.Join(valueStrings, ","))
+		if _, e = dbmap.Exec(stmt, valueArgs...); e != nil {
+			return errors.Wrap(e, "failed to save top holders")
+		}
+	}
+	return nil
+}
+
+//TopHolders loads the top 10 holders, or the top 10 float holders, of the stock at the end of the period.
+func TopHolders(code, date string, float bool) (ths []*model.TopHolder) {
+	_, e := dbmap.Select(&ths, "select * from top_holder where code = ? and date = ? and is_float = ? "+
+		"order by holder_rank", code, date, float)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("%s failed to load top holders of %s: %+v", code, date, e)
+	}
+	return
+}
+
+//HolderHist loads the shareholder counts of the stock in chronological order. If limit is positive,
+//only the latest periods are returned.
+func HolderHist(code string, limit int) (hns []*model.HolderNum) {
+	qry := "select * from holder_num where code = ? order by date"
+	if limit > 0 {
+		qry = fmt.Sprintf("select * from (select * from holder_num where code = ? order by date desc limit %d) t "+
+			"order by date", limit)
+	}
+	_, e := dbmap.Select(&hns, qry, code)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("%s failed to load shareholder counts: %+v", code, e)
+	}
+	return
+}
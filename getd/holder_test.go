@@ -0,0 +1,104 @@
+package getd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/carusyte/stock/model"
+)
+
+func TestParseHolderNum(t *testing.T) {
+	hns, pages, e := parseHolderNum([]byte(`{"result":{"pages":1,"data":[` +
+		`{"SECURITY_CODE":"600000","END_DATE":"2020-09-30 00:00:00","HOLD_NOTICE_DATE":"2020-10-30 00:00:00",` +
+		`"HOLDER_NUM":213456,"AVG_HOLD_NUM":13780.5},` +
+		`{"SECURITY_CODE":"600000","END_DATE":"2020-06-30 00:00:00","HOLDER_NUM":null}]}}`))
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if pages != 1 || len(hns) != 1 {
+		t.Fatalf("want 1 record of 1 page, got %d of %d", len(hns), pages)
+	}
+	if h := hns[0]; h.Date != "2020-09-30" || h.NoticeDate.String != "2020-10-30" || h.Holders != 213456 {
+		t.Errorf("unexpected shareholder count: %+v", h)
+	}
+}
+
+func TestTopHolderChanges(t *testing.T) {
+	th := func(date string, rank int, name string, shares float64) *model.TopHolder {
+		return &model.TopHolder{Code: "600000", Date: date, Rank: rank, Name: name, Shares: shares}
+	}
+	periods := rankTopHolders([]*model.TopHolder{
+		th("2020-09-30", 1, "A", 120),
+		th("2020-06-30", 2, "B", 80),
+		th("2020-06-30", 1, "A", 100),
+		th("2020-09-30", 2, "C", 60),
+		//tied with C
+		th("2020-09-30", 2, "B", 70),
+	})
+	if len(periods) != 2 || len(periods[0]) != 2 || len(periods[1]) != 3 {
+		t.Fatalf("unexpected periods: %+v", periods)
+	}
+	if periods[1][1].Name != "B" || periods[1][2].Rank != 3 {
+		t.Errorf("unexpected ranks: %+v, %+v", periods[1][1], periods[1][2])
+	}
+	diffTopHolders(nil, periods[0])
+	diffTopHolders(periods[0], periods[1])
+	for _, c := range []struct {
+		h      *model.TopHolder
+		status string
+		chg    float64
+	}{
+		{periods[0][0], HolderNew, 0},
+		{periods[1][0], HolderInc, 20},
+		{periods[1][1], HolderDec, -10},
+		{periods[1][2], HolderNew, 0},
+	} {
+		if c.h.Status != c.status || c.h.Chg.Float64 != c.chg || c.h.Chg.Valid == (c.status == HolderNew) {
+			t.Errorf("%s of %s: want %s %v, got %s %+v", c.h.Name, c.h.Date, c.status, c.chg, c.h.Status, c.h.Chg)
+		}
+	}
+}
+
+func TestHolderMetrics(t *testing.T) {
+	nf := func(f float64) sql.NullFloat64 { return sql.NullFloat64{Float64: f, Valid: true} }
+	hns := []*model.HolderNum{
+		{Date: "2019-12-31", Holders: 1000},
+		{Date: "2020-03-31", Holders: 800},
+		{Date: "2020-06-30", Holders: 1000},
+	}
+	chgs := []*model.ShareChg{
+		{Date: "2020-01-10", FloatAShares: nf(1e6)},
+		{Date: "2020-05-20"},
+		{Date: "2020-07-01", FloatAShares: nf(2e6)},
+	}
+	holderMetrics(hns, map[string]float64{"2020-03-31": 40, "2020-06-30": 45.5}, chgs)
+	h0, h1, h2 := hns[0], hns[1], hns[2]
+	if h0.FloatPerHolder.Valid || h0.HoldersChg.Valid || h0.Top10Float.Valid {
+		t.Errorf("unexpected metrics of the first period: %+v", h0)
+	}
+	if h1.FloatPerHolder.Float64 != 1250 || h1.HoldersChg.Float64 != -20 || h1.Top10Float.Float64 != 40 ||
+		h1.Top10FloatChg.Valid {
+		t.Errorf("unexpected metrics: %+v", h1)
+	}
+	if h2.FloatPerHolder.Float64 != 1000 || h2.HoldersChg.Float64 != 25 ||
+		math.Abs(h2.Top10FloatChg.Float64-5.5) > 1e-9 {
+		t.Errorf("unexpected metrics: %+v", h2)
+	}
+}
+
+func TestParseXqShareChg(t *testing.T) {
+	x := new(model.XqSharesChg)
+	if e := json.Unmarshal([]byte(`{"data":{"items":[`+
+		`{"chg_date":1593446400000,"total_shares":2935208,"float_shares_float_ashare":2810376,"chg_reason":"增发"},`+
+		`{"chg_date":1577721600000,"total_shares":2810376,"float_shares_float_ashare":2810376},`+
+		`{"total_shares":1}]}}`), x); e != nil {
+		t.Fatal(e)
+	}
+	chgs := parseXqShareChg("600000", x)
+	if len(chgs) != 2 || chgs[0].Date >= chgs[1].Date || chgs[1].Reason.String != "增发" ||
+		chgs[1].FloatAShares.Float64 != 2810376 {
+		t.Errorf("unexpected share changes: %+v, %+v", chgs[0], chgs[len(chgs)-1])
+	}
+}
@@ -10,16 +10,54 @@ import (
 
 //GetIndicesV2 fetches index data from configured source.
 func GetIndicesV2() (idxlst, suclst []*model.IdxLst) {
+	idxlst = indexList()
+	idxMap := make(map[string]*model.IdxLst)
+	for _, idx := range idxlst {
+		idxMap[idx.Code] = idx
+	}
+	rstks := GetIndexKlines(indexStocks(idxlst))
+	for _, c := range rstks.Codes {
+		suclst = append(suclst, idxMap[c])
+	}
+	return
+}
+
+//IndexStocks returns the indices of configured source as stocks, with the Source field set.
+func IndexStocks() *model.Stocks {
+	return indexStocks(indexList())
+}
+
+//GetIndexKlines fetches the klines of the given indices from configured source,
+//returning the ones successfully fetched.
+func GetIndexKlines(stks *model.Stocks) *model.Stocks {
+	src := conf.Args.DataSource.Index
+	fr := FetchRequest{
+		RemoteSource: model.DataSource(src),
+		LocalSource:  model.Index,
+		Reinstate:    model.None,
+	}
+	cs := []model.CYTP{model.DAY, model.WEEK, model.MONTH}
+	frs := make([]FetchRequest, len(cs))
+	for i, c := range cs {
+		fr.Cycle = c
+		frs[i] = fr
+	}
+	return GetKlinesV2(stks, frs...)
+}
+
+func indexList() (idxlst []*model.IdxLst) {
 	src := conf.Args.DataSource.Index
 	log.Infof("Querying index list for source: %s", src)
 	_, e := dbmap.Select(&idxlst, `select * from idxlst where src = ?`, src)
 	util.CheckErr(e, "failed to query idxlst")
 	log.Infof("# indices: %d", len(idxlst))
-	idxMap := make(map[string]*model.IdxLst)
 	for _, idx := range idxlst {
 		log.Infof("%+v", *idx)
-		idxMap[idx.Code] = idx
 	}
+	return
+}
+
+func indexStocks(idxlst []*model.IdxLst) *model.Stocks {
 	stks := &model.Stocks{}
 	for _, idx := range idxlst {
 		stks.Add(&model.Stock{
@@ -29,23 +67,8 @@ func GetIndicesV2() (idxlst, suclst []*model.IdxLst) {
 			},
 			Code:   idx.Code,
 			Name:   idx.Name,
-			Source: src,
+			Source: idx.Src,
 		})
 	}
-	fr := FetchRequest{
-		RemoteSource: model.DataSource(src),
-		LocalSource:  model.Index,
-		Reinstate:    model.None,
-	}
-	cs := []model.CYTP{model.DAY, model.WEEK, model.MONTH}
-	frs := make([]FetchRequest, len(cs))
-	for i, c := range cs {
-		fr.Cycle = c
-		frs[i] = fr
-	}
-	rstks := GetKlinesV2(stks, frs...)
-	for _, c := range rstks.Codes {
-		suclst = append(suclst, idxMap[c])
-	}
-	return
+	return stks
 }
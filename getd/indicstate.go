@@ -0,0 +1,61 @@
+package getd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/indc"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+)
+
+//IndicStateLag is the number of latest bars excluded from the persisted indicator state.
+const IndicStateLag = 5
+
+//loadIndicStates loads the persisted indicator states of the stock for the given cycle, keyed by indicator name.
+func loadIndicStates(code string, cycle model.CYTP) (sts map[string]*indc.State) {
+	sts = make(map[string]*indc.State)
+	var rows []*model.IndicatorState
+	_, e := dbmap.Select(&rows, "select * from indicator_state where code = ? and cycle = ?", code, string(cycle))
+	if e != nil {
+		if "sql: no rows in result set" != e.Error() {
+			log.Warnf("%s failed to load indicator states for %s: %+v", code, cycle, e)
+		}
+		return
+	}
+	for _, r := range rows {
+		st, e := indc.UnmarshalState([]byte(r.State))
+		if e != nil {
+			log.Warnf("%s discarding corrupted %s state for %s: %+v", code, r.Indicator, cycle, e)
+			continue
+		}
+		sts[r.Indicator] = st
+	}
+	return
+}
+
+//saveIndicState persists the indicator state of the stock for the given cycle.
+func saveIndicState(code string, cycle model.CYTP, name string, st *indc.State) {
+	b, e := indc.MarshalState(st)
+	if e != nil {
+		log.Panicf("%s failed to marshal %s state for %s: %+v", code, name, cycle, e)
+	}
+	d, t := util.TimeStr()
+	retry := conf.Args.DeadlockRetry
+	for rt := 0; rt < retry; rt++ {
+		_, e = dbmap.Exec("insert into indicator_state (code,cycle,indicator,klid,state,udate,utime) "+
+			"values (?,?,?,?,?,?,?) on duplicate key update klid=values(klid),state=values(state),"+
+			"udate=values(udate),utime=values(utime)",
+			code, string(cycle), name, st.Klid, string(b), d, t)
+		if e != nil {
+			fmt.Println(e)
+			if strings.Contains(e.Error(), "Deadlock") {
+				continue
+			}
+			log.Panicf("%s failed to save %s state for %s: %+v", code, name, cycle, e)
+		}
+		return
+	}
+	log.Panicf("%s failed to save %s state for %s: %+v", code, name, cycle, e)
+}
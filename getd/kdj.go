@@ -10,6 +10,7 @@ import (
 	"time"
 
 	rm "github.com/carusyte/rima/model"
+	"github.com/carusyte/stock/calendar"
 	"github.com/carusyte/stock/conf"
 	"github.com/carusyte/stock/indc"
 	"github.com/carusyte/stock/model"
@@ -79,16 +80,24 @@ func GetKdjHist(code string, tab model.DBTab, retro int, toDate string) (indcs [
 			return
 		}
 		if len(indcs) > 1 {
+			//the daily klines of the period up to toDate are merged into one, preceded by the last
+			//bar of the previous period, which may be earlier than the last trading day if suspended
+			pstart := ""
 			switch tab {
 			case model.INDICATOR_DAY:
 				return
 			case model.INDICATOR_WEEK:
 				sql = "select * from kline_w_f where code = ? and date < ? order by klid"
+				pstart = calendar.WeekStart(toDate)
 			case model.INDICATOR_MONTH:
 				sql = "select * from kline_m_f where code = ? and date < ? order by klid"
+				pstart = calendar.MonthStart(toDate)
+			}
+			if pstart == "" {
+				pstart = toDate
 			}
 			var oqs []*model.TradeDataBasic
-			_, e = dbmap.Select(&oqs, sql, code, toDate)
+			_, e = dbmap.Select(&oqs, sql, code, pstart)
 			if e != nil {
 				if "sql: no rows in result set" == e.Error() {
 					log.Warnf("%s, %s, sql: %s, %s: %+v, ", code, tab, toDate, sql, e.Error())
@@ -96,6 +105,11 @@ func GetKdjHist(code string, tab model.DBTab, retro int, toDate string) (indcs [
 				}
 				log.Panicf("%s failed to query kline, sql: %s, \n%+v", code, sql, e)
 			}
+			if len(oqs) == 0 {
+				log.Warnf("%s, %s, no kline before %s", code, tab, pstart)
+				return
+			}
+			from := oqs[len(oqs)-1].Date
 			qsdy := GetTrDataBtwn(
 				code,
 				TrDataQry{
@@ -104,7 +118,7 @@ func GetKdjHist(code string, tab model.DBTab, retro int, toDate string) (indcs [
 					Basic:     true,
 				},
 				Date,
-				"["+indcs[len(indcs)-1].Date,
+				"["+from,
 				toDate+"]",
 				false)
 			nq := ToOne(qsdy.Base[1:], qsdy.Base[0].Close, oqs[len(oqs)-1].Klid)
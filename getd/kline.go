@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/carusyte/stock/calendar"
 	"github.com/carusyte/stock/conf"
 	"github.com/carusyte/stock/model"
 	"github.com/carusyte/stock/util"
@@ -745,9 +746,45 @@ func getMinuteKlines(code string, tab model.DBTab) (klmin []*model.Quote, suc bo
 }
 
 func tryMinuteKlines(code string, tab model.DBTab) (klmin []*model.Quote, suc, retry bool) {
-	//TODO implement minute klines
-	//urlt := `https://xueqiu.com/stock/forchartk/stocklist.json?symbol=%s&period=60m&type=before`
-	panic("implement me ")
+	var cycle model.CYTP
+	switch tab {
+	case model.KLINE_60M:
+		cycle = model.M60
+	default:
+		log.Panicf("unsupported minute kline table: %s", tab)
+	}
+	stks := StocksDbByCode(code)
+	if len(stks) == 0 {
+		log.Warnf("%s not found in basics", code)
+		return
+	}
+	fr := FetchRequest{
+		RemoteSource: model.DataSource(conf.Args.DataSource.Kline),
+		LocalSource:  model.KlineMaster,
+		Cycle:        cycle,
+		Reinstate:    model.Forward,
+	}
+	initKlineFetcher(fr)
+	tdmap, _, suc, retry := kfmap[fr.RemoteSource].fetchKline(stks[0], fr, false)
+	if !suc || tdmap[fr] == nil {
+		return
+	}
+	for i, b := range tdmap[fr].Base {
+		klmin = append(klmin, &model.Quote{
+			Type:   tab,
+			Code:   b.Code,
+			Date:   b.Date,
+			Klid:   i,
+			Open:   b.Open,
+			High:   b.High,
+			Close:  b.Close,
+			Low:    b.Low,
+			Volume: b.Volume,
+			Amount: b.Amount,
+			Xrate:  b.Xrate,
+		})
+	}
+	return klmin, true, false
 }
 
 func binsert(quotes []*model.Quote, table string, lklid int) (c int) {
@@ -1366,11 +1403,18 @@ func MergeXdxrBetween(start, end string, xemap map[string]*model.Xdxr) (rx *mode
 
 func mergeXdxr(xemap map[string]*model.Xdxr, date string, tab model.DBTab) (xe *model.Xdxr, in bool, e error) {
 	for dt, x := range xemap {
+		//event on a closed day takes effect on the next trading day
+		td := dt
+		if !calendar.IsTradingDay(td) {
+			if td = calendar.NextTD(dt); td == "" {
+				td = dt
+			}
+		}
 		switch tab {
 		case model.KLINE_WEEK_NR:
-			in, e = util.SameWeek(dt, date, "")
+			in = calendar.WeekEnd(td) == calendar.WeekEnd(date)
 		case model.KLINE_MONTH_NR:
-			in = dt[:8] == date[:8]
+			in = calendar.MonthEnd(td) == calendar.MonthEnd(date)
 		}
 		if e != nil {
 			return xe, false, e
@@ -0,0 +1,100 @@
+package getd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/global"
+	"github.com/carusyte/stock/model"
+)
+
+var (
+	//klineTabs caches the intraday kline tables already ensured.
+	klineTabs sync.Map
+)
+
+//cycleTabCode returns the abbreviation of the cycle used in table names,
+//e.g. "d" for daily klines and "m60" for 60-minute klines.
+func cycleTabCode(c model.CYTP) (code string, ok bool) {
+	switch c {
+	case model.DAY, model.WEEK, model.MONTH,
+		model.M120, model.M60, model.M30, model.M15, model.M5, model.M1:
+		return strings.ToLower(string(c)), true
+	default:
+		return "", false
+	}
+}
+
+//MinuteCycles returns the intraday cycles enabled by configuration.
+func MinuteCycles() (cs []model.CYTP) {
+	for _, c := range conf.Args.DataSource.MinuteKlines {
+		cy := model.CYTP(strings.ToUpper(c))
+		if !cy.Intraday() {
+			log.Panicf("invalid minute kline cycle: %s", c)
+		}
+		cs = append(cs, cy)
+	}
+	return
+}
+
+//GetMinuteKlines fetches intraday klines of the given cycles, in both non-reinstated and
+//forward reinstated form. Returns the stocks that have been successfully processed.
+func GetMinuteKlines(stks *model.Stocks, cycles ...model.CYTP) (rstks *model.Stocks) {
+	src := model.DataSource(conf.Args.DataSource.Kline)
+	var frs []FetchRequest
+	for _, c := range cycles {
+		for _, r := range []model.Rtype{model.None, model.Forward} {
+			frs = append(frs, FetchRequest{
+				RemoteSource: src,
+				LocalSource:  model.KlineMaster,
+				Reinstate:    r,
+				Cycle:        c,
+			})
+		}
+	}
+	return GetKlinesV2(stks, frs...)
+}
+
+//ensureKlineTables creates the tables for intraday klines of the fetch request if they don't exist yet.
+//The tables share the structure of their daily counterparts.
+func ensureKlineTables(fr FetchRequest) {
+	if !fr.Cycle.Intraday() {
+		return
+	}
+	dfr := fr
+	dfr.Cycle = model.DAY
+	dtabs := resolveTableNames(dfr)
+	for i, tab := range resolveTableNames(fr) {
+		for _, sfx := range []string{"", "_lr", "_ma", "_ma_lr"} {
+			t := tab + sfx
+			if _, ok := klineTabs.Load(t); ok {
+				continue
+			}
+			stmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s LIKE %s", t, dtabs[i]+sfx)
+			if _, e := dbmap.Exec(stmt); e != nil {
+				log.Panicf("failed to create table %s: %+v", t, e)
+			}
+			klineTabs.Store(t, true)
+		}
+	}
+}
+
+//parseKlineDate parses the date of daily or intraday klines.
+func parseKlineDate(d string) (time.Time, error) {
+	if len(d) > len(global.DateFormat) {
+		return time.ParseInLocation(global.MinuteFormat, d, time.Local)
+	}
+	return time.Parse(global.DateFormat, d)
+}
+
+//barsPerDay returns the number of bars within a trading day for the given cycle.
+func barsPerDay(c model.CYTP) int {
+	if m := c.Minutes(); m > 0 {
+		//4 trading hours a day
+		return (240 + m - 1) / m
+	}
+	return 1
+}
@@ -86,6 +86,12 @@ func GetTrDataDB(code string, qry TrDataQry, limit int, desc bool) (trdat *model
 	//A slice of trading data of arbitrary kind
 	ochan := make(chan interface{}, 4)
 
+	trdat = &model.TradeData{
+		Code:          code,
+		Cycle:         qry.Cycle,
+		Reinstatement: qry.Reinstate,
+	}
+
 	//Collect and merge query results
 	wgr.Add(1)
 	go func() {
@@ -375,6 +381,12 @@ func resolveKlineFetcher(src model.DataSource) (f klineFetcher) {
 		f = &XqKlineFetcher{}
 	case model.EM:
 		f = &EmKlineFetcher{}
+	case model.Local:
+		f = &LocalKlineFetcher{}
+	case model.Reinstated:
+		f = &ReinstateKlineFetcher{}
+	case model.Validated:
+		f = &ValidatedKlineFetcher{}
 	default:
 		log.Panicf("unsupported data source: %+v", src)
 	}
@@ -391,14 +403,9 @@ func resolveTableNames(qs ...FetchRequest) (tables []string) {
 		} else {
 			base = string(model.KlineMaster) + "_"
 		}
-		switch q.Cycle {
-		case model.DAY:
-			base += "d_"
-		case model.WEEK:
-			base += "w_"
-		case model.MONTH:
-			base += "m_"
-		default:
+		if c, ok := cycleTabCode(q.Cycle); ok {
+			base += c + "_"
+		} else {
 			//cycle not specified, all cycle type will be used
 			if len(qtabs) == 0 {
 				qtabs = append(qtabs, base+"d_")
@@ -457,14 +464,9 @@ func resolveTables(q TrDataQry) (tables map[string]reflect.Type) {
 	if len(q.LocalSource) > 0 {
 		base = string(q.LocalSource) + "_"
 	}
-	switch q.Cycle {
-	case model.DAY:
-		base += "d_"
-	case model.WEEK:
-		base += "w_"
-	case model.MONTH:
-		base += "m_"
-	default:
+	if c, ok := cycleTabCode(q.Cycle); ok {
+		base += c + "_"
+	} else {
 		log.Panicf("Unsupported cycle type: %v, query param: %+v", q.Cycle, q)
 	}
 	switch q.Reinstate {
@@ -506,14 +508,9 @@ func resolveTradeDataTables(td *model.TradeData) (tabCols map[string][]string, t
 	default:
 		base = string(td.Source) + "_"
 	}
-	switch td.Cycle {
-	case model.DAY:
-		base += "d_"
-	case model.WEEK:
-		base += "w_"
-	case model.MONTH:
-		base += "m_"
-	default:
+	if c, ok := cycleTabCode(td.Cycle); ok {
+		base += c + "_"
+	} else {
 		log.Panicf("Unsupported cycle type: %v, query param: %+v", td.Cycle, td)
 	}
 	switch td.Reinstatement {
@@ -1387,6 +1384,9 @@ func GetKlinesV2(stks *model.Stocks, fetReq ...FetchRequest) (rstks *model.Stock
 	wgr := collect(rstks, outstks)
 	dsmap := initKlineFetcher(fetReq...)
 	dbcMap := createDbTaskQueues(dsmap)
+	for fr := range dbcMap {
+		ensureKlineTables(fr)
+	}
 	wgdb := saveTradeData(outstks, dbcMap, stks.Size())
 	for _, stk := range stks.List {
 		wg.Add(1)
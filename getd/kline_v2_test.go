@@ -72,3 +72,27 @@ func TestTrDataQryMap(t *testing.T) {
 	log.Debugf("qs:%+v", qs)
 	log.Debugf("qmap:%+v", qmap)
 }
+
+func TestResolveMinuteTables(t *testing.T) {
+	tabs := resolveTables(TrDataQry{
+		Cycle:     model.M60,
+		Reinstate: model.Forward,
+		Basic:     true,
+		LogRtn:    true,
+	})
+	for _, tab := range []string{"kline_m60_f", "kline_m60_f_lr"} {
+		if _, ok := tabs[tab]; !ok {
+			t.Errorf("expected table %s, got %+v", tab, tabs)
+		}
+	}
+	names := resolveTableNames(FetchRequest{LocalSource: model.EM, Cycle: model.M5, Reinstate: model.None})
+	if len(names) != 1 || names[0] != "em_m5_n" {
+		t.Errorf("expected [em_m5_n], got %+v", names)
+	}
+	if n := barsPerDay(model.M30); n != 8 {
+		t.Errorf("expected 8 bars per day for M30, got %d", n)
+	}
+	if _, e := parseKlineDate("2020-01-02 10:30"); e != nil {
+		t.Error(e)
+	}
+}
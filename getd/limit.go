@@ -0,0 +1,288 @@
+package getd
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+const (
+	//chinextReform is the first day of the registration-based ChiNext, since which its limit is ±20%
+	chinextReform = "2020-08-24"
+	//mainReform is the first day of the registration-based main boards of SSE and SZSE, since which
+	//the stocks listed are not limited in the first limitFreeDays trading days
+	mainReform = "2023-04-10"
+	//limitFreeDays is the number of trading days without price limit after listing on STAR,
+	//registration-based ChiNext or main boards
+	limitFreeDays = 5
+)
+
+//isBSE reports whether the stock is listed on the Beijing Stock Exchange.
+func isBSE(code string) bool {
+	for _, p := range []string{"43", "83", "87", "88", "920"} {
+		if strings.HasPrefix(code, p) {
+			return true
+		}
+	}
+	return false
+}
+
+//limitRate returns the price limit rate of the stock on the date, given the listing date, empty if
+//unknown, and the number of trading days since listing, 0 for the listing day. ok is false if the price
+//is not limited.
+func limitRate(code, date, listDate string, nth int, hist StatusHist) (rate float64, ok bool) {
+	star := strings.HasPrefix(code, "688")
+	chinext := strings.HasPrefix(code, "300") || strings.HasPrefix(code, "301")
+	switch {
+	case isBSE(code):
+		//ST stocks are limited by ±30% as well, and only the listing day is free
+		return 0.3, nth >= 1
+	case star || chinext && date >= chinextReform:
+		//ST stocks on these boards are limited by ±20% as well
+		return 0.2, nth >= limitFreeDays
+	}
+	free := 1
+	if listDate >= mainReform {
+		free = limitFreeDays
+	}
+	if nth < free {
+		return 0, false
+	}
+	if hist.Is(code, date, StatusST, StatusSST) {
+		return 0.05, true
+	}
+	return 0.1, true
+}
+
+//listingKlid returns the klid of the listing day in the daily klines, given the first bar on or after
+//the listing date. ok is false if the listing day is unknown.
+func listingKlid(ttm sql.NullString, first *model.TradeDataBasic) (klid int, ok bool) {
+	if !ttm.Valid || len(ttm.String) < 10 || first == nil {
+		return 0, false
+	}
+	if first.Klid == 0 && first.Date > ttm.String[:10] {
+		//history starts after listing
+		return -limitFreeDays, true
+	}
+	return first.Klid, true
+}
+
+//limitPrice returns the limit price rounded to cents, half up, from the previous close.
+func limitPrice(prev, rate float64) float64 {
+	//tolerates the binary representation error, e.g. 10.45 as 10.4499999
+	return math.Floor(prev*(1+rate)*100+0.5+1e-6) / 100
+}
+
+func atPrice(p, lp float64) bool {
+	return math.Abs(p-lp) < 0.005
+}
+
+//annotateLimits returns the annotations of the non-reinstated daily bars reaching the price limit, except
+//the first bar, which only provides the previous close. The previous close is adjusted by the xdxr events
+//in between, as the exchanges do. list is the klid of the listing day, and the bars within limitFreeDays
+//after listing are skipped if it's unknown.
+func annotateLimits(stk *model.Stock, bars []*model.TradeDataBasic, xemap map[string]*model.Xdxr,
+	hist StatusHist, list int, listed bool) (lims []*model.KlineLimit) {
+	listDate := ""
+	if listed {
+		listDate = stk.TimeToMarket.String[:10]
+	}
+	for i := 1; i < len(bars); i++ {
+		b, p := bars[i], bars[i-1]
+		nth := b.Klid - list
+		if !listed && b.Klid < limitFreeDays {
+			continue
+		}
+		rate, ok := limitRate(stk.Code, b.Date, listDate, nth, hist)
+		if !ok {
+			continue
+		}
+		prev := Reinstate(p.Close, MergeXdxrBetween(p.Date, b.Date, xemap))
+		if prev <= 0 {
+			continue
+		}
+		l := &model.KlineLimit{
+			Code:      stk.Code,
+			Date:      b.Date,
+			Klid:      b.Klid,
+			Rate:      rate,
+			UpPrice:   limitPrice(prev, rate),
+			DownPrice: limitPrice(prev, -rate),
+		}
+		l.LimitUp = atPrice(b.Close, l.UpPrice)
+		l.LimitDown = atPrice(b.Close, l.DownPrice)
+		l.OneWord = (l.LimitUp || l.LimitDown) && atPrice(b.High, b.Low)
+		l.TouchUp = !l.LimitUp && atPrice(b.High, l.UpPrice)
+		l.TouchDown = !l.LimitDown && atPrice(b.Low, l.DownPrice)
+		if l.LimitUp || l.LimitDown || l.TouchUp || l.TouchDown {
+			lims = append(lims, l)
+		}
+	}
+	return
+}
+
+//UpdateLimits annotates the non-reinstated daily klines of the stocks reaching the price limit, after
+//the last bar scanned for each stock, or the whole history if there is none. Returns the stocks
+//successfully annotated.
+func UpdateLimits(stks *model.Stocks) (rstks *model.Stocks) {
+	start := time.Now()
+	defer StopWatch("KLINE_LIMIT", start)
+	rstks = new(model.Stocks)
+	if stks.Size() == 0 {
+		return
+	}
+	log.Printf("annotating price limits for %d stocks...", stks.Size())
+	hist := LoadStatusHist(stks.Codes...)
+	d, t := util.TimeStr()
+	qry := TrDataQry{LocalSource: model.KlineMaster, Cycle: model.DAY, Reinstate: model.None, Basic: true}
+	for _, stk := range stks.List {
+		since, e := dbmap.SelectNullStr("select date from kline_limit_scan where code = ?", stk.Code)
+		if e != nil {
+			log.Warnf("%s failed to query the last bar scanned for price limits: %+v", stk.Code, e)
+			continue
+		}
+		var bars []*model.TradeDataBasic
+		if since.Valid {
+			//the last bar scanned provides the previous close
+			bars = GetTrDataBtwn(stk.Code, qry, Date, "["+since.String, "", false).Base
+		} else {
+			bars = GetTrDataDB(stk.Code, qry, 0, false).Base
+		}
+		if len(bars) < 2 {
+			rstks.Add(stk)
+			continue
+		}
+		var lims []*model.KlineLimit
+		{
+			xemap, e := XdxrDateBetween(stk.Code, bars[0].Date, bars[len(bars)-1].Date)
+			if e != nil {
+				log.Warnf("%s failed to query xdxr for price limits: %+v", stk.Code, e)
+				continue
+			}
+			list, listed := listingKlid(stk.TimeToMarket, firstBarSince(stk.Code, stk.TimeToMarket))
+			if !listed {
+				log.Warnf("%s listing date unknown: %+v, the first %d bars are not annotated",
+					stk.Code, stk.TimeToMarket, limitFreeDays)
+			}
+			lims = annotateLimits(stk, bars, xemap, hist, list, listed)
+		}
+		for _, l := range lims {
+			l.Udate = sql.NullString{String: d, Valid: true}
+			l.Utime = sql.NullString{String: t, Valid: true}
+		}
+		if e := saveLimits(stk.Code, since.String, bars[len(bars)-1].Date, d, t, lims); e != nil {
+			log.Warnf("%s failed to save price limits: %+v", stk.Code, e)
+			continue
+		}
+		rstks.Add(stk)
+	}
+	log.Printf("price limits of %d stocks annotated", rstks.Size())
+	return
+}
+
+//firstBarSince returns the first non-reinstated daily bar of the stock on or after the listing date,
+//or nil if there is none or the listing date is unknown.
+func firstBarSince(code string, ttm sql.NullString) *model.TradeDataBasic {
+	if !ttm.Valid || len(ttm.String) < 10 {
+		return nil
+	}
+	var bars []*model.TradeDataBasic
+	_, e := dbmap.Select(&bars, "select code, date, klid from kline_d_n where code = ? and date >= ? "+
+		"order by klid limit 1", code, ttm.String[:10])
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("%s failed to query the first daily kline: %+v", code, e)
+	}
+	if len(bars) == 0 {
+		return nil
+	}
+	return bars[0]
+}
+
+//saveLimits replaces the annotations of the stock after the date, or all of them if the date is empty,
+//and records the last bar scanned.
+func saveLimits(code, since, last, udate, utime string, lims []*model.KlineLimit) (e error) {
+	tran, e := dbmap.Begin()
+	if e != nil {
+		return errors.WithStack(e)
+	}
+	if _, e = tran.Exec("delete from kline_limit where code = ? and date > ?", code, since); e != nil {
+		tran.Rollback()
+		return errors.WithStack(e)
+	}
+	batch := 500
+	for i := 0; i < len(lims); i += batch {
+		end := i + batch
+		if end > len(lims) {
+			end = len(lims)
+		}
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]interface{}, 0, (end-i)*13)
+		for _, l := range lims[i:end] {
+			valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?,?,?,?,?,?)")
+			valueArgs = append(valueArgs, l.Code, l.Date, l.Klid, l.Rate, l.UpPrice, l.DownPrice, l.LimitUp,
+				l.LimitDown, l.OneWord, l.TouchUp, l.TouchDown, l.Udate, l.Utime)
+		}
+		stmt := fmt.Sprintf("insert into kline_limit (code,date,klid,rate,up_price,down_price,limit_up,"+
+			"limit_down,one_word,touch_up,touch_down,udate,utime) values %s", strings.Join(valueStrings, ","))
+		if _, e = tran.Exec(stmt, valueArgs...); e != nil {
+			tran.Rollback()
+			return errors.WithStack(e)
+		}
+	}
+	if _, e = tran.Exec("insert into kline_limit_scan (code,date,udate,utime) values (?,?,?,?) "+
+		"on duplicate key update date=values(date),udate=values(udate),utime=values(utime)",
+		code, last, udate, utime); e != nil {
+		tran.Rollback()
+		return errors.WithStack(e)
+	}
+	return errors.WithStack(tran.Commit())
+}
+
+//LoadLimits loads the price limit annotations of the stock between from and to inclusive, keyed by date.
+//The range is unbounded on either side if empty.
+func LoadLimits(code, from, to string) map[string]*model.KlineLimit {
+	qry := "select * from kline_limit where code = ?"
+	args := []interface{}{code}
+	if from != "" {
+		qry += " and date >= ?"
+		args = append(args, from)
+	}
+	if to != "" {
+		qry += " and date <= ?"
+		args = append(args, to)
+	}
+	var lims []*model.KlineLimit
+	_, e := dbmap.Select(&lims, qry, args...)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("%s failed to load price limits: %+v", code, e)
+	}
+	m := make(map[string]*model.KlineLimit, len(lims))
+	for _, l := range lims {
+		m[l.Date] = l
+	}
+	return m
+}
+
+//LimitsOn loads the price limit annotations of the stocks on the date, keyed by code.
+func LimitsOn(date string, codes ...string) map[string]*model.KlineLimit {
+	qry := "select * from kline_limit where date = ?"
+	if len(codes) > 0 {
+		qry += fmt.Sprintf(" and code in (%s)", util.Join(codes, ",", true))
+	}
+	var lims []*model.KlineLimit
+	_, e := dbmap.Select(&lims, qry, date)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("failed to load price limits on %s: %+v", date, e)
+	}
+	m := make(map[string]*model.KlineLimit, len(lims))
+	for _, l := range lims {
+		m[l.Code] = l
+	}
+	return m
+}
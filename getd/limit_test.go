@@ -0,0 +1,140 @@
+package getd
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/carusyte/stock/model"
+)
+
+func TestLimitPrice(t *testing.T) {
+	for _, c := range []struct{ prev, rate, want float64 }{
+		{9.5, 0.1, 10.45},
+		{9.5, -0.1, 8.55},
+		{3.33, 0.05, 3.5},
+		{11.11, 0.1, 12.22},
+		{25.05, 0.2, 30.06},
+	} {
+		if got := limitPrice(c.prev, c.rate); got != c.want {
+			t.Errorf("limitPrice(%v, %v): want %v, got %v", c.prev, c.rate, c.want, got)
+		}
+	}
+}
+
+func TestLimitRate(t *testing.T) {
+	hist := StatusHist{"600001": {{Code: "600001", Status: StatusSST, Start: "2020-05-06"}}}
+	for _, c := range []struct {
+		code, date, list string
+		nth              int
+		rate             float64
+		ok               bool
+	}{
+		{"600000", "2020-06-01", "2020-06-01", 0, 0, false},
+		{"600000", "2020-06-02", "2020-06-01", 1, 0.1, true},
+		{"600001", "2020-06-02", "1999-11-10", 100, 0.05, true},
+		{"600001", "2020-05-05", "", 100, 0.1, true},
+		{"688001", "2020-06-02", "2020-05-27", 4, 0.2, false},
+		{"688001", "2020-06-03", "2020-05-27", 5, 0.2, true},
+		{"300001", "2020-08-21", "2009-10-30", 100, 0.1, true},
+		{"300001", "2020-08-24", "2009-10-30", 100, 0.2, true},
+		{"300999", "2020-09-01", "2020-08-27", 2, 0.2, false},
+		//registration-based main boards
+		{"603137", "2023-04-14", "2023-04-10", 4, 0, false},
+		{"603137", "2023-04-17", "2023-04-10", 5, 0.1, true},
+		{"001286", "2023-04-17", "2023-04-14", 1, 0, false},
+		//BSE
+		{"830799", "2021-11-15", "2021-11-15", 0, 0, false},
+		{"830799", "2021-11-16", "2021-11-15", 1, 0.3, true},
+		{"920001", "2024-10-10", "2021-11-15", 500, 0.3, true},
+	} {
+		rate, ok := limitRate(c.code, c.date, c.list, c.nth, hist)
+		if ok != c.ok || ok && rate != c.rate {
+			t.Errorf("limitRate(%s, %s, %s, %d): want %v %v, got %v %v", c.code, c.date, c.list, c.nth, c.rate,
+				c.ok, rate, ok)
+		}
+	}
+}
+
+func TestListingKlid(t *testing.T) {
+	ttm := sql.NullString{String: "2020-06-01", Valid: true}
+	for _, c := range []struct {
+		ttm   sql.NullString
+		first *model.TradeDataBasic
+		klid  int
+		ok    bool
+	}{
+		{ttm, &model.TradeDataBasic{Date: "2020-06-01", Klid: 0}, 0, true},
+		{ttm, &model.TradeDataBasic{Date: "2020-06-01", Klid: 3}, 3, true},
+		//history starts after listing
+		{ttm, &model.TradeDataBasic{Date: "2020-07-01", Klid: 0}, -limitFreeDays, true},
+		{ttm, nil, 0, false},
+		{sql.NullString{}, &model.TradeDataBasic{Date: "2020-06-01"}, 0, false},
+		{sql.NullString{String: "--", Valid: true}, &model.TradeDataBasic{Date: "2020-06-01"}, 0, false},
+	} {
+		if klid, ok := listingKlid(c.ttm, c.first); klid != c.klid || ok != c.ok {
+			t.Errorf("listingKlid(%+v, %+v): want %d %v, got %d %v", c.ttm, c.first, c.klid, c.ok, klid, ok)
+		}
+	}
+}
+
+func TestAnnotateLimits(t *testing.T) {
+	klid := 0
+	bar := func(date string, o, h, l, c float64) *model.TradeDataBasic {
+		klid++
+		return &model.TradeDataBasic{Code: "600000", Date: date, Klid: klid - 1, Open: o, High: h, Low: l, Close: c}
+	}
+	stk := &model.Stock{Code: "600000", TimeToMarket: sql.NullString{String: "2020-06-01", Valid: true}}
+	bars := []*model.TradeDataBasic{
+		//listing day without limit
+		bar("2020-06-01", 8, 12, 8, 10),
+		bar("2020-06-02", 11, 11, 11, 11),
+		bar("2020-06-03", 11.5, 12.1, 11.2, 11.5),
+		bar("2020-06-04", 11, 11, 10.35, 10.35),
+		//ex-rights with 10 bonus shares per 10
+		bar("2020-06-05", 5.2, 5.69, 5.1, 5.69),
+		//ST since the day
+		bar("2020-06-08", 5.6, 5.98, 5.41, 5.41),
+	}
+	xemap := map[string]*model.Xdxr{"2020-06-05": {
+		Code:        "600000",
+		SharesAllot: sql.NullFloat64{Float64: 10, Valid: true},
+	}}
+	hist := StatusHist{"600000": {{Code: "600000", Status: StatusST, Start: "2020-06-08"}}}
+	lims := annotateLimits(stk, bars, xemap, hist, 0, true)
+	want := []struct {
+		date                                            string
+		up, down                                        float64
+		limitUp, limitDown, oneWord, touchUp, touchDown bool
+	}{
+		{"2020-06-02", 11, 9, true, false, true, false, false},
+		{"2020-06-03", 12.1, 9.9, false, false, false, true, false},
+		{"2020-06-04", 12.65, 10.35, false, true, false, false, false},
+		{"2020-06-05", 5.69, 4.66, true, false, false, false, false},
+		{"2020-06-08", 5.97, 5.41, false, true, false, false, false},
+	}
+	if len(lims) != len(want) {
+		for _, l := range lims {
+			t.Logf("%+v", l)
+		}
+		t.Fatalf("want %d annotations, got %d", len(want), len(lims))
+	}
+	for i, w := range want {
+		l := lims[i]
+		if l.Date != w.date || l.UpPrice != w.up || l.DownPrice != w.down || l.LimitUp != w.limitUp ||
+			l.LimitDown != w.limitDown || l.OneWord != w.oneWord || l.TouchUp != w.touchUp ||
+			l.TouchDown != w.touchDown {
+			t.Errorf("#%d: want %+v, got %+v", i, w, l)
+		}
+	}
+
+	//incremental from the latest annotation
+	lims = annotateLimits(stk, bars[3:], xemap, hist, 0, true)
+	if len(lims) != 2 || lims[0].Date != "2020-06-05" || lims[1].Date != "2020-06-08" {
+		t.Errorf("unexpected incremental annotations: %+v", lims)
+	}
+	//the bars right after listing are skipped if the listing day is unknown
+	lims = annotateLimits(stk, bars, xemap, hist, 0, false)
+	if len(lims) != 1 || lims[0].Date != "2020-06-08" {
+		t.Errorf("unexpected annotations without listing day: %+v", lims)
+	}
+}
@@ -0,0 +1,222 @@
+package getd
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/carusyte/stock/calendar"
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/global"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+//lockupRefreshDays is how many days before today the lock-up expiries are refetched on later runs, as the
+//scheduled ones may be revised or newly announced.
+const lockupRefreshDays = 30
+
+//emLockup is a row of the lock-up expiry report of eastmoney.
+type emLockup struct {
+	Code      string   `json:"SECURITY_CODE"`
+	Date      string   `json:"FREE_DATE"`
+	ShareType string   `json:"FREE_SHARES_TYPE"`
+	Shares    *float64 `json:"CURRENT_FREE_SHARES"`
+	Able      *float64 `json:"ABLE_FREE_SHARES"`
+	MarketCap *float64 `json:"LIFT_MARKET_CAP"`
+	//ratio to float shares before unlocking
+	FreeRatio *float64 `json:"FREE_RATIO"`
+	//ratio to total shares
+	TotalRatio *float64 `json:"TOTAL_RATIO"`
+}
+
+//GetLockup fetches the lock-up expiries since the configured date on the first run, or those recently
+//passed and upcoming on later runs. Returns the stocks as is if successful, or none otherwise.
+func GetLockup(stks *model.Stocks) *model.Stocks {
+	log.Println("getting lock-up expiries...")
+	if e := getLockup(); e != nil {
+		log.Warnf("failed to get lock-up expiries: %+v", e)
+		return new(model.Stocks)
+	}
+	return stks
+}
+
+func getLockup() (e error) {
+	latest, e := dbmap.SelectNullStr("select max(date) from lockup")
+	if e != nil {
+		return errors.WithStack(e)
+	}
+	since := conf.Args.DataSource.LockupSince
+	if latest.Valid {
+		since = time.Now().AddDate(0, 0, -lockupRefreshDays).Format(global.DateFormat)
+	}
+	filter := fmt.Sprintf(`(FREE_DATE>='%s')`, since)
+	var lks []*model.Lockup
+	for page, pages := 1, 1; page <= pages; page++ {
+		body, e := fetchEmReport("RPT_LIFT_STAGE", "FREE_DATE", filter, page)
+		if e != nil {
+			return errors.Wrap(e, "failed to get lock-up expiries")
+		}
+		var ps []*model.Lockup
+		if ps, pages, e = parseLockup(body); e != nil {
+			return e
+		}
+		lks = append(lks, ps...)
+	}
+	var floats []*struct {
+		Code         string
+		FloatAShares float64 `db:"float_a_shares"`
+	}
+	_, e = dbmap.Select(&floats, "select c.code, c.float_a_shares from share_chg c inner join "+
+		"(select code, max(date) date from share_chg where float_a_shares > 0 group by code) l "+
+		"using (code, date)")
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		return errors.Wrap(e, "failed to query float shares")
+	}
+	fmap := make(map[string]float64, len(floats))
+	for _, f := range floats {
+		fmap[f.Code] = f.FloatAShares
+	}
+	lockupRatios(lks, fmap)
+	log.Printf("%d lock-up expiries since %s", len(lks), since)
+	return saveLockup(since, lks)
+}
+
+func parseLockup(body []byte) (lks []*model.Lockup, pages int, e error) {
+	var rows []*emLockup
+	if pages, e = parseEmReport(body, &rows); e != nil {
+		return nil, 0, e
+	}
+	pct := func(f *float64) (n sql.NullFloat64) {
+		n = emNullFloat(f)
+		n.Float64 *= 100
+		return
+	}
+	for _, r := range rows {
+		date, e := emDate(r.Date)
+		if e != nil {
+			return nil, 0, errors.Wrapf(e, "invalid lock-up expiry date of %s", r.Code)
+		}
+		if r.Shares == nil {
+			continue
+		}
+		lks = append(lks, &model.Lockup{
+			Code:       r.Code,
+			Date:       date,
+			ShareType:  r.ShareType,
+			Shares:     *r.Shares,
+			AbleShares: emNullFloat(r.Able),
+			MarketCap:  emNullFloat(r.MarketCap),
+			FloatRatio: pct(r.FreeRatio),
+			TotalRatio: pct(r.TotalRatio),
+		})
+	}
+	return lks, pages, nil
+}
+
+//lockupRatios fills in the missing float ratios by the latest float A shares of the stocks.
+func lockupRatios(lks []*model.Lockup, floats map[string]float64) {
+	for _, l := range lks {
+		if l.FloatRatio.Valid {
+			continue
+		}
+		if f, ok := floats[l.Code]; ok && f > 0 {
+			l.FloatRatio.Float64, l.FloatRatio.Valid = l.Shares/f*100, true
+		}
+	}
+}
+
+//saveLockup replaces the lock-up expiries since the date with the refreshed ones, dropping those revised
+//away by the source. Nothing is deleted if none is fetched, in case the report is temporarily empty.
+func saveLockup(since string, lks []*model.Lockup) (e error) {
+	if len(lks) == 0 {
+		return nil
+	}
+	tran, e := dbmap.Begin()
+	if e != nil {
+		return errors.WithStack(e)
+	}
+	if _, e = tran.Exec("delete from lockup where date >= ?", since); e != nil {
+		tran.Rollback()
+		return errors.Wrap(e, "failed to delete refreshed lock-up expiries")
+	}
+	d, t := util.TimeStr()
+	batch := 500
+	for i := 0; i < len(lks); i += batch {
+		end := i + batch
+		if end > len(lks) {
+			end = len(lks)
+		}
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]interface{}, 0, (end-i)*10)
+		for _, l := range lks[i:end] {
+			valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?,?,?)")
+			valueArgs = append(valueArgs, l.Code, l.Date, l.ShareType, l.Shares, l.AbleShares, l.MarketCap,
+				l.FloatRatio, l.TotalRatio, d, t)
+		}
+		stmt := fmt.Sprintf("insert into lockup (code,date,share_type,shares,able_shares,market_cap,float_ratio,"+
+			"total_ratio,udate,utime) values %s on duplicate key update shares=values(shares),"+
+			"able_shares=values(able_shares),market_cap=values(market_cap),float_ratio=values(float_ratio),"+
+			"total_ratio=values(total_ratio),udate=values(udate),utime=values(utime)",
+			strings.Join(valueStrings, ","))
+		if _, e = tran.Exec(stmt, valueArgs...); e != nil {
+			tran.Rollback()
+			return errors.Wrap(e, "failed to save lock-up expiries")
+		}
+	}
+	return errors.WithStack(tran.Commit())
+}
+
+//Lockups loads the lock-up expiries of the stock in chronological order.
+func Lockups(code string) (lks []*model.Lockup) {
+	_, e := dbmap.Select(&lks, "select * from lockup where code = ? order by date, share_type", code)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("%s failed to load lock-up expiries: %+v", code, e)
+	}
+	return
+}
+
+//UpcomingLockups returns the total float ratio (%) of the lock-up expiries of each stock within the
+//trading days starting from the date, keeping only those above minRatio, e.g. the stocks with more than
+//5% of float unlocking within 30 trading days. All stocks are screened if none is specified.
+func UpcomingLockups(date string, days int, minRatio float64, codes ...string) (ratios map[string]float64) {
+	ratios = make(map[string]float64)
+	end := lockupWindowEnd(calendar.Default(), date, days)
+	if end == "" {
+		return
+	}
+	qry := "select code, sum(float_ratio) ratio from lockup where date >= ? and date <= ?"
+	if len(codes) > 0 {
+		qry += fmt.Sprintf(" and code in (%s)", util.Join(codes, ",", true))
+	}
+	var rs []*struct {
+		Code  string
+		Ratio float64
+	}
+	_, e := dbmap.Select(&rs, qry+" group by code having ratio > ?", date, end, minRatio)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("failed to query upcoming lock-up expiries: %+v", e)
+	}
+	for _, r := range rs {
+		ratios[r.Code] = r.Ratio
+	}
+	return
+}
+
+//lockupWindowEnd returns the last of the trading days starting from the date, which counts as the first
+//if it is a trading day.
+func lockupWindowEnd(cal *calendar.Calendar, date string, days int) string {
+	if days <= 0 {
+		return ""
+	}
+	end := date
+	if !cal.IsTradingDay(date) {
+		end = cal.NextTD(date)
+	}
+	for i := 1; i < days && end != ""; i++ {
+		end = cal.NextTD(end)
+	}
+	return end
+}
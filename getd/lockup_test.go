@@ -0,0 +1,60 @@
+package getd
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+
+	"github.com/carusyte/stock/calendar"
+	"github.com/carusyte/stock/model"
+)
+
+func TestParseLockup(t *testing.T) {
+	defer useCassettes(t)()
+	body, e := fetchEmReport("RPT_LIFT_STAGE", "FREE_DATE", `(FREE_DATE>='2020-10-19')`, 1)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	lks, pages, e := parseLockup(body)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if pages != 1 || len(lks) != 2 {
+		t.Fatalf("want 2 lock-up expiries of 1 page, got %d of %d", len(lks), pages)
+	}
+	l := lks[0]
+	if l.Code != "600000" || l.Date != "2020-10-19" || l.Shares != 1000000 || l.AbleShares.Float64 != 800000 ||
+		math.Abs(l.FloatRatio.Float64-5.12) > 1e-9 || l.TotalRatio.Valid {
+		t.Errorf("unexpected lock-up expiry: %+v", l)
+	}
+}
+
+func TestLockupRatios(t *testing.T) {
+	lks := []*model.Lockup{
+		{Code: "600000", Shares: 1000000, FloatRatio: sql.NullFloat64{Float64: 5.12, Valid: true}},
+		{Code: "600001", Shares: 500},
+	}
+	lockupRatios(lks, map[string]float64{"600000": 1e9, "600001": 1e4})
+	if math.Abs(lks[0].FloatRatio.Float64-5.12) > 1e-9 || lks[1].FloatRatio.Float64 != 5 {
+		t.Errorf("unexpected float ratios: %v %v", lks[0].FloatRatio, lks[1].FloatRatio)
+	}
+}
+
+func TestLockupWindowEnd(t *testing.T) {
+	tds := []string{"2020-09-28", "2020-09-29", "2020-09-30", "2020-10-09", "2020-10-12"}
+	cal := calendar.New(calendar.Derive("2020-09-28", "2020-10-12", tds))
+	for _, c := range []struct {
+		date string
+		days int
+		want string
+	}{
+		{"2020-09-28", 1, "2020-09-28"},
+		{"2020-09-29", 3, "2020-10-09"},
+		{"2020-10-01", 2, "2020-10-12"},
+		{"2020-09-28", 0, ""},
+	} {
+		if got := lockupWindowEnd(cal, c.date, c.days); got != c.want {
+			t.Errorf("%s %d: want %q, got %q", c.date, c.days, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package getd
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/global"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+//MarginLrCols are the columns of the margin trading log returns, which can be referenced in
+//sampler feature columns.
+var MarginLrCols = []string{
+	"fin_bal", "fin_buy", "fin_repay", "sec_bal", "sec_vol", "sec_sell", "sec_repay", "total_bal",
+}
+
+//emMargin is a row of the margin trading report of eastmoney.
+type emMargin struct {
+	Code     string   `json:"SCODE"`
+	Date     string   `json:"DATE"`
+	FinBal   *float64 `json:"RZYE"`
+	FinBuy   *float64 `json:"RZMRE"`
+	FinRepay *float64 `json:"RZCHE"`
+	SecBal   *float64 `json:"RQYE"`
+	SecVol   *float64 `json:"RQYL"`
+	SecSell  *float64 `json:"RQMCL"`
+	SecRepay *float64 `json:"RQCHL"`
+	TotalBal *float64 `json:"RZRQYE"`
+}
+
+//GetMargin fetches the daily margin trading data of the stocks since the latest ones in database,
+//and calculates their log returns. Returns the stocks successfully updated, including those
+//not eligible for margin trading.
+func GetMargin(stocks *model.Stocks) (rstks *model.Stocks) {
+	log.Println("getting margin trading data...")
+	var wg sync.WaitGroup
+	chstk := make(chan *model.Stock, global.JobCapacity)
+	chrstk := make(chan *model.Stock, global.JobCapacity)
+	rstks = new(model.Stocks)
+	wgr := collect(rstks, chrstk)
+	for i := 0; i < conf.Args.Concurrency; i++ {
+		wg.Add(1)
+		go getMargin(chstk, &wg, chrstk)
+	}
+	for _, s := range stocks.List {
+		chstk <- s
+	}
+	close(chstk)
+	wg.Wait()
+	close(chrstk)
+	wgr.Wait()
+	log.Printf("%d margin trading data updated", rstks.Size())
+	if stocks.Size() != rstks.Size() {
+		same, skp := stocks.Diff(rstks)
+		if !same {
+			log.Printf("Failed: %+v", skp)
+		}
+	}
+	return
+}
+
+func getMargin(chstk chan *model.Stock, wg *sync.WaitGroup, chrstk chan *model.Stock) {
+	defer wg.Done()
+	RETRIES := conf.Args.DataSource.KlineFailureRetry
+	for stock := range chstk {
+		wait := 1000
+		for rtCount := 0; rtCount <= RETRIES; rtCount++ {
+			ok, r := doGetMargin(stock.Code)
+			if ok {
+				chrstk <- stock
+				break
+			} else if r {
+				log.Printf("%s retrying %d...", stock.Code, rtCount+1)
+				time.Sleep(time.Millisecond * time.Duration(wait+rand.Intn(wait)))
+				continue
+			} else {
+				log.Printf("%s retried %d, giving up. restart the program to recover", stock.Code, rtCount+1)
+				break
+			}
+		}
+	}
+}
+
+func doGetMargin(code string) (ok, retry bool) {
+	latest, e := dbmap.SelectNullStr("select max(date) from margin where code = ?", code)
+	if e != nil {
+		log.Printf("%s failed to query latest margin trading data: %+v", code, e)
+		return false, true
+	}
+	filter := fmt.Sprintf(`(scode="%s")`, code)
+	var ms []*model.Margin
+	for page, pages := 1, 1; page <= pages; page++ {
+		body, e := fetchEmReport("RPTA_WEB_RZRQ_GGMX", "DATE", filter, page)
+		if e != nil {
+			log.Printf("%s failed to get margin trading data: %+v", code, e)
+			return false, true
+		}
+		var ps []*model.Margin
+		if ps, pages, e = parseMargin(body); e != nil {
+			log.Printf("%s %+v", code, e)
+			return false, true
+		}
+		for _, m := range ps {
+			if m.Date <= latest.String {
+				pages = 0
+				break
+			}
+			ms = append(ms, m)
+		}
+	}
+	if len(ms) == 0 {
+		return true, false
+	}
+	//the report is in descending order
+	for i, j := 0, len(ms)-1; i < j; i, j = i+1, j-1 {
+		ms[i], ms[j] = ms[j], ms[i]
+	}
+	var prev *model.Margin
+	if latest.Valid {
+		prev = new(model.Margin)
+		e = dbmap.SelectOne(prev, "select * from margin where code = ? and date = ?", code, latest.String)
+		if e != nil {
+			log.Printf("%s failed to query margin trading data of %s: %+v", code, latest.String, e)
+			return false, true
+		}
+	}
+	if e = saveMargin(ms, calMarginLr(prev, ms)); e != nil {
+		log.Printf("%s %+v", code, e)
+		return false, true
+	}
+	return true, false
+}
+
+func parseMargin(body []byte) (ms []*model.Margin, pages int, e error) {
+	var rows []*emMargin
+	if pages, e = parseEmReport(body, &rows); e != nil {
+		return nil, 0, e
+	}
+	for _, r := range rows {
+		date, e := emDate(r.Date)
+		if e != nil {
+			return nil, 0, errors.Wrapf(e, "invalid margin trading date of %s", r.Code)
+		}
+		ms = append(ms, &model.Margin{
+			Code:     r.Code,
+			Date:     date,
+			FinBal:   emNullFloat(r.FinBal),
+			FinBuy:   emNullFloat(r.FinBuy),
+			FinRepay: emNullFloat(r.FinRepay),
+			SecBal:   emNullFloat(r.SecBal),
+			SecVol:   emNullFloat(r.SecVol),
+			SecSell:  emNullFloat(r.SecSell),
+			SecRepay: emNullFloat(r.SecRepay),
+			TotalBal: emNullFloat(r.TotalBal),
+		})
+	}
+	return ms, pages, nil
+}
+
+//calMarginLr calculates the log returns of the margin trading data in chronological order, given the
+//previous record, which may be nil. The first log returns are 0 without previous record.
+func calMarginLr(prev *model.Margin, ms []*model.Margin) (lrs []*model.MarginLogRtn) {
+	bias := .01
+	lr := func(p, c sql.NullFloat64) sql.NullFloat64 {
+		if !c.Valid {
+			return sql.NullFloat64{}
+		}
+		if !p.Valid {
+			return sql.NullFloat64{Float64: 0, Valid: true}
+		}
+		return sql.NullFloat64{Float64: util.LogReturn(p.Float64, c.Float64, bias), Valid: true}
+	}
+	p := prev
+	if p == nil {
+		p = new(model.Margin)
+	}
+	for _, m := range ms {
+		lrs = append(lrs, &model.MarginLogRtn{
+			Code:     m.Code,
+			Date:     m.Date,
+			FinBal:   lr(p.FinBal, m.FinBal),
+			FinBuy:   lr(p.FinBuy, m.FinBuy),
+			FinRepay: lr(p.FinRepay, m.FinRepay),
+			SecBal:   lr(p.SecBal, m.SecBal),
+			SecVol:   lr(p.SecVol, m.SecVol),
+			SecSell:  lr(p.SecSell, m.SecSell),
+			SecRepay: lr(p.SecRepay, m.SecRepay),
+			TotalBal: lr(p.TotalBal, m.TotalBal),
+		})
+		p = m
+	}
+	return
+}
+
+//saveMargin saves the margin trading data along with the log returns in a transaction, so that the
+//log returns are not missed by the next incremental update.
+func saveMargin(ms []*model.Margin, lrs []*model.MarginLogRtn) (e error) {
+	d, t := util.TimeStr()
+	tran, e := dbmap.Begin()
+	if e != nil {
+		return errors.WithStack(e)
+	}
+	cols := "code,date," + strings.Join(MarginLrCols, ",") + ",udate,utime"
+	var ups []string
+	for _, c := range append(MarginLrCols, "udate", "utime") {
+		ups = append(ups, fmt.Sprintf("%[1]s=values(%[1]s)", c))
+	}
+	batch := 500
+	for i := 0; i < len(ms); i += batch {
+		end := i + batch
+		if end > len(ms) {
+			end = len(ms)
+		}
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]interface{}, 0, (end-i)*12)
+		lrArgs := make([]interface{}, 0, (end-i)*12)
+		for j := i; j < end; j++ {
+			m, l := ms[j], lrs[j]
+			valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?,?,?,?,?)")
+			valueArgs = append(valueArgs, m.Code, m.Date, m.FinBal, m.FinBuy, m.FinRepay, m.SecBal, m.SecVol,
+				m.SecSell, m.SecRepay, m.TotalBal, d, t)
+			lrArgs = append(lrArgs, l.Code, l.Date, l.FinBal, l.FinBuy, l.FinRepay, l.SecBal, l.SecVol,
+				l.SecSell, l.SecRepay, l.TotalBal, d, t)
+		}
+		for tab, args := range map[string][]interface{}{"margin": valueArgs, "margin_lr": lrArgs} {
+			stmt := fmt.Sprintf("insert into %s (%s) values %s on duplicate key update %s",
+				tab, cols, strings.Join(valueStrings, ","), strings.Join(ups, ","))
+			if _, e = tran.Exec(stmt, args...); e != nil {
+				tran.Rollback()
+				return errors.Wrapf(e, "failed to save %s", tab)
+			}
+		}
+	}
+	return errors.WithStack(tran.Commit())
+}
@@ -0,0 +1,58 @@
+package getd
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+
+	"github.com/carusyte/stock/model"
+)
+
+func TestParseMargin(t *testing.T) {
+	defer useCassettes(t)()
+	body, e := fetchEmReport("RPTA_WEB_RZRQ_GGMX", "DATE", `(scode="600000")`, 1)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	ms, pages, e := parseMargin(body)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if pages != 2 || len(ms) != 1 {
+		t.Fatalf("want 1 record of 2 pages, got %d of %d", len(ms), pages)
+	}
+	m := ms[0]
+	if m.Code != "600000" || m.Date != "2020-10-16" || m.FinBal.Float64 != 4521234567.5 ||
+		m.SecVol.Float64 != 123400 || m.SecRepay.Valid {
+		t.Errorf("unexpected margin trading data: %+v", m)
+	}
+	body, e = fetchEmReport("RPTA_WEB_RZRQ_GGMX", "DATE", `(scode="000002")`, 1)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if _, _, e = parseMargin(body); e == nil {
+		t.Error("want error for invalid date")
+	}
+}
+
+func TestCalMarginLr(t *testing.T) {
+	nf := func(f float64) sql.NullFloat64 { return sql.NullFloat64{Float64: f, Valid: true} }
+	ms := []*model.Margin{
+		{Code: "600000", Date: "2020-10-15", FinBal: nf(100), SecBal: nf(10)},
+		{Code: "600000", Date: "2020-10-16", FinBal: nf(110), SecBal: nf(10)},
+	}
+	lrs := calMarginLr(nil, ms)
+	if len(lrs) != 2 {
+		t.Fatalf("want 2 log returns, got %d", len(lrs))
+	}
+	if !lrs[0].FinBal.Valid || lrs[0].FinBal.Float64 != 0 || lrs[0].FinBuy.Valid {
+		t.Errorf("unexpected first log returns: %+v", lrs[0])
+	}
+	if math.Abs(lrs[1].FinBal.Float64-math.Log(1.1)) > 1e-9 || lrs[1].SecBal.Float64 != 0 {
+		t.Errorf("unexpected log returns: %+v", lrs[1])
+	}
+	lrs = calMarginLr(&model.Margin{FinBal: nf(50)}, ms[:1])
+	if math.Abs(lrs[0].FinBal.Float64-math.Log(2)) > 1e-9 || !lrs[0].SecBal.Valid || lrs[0].SecBal.Float64 != 0 {
+		t.Errorf("unexpected log returns from previous record: %+v", lrs[0])
+	}
+}
@@ -0,0 +1,317 @@
+package getd
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/global"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+//northMarkets maps the eastmoney mutual types of the northbound connects to the markets.
+var northMarkets = map[string]string{
+	//沪股通
+	"001": "SH",
+	//深股通
+	"003": "SZ",
+}
+
+//emNorthHolding is a row of the northbound holding report of eastmoney.
+type emNorthHolding struct {
+	Code       string   `json:"SECURITY_CODE"`
+	Date       string   `json:"TRADE_DATE"`
+	Shares     *float64 `json:"HOLD_SHARES"`
+	MarketCap  *float64 `json:"HOLD_MARKET_CAP"`
+	FreeRatio  *float64 `json:"FREE_SHARES_RATIO"`
+	TotalRatio *float64 `json:"TOTAL_SHARES_RATIO"`
+}
+
+//emNorthFlow is a row of the northbound deal history report of eastmoney.
+type emNorthFlow struct {
+	Date   string   `json:"TRADE_DATE"`
+	NetBuy *float64 `json:"NET_DEAL_AMT"`
+	Buy    *float64 `json:"BUY_AMT"`
+	Sell   *float64 `json:"SELL_AMT"`
+}
+
+//NorthQry northbound data query parameters
+type NorthQry struct {
+	//Holding queries the daily holdings of the stock.
+	Holding bool
+	//Flow queries the net flows to the exchange of the stock, or to both exchanges if code is not specified.
+	Flow bool
+}
+
+//GetNorthbound fetches the northbound holdings of the stocks since the latest ones in database,
+//as well as the market-level net flows. Returns the stocks successfully updated, including those
+//not eligible for the Stock Connect.
+func GetNorthbound(stocks *model.Stocks) (rstks *model.Stocks) {
+	log.Println("getting northbound holdings...")
+	var wg sync.WaitGroup
+	chstk := make(chan *model.Stock, global.JobCapacity)
+	chrstk := make(chan *model.Stock, global.JobCapacity)
+	rstks = new(model.Stocks)
+	wgr := collect(rstks, chrstk)
+	for i := 0; i < conf.Args.Concurrency; i++ {
+		wg.Add(1)
+		go getNorthHolding(chstk, &wg, chrstk)
+	}
+	for _, s := range stocks.List {
+		chstk <- s
+	}
+	close(chstk)
+	wg.Wait()
+	close(chrstk)
+	wgr.Wait()
+	log.Printf("%d northbound holdings updated", rstks.Size())
+	if stocks.Size() != rstks.Size() {
+		same, skp := stocks.Diff(rstks)
+		if !same {
+			log.Printf("Failed: %+v", skp)
+		}
+	}
+	if e := getNorthFlow(); e != nil {
+		log.Warnf("failed to get northbound flows: %+v", e)
+	}
+	return
+}
+
+func getNorthHolding(chstk chan *model.Stock, wg *sync.WaitGroup, chrstk chan *model.Stock) {
+	defer wg.Done()
+	RETRIES := conf.Args.DataSource.KlineFailureRetry
+	for stock := range chstk {
+		wait := 1000
+		for rtCount := 0; rtCount <= RETRIES; rtCount++ {
+			ok, r := doGetNorthHolding(stock.Code)
+			if ok {
+				chrstk <- stock
+				break
+			} else if r {
+				log.Printf("%s retrying %d...", stock.Code, rtCount+1)
+				time.Sleep(time.Millisecond * time.Duration(wait+rand.Intn(wait)))
+				continue
+			} else {
+				log.Printf("%s retried %d, giving up. restart the program to recover", stock.Code, rtCount+1)
+				break
+			}
+		}
+	}
+}
+
+func doGetNorthHolding(code string) (ok, retry bool) {
+	latest, e := dbmap.SelectNullStr("select max(date) from north_holding where code = ?", code)
+	if e != nil {
+		log.Printf("%s failed to query latest northbound holding: %+v", code, e)
+		return false, true
+	}
+	filter := fmt.Sprintf(`(SECURITY_CODE="%s")(INTERVAL_TYPE="1")`, code)
+	var hs []*model.NorthHolding
+	for page, pages := 1, 1; page <= pages; page++ {
+		body, e := fetchEmReport("RPT_MUTUAL_HOLDSTOCKNORTH_STA", "TRADE_DATE", filter, page)
+		if e != nil {
+			log.Printf("%s failed to get northbound holding: %+v", code, e)
+			return false, true
+		}
+		var ps []*model.NorthHolding
+		if ps, pages, e = parseNorthHolding(body); e != nil {
+			log.Printf("%s %+v", code, e)
+			return false, true
+		}
+		for _, h := range ps {
+			if h.Date <= latest.String {
+				pages = 0
+				break
+			}
+			hs = append(hs, h)
+		}
+	}
+	if e = saveNorthHolding(hs); e != nil {
+		log.Printf("%s %+v", code, e)
+		return false, true
+	}
+	return true, false
+}
+
+func parseNorthHolding(body []byte) (hs []*model.NorthHolding, pages int, e error) {
+	var rows []*emNorthHolding
+	if pages, e = parseEmReport(body, &rows); e != nil {
+		return nil, 0, e
+	}
+	for _, r := range rows {
+		date, e := emDate(r.Date)
+		if e != nil {
+			return nil, 0, errors.Wrapf(e, "invalid northbound holding date of %s", r.Code)
+		}
+		if r.Shares == nil {
+			continue
+		}
+		hs = append(hs, &model.NorthHolding{
+			Code:       r.Code,
+			Date:       date,
+			Shares:     *r.Shares,
+			MarketCap:  emNullFloat(r.MarketCap),
+			FreeRatio:  emNullFloat(r.FreeRatio),
+			TotalRatio: emNullFloat(r.TotalRatio),
+		})
+	}
+	return hs, pages, nil
+}
+
+func saveNorthHolding(hs []*model.NorthHolding) (e error) {
+	if len(hs) == 0 {
+		return nil
+	}
+	d, t := util.TimeStr()
+	batch := 500
+	for i := 0; i < len(hs); i += batch {
+		end := i + batch
+		if end > len(hs) {
+			end = len(hs)
+		}
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]interface{}, 0, (end-i)*8)
+		for _, h := range hs[i:end] {
+			valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?)")
+			valueArgs = append(valueArgs, h.Code, h.Date, h.Shares, h.MarketCap, h.FreeRatio, h.TotalRatio, d, t)
+		}
+		stmt := fmt.Sprintf("insert into north_holding (code,date,shares,market_cap,free_ratio,total_ratio,"+
+			"udate,utime) values %s on duplicate key update shares=values(shares),market_cap=values(market_cap),"+
+			"free_ratio=values(free_ratio),total_ratio=values(total_ratio),udate=values(udate),utime=values(utime)",
+			strings.Join(valueStrings, ","))
+		if _, e = dbmap.Exec(stmt, valueArgs...); e != nil {
+			return errors.Wrap(e, "failed to save northbound holding")
+		}
+	}
+	return nil
+}
+
+//getNorthFlow fetches the northbound net flows of each market since the latest ones in database.
+func getNorthFlow() (e error) {
+	var fs []*model.NorthFlow
+	for mt, mkt := range northMarkets {
+		latest, e := dbmap.SelectNullStr("select max(date) from north_flow where market = ?", mkt)
+		if e != nil {
+			return errors.WithStack(e)
+		}
+		filter := fmt.Sprintf(`(MUTUAL_TYPE="%s")`, mt)
+		for page, pages := 1, 1; page <= pages; page++ {
+			body, e := fetchEmReport("RPT_MUTUAL_DEAL_HISTORY", "TRADE_DATE", filter, page)
+			if e != nil {
+				return e
+			}
+			var ps []*model.NorthFlow
+			if ps, pages, e = parseNorthFlow(body, mkt); e != nil {
+				return e
+			}
+			for _, f := range ps {
+				if f.Date <= latest.String {
+					pages = 0
+					break
+				}
+				fs = append(fs, f)
+			}
+		}
+	}
+	if len(fs) == 0 {
+		return nil
+	}
+	d, t := util.TimeStr()
+	valueStrings := make([]string, 0, len(fs))
+	valueArgs := make([]interface{}, 0, len(fs)*7)
+	for _, f := range fs {
+		valueStrings = append(valueStrings, "(?,?,?,?,?,?,?)")
+		valueArgs = append(valueArgs, f.Date, f.Market, f.NetBuy, f.Buy, f.Sell, d, t)
+	}
+	stmt := fmt.Sprintf("insert into north_flow (date,market,net_buy,buy,sell,udate,utime) values %s "+
+		"on duplicate key update net_buy=values(net_buy),buy=values(buy),sell=values(sell),"+
+		"udate=values(udate),utime=values(utime)", strings.Join(valueStrings, ","))
+	if _, e = dbmap.Exec(stmt, valueArgs...); e != nil {
+		return errors.Wrap(e, "failed to save northbound flows")
+	}
+	log.Printf("%d northbound flows updated", len(fs))
+	return nil
+}
+
+func parseNorthFlow(body []byte, market string) (fs []*model.NorthFlow, pages int, e error) {
+	var rows []*emNorthFlow
+	if pages, e = parseEmReport(body, &rows); e != nil {
+		return nil, 0, e
+	}
+	for _, r := range rows {
+		date, e := emDate(r.Date)
+		if e != nil {
+			return nil, 0, errors.Wrapf(e, "invalid northbound flow date of %s", market)
+		}
+		fs = append(fs, &model.NorthFlow{
+			Date:   date,
+			Market: market,
+			NetBuy: emNullFloat(r.NetBuy),
+			Buy:    emNullFloat(r.Buy),
+			Sell:   emNullFloat(r.Sell),
+		})
+	}
+	return fs, pages, nil
+}
+
+//GetNorthDB queries the northbound data of the stock from database. If limit is positive, only the latest
+//records are returned. Records are in chronological order unless desc is set. Flows are limited to the
+//dates of the holdings if both are queried.
+func GetNorthDB(code string, qry NorthQry, limit int, desc bool) (nd *model.NorthData) {
+	if !qry.Holding && !qry.Flow {
+		log.Panicf("Invalid query parameters. Please specify at least one data set to query. Params: %+v", qry)
+	}
+	nd = &model.NorthData{Code: code}
+	order := func(q string) string {
+		if limit > 0 {
+			q = fmt.Sprintf("select * from (%s order by date desc limit %d) t", q, limit)
+		}
+		if desc {
+			return q + " order by date desc"
+		}
+		return q + " order by date"
+	}
+	if qry.Holding {
+		_, e := dbmap.Select(&nd.Holding, order("select * from north_holding where code = ?"), code)
+		if e != nil && "sql: no rows in result set" != e.Error() {
+			log.Panicf("%s failed to query northbound holdings: %+v", code, e)
+		}
+	}
+	if qry.Flow {
+		q := "select * from north_flow where 1=1"
+		var args []interface{}
+		if code != "" {
+			q += " and market = ?"
+			args = append(args, northMarket(code))
+		}
+		if qry.Holding {
+			if len(nd.Holding) == 0 {
+				return
+			}
+			f, l := nd.Holding[0].Date, nd.Holding[len(nd.Holding)-1].Date
+			if f > l {
+				f, l = l, f
+			}
+			q += " and date between ? and ?"
+			args = append(args, f, l)
+		}
+		_, e := dbmap.Select(&nd.Flow, order(q), args...)
+		if e != nil && "sql: no rows in result set" != e.Error() {
+			log.Panicf("%s failed to query northbound flows: %+v", code, e)
+		}
+	}
+	return
+}
+
+//northMarket returns the market of the northbound connect to the exchange of the stock.
+func northMarket(code string) string {
+	if strings.HasPrefix(code, "6") {
+		return "SH"
+	}
+	return "SZ"
+}
@@ -0,0 +1,57 @@
+package getd
+
+import "testing"
+
+func TestParseNorthHolding(t *testing.T) {
+	defer useCassettes(t)()
+	body, e := fetchEmReport("RPT_MUTUAL_HOLDSTOCKNORTH_STA", "TRADE_DATE",
+		`(SECURITY_CODE="600000")(INTERVAL_TYPE="1")`, 1)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	hs, pages, e := parseNorthHolding(body)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if pages != 3 || len(hs) != 1 {
+		t.Fatalf("want 1 holding of 3 pages, got %d of %d", len(hs), pages)
+	}
+	h := hs[0]
+	if h.Date != "2020-10-16" || h.Shares != 401234567 || h.FreeRatio.Float64 != 1.37 || h.TotalRatio.Valid {
+		t.Errorf("unexpected holding: %+v", h)
+	}
+	body, e = fetchEmReport("RPT_MUTUAL_HOLDSTOCKNORTH_STA", "TRADE_DATE",
+		`(SECURITY_CODE="688981")(INTERVAL_TYPE="1")`, 1)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if hs, pages, e = parseNorthHolding(body); e != nil || pages != 0 || len(hs) != 0 {
+		t.Errorf("want no holding, got %d of %d pages: %+v", len(hs), pages, e)
+	}
+}
+
+func TestParseNorthFlow(t *testing.T) {
+	defer useCassettes(t)()
+	body, e := fetchEmReport("RPT_MUTUAL_DEAL_HISTORY", "TRADE_DATE", `(MUTUAL_TYPE="003")`, 1)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	fs, _, e := parseNorthFlow(body, "SZ")
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if len(fs) != 1 || fs[0].Market != "SZ" || fs[0].Date != "2020-10-16" || fs[0].NetBuy.Float64 != -1523.45 {
+		t.Errorf("unexpected flows: %+v", fs)
+	}
+	if _, _, e = parseNorthFlow([]byte(`{"result":{"pages":1,"data":[{"TRADE_DATE":""}]}}`), "SH"); e == nil {
+		t.Error("want error for invalid date")
+	}
+}
+
+func TestNorthMarket(t *testing.T) {
+	for code, want := range map[string]string{"600000": "SH", "688001": "SH", "000001": "SZ", "300750": "SZ"} {
+		if got := northMarket(code); got != want {
+			t.Errorf("northMarket(%s): want %s, got %s", code, want, got)
+		}
+	}
+}
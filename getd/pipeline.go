@@ -0,0 +1,380 @@
+package getd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+//ckptAll is the checkpoint code of the stages processing all data at once.
+const ckptAll = "*"
+
+//Stage is a step of the data pipeline.
+type Stage struct {
+	Name string
+	//Deps are the names of the stages this stage depends on. The stocks passing all the
+	//dependencies are processed, or any of them if Union is set.
+	Deps  []string
+	Union bool
+	//Auto stages are selected along with any of their dependencies.
+	Auto bool
+	//Global stages process all data at once, thus are checkpointed as a whole.
+	Global bool
+	//Skip reports whether the stage is disabled by configuration.
+	Skip func() bool
+	//Source provides the stocks for the stage without dependencies.
+	Source func() *model.Stocks
+	//Run processes the stocks and returns the ones successfully processed.
+	Run func(stks *model.Stocks) *model.Stocks
+}
+
+//Pipeline runs the stages in dependency order. A stage not selected or skipped passes its input
+//through to its dependents as if all stocks succeeded.
+type Pipeline struct {
+	stages []*Stage
+	smap   map[string]*Stage
+}
+
+//NewPipeline validates the stages and sorts them in topological order.
+func NewPipeline(stages ...*Stage) (p *Pipeline, e error) {
+	p = &Pipeline{smap: make(map[string]*Stage)}
+	for _, s := range stages {
+		if _, ok := p.smap[s.Name]; ok {
+			return nil, errors.Errorf("duplicate stage: %s", s.Name)
+		}
+		p.smap[s.Name] = s
+	}
+	//0: unvisited, 1: visiting, 2: visited
+	state := make(map[string]int)
+	var visit func(s *Stage, path []string) error
+	visit = func(s *Stage, path []string) error {
+		switch state[s.Name] {
+		case 1:
+			return errors.Errorf("cyclic stage dependency: %s", strings.Join(append(path, s.Name), " -> "))
+		case 2:
+			return nil
+		}
+		state[s.Name] = 1
+		for _, d := range s.Deps {
+			ds, ok := p.smap[d]
+			if !ok {
+				return errors.Errorf("stage %s depends on unknown stage: %s", s.Name, d)
+			}
+			if e := visit(ds, append(path, s.Name)); e != nil {
+				return e
+			}
+		}
+		state[s.Name] = 2
+		p.stages = append(p.stages, s)
+		return nil
+	}
+	for _, s := range stages {
+		if e = visit(s, nil); e != nil {
+			return nil, e
+		}
+	}
+	return
+}
+
+//Stages returns the names of the stages in execution order.
+func (p *Pipeline) Stages() (names []string) {
+	for _, s := range p.stages {
+		names = append(names, s.Name)
+	}
+	return
+}
+
+//Select returns the set of the stages to run. All stages are selected if only is empty.
+func (p *Pipeline) Select(only ...string) (sel map[string]bool, e error) {
+	sel = make(map[string]bool)
+	for _, n := range only {
+		n = strings.TrimSpace(n)
+		if _, ok := p.smap[n]; !ok {
+			return nil, errors.Errorf("unknown stage: %s, available stages: %s", n, strings.Join(p.Stages(), ","))
+		}
+		sel[n] = true
+	}
+	for _, s := range p.stages {
+		if len(only) == 0 {
+			sel[s.Name] = true
+			continue
+		}
+		if !s.Auto {
+			continue
+		}
+		for _, d := range s.Deps {
+			if sel[d] {
+				sel[s.Name] = true
+				break
+			}
+		}
+	}
+	return
+}
+
+//Run runs the selected stages in dependency order. If resume is set, the stocks checkpointed
+//by the previous run of a stage on the same day are not processed again. Returns the output of
+//each stage.
+func (p *Pipeline) Run(sel map[string]bool, resume bool) (outs map[string]*model.Stocks) {
+	outs = make(map[string]*model.Stocks)
+	for _, s := range p.stages {
+		in := p.input(s, outs)
+		if !sel[s.Name] {
+			outs[s.Name] = in
+			continue
+		}
+		if s.Skip != nil && s.Skip() {
+			log.Printf("stage %s skipped by configuration", s.Name)
+			outs[s.Name] = in
+			continue
+		}
+		outs[s.Name] = p.runStage(s, in, resume)
+	}
+	return
+}
+
+func (p *Pipeline) input(s *Stage, outs map[string]*model.Stocks) *model.Stocks {
+	if len(s.Deps) == 0 {
+		if s.Source == nil {
+			return new(model.Stocks)
+		}
+		return s.Source()
+	}
+	var ins []*model.Stocks
+	for _, d := range s.Deps {
+		ins = append(ins, outs[d])
+	}
+	if s.Union {
+		return unionStocks(ins...)
+	}
+	return intersectStocks(ins...)
+}
+
+func (p *Pipeline) runStage(s *Stage, in *model.Stocks, resume bool) (out *model.Stocks) {
+	start := time.Now()
+	defer StopWatch("STAGE_"+strings.ToUpper(s.Name), start)
+	if !resume {
+		clearCheckpoints(s.Name)
+	}
+	done := make(map[string]bool)
+	if resume {
+		done = loadCheckpoints(s.Name)
+	}
+	if s.Global {
+		if done[ckptAll] {
+			log.Printf("stage %s already completed, skipped", s.Name)
+			return in
+		}
+		log.Printf("running stage %s", s.Name)
+		out = s.Run(in)
+		saveCheckpoints(s.Name, []string{ckptAll})
+		return
+	}
+	pending, finished := new(model.Stocks), new(model.Stocks)
+	for _, stk := range in.List {
+		if done[stockKey(stk)] {
+			finished.Add(stk)
+		} else {
+			pending.Add(stk)
+		}
+	}
+	if finished.Size() > 0 {
+		log.Printf("stage %s: %d stocks already completed", s.Name, finished.Size())
+	}
+	if pending.Size() == 0 {
+		return finished
+	}
+	log.Printf("running stage %s for %d stocks", s.Name, pending.Size())
+	//stocks are checkpointed as they are collected, so that the progress survives a crash
+	stop := onCollected(func(stk *model.Stock) {
+		saveCheckpoints(s.Name, []string{stockKey(stk)})
+	})
+	rstks := s.Run(pending)
+	stop()
+	//the stocks collected by an intermediate step may still fail the stage
+	var keys, failed []string
+	succ := make(map[string]bool, rstks.Size())
+	for _, stk := range rstks.List {
+		succ[stockKey(stk)] = true
+		keys = append(keys, stockKey(stk))
+	}
+	for _, stk := range pending.List {
+		if k := stockKey(stk); !succ[k] {
+			failed = append(failed, k)
+		}
+	}
+	saveCheckpoints(s.Name, keys)
+	deleteCheckpoints(s.Name, failed)
+	log.Printf("stage %s:", s.Name)
+	rptFailed(pending, rstks)
+	return unionStocks(finished, rstks)
+}
+
+//stockKey distinguishes indices from stocks of the same code.
+func stockKey(stk *model.Stock) string {
+	if stk.Source == "" {
+		return stk.Code
+	}
+	return stk.Source + ":" + stk.Code
+}
+
+//unionStocks returns the stocks in any of the lists, in order of first appearance.
+func unionStocks(lists ...*model.Stocks) (r *model.Stocks) {
+	r = new(model.Stocks)
+	seen := make(map[string]bool)
+	for _, l := range lists {
+		for _, stk := range l.List {
+			if k := stockKey(stk); !seen[k] {
+				seen[k] = true
+				r.Add(stk)
+			}
+		}
+	}
+	return
+}
+
+//intersectStocks returns the stocks in all of the lists, in order of the first list.
+func intersectStocks(lists ...*model.Stocks) (r *model.Stocks) {
+	r = new(model.Stocks)
+	if len(lists) == 0 {
+		return
+	}
+	cnt := make(map[string]int)
+	for _, l := range lists[1:] {
+		seen := make(map[string]bool)
+		for _, stk := range l.List {
+			if k := stockKey(stk); !seen[k] {
+				seen[k] = true
+				cnt[k]++
+			}
+		}
+	}
+	for _, stk := range lists[0].List {
+		if cnt[stockKey(stk)] == len(lists)-1 {
+			r.Add(stk)
+		}
+	}
+	return
+}
+
+var (
+	collectMu   sync.Mutex
+	collectHook func(stk *model.Stock)
+)
+
+//onCollected has the hook called for each stock collected as processed, until the returned
+//function is called.
+func onCollected(hook func(stk *model.Stock)) (stop func()) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	collectHook = hook
+	return func() {
+		collectMu.Lock()
+		defer collectMu.Unlock()
+		collectHook = nil
+	}
+}
+
+func collected(stk *model.Stock) {
+	collectMu.Lock()
+	hook := collectHook
+	collectMu.Unlock()
+	if hook != nil {
+		hook(stk)
+	}
+}
+
+//loadCheckpoints returns the set of the stock keys checkpointed for the stage today. Checkpoints
+//of the previous days are stale, since the data to fetch have changed since then.
+func loadCheckpoints(stage string) (done map[string]bool) {
+	done = make(map[string]bool)
+	var rows []*model.PipelineCkpt
+	d, _ := util.TimeStr()
+	_, e := dbmap.Select(&rows, "select * from pipeline_ckpt where stage = ? and udate = ?", stage, d)
+	if e != nil {
+		if "sql: no rows in result set" != e.Error() {
+			log.Panicf("failed to load checkpoints of stage %s: %+v", stage, e)
+		}
+		return
+	}
+	for _, r := range rows {
+		if r.Src == "" {
+			done[r.Code] = true
+		} else {
+			done[r.Src+":"+r.Code] = true
+		}
+	}
+	return
+}
+
+//clearCheckpoints removes the checkpoints of the stage left by the previous run.
+func clearCheckpoints(stage string) {
+	_, e := dbmap.Exec("delete from pipeline_ckpt where stage = ?", stage)
+	if e != nil {
+		log.Panicf("failed to clear checkpoints of stage %s: %+v", stage, e)
+	}
+}
+
+//deleteCheckpoints removes the checkpoints of the stocks of the given keys for the stage.
+func deleteCheckpoints(stage string, keys []string) {
+	for _, k := range keys {
+		src, code := splitStockKey(k)
+		_, e := dbmap.Exec("delete from pipeline_ckpt where stage = ? and src = ? and code = ?", stage, src, code)
+		if e != nil {
+			log.Panicf("failed to delete checkpoint of %s for stage %s: %+v", k, stage, e)
+		}
+	}
+}
+
+//splitStockKey returns the source and code of the stock key.
+func splitStockKey(k string) (src, code string) {
+	if p := strings.LastIndex(k, ":"); p >= 0 {
+		return k[:p], k[p+1:]
+	}
+	return "", k
+}
+
+//saveCheckpoints marks the stocks of the given keys completed for the stage.
+func saveCheckpoints(stage string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	d, t := util.TimeStr()
+	batch := 500
+	for i := 0; i < len(keys); i += batch {
+		end := int(math.Min(float64(i+batch), float64(len(keys))))
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]interface{}, 0, (end-i)*5)
+		for _, k := range keys[i:end] {
+			src, code := splitStockKey(k)
+			valueStrings = append(valueStrings, "(?,?,?,?,?)")
+			valueArgs = append(valueArgs, stage, src, code, d, t)
+		}
+		saveCkptBatch(stage, strings.Join(valueStrings, ","), valueArgs)
+	}
+}
+
+func saveCkptBatch(stage, values string, args []interface{}) {
+	stmt := fmt.Sprintf("insert into pipeline_ckpt (stage,src,code,udate,utime) values %s "+
+		"on duplicate key update udate=values(udate),utime=values(utime)", values)
+	var e error
+	retry := conf.Args.DeadlockRetry
+	for rt := 0; rt < retry; rt++ {
+		_, e = dbmap.Exec(stmt, args...)
+		if e != nil {
+			if strings.Contains(e.Error(), "Deadlock") {
+				continue
+			}
+			log.Panicf("failed to save checkpoints of stage %s: %+v", stage, e)
+		}
+		return
+	}
+	log.Panicf("failed to save checkpoints of stage %s: %+v", stage, e)
+}
@@ -0,0 +1,146 @@
+package getd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/carusyte/stock/model"
+)
+
+func testStocks(keys ...string) *model.Stocks {
+	stks := new(model.Stocks)
+	for _, k := range keys {
+		stk := &model.Stock{Code: k}
+		if p := strings.Index(k, ":"); p >= 0 {
+			stk.Source, stk.Code = k[:p], k[p+1:]
+		}
+		stks.Add(stk)
+	}
+	return stks
+}
+
+func stockKeys(stks *model.Stocks) (keys []string) {
+	for _, stk := range stks.List {
+		keys = append(keys, stockKey(stk))
+	}
+	return
+}
+
+func TestNewPipeline(t *testing.T) {
+	p, e := NewPipeline(
+		&Stage{Name: "c", Deps: []string{"b", "a"}},
+		&Stage{Name: "a"},
+		&Stage{Name: "b", Deps: []string{"a"}},
+	)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if got := p.Stages(); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("unexpected order: %v", got)
+	}
+	if _, e = NewPipeline(&Stage{Name: "a", Deps: []string{"b"}}, &Stage{Name: "b", Deps: []string{"a"}}); e == nil {
+		t.Error("want error for cyclic dependency")
+	}
+	if _, e = NewPipeline(&Stage{Name: "a", Deps: []string{"x"}}); e == nil {
+		t.Error("want error for unknown dependency")
+	}
+
+	names := StageNames()
+	pos := make(map[string]int)
+	for i, n := range names {
+		pos[n] = i
+	}
+	for _, s := range dataStages() {
+		for _, d := range s.Deps {
+			if pos[d] > pos[s.Name] {
+				t.Errorf("stage %s runs before its dependency %s", s.Name, d)
+			}
+		}
+	}
+}
+
+func TestPipelineSelect(t *testing.T) {
+	p, e := NewPipeline(dataStages()...)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	sel, e := p.Select("klines", " indicators")
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	want := map[string]bool{StageKlines: true, StageKlinePost: true, StageIndicators: true}
+	if !reflect.DeepEqual(sel, want) {
+		t.Errorf("unexpected selection: %v", sel)
+	}
+	if sel, _ = p.Select(); len(sel) != len(p.Stages()) {
+		t.Errorf("want all stages selected, got %v", sel)
+	}
+	if _, e = p.Select("kline"); e == nil {
+		t.Error("want error for unknown stage")
+	}
+}
+
+func TestPipelinePassThrough(t *testing.T) {
+	ran := false
+	p, e := NewPipeline(
+		&Stage{Name: "stocks", Source: func() *model.Stocks { return testStocks("1", "2", "3") }},
+		&Stage{Name: "idx", Source: func() *model.Stocks { return testStocks("sh:1") }},
+		&Stage{Name: "a", Deps: []string{"stocks"}},
+		&Stage{Name: "b", Deps: []string{"a", "stocks"}, Skip: func() bool { return true },
+			Run: func(*model.Stocks) *model.Stocks { ran = true; return nil }},
+		&Stage{Name: "c", Deps: []string{"b", "idx"}, Union: true},
+	)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	outs := p.Run(map[string]bool{"b": true}, false)
+	if ran {
+		t.Error("stage skipped by configuration should not run")
+	}
+	if got := stockKeys(outs["c"]); !reflect.DeepEqual(got, []string{"1", "2", "3", "sh:1"}) {
+		t.Errorf("unexpected output: %v", got)
+	}
+}
+
+func TestCombineStocks(t *testing.T) {
+	a, b := testStocks("1", "2", "sh:1", "3"), testStocks("3", "1", "sh:1", "4")
+	if got := stockKeys(intersectStocks(a, b, testStocks("1", "3", "sh:1"))); !reflect.DeepEqual(got, []string{"1", "sh:1", "3"}) {
+		t.Errorf("unexpected intersection: %v", got)
+	}
+	if got := stockKeys(unionStocks(a, b)); !reflect.DeepEqual(got, []string{"1", "2", "sh:1", "3", "4"}) {
+		t.Errorf("unexpected union: %v", got)
+	}
+}
+
+func TestOnCollected(t *testing.T) {
+	var got []string
+	stop := onCollected(func(stk *model.Stock) { got = append(got, stockKey(stk)) })
+	ch := make(chan *model.Stock)
+	rstks := new(model.Stocks)
+	wgr := collect(rstks, ch)
+	for _, stk := range testStocks("1", "sh:1").List {
+		ch <- stk
+	}
+	close(ch)
+	wgr.Wait()
+	stop()
+	if !reflect.DeepEqual(got, []string{"1", "sh:1"}) {
+		t.Errorf("unexpected stocks collected by the hook: %v", got)
+	}
+	ch = make(chan *model.Stock, 1)
+	ch <- &model.Stock{Code: "2"}
+	close(ch)
+	collect(rstks, ch).Wait()
+	if len(got) != 2 || rstks.Size() != 3 {
+		t.Errorf("hook called after stopped: %v, %d", got, rstks.Size())
+	}
+}
+
+func TestSplitStockKey(t *testing.T) {
+	for k, want := range map[string][2]string{"600000": {"", "600000"}, "sh:000001": {"sh", "000001"}} {
+		if src, code := splitStockKey(k); src != want[0] || code != want[1] {
+			t.Errorf("%s: want %v, got %s, %s", k, want, src, code)
+		}
+	}
+}
@@ -0,0 +1,427 @@
+package getd
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/carusyte/stock/calendar"
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+//kinds of kline discrepancy
+const (
+	//DiscMissing means the day is missing in master klines
+	DiscMissing = "missing"
+	//DiscExtra means the day is missing in validate klines
+	DiscExtra = "extra"
+	//DiscSplit means all prices differ by the same ratio, usually caused by mis-adjustment of
+	//share split or reinstatement
+	DiscSplit = "split"
+	//DiscPrice means prices differ inconsistently
+	DiscPrice = "price"
+	//DiscVolumeScale means volume or amount differ by a power of 10, usually caused by different units
+	DiscVolumeScale = "volume_scale"
+	//DiscVolume means volume or amount differ otherwise
+	DiscVolume = "volume"
+	//DiscXrate means exchange rates differ
+	DiscXrate = "xrate"
+	//DiscGap means the trading day is missing in both master and validate klines
+	DiscGap = "gap"
+)
+
+//ValidatedKlineFetcher copies the klines of the validate source in database to the requested tables,
+//which is used to patch the master klines.
+type ValidatedKlineFetcher struct{}
+
+//fetchKline reads the validate klines for the given stock.
+func (f *ValidatedKlineFetcher) fetchKline(stk *model.Stock, fr FetchRequest, incr bool) (
+	tdmap map[FetchRequest]*model.TradeData, lkmap map[FetchRequest]int, suc, retry bool) {
+
+	tdmap = make(map[FetchRequest]*model.TradeData)
+	lkmap = make(map[FetchRequest]int)
+	lkmap[fr] = -1
+	code := stk.Code
+	vsrc := model.DataSource(conf.Args.DataSource.Validate.Source)
+	data := GetTrDataDB(code, TrDataQry{LocalSource: vsrc, Cycle: fr.Cycle, Reinstate: fr.Reinstate, Basic: true},
+		0, false).Base
+	if len(data) == 0 {
+		log.Warnf("%s no %v %v kline found in %s", code, fr.Cycle, fr.Reinstate, vsrc)
+		return tdmap, lkmap, false, false
+	}
+	if incr {
+		data, lkmap[fr] = sliceFromLatest(code, fr, data)
+	}
+	tdmap[fr] = &model.TradeData{
+		Code:          code,
+		Source:        fr.LocalSource,
+		Cycle:         fr.Cycle,
+		Reinstatement: fr.Reinstate,
+		Base:          data,
+	}
+	return tdmap, lkmap, true, false
+}
+
+//ReconcileKlines compares the latest master klines of the stocks with those of the validate source
+//field by field, and saves the discrepancies found, replacing the previous ones in the compared range.
+//Returns the discrepancies found.
+func ReconcileKlines(stks *model.Stocks, cycles []model.CYTP, rtypes []model.Rtype, bars int) (
+	discs []*model.KlineDiscrepancy) {
+	start := time.Now()
+	defer StopWatch("RECONCILE_KLINES", start)
+	vsrc := model.DataSource(conf.Args.DataSource.Validate.Source)
+	ptol := conf.Args.DataSource.Reconcile.PriceTolerance
+	vtol := conf.Args.DataSource.Reconcile.VolumeTolerance
+	log.Printf("reconciling klines of %d stocks against %s", stks.Size(), vsrc)
+	d, t := util.TimeStr()
+	for i, stk := range stks.List {
+		hist := LoadStatusHist(stk.Code)
+		for _, c := range cycles {
+			for _, r := range rtypes {
+				qry := TrDataQry{LocalSource: model.KlineMaster, Cycle: c, Reinstate: r, Basic: true}
+				master := GetTrDataDB(stk.Code, qry, bars, false).Base
+				qry.LocalSource = vsrc
+				vld := GetTrDataDB(stk.Code, qry, bars, false).Base
+				if len(master) == 0 || len(vld) == 0 {
+					log.Debugf("%s no %v %v kline to reconcile", stk.Code, c, r)
+					continue
+				}
+				from := master[0].Date
+				if vld[0].Date > from {
+					from = vld[0].Date
+				}
+				var tds []string
+				if c == model.DAY {
+					to := master[len(master)-1].Date
+					if vld[len(vld)-1].Date > to {
+						to = vld[len(vld)-1].Date
+					}
+					//suspended days are not gaps
+					tds = hist.Without(stk.Code, calendar.TDBetween(from, to), StatusSuspended)
+				}
+				ds := reconcileBars(master, vld, tds, ptol, vtol)
+				for _, x := range ds {
+					x.Code, x.Cycle, x.Rtype, x.Src = stk.Code, string(c), string(r), string(vsrc)
+					x.Udate = sql.NullString{String: d, Valid: true}
+					x.Utime = sql.NullString{String: t, Valid: true}
+				}
+				if e := saveDiscrepancies(stk.Code, c, r, from, ds); e != nil {
+					log.Panicf("%s failed to save %v %v kline discrepancies: %+v", stk.Code, c, r, e)
+				}
+				discs = append(discs, ds...)
+			}
+		}
+		log.Printf("%s reconciled. Progress: [%.2f%%]", stk.Code, float64(i+1)/float64(stks.Size())*100.)
+	}
+	log.Printf("%d kline discrepancies found", len(discs))
+	return
+}
+
+//reconcileBars classifies the differences between the chronological master and validate bars,
+//from the later of their first dates. Trading days in tds missing in both are reported as gaps.
+func reconcileBars(master, vld []*model.TradeDataBasic, tds []string, ptol, vtol float64) (
+	discs []*model.KlineDiscrepancy) {
+	if len(master) == 0 || len(vld) == 0 {
+		return
+	}
+	from := master[0].Date
+	if vld[0].Date > from {
+		from = vld[0].Date
+	}
+	mmap := make(map[string]*model.TradeDataBasic, len(master))
+	vmap := make(map[string]*model.TradeDataBasic, len(vld))
+	var dates []string
+	for _, m := range master {
+		if m.Date >= from {
+			mmap[m.Date] = m
+			dates = append(dates, m.Date)
+		}
+	}
+	for _, v := range vld {
+		if v.Date >= from {
+			if _, ok := mmap[v.Date]; !ok {
+				dates = append(dates, v.Date)
+			}
+			vmap[v.Date] = v
+		}
+	}
+	for _, td := range tds {
+		if _, ok := mmap[td]; ok || td < from {
+			continue
+		}
+		if _, ok := vmap[td]; !ok {
+			dates = append(dates, td)
+		}
+	}
+	sort.Strings(dates)
+	for _, date := range dates {
+		m, v := mmap[date], vmap[date]
+		switch {
+		case m == nil && v == nil:
+			discs = append(discs, &model.KlineDiscrepancy{Date: date, Kind: DiscGap})
+		case m == nil:
+			discs = append(discs, &model.KlineDiscrepancy{Date: date, Kind: DiscMissing})
+		case v == nil:
+			discs = append(discs, &model.KlineDiscrepancy{Date: date, Kind: DiscExtra})
+		default:
+			discs = append(discs, compareBar(m, v, ptol, vtol)...)
+		}
+	}
+	return
+}
+
+//compareBar classifies the differences between the master and validate bar of the same date.
+func compareBar(m, v *model.TradeDataBasic, ptol, vtol float64) (discs []*model.KlineDiscrepancy) {
+	disc := func(kind string, fields []string, ratio, dev float64) *model.KlineDiscrepancy {
+		x := &model.KlineDiscrepancy{
+			Date:   m.Date,
+			Kind:   kind,
+			Fields: strings.Join(fields, ","),
+			Dev:    sql.NullFloat64{Float64: dev, Valid: true},
+		}
+		if !math.IsNaN(ratio) {
+			x.Ratio = sql.NullFloat64{Float64: ratio, Valid: true}
+		}
+		return x
+	}
+
+	var pfields []string
+	pdev, rmin, rmax := 0., math.Inf(1), math.Inf(-1)
+	for _, p := range []struct {
+		name string
+		m, v float64
+	}{
+		{"open", m.Open, v.Open}, {"high", m.High, v.High}, {"low", m.Low, v.Low}, {"close", m.Close, v.Close},
+	} {
+		if d := relDev(p.m, p.v); d > ptol {
+			pfields = append(pfields, p.name)
+			pdev = math.Max(pdev, d)
+		}
+		if p.m != 0 {
+			r := p.v / p.m
+			rmin, rmax = math.Min(rmin, r), math.Max(rmax, r)
+		}
+	}
+	if len(pfields) > 0 {
+		//all prices scaled by the same ratio
+		if len(pfields) == 4 && rmin > 0 && (rmax-rmin)/rmin <= ptol {
+			discs = append(discs, disc(DiscSplit, pfields, (rmin+rmax)/2, pdev))
+		} else {
+			discs = append(discs, disc(DiscPrice, pfields, math.NaN(), pdev))
+		}
+	}
+
+	var sfields, vfields []string
+	sratio, sdev, vdev := math.NaN(), 0., 0.
+	for _, q := range []struct {
+		name string
+		m, v sql.NullFloat64
+	}{
+		{"volume", m.Volume, v.Volume}, {"amount", m.Amount, v.Amount},
+	} {
+		if !q.m.Valid || !q.v.Valid {
+			continue
+		}
+		d := relDev(q.m.Float64, q.v.Float64)
+		if d <= vtol {
+			continue
+		}
+		if r, ok := powerOf10Ratio(q.m.Float64, q.v.Float64, vtol); ok {
+			sfields = append(sfields, q.name)
+			sdev = math.Max(sdev, d)
+			if math.IsNaN(sratio) {
+				sratio = r
+			}
+			continue
+		}
+		vfields = append(vfields, q.name)
+		vdev = math.Max(vdev, d)
+	}
+	if len(sfields) > 0 {
+		discs = append(discs, disc(DiscVolumeScale, sfields, sratio, sdev))
+	}
+	if len(vfields) > 0 {
+		discs = append(discs, disc(DiscVolume, vfields, math.NaN(), vdev))
+	}
+
+	if m.Xrate.Valid && v.Xrate.Valid {
+		if d := relDev(m.Xrate.Float64, v.Xrate.Float64); d > vtol {
+			discs = append(discs, disc(DiscXrate, []string{"xrate"}, math.NaN(), d))
+		}
+	}
+	return
+}
+
+//relDev returns the deviation of v relative to m.
+func relDev(m, v float64) float64 {
+	if m == v {
+		return 0
+	}
+	if m == 0 {
+		return 1
+	}
+	return math.Abs(v-m) / math.Abs(m)
+}
+
+//powerOf10Ratio checks whether v/m is a power of 10 other than 1 within the tolerance.
+func powerOf10Ratio(m, v, tol float64) (ratio float64, ok bool) {
+	if m <= 0 || v <= 0 {
+		return math.NaN(), false
+	}
+	r := v / m
+	k := math.Round(math.Log10(r))
+	if k == 0 {
+		return r, false
+	}
+	return r, math.Abs(r/math.Pow(10, k)-1) <= tol
+}
+
+//saveDiscrepancies replaces the discrepancies of the stock from the given date.
+func saveDiscrepancies(code string, cycle model.CYTP, rtype model.Rtype, from string,
+	discs []*model.KlineDiscrepancy) (e error) {
+	tran, e := dbmap.Begin()
+	if e != nil {
+		return errors.WithStack(e)
+	}
+	_, e = tran.Exec("delete from kline_discrepancy where code = ? and cycle = ? and rtype = ? and date >= ?",
+		code, string(cycle), string(rtype), from)
+	if e != nil {
+		tran.Rollback()
+		return errors.WithStack(e)
+	}
+	if len(discs) > 0 {
+		valueStrings := make([]string, 0, len(discs))
+		valueArgs := make([]interface{}, 0, len(discs)*11)
+		for _, x := range discs {
+			valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?,?,?,?)")
+			valueArgs = append(valueArgs, x.Code, x.Cycle, x.Rtype, x.Date, x.Kind, x.Fields, x.Src,
+				x.Ratio, x.Dev, x.Udate, x.Utime)
+		}
+		stmt := fmt.Sprintf("insert into kline_discrepancy (code,cycle,rtype,date,kind,fields,src,ratio,dev,"+
+			"udate,utime) values %s", strings.Join(valueStrings, ","))
+		if _, e = tran.Exec(stmt, valueArgs...); e != nil {
+			tran.Rollback()
+			return errors.WithStack(e)
+		}
+	}
+	return errors.WithStack(tran.Commit())
+}
+
+//LoadDiscrepancies loads the discrepancies of the given stocks, cycles and reinstatement types,
+//or all if not specified, in order of code, cycle, reinstatement type and date.
+func LoadDiscrepancies(codes []string, cycles []model.CYTP, rtypes []model.Rtype) (
+	discs []*model.KlineDiscrepancy) {
+	var conds []string
+	if len(codes) > 0 {
+		conds = append(conds, fmt.Sprintf("code in (%s)", util.Join(codes, ",", true)))
+	}
+	if len(cycles) > 0 {
+		var cs []string
+		for _, c := range cycles {
+			cs = append(cs, string(c))
+		}
+		conds = append(conds, fmt.Sprintf("cycle in (%s)", util.Join(cs, ",", true)))
+	}
+	if len(rtypes) > 0 {
+		var rs []string
+		for _, r := range rtypes {
+			rs = append(rs, string(r))
+		}
+		conds = append(conds, fmt.Sprintf("rtype in (%s)", util.Join(rs, ",", true)))
+	}
+	stmt := "select * from kline_discrepancy"
+	if len(conds) > 0 {
+		stmt += " where " + strings.Join(conds, " and ")
+	}
+	stmt += " order by code, cycle, rtype, date, kind"
+	_, e := dbmap.Select(&discs, stmt)
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("failed to load kline discrepancies: %+v", e)
+	}
+	return
+}
+
+//RepairDiscrepancies re-fetches the master klines from the earliest discrepancy of each stock,
+//cycle and reinstatement type. If patch is set, the klines are copied from the validate source
+//instead of downloaded from the master source. Returns the stocks successfully repaired.
+func RepairDiscrepancies(discs []*model.KlineDiscrepancy, patch bool) (rstks *model.Stocks) {
+	start := time.Now()
+	defer StopWatch("REPAIR_DISCREPANCIES", start)
+	earliest := make(map[FetchRequest]map[string]string)
+	for _, x := range discs {
+		fr := FetchRequest{
+			RemoteSource: repairSource(model.Rtype(x.Rtype), patch),
+			LocalSource:  model.KlineMaster,
+			Cycle:        model.CYTP(x.Cycle),
+			Reinstate:    model.Rtype(x.Rtype),
+		}
+		if earliest[fr] == nil {
+			earliest[fr] = make(map[string]string)
+		}
+		if d, ok := earliest[fr][x.Code]; !ok || x.Date < d {
+			earliest[fr][x.Code] = x.Date
+		}
+	}
+	var codes []string
+	seen := make(map[string]bool)
+	for _, x := range discs {
+		if !seen[x.Code] {
+			seen[x.Code] = true
+			codes = append(codes, x.Code)
+		}
+	}
+	if len(codes) == 0 {
+		return new(model.Stocks)
+	}
+	all := new(model.Stocks)
+	all.Add(StocksDbByCode(codes...)...)
+	failed := make(map[string]bool)
+	for fr, dmap := range earliest {
+		stks := new(model.Stocks)
+		for _, code := range codes {
+			date, ok := dmap[code]
+			stk, found := all.Map[code]
+			if !ok || !found {
+				continue
+			}
+			if _, e := deleteTradeDataFromDate(code, date, fr.Cycle, fr.Reinstate); e != nil {
+				log.Warnf("%s failed to delete %v %v klines from %s: %+v", code, fr.Cycle, fr.Reinstate, date, e)
+				failed[code] = true
+				continue
+			}
+			stks.Add(stk)
+		}
+		rs := GetKlinesV2(stks, fr)
+		for _, code := range stks.Codes {
+			if _, ok := rs.Map[code]; !ok {
+				failed[code] = true
+			}
+		}
+	}
+	FreeFetcherResources()
+	rstks = new(model.Stocks)
+	for _, stk := range all.List {
+		if !failed[stk.Code] {
+			rstks.Add(stk)
+		}
+	}
+	log.Printf("%d of %d stocks repaired", rstks.Size(), len(codes))
+	return
+}
+
+//repairSource resolves the remote source to repair the master klines of the reinstatement type.
+func repairSource(rtype model.Rtype, patch bool) model.DataSource {
+	if patch {
+		return model.Validated
+	}
+	if rtype != model.None && conf.Args.DataSource.Reinstate.Local {
+		return model.Reinstated
+	}
+	return model.DataSource(conf.Args.DataSource.Kline)
+}
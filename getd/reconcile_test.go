@@ -0,0 +1,97 @@
+package getd
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/carusyte/stock/model"
+)
+
+func reconcileTestBar(date string, p, vol float64) *model.TradeDataBasic {
+	return &model.TradeDataBasic{
+		Date:   date,
+		Open:   p,
+		High:   p * 1.02,
+		Low:    p * 0.98,
+		Close:  p * 1.01,
+		Volume: sql.NullFloat64{Float64: vol, Valid: true},
+		Amount: sql.NullFloat64{Float64: vol * p, Valid: true},
+		Xrate:  sql.NullFloat64{Float64: 1.5, Valid: true},
+	}
+}
+
+func TestReconcileBars(t *testing.T) {
+	master := []*model.TradeDataBasic{
+		reconcileTestBar("2020-06-08", 10, 1000),
+		reconcileTestBar("2020-06-09", 10, 1000),
+		reconcileTestBar("2020-06-10", 10, 1000),
+		reconcileTestBar("2020-06-11", 10, 1000),
+		reconcileTestBar("2020-06-12", 10, 1000),
+		reconcileTestBar("2020-06-15", 10, 1000),
+	}
+	vld := []*model.TradeDataBasic{
+		//same as master
+		reconcileTestBar("2020-06-09", 10, 1000),
+		//split mis-adjustment
+		reconcileTestBar("2020-06-10", 5, 1000),
+		//price mismatch and volume in lots
+		reconcileTestBar("2020-06-11", 10, 10),
+		//2020-06-12 missing in validate source
+		reconcileTestBar("2020-06-15", 10, 1030),
+		//missing in master
+		reconcileTestBar("2020-06-16", 10, 1000),
+	}
+	vld[1].Amount = master[2].Amount
+	vld[2].Close = 10.3
+	vld[3].Xrate.Float64 = 1.8
+
+	want := []struct{ date, kind, fields string }{
+		{"2020-06-10", DiscSplit, "open,high,low,close"},
+		{"2020-06-11", DiscPrice, "close"},
+		{"2020-06-11", DiscVolumeScale, "volume,amount"},
+		{"2020-06-12", DiscExtra, ""},
+		{"2020-06-15", DiscXrate, "xrate"},
+		{"2020-06-16", DiscMissing, ""},
+		{"2020-06-17", DiscGap, ""},
+	}
+	//2020-06-05 is before the compared range, 2020-06-17 missing in both
+	tds := []string{"2020-06-05", "2020-06-09", "2020-06-10", "2020-06-11", "2020-06-12", "2020-06-15",
+		"2020-06-16", "2020-06-17"}
+	discs := reconcileBars(master, vld, tds, 0.005, 0.05)
+	if len(discs) != len(want) {
+		for _, x := range discs {
+			t.Logf("%+v", x)
+		}
+		t.Fatalf("want %d discrepancies, got %d", len(want), len(discs))
+	}
+	for i, w := range want {
+		x := discs[i]
+		if x.Date != w.date || x.Kind != w.kind || x.Fields != w.fields {
+			t.Errorf("#%d: want %+v, got %+v", i, w, x)
+		}
+	}
+	assertFloat(t, "split ratio", 0.5, discs[0].Ratio.Float64)
+	if discs[1].Ratio.Valid {
+		t.Errorf("unexpected price ratio: %+v", discs[1].Ratio)
+	}
+	assertFloat(t, "volume scale ratio", 0.01, discs[2].Ratio.Float64)
+	assertFloat(t, "xrate deviation", 0.2, discs[4].Dev.Float64)
+}
+
+func TestPowerOf10Ratio(t *testing.T) {
+	for _, c := range []struct {
+		m, v float64
+		ok   bool
+	}{
+		{100, 10000, true},
+		{10000, 102, true},
+		{100, 120, false},
+		{100, 100, false},
+		{100, 5000, false},
+		{0, 100, false},
+	} {
+		if _, ok := powerOf10Ratio(c.m, c.v, 0.05); ok != c.ok {
+			t.Errorf("powerOf10Ratio(%v, %v): want %v, got %v", c.m, c.v, c.ok, ok)
+		}
+	}
+}
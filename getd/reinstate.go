@@ -0,0 +1,229 @@
+package getd
+
+import (
+	"math"
+	"time"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/model"
+	"github.com/pkg/errors"
+)
+
+//ReinstateKlineFetcher derives forward and backward reinstated klines from the non-reinstated klines
+//in database and the ex-rights/ex-dividend events in xdxr table, instead of downloading them.
+//Daily and intraday klines are reinstated bar by bar. Weekly and monthly klines are aggregated from
+//the reinstated daily klines and aligned with the non-reinstated bars of the same cycle, so that the
+//open, high and low prices of a period with xdxr events are consistent with its daily klines.
+type ReinstateKlineFetcher struct{}
+
+//fetchKline derives the reinstated klines for the given stock.
+func (f *ReinstateKlineFetcher) fetchKline(stk *model.Stock, fr FetchRequest, incr bool) (
+	tdmap map[FetchRequest]*model.TradeData, lkmap map[FetchRequest]int, suc, retry bool) {
+
+	tdmap = make(map[FetchRequest]*model.TradeData)
+	lkmap = make(map[FetchRequest]int)
+	lkmap[fr] = -1
+	code := stk.Code
+	tabs := resolveTableNames(fr)
+
+	data, e := reinstateKline(code, fr.LocalSource, fr.Cycle, fr.Reinstate)
+	if e != nil {
+		log.Warnf("%s %+v failed to reinstate kline: %+v", code, tabs, e)
+		return tdmap, lkmap, false, false
+	}
+	if incr {
+		data, lkmap[fr] = sliceFromLatest(code, fr, data)
+	} else {
+		log.Printf("%s %+v data will be fully refreshed", code, tabs)
+	}
+
+	tdmap[fr] = &model.TradeData{
+		Code:          code,
+		Source:        fr.LocalSource,
+		Cycle:         fr.Cycle,
+		Reinstatement: fr.Reinstate,
+		Base:          data,
+	}
+	return tdmap, lkmap, true, false
+}
+
+//reinstateKline reinstates the non-reinstated klines of the local source in chronological order.
+func reinstateKline(code string, src model.DataSource, cycle model.CYTP, rtype model.Rtype) (
+	data []*model.TradeDataBasic, e error) {
+	if rtype != model.Forward && rtype != model.Backward {
+		return nil, errors.Errorf("unsupported reinstatement type: %v", rtype)
+	}
+	bc := cycle
+	if cycle == model.WEEK || cycle == model.MONTH {
+		bc = model.DAY
+	}
+	bars := GetTrDataDB(code, TrDataQry{LocalSource: src, Cycle: bc, Reinstate: model.None, Basic: true},
+		0, false).Base
+	if len(bars) == 0 {
+		return nil, errors.Errorf("no non-reinstated %v kline found", bc)
+	}
+	xemap, e := XdxrDateBetween(code, bars[0].Date[:10], bars[len(bars)-1].Date[:10])
+	if e != nil {
+		return nil, e
+	}
+	data = reinstateBasic(bars, xemap, rtype)
+	if bc == cycle {
+		return
+	}
+	target := GetTrDataDB(code, TrDataQry{LocalSource: src, Cycle: cycle, Reinstate: model.None, Basic: true},
+		0, false).Base
+	key := weekKey(tradeCal())
+	if cycle == model.MONTH {
+		key = monthKey(tradeCal())
+	}
+	return alignKline(data, target, key)
+}
+
+//reinstateBasic returns the reinstated copies of the non-reinstated bars in chronological order.
+//Forward reinstatement keeps the latest bar unchanged and adjusts each preceding bar by the xdxr
+//events after it, earliest first. Backward reinstatement keeps the first bar unchanged and adjusts
+//each following bar by the inverse of the xdxr events up to it, latest first. Volume, amount and
+//exchange rate are not adjusted.
+func reinstateBasic(bars []*model.TradeDataBasic, xemap map[string]*model.Xdxr, rtype model.Rtype) (
+	data []*model.TradeDataBasic) {
+	data = make([]*model.TradeDataBasic, len(bars))
+	//the adjustment accumulated so far is kept as p -> a*p + b, since Reinstate is affine in price
+	a, b := 1., 0.
+	if rtype == model.Forward {
+		for i := len(bars) - 1; i >= 0; i-- {
+			if i < len(bars)-1 {
+				if x := MergeXdxrBetween(bars[i].Date, bars[i+1].Date, xemap); x != nil {
+					k, c := Reinstate(1, x)-Reinstate(0, x), Reinstate(0, x)
+					a, b = a*k, a*c+b
+				}
+			}
+			data[i] = adjustBasic(bars[i], a, b)
+		}
+		return
+	}
+	for i := range bars {
+		if i > 0 {
+			if x := MergeXdxrBetween(bars[i-1].Date, bars[i].Date, xemap); x != nil {
+				k, c := Reinstate(1, x)-Reinstate(0, x), Reinstate(0, x)
+				a, b = a/k, b-a*c/k
+			}
+		}
+		data[i] = adjustBasic(bars[i], a, b)
+	}
+	return
+}
+
+func adjustBasic(src *model.TradeDataBasic, a, b float64) *model.TradeDataBasic {
+	d := &model.TradeDataBasic{
+		Code:   src.Code,
+		Date:   src.Date,
+		Klid:   src.Klid,
+		Volume: src.Volume,
+		Amount: src.Amount,
+		Xrate:  src.Xrate,
+	}
+	d.Open = a*src.Open + b
+	d.High = a*src.High + b
+	d.Low = a*src.Low + b
+	d.Close = a*src.Close + b
+	return d
+}
+
+//alignKline aggregates the daily klines into the periods of the target bars, grouped by the key of
+//the date. Date, klid, volume, amount and exchange rate follow the target bars.
+func alignKline(daily, target []*model.TradeDataBasic, key func(date string) (string, error)) (
+	data []*model.TradeDataBasic, e error) {
+	agg, e := aggregateKline(daily, key)
+	if e != nil {
+		return nil, e
+	}
+	amap := make(map[string]*model.TradeDataBasic, len(agg))
+	for _, a := range agg {
+		k, e := key(a.Date)
+		if e != nil {
+			return nil, e
+		}
+		amap[k] = a
+	}
+	for _, t := range target {
+		k, e := key(t.Date)
+		if e != nil {
+			return nil, e
+		}
+		a, ok := amap[k]
+		if !ok {
+			return nil, errors.Errorf("no daily kline found for the period of %s", t.Date)
+		}
+		d := *a
+		d.Date, d.Klid = t.Date, t.Klid
+		d.Volume, d.Amount, d.Xrate = t.Volume, t.Amount, t.Xrate
+		data = append(data, &d)
+	}
+	return
+}
+
+//VerifyReinstatement compares the latest locally reinstated klines in the master tables with those
+//downloaded from the validate source. Stocks with price deviation exceeding the configured tolerance
+//are dropped if DataSource.Validate.DropInconsistent is set. Returns the stocks passing verification.
+func VerifyReinstatement(stks *model.Stocks) (rstks *model.Stocks) {
+	start := time.Now()
+	defer StopWatch("VERIFY_REINSTATEMENT", start)
+	vsrc := model.DataSource(conf.Args.DataSource.Validate.Source)
+	bars := conf.Args.DataSource.Reinstate.VerifyBars
+	tol := conf.Args.DataSource.Reinstate.Tolerance
+	log.Printf("verifying reinstated klines of %d stocks against %s", stks.Size(), vsrc)
+	rstks = new(model.Stocks)
+	for _, stk := range stks.List {
+		ok := true
+		for _, c := range []model.CYTP{model.DAY, model.WEEK, model.MONTH} {
+			for _, r := range []model.Rtype{model.Forward, model.Backward} {
+				qry := TrDataQry{LocalSource: model.KlineMaster, Cycle: c, Reinstate: r, Basic: true}
+				local := GetTrDataDB(stk.Code, qry, bars, false).Base
+				qry.LocalSource = vsrc
+				ref := GetTrDataDB(stk.Code, qry, bars, false).Base
+				if len(ref) == 0 {
+					log.Debugf("%s no %v %v kline from %s to verify against", stk.Code, c, r, vsrc)
+					continue
+				}
+				n, dev, date := compareKline(local, ref)
+				if dev > tol {
+					log.Warnf("%s %v %v reinstated kline deviates from %s by %.4f%% at %s (%d bars compared)",
+						stk.Code, c, r, vsrc, dev*100, date, n)
+					ok = false
+				}
+			}
+		}
+		if ok || !conf.Args.DataSource.Validate.DropInconsistent {
+			rstks.Add(stk)
+		}
+	}
+	log.Printf("%d stocks passed reinstatement verification", rstks.Size())
+	return
+}
+
+//compareKline returns the number of bars of the same date, and the maximum relative deviation of
+//the prices in the bars and its date.
+func compareKline(bars, ref []*model.TradeDataBasic) (n int, dev float64, date string) {
+	rmap := make(map[string]*model.TradeDataBasic, len(ref))
+	for _, r := range ref {
+		rmap[r.Date] = r
+	}
+	for _, b := range bars {
+		r, ok := rmap[b.Date]
+		if !ok {
+			continue
+		}
+		n++
+		for _, p := range [][2]float64{
+			{b.Open, r.Open}, {b.High, r.High}, {b.Low, r.Low}, {b.Close, r.Close},
+		} {
+			if p[1] == 0 {
+				continue
+			}
+			if d := math.Abs(p[0]-p[1]) / math.Abs(p[1]); d > dev {
+				dev, date = d, b.Date
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,106 @@
+package getd
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/carusyte/stock/calendar"
+	"github.com/carusyte/stock/model"
+)
+
+func reinstateTestBars() []*model.TradeDataBasic {
+	var bars []*model.TradeDataBasic
+	for i, p := range [][2]float64{{19.8, 20}, {9.4, 9.5}, {9.6, 10}} {
+		bars = append(bars, &model.TradeDataBasic{
+			Code:   "600000",
+			Date:   []string{"2020-06-10", "2020-06-11", "2020-06-12"}[i],
+			Klid:   i,
+			Open:   p[0],
+			High:   p[1],
+			Low:    p[0],
+			Close:  p[1],
+			Volume: sql.NullFloat64{Float64: float64(1000 * (i + 1)), Valid: true},
+		})
+	}
+	return bars
+}
+
+func TestReinstateBasic(t *testing.T) {
+	//10 for 10 shares converted plus 10 yuan for 10 shares on 06-11, 5 yuan for 10 shares on 06-12
+	xemap := map[string]*model.Xdxr{
+		"2020-06-11": {
+			Divi:      sql.NullFloat64{Float64: 10, Valid: true},
+			SharesCvt: sql.NullFloat64{Float64: 10, Valid: true},
+		},
+		"2020-06-12": {
+			Divi: sql.NullFloat64{Float64: 5, Valid: true},
+		},
+	}
+	bars := reinstateTestBars()
+	fwd := reinstateBasic(bars, xemap, model.Forward)
+	for i, want := range []float64{9, 9, 10} {
+		assertFloat(t, "forward close", want, fwd[i].Close)
+	}
+	assertFloat(t, "forward open", 8.9, fwd[0].Open)
+	bwd := reinstateBasic(bars, xemap, model.Backward)
+	for i, want := range []float64{20, 20, 22} {
+		assertFloat(t, "backward close", want, bwd[i].Close)
+	}
+	assertFloat(t, "backward open", 21.2, bwd[2].Open)
+	for i, b := range bars {
+		if fwd[i].Klid != b.Klid || fwd[i].Date != b.Date || fwd[i].Volume != b.Volume {
+			t.Errorf("#%d unexpected forward bar: %+v", i, fwd[i])
+		}
+	}
+	//source bars are intact
+	assertFloat(t, "source close", 20, bars[0].Close)
+
+	none := reinstateBasic(bars, nil, model.Forward)
+	for i, b := range bars {
+		assertFloat(t, "unadjusted close", b.Close, none[i].Close)
+	}
+}
+
+func TestAlignKline(t *testing.T) {
+	daily := []*model.TradeDataBasic{
+		{Date: "2020-06-04", Open: 1, High: 2, Low: 0.5, Close: 1.5},
+		{Date: "2020-06-05", Open: 1.5, High: 3, Low: 1, Close: 2.5},
+		{Date: "2020-06-08", Open: 2.5, High: 2.8, Low: 2, Close: 2.2},
+		{Date: "2020-06-10", Open: 2.2, High: 2.4, Low: 1.8, Close: 2},
+	}
+	target := []*model.TradeDataBasic{
+		{Date: "2020-06-05", Klid: 7, Volume: sql.NullFloat64{Float64: 100, Valid: true}},
+		{Date: "2020-06-10", Klid: 8, Volume: sql.NullFloat64{Float64: 200, Valid: true}},
+	}
+	data, e := alignKline(daily, target, weekKey(calendar.New(nil)))
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if len(data) != 2 {
+		t.Fatalf("want 2 bars, got %d", len(data))
+	}
+	w := data[1]
+	if w.Date != "2020-06-10" || w.Klid != 8 || w.Volume.Float64 != 200 {
+		t.Errorf("unexpected bar: %+v", w)
+	}
+	assertFloat(t, "open", 2.5, w.Open)
+	assertFloat(t, "high", 2.8, w.High)
+	assertFloat(t, "low", 1.8, w.Low)
+	assertFloat(t, "close", 2, w.Close)
+
+	target = append(target, &model.TradeDataBasic{Date: "2020-06-19", Klid: 9})
+	if _, e = alignKline(daily, target, weekKey(calendar.New(nil))); e == nil {
+		t.Error("want error for period without daily kline")
+	}
+}
+
+func TestCompareKline(t *testing.T) {
+	bars := reinstateTestBars()
+	ref := reinstateTestBars()[1:]
+	ref[1].High = 10.1
+	n, dev, date := compareKline(bars, ref)
+	if n != 2 || date != "2020-06-12" {
+		t.Errorf("unexpected comparison: %d bars, deviation at %s", n, date)
+	}
+	assertFloat(t, "deviation", 0.1/10.1, dev)
+}
@@ -0,0 +1,62 @@
+package getd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+//emReportURL is the url template of eastmoney datacenter reports, taking the report name, sort column,
+//page number and filter. Rows are sorted descending.
+const emReportURL = `http://datacenter-web.eastmoney.com/api/data/v1/get?reportName=%s&columns=ALL&source=WEB` +
+	`&client=WEB&sortColumns=%s&sortTypes=-1&pageSize=500&pageNumber=%d&filter=%s`
+
+//emReport is the response of eastmoney datacenter reports.
+type emReport struct {
+	//null if there's no data
+	Result *struct {
+		Pages int             `json:"pages"`
+		Data  json.RawMessage `json:"data"`
+	} `json:"result"`
+}
+
+//fetchEmReport fetches a page of the eastmoney datacenter report, starting from 1.
+func fetchEmReport(report, sortCol, filter string, page int) ([]byte, error) {
+	return util.HttpGetBytes(fmt.Sprintf(emReportURL, report, sortCol, page, url.QueryEscape(filter)))
+}
+
+//parseEmReport unmarshals the rows of the report page into data, and returns the total number of pages,
+//which is 0 if there's no data.
+func parseEmReport(body []byte, data interface{}) (pages int, e error) {
+	r := new(emReport)
+	if e = json.Unmarshal(body, r); e != nil {
+		return 0, errors.Wrapf(e, "invalid eastmoney report: %s", body)
+	}
+	if r.Result == nil {
+		return 0, nil
+	}
+	if e = json.Unmarshal(r.Result.Data, data); e != nil {
+		return 0, errors.Wrapf(e, "invalid eastmoney report data: %s", r.Result.Data)
+	}
+	return r.Result.Pages, nil
+}
+
+//emNullFloat converts the nullable number of eastmoney reports.
+func emNullFloat(f *float64) sql.NullFloat64 {
+	if f == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *f, Valid: true}
+}
+
+//emDate returns the date part of the datetime of eastmoney reports, e.g. 2020-10-16 00:00:00.
+func emDate(dt string) (string, error) {
+	if len(dt) < 10 {
+		return "", errors.Errorf("invalid date: %s", dt)
+	}
+	return dt[:10], nil
+}
@@ -0,0 +1,330 @@
+package getd
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/carusyte/stock/calendar"
+	"github.com/carusyte/stock/global"
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+	"github.com/pkg/errors"
+)
+
+//kinds of stock status
+const (
+	StatusSuspended = "suspended"
+	//StatusST means special treatment, with ±5% price limit
+	StatusST = "st"
+	//StatusSST means delisting risk warning, with ±5% price limit
+	StatusSST = "*st"
+	//StatusDelisting means the stock is in the delisting arrangement period
+	StatusDelisting = "delisting"
+	//StatusDelisted means the stock is no longer in the exchange lists
+	StatusDelisted = "delisted"
+)
+
+//maxDelisted is the maximum number of stocks marked delisted by one update. If more stocks are missing
+//from the latest list, the list is presumed incomplete rather than the stocks delisted.
+const maxDelisted = 10
+
+//emSuspension is a row of the suspension report of eastmoney.
+type emSuspension struct {
+	Code      string  `json:"SECURITY_CODE"`
+	Name      string  `json:"SECURITY_NAME_ABBR"`
+	StartDate string  `json:"SUSPEND_START_DATE"`
+	EndDate   *string `json:"SUSPEND_END_DATE"`
+	Reason    string  `json:"SUSPEND_REASON"`
+}
+
+//StatusHist is the status history of stocks, keyed by stock code.
+type StatusHist map[string][]*model.StockStatus
+
+//Periods returns the status periods of the stock overlapping the range from and to inclusive,
+//optionally of the specified status only.
+func (h StatusHist) Periods(code, from, to string, status ...string) (ps []*model.StockStatus) {
+	for _, p := range h[code] {
+		if p.Start > to || p.End.Valid && p.End.String < from {
+			continue
+		}
+		if len(status) == 0 {
+			ps = append(ps, p)
+			continue
+		}
+		for _, s := range status {
+			if p.Status == s {
+				ps = append(ps, p)
+				break
+			}
+		}
+	}
+	return
+}
+
+//During reports whether the stock is in any of the status in the range from and to inclusive,
+//or in any special status if none is specified.
+func (h StatusHist) During(code, from, to string, status ...string) bool {
+	return len(h.Periods(code, from, to, status...)) > 0
+}
+
+//Is reports whether the stock is in any of the status on the date, or in any special status
+//if none is specified.
+func (h StatusHist) Is(code, date string, status ...string) bool {
+	return h.During(code, date, date, status...)
+}
+
+//Without returns the dates on which the stock is not in any of the status.
+func (h StatusHist) Without(code string, dates []string, status ...string) (r []string) {
+	if len(h[code]) == 0 {
+		return dates
+	}
+	for _, d := range dates {
+		if !h.Is(code, d, status...) {
+			r = append(r, d)
+		}
+	}
+	return
+}
+
+//LoadStatusHist loads the status history of the specified stocks, or of all stocks if none is specified.
+func LoadStatusHist(codes ...string) StatusHist {
+	var ps []*model.StockStatus
+	qry := "select * from stock_status"
+	if len(codes) > 0 {
+		qry += fmt.Sprintf(" where code in (%s)", util.Join(codes, ",", true))
+	}
+	_, e := dbmap.Select(&ps, qry+" order by code, start_date")
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("failed to load stock status history: %+v", e)
+	}
+	h := make(StatusHist)
+	for _, p := range ps {
+		h[p.Code] = append(h[p.Code], p)
+	}
+	return h
+}
+
+//nameStatus returns the special status indicated by the stock name, or empty string if there is none.
+func nameStatus(name string) string {
+	n := strings.Join(strings.Fields(name), "")
+	switch {
+	case strings.Contains(n, "退"):
+		return StatusDelisting
+	case strings.HasPrefix(n, "*ST"), strings.HasPrefix(n, "S*ST"):
+		return StatusSST
+	case strings.HasPrefix(n, "ST"), strings.HasPrefix(n, "SST"):
+		return StatusST
+	}
+	return ""
+}
+
+//updateStatus records the status changes observed in the latest stock list, comparing with the
+//previous list in basics table and the ongoing periods in stock_status table. Status is observed
+//effective on the current or next trading day. Suspensions are fetched from eastmoney.
+func updateStatus(stks *model.Stocks) {
+	date := time.Now().Format(global.DateFormat)
+	if !calendar.IsTradingDay(date) {
+		date = calendar.NextTD(date)
+	}
+	var open []*model.StockStatus
+	_, e := dbmap.Select(&open, "select * from stock_status where end_date is null")
+	if e != nil && "sql: no rows in result set" != e.Error() {
+		log.Panicf("failed to query ongoing stock status: %+v", e)
+	}
+	susp, e := fetchSuspensions(date)
+	if e != nil {
+		log.Warnf("failed to fetch suspensions on %s, suspension status is not updated: %+v", date, e)
+	}
+	ps := diffStatus(StocksDb(), stks, open, susp, date, calendar.PrevTD(date))
+	if e = saveStatus(ps); e != nil {
+		log.Panicf("failed to save stock status: %+v", e)
+	}
+}
+
+//diffStatus returns the status periods to be started, closed or refreshed on the date. Closed periods end on
+//the specified end date. Suspension periods are left untouched if susp is nil. Stocks missing from the
+//current list are marked delisted, unless there are more than maxDelisted of them.
+func diffStatus(prev []*model.Stock, cur *model.Stocks, open, susp []*model.StockStatus, date, end string) (
+	ps []*model.StockStatus) {
+	omap := make(map[string][]*model.StockStatus)
+	for _, p := range open {
+		omap[p.Code] = append(omap[p.Code], p)
+	}
+	smap := make(map[string]*model.StockStatus)
+	for _, s := range susp {
+		if _, ok := cur.Map[s.Code]; ok {
+			smap[s.Code] = s
+		}
+	}
+	closeExcept := func(code string, keep map[string]bool) {
+		for _, p := range omap[code] {
+			if keep[p.Status] {
+				continue
+			}
+			if p.Status == StatusSuspended {
+				//suspension is still reported, or unknown
+				if sp, ok := smap[code]; susp == nil || ok && sp.Start == p.Start {
+					continue
+				}
+			}
+			p.End = sql.NullString{String: end, Valid: true}
+			ps = append(ps, p)
+		}
+	}
+	hasOpen := func(code, status string) bool {
+		for _, p := range omap[code] {
+			if p.Status == status {
+				return true
+			}
+		}
+		return false
+	}
+	for _, s := range cur.List {
+		keep := make(map[string]bool)
+		if st := nameStatus(s.Name); st != "" {
+			keep[st] = true
+			if !hasOpen(s.Code, st) {
+				ps = append(ps, &model.StockStatus{Code: s.Code, Status: st, Start: date,
+					Name: sql.NullString{String: s.Name, Valid: true}})
+			}
+		}
+		if sp, ok := smap[s.Code]; ok {
+			ps = append(ps, sp)
+		}
+		closeExcept(s.Code, keep)
+	}
+	var missing []*model.Stock
+	for _, s := range prev {
+		if _, ok := cur.Map[s.Code]; !ok {
+			missing = append(missing, s)
+		}
+	}
+	if len(missing) > maxDelisted {
+		log.Warnf("%d of %d stocks missing from the latest list, more than %d, delisting status is not updated",
+			len(missing), len(prev), maxDelisted)
+		return
+	}
+	for _, s := range missing {
+		keep := map[string]bool{StatusDelisted: true}
+		if !hasOpen(s.Code, StatusDelisted) {
+			ps = append(ps, &model.StockStatus{Code: s.Code, Status: StatusDelisted, Start: date,
+				Name: sql.NullString{String: s.Name, Valid: true}})
+		}
+		closeExcept(s.Code, keep)
+	}
+	return
+}
+
+//BackfillStatus derives the past suspensions of the stocks from the trading days missing between their
+//daily klines, which predate the suspensions fetched by the daily updates. ST and delisting history
+//before the first update is not available, since the stock lists only carry the current names.
+func BackfillStatus(stks *model.Stocks) (rstks *model.Stocks) {
+	start := time.Now()
+	defer StopWatch("STATUS_BACKFILL", start)
+	rstks = new(model.Stocks)
+	cal := calendar.Default()
+	for _, stk := range stks.List {
+		var dates []string
+		_, e := dbmap.Select(&dates, "select date from kline_d_n where code = ? order by klid", stk.Code)
+		if e != nil && "sql: no rows in result set" != e.Error() {
+			log.Warnf("%s failed to query daily kline dates: %+v", stk.Code, e)
+			continue
+		}
+		if e = saveStatus(suspensionGaps(stk.Code, dates, cal)); e != nil {
+			log.Warnf("%s failed to save suspensions: %+v", stk.Code, e)
+			continue
+		}
+		rstks.Add(stk)
+	}
+	log.Printf("status history of %d stocks backfilled", rstks.Size())
+	return
+}
+
+//suspensionGaps returns the suspension periods implied by the trading days missing between the
+//chronological daily kline dates of the stock. Days not covered by the calendar are ignored.
+func suspensionGaps(code string, dates []string, cal *calendar.Calendar) (ps []*model.StockStatus) {
+	for i := 1; i < len(dates); i++ {
+		next := cal.NextTD(dates[i-1][:10])
+		if next == "" || next >= dates[i][:10] || !cal.Covers(next) {
+			continue
+		}
+		ps = append(ps, &model.StockStatus{
+			Code:   code,
+			Status: StatusSuspended,
+			Start:  next,
+			End:    sql.NullString{String: cal.PrevTD(dates[i][:10]), Valid: true},
+			Reason: sql.NullString{String: "derived from daily kline gap", Valid: true},
+		})
+	}
+	return
+}
+
+//fetchSuspensions fetches the stocks suspended on the date from eastmoney.
+func fetchSuspensions(date string) (susp []*model.StockStatus, e error) {
+	filter := fmt.Sprintf(`(MARKET="全部")(DATETIME='%s')`, date)
+	//non-nil even if there's no suspension
+	susp = make([]*model.StockStatus, 0, 64)
+	for page, pages := 1, 1; page <= pages; page++ {
+		body, e := fetchEmReport("RPT_CUSTOM_SUSPEND_DATA_INTERFACE", "SUSPEND_START_DATE", filter, page)
+		if e != nil {
+			return nil, e
+		}
+		var ps []*model.StockStatus
+		if ps, pages, e = parseSuspensions(body); e != nil {
+			return nil, e
+		}
+		susp = append(susp, ps...)
+	}
+	return
+}
+
+func parseSuspensions(body []byte) (susp []*model.StockStatus, pages int, e error) {
+	var rows []*emSuspension
+	if pages, e = parseEmReport(body, &rows); e != nil {
+		return nil, 0, e
+	}
+	for _, d := range rows {
+		start, e := emDate(d.StartDate)
+		if e != nil {
+			return nil, 0, errors.Wrapf(e, "invalid suspension start date of %s", d.Code)
+		}
+		p := &model.StockStatus{
+			Code:   d.Code,
+			Status: StatusSuspended,
+			Start:  start,
+			Name:   sql.NullString{String: d.Name, Valid: d.Name != ""},
+			Reason: sql.NullString{String: d.Reason, Valid: d.Reason != ""},
+		}
+		if d.EndDate != nil {
+			if end, e := emDate(*d.EndDate); e == nil {
+				p.End = sql.NullString{String: end, Valid: true}
+			}
+		}
+		susp = append(susp, p)
+	}
+	return susp, pages, nil
+}
+
+func saveStatus(ps []*model.StockStatus) (e error) {
+	if len(ps) == 0 {
+		return nil
+	}
+	d, t := util.TimeStr()
+	valueStrings := make([]string, 0, len(ps))
+	valueArgs := make([]interface{}, 0, len(ps)*8)
+	for _, p := range ps {
+		valueStrings = append(valueStrings, "(?,?,?,?,?,?,?,?)")
+		valueArgs = append(valueArgs, p.Code, p.Status, p.Start, p.End, p.Name, p.Reason, d, t)
+	}
+	stmt := fmt.Sprintf("insert into stock_status (code,status,start_date,end_date,name,reason,udate,utime) "+
+		"values %s on duplicate key update end_date=values(end_date),name=coalesce(values(name),name),"+
+		"reason=coalesce(values(reason),reason),udate=values(udate),utime=values(utime)",
+		strings.Join(valueStrings, ","))
+	if _, e = dbmap.Exec(stmt, valueArgs...); e != nil {
+		return errors.Wrap(e, "failed to save stock status")
+	}
+	log.Printf("%d stock status periods updated", len(ps))
+	return nil
+}
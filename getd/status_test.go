@@ -0,0 +1,171 @@
+package getd
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/carusyte/stock/calendar"
+	"github.com/carusyte/stock/model"
+)
+
+func TestNameStatus(t *testing.T) {
+	for name, want := range map[string]string{
+		"平安银行":   "",
+		"ST康美":   StatusST,
+		"*ST大集":  StatusSST,
+		"S*ST前锋": StatusSST,
+		"SST华新":  StatusST,
+		"长生退":    StatusDelisting,
+		"退市大控":   StatusDelisting,
+		"万  科Ａ":  "",
+	} {
+		if got := nameStatus(name); got != want {
+			t.Errorf("nameStatus(%s): want %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestDiffStatus(t *testing.T) {
+	open := func(code, status, start string) *model.StockStatus {
+		return &model.StockStatus{Code: code, Status: status, Start: start}
+	}
+	prev := []*model.Stock{
+		{Code: "000001", Name: "平安银行"},
+		{Code: "000002", Name: "ST万科"},
+		{Code: "000003", Name: "*ST国农"},
+		{Code: "000004", Name: "国华网安"},
+		{Code: "000005", Name: "长生退"},
+	}
+	cur := new(model.Stocks)
+	cur.Add(
+		&model.Stock{Code: "000001", Name: "ST平安"},
+		&model.Stock{Code: "000002", Name: "万科A"},
+		&model.Stock{Code: "000003", Name: "*ST国农"},
+		&model.Stock{Code: "000004", Name: "国华网安"},
+	)
+	ongoing := []*model.StockStatus{
+		open("000002", StatusST, "2020-03-02"),
+		open("000003", StatusSST, "2020-05-06"),
+		open("000003", StatusSuspended, "2020-10-12"),
+		open("000004", StatusSuspended, "2020-09-01"),
+		open("000005", StatusDelisting, "2020-09-21"),
+	}
+	susp := []*model.StockStatus{
+		open("000003", StatusSuspended, "2020-10-12"),
+		//not a listed stock
+		open("900901", StatusSuspended, "2020-10-16"),
+	}
+	ps := diffStatus(prev, cur, ongoing, susp, "2020-10-19", "2020-10-16")
+	want := []struct{ code, status, start, end string }{
+		{"000001", StatusST, "2020-10-19", ""},
+		{"000002", StatusST, "2020-03-02", "2020-10-16"},
+		{"000003", StatusSuspended, "2020-10-12", ""},
+		{"000004", StatusSuspended, "2020-09-01", "2020-10-16"},
+		{"000005", StatusDelisted, "2020-10-19", ""},
+		{"000005", StatusDelisting, "2020-09-21", "2020-10-16"},
+	}
+	if len(ps) != len(want) {
+		for _, p := range ps {
+			t.Logf("%+v", p)
+		}
+		t.Fatalf("want %d periods, got %d", len(want), len(ps))
+	}
+	for i, w := range want {
+		p := ps[i]
+		if p.Code != w.code || p.Status != w.status || p.Start != w.start || p.End.String != w.end {
+			t.Errorf("#%d: want %+v, got %+v", i, w, p)
+		}
+	}
+
+	//suspensions unknown
+	cur = new(model.Stocks)
+	cur.Add(prev[3])
+	ps = diffStatus(prev[3:4], cur, []*model.StockStatus{open("000004", StatusSuspended, "2020-09-01")}, nil,
+		"2020-10-19", "2020-10-16")
+	if len(ps) != 0 {
+		t.Errorf("want ongoing suspension untouched, got %+v", ps[0])
+	}
+
+	//a partial list doesn't delist the missing stocks
+	var many []*model.Stock
+	for i := 0; i <= maxDelisted+1; i++ {
+		many = append(many, &model.Stock{Code: fmt.Sprintf("6%05d", i), Name: "浦发银行"})
+	}
+	cur = new(model.Stocks)
+	cur.Add(many[0])
+	if ps = diffStatus(many, cur, nil, []*model.StockStatus{}, "2020-10-19", "2020-10-16"); len(ps) != 0 {
+		t.Errorf("want no stock delisted, got %+v", ps[0])
+	}
+}
+
+func TestSuspensionGaps(t *testing.T) {
+	//2020-10-01 to 2020-10-08 were national day holidays
+	cal := calendar.New(calendar.Derive("2020-09-21", "2020-10-16", []string{
+		"2020-09-21", "2020-09-22", "2020-09-23", "2020-09-24", "2020-09-25",
+		"2020-09-28", "2020-09-29", "2020-09-30", "2020-10-09", "2020-10-12",
+		"2020-10-13", "2020-10-14", "2020-10-15", "2020-10-16"}))
+	ps := suspensionGaps("000001", []string{"2020-09-21", "2020-09-22", "2020-09-25", "2020-09-30",
+		"2020-10-09", "2020-10-12", "2020-10-16"}, cal)
+	want := [][2]string{{"2020-09-23", "2020-09-24"}, {"2020-09-28", "2020-09-29"}, {"2020-10-13", "2020-10-15"}}
+	if len(ps) != len(want) {
+		t.Fatalf("want %d gaps, got %d", len(want), len(ps))
+	}
+	for i, w := range want {
+		if p := ps[i]; p.Status != StatusSuspended || p.Start != w[0] || p.End.String != w[1] {
+			t.Errorf("#%d: want %v, got %+v", i, w, p)
+		}
+	}
+	//beyond the calendar
+	if ps = suspensionGaps("000001", []string{"2020-10-16", "2020-10-21"}, cal); len(ps) != 0 {
+		t.Errorf("want no gap beyond the calendar, got %+v", ps[0])
+	}
+}
+
+func TestParseSuspensions(t *testing.T) {
+	susp, pages, e := parseSuspensions([]byte(`{"result":{"pages":1,"data":[` +
+		`{"SECURITY_CODE":"000003","SECURITY_NAME_ABBR":"*ST国农","SUSPEND_START_DATE":"2020-10-12 00:00:00",` +
+		`"SUSPEND_END_DATE":null,"SUSPEND_REASON":"重大事项"},` +
+		`{"SECURITY_CODE":"600001","SECURITY_NAME_ABBR":"邯郸钢铁","SUSPEND_START_DATE":"2020-10-16 09:30:00",` +
+		`"SUSPEND_END_DATE":"2020-10-16 15:00:00","SUSPEND_REASON":""}]},"success":true}`))
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if pages != 1 || len(susp) != 2 {
+		t.Fatalf("want 2 suspensions in 1 page, got %d in %d", len(susp), pages)
+	}
+	if p := susp[0]; p.Start != "2020-10-12" || p.End.Valid || p.Reason.String != "重大事项" {
+		t.Errorf("unexpected suspension: %+v", p)
+	}
+	if p := susp[1]; p.End.String != "2020-10-16" || p.Reason.Valid {
+		t.Errorf("unexpected suspension: %+v", p)
+	}
+	susp, _, e = parseSuspensions([]byte(`{"result":null,"success":false,"message":"返回数据为空"}`))
+	if e != nil || len(susp) != 0 {
+		t.Errorf("want no suspension, got %v, %+v", susp, e)
+	}
+	if _, _, e = parseSuspensions([]byte(`<html>`)); e == nil {
+		t.Error("want error for invalid response")
+	}
+}
+
+func TestStatusHist(t *testing.T) {
+	h := StatusHist{"000001": {
+		{Code: "000001", Status: StatusST, Start: "2020-05-06", End: sql.NullString{String: "2020-06-30", Valid: true}},
+		{Code: "000001", Status: StatusSuspended, Start: "2020-10-12"},
+	}}
+	if !h.Is("000001", "2020-06-30") || h.Is("000001", "2020-07-01") || h.Is("000002", "2020-06-01") {
+		t.Error("unexpected special status")
+	}
+	if h.Is("000001", "2020-06-01", StatusSuspended) || !h.Is("000001", "2020-10-19", StatusSuspended) {
+		t.Error("unexpected suspension")
+	}
+	if !h.During("000001", "2020-10-01", "2020-10-12", StatusSuspended) {
+		t.Error("want suspension within range")
+	}
+	dates := []string{"2020-10-09", "2020-10-12", "2020-10-13"}
+	if got := h.Without("000001", dates, StatusSuspended); !reflect.DeepEqual(got, dates[:1]) {
+		t.Errorf("want %v, got %v", dates[:1], got)
+	}
+}
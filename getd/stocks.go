@@ -59,6 +59,8 @@ func GetStockInfo() (allstk *model.Stocks) {
 	getIndustry(allstk)
 	getShares(allstk)
 
+	//must precede overwrite, which deletes the delisted stocks
+	updateStatus(allstk)
 	overwrite(allstk.List)
 
 	return
@@ -932,6 +934,7 @@ func collect(stocks *model.Stocks, rstks chan *model.Stock) (wgr *sync.WaitGroup
 		defer wgr.Done()
 		for stk := range rstks {
 			stocks.Add(stk)
+			collected(stk)
 		}
 	}()
 	return wgr
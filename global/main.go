@@ -30,6 +30,7 @@ const (
 	DateFormat      = "2006-01-02"
 	TimeFormat      = "15:04:05"
 	DateTimeFormat  = "2006-01-02 15:04:05"
+	MinuteFormat    = "2006-01-02 15:04"
 )
 
 func init() {
@@ -0,0 +1,48 @@
+package indc
+
+import (
+	"math"
+
+	"github.com/carusyte/stock/model"
+)
+
+func init() {
+	Register(&Spec{
+		Name:   "atr",
+		Params: []float64{14},
+		Cols:   []string{"TR", "ATR"},
+		WarmUp: 14,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return zipRows(ATR(src, int(p[0])))
+		},
+	})
+}
+
+//ATR calculates Average True Range for the given parameters, returning the true range and its moving average.
+//formula: TR:=MAX(MAX(HIGH-LOW,ABS(REF(CLOSE,1)-HIGH)),ABS(REF(CLOSE,1)-LOW)); ATR:=MA(TR,N)
+func ATR(src []*model.TradeDataBasic, n int) (tr, atr []float64) {
+	tr = make([]float64, len(src))
+	atr = make([]float64, len(src))
+	for i := range src {
+		tr[i] = trueRange(src, i)
+		atr[i] = MA(tr, i, n)
+	}
+	return
+}
+
+//trueRange calculates the true range of the bar at curIdx. The first bar has no previous close
+//and takes its high-low range.
+func trueRange(src []*model.TradeDataBasic, curIdx int) float64 {
+	s := src[curIdx]
+	r := s.High - s.Low
+	if curIdx == 0 {
+		return r
+	}
+	pc := src[curIdx-1].Close
+	return math.Max(math.Max(r, math.Abs(pc-s.High)), math.Abs(pc-s.Low))
+}
+
+//DeftATR calculates ATR indicator using default parameters (14)
+func DeftATR(src []*model.TradeDataBasic) (tr, atr []float64) {
+	return ATR(src, 14)
+}
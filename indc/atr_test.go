@@ -0,0 +1,9 @@
+package indc
+
+import "testing"
+
+func TestATR(t *testing.T) {
+	tr, atr := ATR(refTradeData(), 3)
+	assertSeries(t, "TR", tr, []float64{0.7, 0.7, 0.7, 0.6, 0.7, 0.6})
+	assertSeries(t, "ATR", atr, []float64{0.233333, 0.466667, 0.7, 0.666667, 0.666667, 0.633333})
+}
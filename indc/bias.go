@@ -6,6 +6,20 @@ import (
 	"github.com/carusyte/stock/model"
 )
 
+func init() {
+	Register(&Spec{
+		Name:   "bias",
+		Params: []float64{6, 12, 24},
+		Cols:   []string{"BIAS1", "BIAS2", "BIAS3"},
+		WarmUp: 24,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return toRows(BIAS(src, int(p[0]), int(p[1]), int(p[2])), func(idc *model.Indicator) []float64 {
+				return []float64{idc.BIAS1, idc.BIAS2, idc.BIAS3}
+			})
+		},
+	})
+}
+
 //BIAS calculates BIAS Indicator for the given parameters
 func BIAS(src []*model.TradeDataBasic, n1, n2, n3 int) []*model.Indicator {
 	r := make([]*model.Indicator, len(src))
@@ -5,6 +5,28 @@ import (
 	"github.com/carusyte/stock/util"
 )
 
+func init() {
+	Register(&Spec{
+		Name:   "boll",
+		Params: []float64{20, 2},
+		Cols: []string{
+			"BOLL_lower", "BOLL_lower_o", "BOLL_lower_h", "BOLL_lower_l", "BOLL_lower_c",
+			"BOLL_mid", "BOLL_mid_o", "BOLL_mid_h", "BOLL_mid_l", "BOLL_mid_c",
+			"BOLL_upper", "BOLL_upper_o", "BOLL_upper_h", "BOLL_upper_l", "BOLL_upper_c",
+		},
+		WarmUp: 20,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return toRows(BOLL(src, int(p[0]), int(p[1])), func(idc *model.Indicator) []float64 {
+				return []float64{
+					idc.BOLL_lower, idc.BOLL_lower_o, idc.BOLL_lower_h, idc.BOLL_lower_l, idc.BOLL_lower_c,
+					idc.BOLL_mid, idc.BOLL_mid_o, idc.BOLL_mid_h, idc.BOLL_mid_l, idc.BOLL_mid_c,
+					idc.BOLL_upper, idc.BOLL_upper_o, idc.BOLL_upper_h, idc.BOLL_upper_l, idc.BOLL_upper_c,
+				}
+			})
+		},
+	})
+}
+
 //BOLL calculates Bollinger Bandwidth indicator for the given parameters
 func BOLL(src []*model.TradeDataBasic, n, p int) []*model.Indicator {
 	r := make([]*model.Indicator, len(src))
@@ -1 +1,69 @@
 package indc
+
+import (
+	"math"
+
+	"github.com/carusyte/stock/model"
+)
+
+func init() {
+	Register(&Spec{
+		Name:   "cci",
+		Params: []float64{14},
+		Cols:   []string{"CCI"},
+		WarmUp: 14,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return toRows(CCI(src, int(p[0])), func(idc *model.Indicator) []float64 {
+				return []float64{idc.CCI}
+			})
+		},
+	})
+}
+
+//CCI calculates Commodity Channel Index for the given parameters
+//formula: TYP:=(HIGH+LOW+CLOSE)/3; CCI:=(TYP-MA(TYP,N))/(0.015*AVEDEV(TYP,N))
+func CCI(src []*model.TradeDataBasic, n int) []*model.Indicator {
+	r := make([]*model.Indicator, len(src))
+	typ := make([]float64, len(src))
+	for i, s := range src {
+		typ[i] = (s.High + s.Low + s.Close) / 3.
+	}
+	for i, s := range src {
+		idc := &model.Indicator{}
+		r[i] = idc
+		idc.Code = s.Code
+		idc.Date = s.Date[:10]
+		idc.Klid = s.Klid
+		ma := MA(typ, i, n)
+		d := AVEDEV(typ, i, n)
+		if d == 0 {
+			continue
+		}
+		idc.CCI = (typ[i] - ma) / (0.015 * d)
+	}
+	return r
+}
+
+//AVEDEV calculates mean absolute deviation for given values.
+func AVEDEV(vals []float64, curIdx, n int) float64 {
+	if curIdx >= len(vals) {
+		log.Panicf("invalid curIdx:%d, maximum:%d", curIdx, len(vals)-1)
+	}
+	bg := int(math.Max(0, float64(curIdx-n+1)))
+	w := vals[bg : curIdx+1]
+	mean := 0.
+	for _, v := range w {
+		mean += v
+	}
+	mean /= float64(len(w))
+	dev := 0.
+	for _, v := range w {
+		dev += math.Abs(v - mean)
+	}
+	return dev / float64(len(w))
+}
+
+//DeftCCI calculates CCI indicator using default parameters (14)
+func DeftCCI(src []*model.TradeDataBasic) []*model.Indicator {
+	return CCI(src, 14)
+}
@@ -0,0 +1,11 @@
+package indc
+
+import "testing"
+
+func TestCCI(t *testing.T) {
+	var cci []float64
+	for _, idc := range CCI(refTradeData(), 3)[2:] {
+		cci = append(cci, idc.CCI)
+	}
+	assertSeries(t, "CCI", cci, []float64{-23.529412, -100, 65, 100})
+}
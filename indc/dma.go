@@ -1 +1,46 @@
 package indc
+
+import (
+	"github.com/carusyte/stock/model"
+)
+
+func init() {
+	Register(&Spec{
+		Name:   "dma",
+		Params: []float64{10, 50, 10},
+		Cols:   []string{"DMA_dif", "DMA_difma"},
+		WarmUp: 60,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return toRows(DMA(src, int(p[0]), int(p[1]), int(p[2])), func(idc *model.Indicator) []float64 {
+				return []float64{idc.DMA_dif, idc.DMA_difma}
+			})
+		},
+	})
+}
+
+//DMA calculates Different of Moving Average indicator for the given parameters
+//formula: DIF:=MA(CLOSE,N1)-MA(CLOSE,N2); DIFMA:=MA(DIF,M)
+func DMA(src []*model.TradeDataBasic, n1, n2, m int) []*model.Indicator {
+	r := make([]*model.Indicator, len(src))
+	close := make([]float64, len(src))
+	dif := make([]float64, len(src))
+	for i, s := range src {
+		close[i] = s.Close
+	}
+	for i, s := range src {
+		idc := &model.Indicator{}
+		r[i] = idc
+		idc.Code = s.Code
+		idc.Date = s.Date[:10]
+		idc.Klid = s.Klid
+		dif[i] = MA(close, i, n1) - MA(close, i, n2)
+		idc.DMA_dif = dif[i]
+		idc.DMA_difma = MA(dif, i, m)
+	}
+	return r
+}
+
+//DeftDMA calculates DMA indicator using default parameters (10,50,10)
+func DeftDMA(src []*model.TradeDataBasic) []*model.Indicator {
+	return DMA(src, 10, 50, 10)
+}
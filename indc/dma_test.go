@@ -0,0 +1,14 @@
+package indc
+
+import "testing"
+
+func TestDMA(t *testing.T) {
+	var dif, difma []float64
+	for _, idc := range DMA(refTradeData(), 2, 3, 2)[2:] {
+		dif = append(dif, idc.DMA_dif)
+		difma = append(difma, idc.DMA_difma)
+	}
+	assertSeries(t, "DIF", dif, []float64{0.05, -0.2, 0.033333, 0.266667})
+	//DIFMA needs a full window of DIF
+	assertSeries(t, "DIFMA", difma[1:], []float64{-0.075, -0.083333, 0.15})
+}
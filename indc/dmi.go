@@ -0,0 +1,63 @@
+package indc
+
+import (
+	"math"
+
+	"github.com/carusyte/stock/model"
+)
+
+func init() {
+	Register(&Spec{
+		Name:   "dmi",
+		Params: []float64{14, 6},
+		Cols:   []string{"DMI_pdi", "DMI_mdi", "DMI_adx", "DMI_adxr"},
+		WarmUp: 26,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return zipRows(DMI(src, int(p[0]), int(p[1])))
+		},
+	})
+}
+
+//DMI calculates Directional Movement Index for the given parameters.
+//formula: MTR:=SUM(TR,N); HD:=HIGH-REF(HIGH,1); LD:=REF(LOW,1)-LOW;
+//DMP:=SUM(IF(HD>0&&HD>LD,HD,0),N); DMM:=SUM(IF(LD>0&&LD>HD,LD,0),N);
+//PDI:=DMP*100/MTR; MDI:=DMM*100/MTR; ADX:=MA(ABS(MDI-PDI)/(MDI+PDI)*100,M); ADXR:=(ADX+REF(ADX,M))/2
+func DMI(src []*model.TradeDataBasic, n, m int) (pdi, mdi, adx, adxr []float64) {
+	tr := make([]float64, len(src))
+	dmp := make([]float64, len(src))
+	dmm := make([]float64, len(src))
+	dx := make([]float64, len(src))
+	pdi = make([]float64, len(src))
+	mdi = make([]float64, len(src))
+	adx = make([]float64, len(src))
+	adxr = make([]float64, len(src))
+	for i, s := range src {
+		tr[i] = trueRange(src, i)
+		if i > 0 {
+			hd := s.High - src[i-1].High
+			ld := src[i-1].Low - s.Low
+			if hd > 0 && hd > ld {
+				dmp[i] = hd
+			}
+			if ld > 0 && ld > hd {
+				dmm[i] = ld
+			}
+		}
+		mtr := SUM(tr, i, n)
+		if mtr != 0 {
+			pdi[i] = SUM(dmp, i, n) * 100. / mtr
+			mdi[i] = SUM(dmm, i, n) * 100. / mtr
+		}
+		if pdi[i]+mdi[i] != 0 {
+			dx[i] = math.Abs(mdi[i]-pdi[i]) / (mdi[i] + pdi[i]) * 100.
+		}
+		adx[i] = MA(dx, i, m)
+		adxr[i] = (adx[i] + adx[int(math.Max(0, float64(i-m)))]) / 2.
+	}
+	return
+}
+
+//DeftDMI calculates DMI indicator using default parameters (14,6)
+func DeftDMI(src []*model.TradeDataBasic) (pdi, mdi, adx, adxr []float64) {
+	return DMI(src, 14, 6)
+}
@@ -0,0 +1,11 @@
+package indc
+
+import "testing"
+
+func TestDMI(t *testing.T) {
+	pdi, mdi, adx, adxr := DMI(refTradeData(), 3, 2)
+	assertSeries(t, "PDI", pdi, []float64{0, 21.428571, 14.285714, 15, 15, 36.842105})
+	assertSeries(t, "MDI", mdi, []float64{0, 0, 4.761905, 20, 20, 15.789474})
+	assertSeries(t, "ADX", adx, []float64{0, 50, 75, 32.142857, 14.285714, 27.142857})
+	assertSeries(t, "ADXR", adxr, []float64{0, 25, 37.5, 41.071429, 44.642857, 29.642857})
+}
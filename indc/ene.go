@@ -1 +1,73 @@
 package indc
+
+import (
+	"github.com/carusyte/stock/model"
+	"github.com/carusyte/stock/util"
+)
+
+func init() {
+	Register(&Spec{
+		Name:   "ene",
+		Params: []float64{10, 11, 9},
+		Cols: []string{
+			"ENE_lower", "ENE_lower_o", "ENE_lower_h", "ENE_lower_l", "ENE_lower_c",
+			"ENE_mid", "ENE_mid_o", "ENE_mid_h", "ENE_mid_l", "ENE_mid_c",
+			"ENE_upper", "ENE_upper_o", "ENE_upper_h", "ENE_upper_l", "ENE_upper_c",
+		},
+		WarmUp: 10,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return toRows(ENE(src, int(p[0]), p[1], p[2]), func(idc *model.Indicator) []float64 {
+				return []float64{
+					idc.ENE_lower, idc.ENE_lower_o, idc.ENE_lower_h, idc.ENE_lower_l, idc.ENE_lower_c,
+					idc.ENE_mid, idc.ENE_mid_o, idc.ENE_mid_h, idc.ENE_mid_l, idc.ENE_mid_c,
+					idc.ENE_upper, idc.ENE_upper_o, idc.ENE_upper_h, idc.ENE_upper_l, idc.ENE_upper_c,
+				}
+			})
+		},
+	})
+}
+
+//ENE calculates Envelope (轨道线) indicator for the given parameters
+//formula: UPPER:=(1+M1/100)*MA(CLOSE,N); LOWER:=(1-M2/100)*MA(CLOSE,N); ENE:=(UPPER+LOWER)/2
+func ENE(src []*model.TradeDataBasic, n int, m1, m2 float64) []*model.Indicator {
+	r := make([]*model.Indicator, len(src))
+	close := make([]float64, len(src))
+	for i, s := range src {
+		close[i] = s.Close
+	}
+	for i, s := range src {
+		idc := &model.Indicator{}
+		r[i] = idc
+		idc.Code = s.Code
+		idc.Date = s.Date[:10]
+		idc.Klid = s.Klid
+		ma := MA(close, i, n)
+		upper := (1. + m1/100.) * ma
+		lower := (1. - m2/100.) * ma
+		mid := (upper + lower) / 2.
+		idc.ENE_upper = upper
+		idc.ENE_mid = mid
+		idc.ENE_lower = lower
+
+		//calculates LR for OHLC
+		bias := 0.01
+		idc.ENE_lower_o = util.LogReturn(lower, s.Open, bias)
+		idc.ENE_lower_h = util.LogReturn(lower, s.High, bias)
+		idc.ENE_lower_l = util.LogReturn(lower, s.Low, bias)
+		idc.ENE_lower_c = util.LogReturn(lower, s.Close, bias)
+		idc.ENE_mid_o = util.LogReturn(mid, s.Open, bias)
+		idc.ENE_mid_h = util.LogReturn(mid, s.High, bias)
+		idc.ENE_mid_l = util.LogReturn(mid, s.Low, bias)
+		idc.ENE_mid_c = util.LogReturn(mid, s.Close, bias)
+		idc.ENE_upper_o = util.LogReturn(upper, s.Open, bias)
+		idc.ENE_upper_h = util.LogReturn(upper, s.High, bias)
+		idc.ENE_upper_l = util.LogReturn(upper, s.Low, bias)
+		idc.ENE_upper_c = util.LogReturn(upper, s.Close, bias)
+	}
+	return r
+}
+
+//DeftENE calculates ENE indicator using default parameters (10,11,9)
+func DeftENE(src []*model.TradeDataBasic) []*model.Indicator {
+	return ENE(src, 10, 11, 9)
+}
@@ -0,0 +1,15 @@
+package indc
+
+import "testing"
+
+func TestENE(t *testing.T) {
+	var upper, mid, lower []float64
+	for _, idc := range ENE(refTradeData(), 3, 11, 9)[2:] {
+		upper = append(upper, idc.ENE_upper)
+		mid = append(mid, idc.ENE_mid)
+		lower = append(lower, idc.ENE_lower)
+	}
+	assertSeries(t, "UPPER", upper, []float64{11.433, 11.322, 11.285, 11.581})
+	assertSeries(t, "MID", mid, []float64{10.403, 10.302, 10.268333, 10.537667})
+	assertSeries(t, "LOWER", lower, []float64{9.373, 9.282, 9.251667, 9.494333})
+}
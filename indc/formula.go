@@ -0,0 +1,323 @@
+package indc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+//Formula is a parsed indicator formula written in TDX/THS syntax, e.g.
+//	MID:=(H+L+C)/3;
+//	K:SMA(RSV,3,1);
+//	CROSS(MA(C,5),MA(C,10));
+//Statements are separated by semicolons. NAME:=EXPR defines an intermediate variable,
+//NAME:EXPR defines an output, and an anonymous expression is an output named OUT<n>.
+//Comments are enclosed in braces.
+type Formula struct {
+	//Text is the source of the formula.
+	Text  string
+	stmts []*fstmt
+}
+
+//Outputs returns the names of the formula outputs in the order of definition.
+func (f *Formula) Outputs() (names []string) {
+	for _, s := range f.stmts {
+		if s.output {
+			names = append(names, s.name)
+		}
+	}
+	return
+}
+
+func (f *Formula) String() string {
+	return f.Text
+}
+
+type fstmt struct {
+	name   string
+	output bool
+	expr   fnode
+}
+
+type ftokKind int
+
+const (
+	ftEOF ftokKind = iota
+	ftNum
+	ftIdent
+	ftOp
+)
+
+type ftok struct {
+	kind ftokKind
+	text string
+	num  float64
+	pos  int
+}
+
+func (t ftok) String() string {
+	if t.kind == ftEOF {
+		return "end of formula"
+	}
+	return fmt.Sprintf("%q at %d", t.text, t.pos)
+}
+
+//lexFormula splits the formula text into tokens. Identifiers and keywords are upper cased.
+func lexFormula(text string) (toks []ftok, e error) {
+	rs := []rune(text)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '{':
+			j := i
+			for j < len(rs) && rs[j] != '}' {
+				j++
+			}
+			if j == len(rs) {
+				return nil, errors.Errorf("unterminated comment at %d", i)
+			}
+			i = j + 1
+		case unicode.IsDigit(r) || r == '.' && i+1 < len(rs) && unicode.IsDigit(rs[i+1]):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			n, err := strconv.ParseFloat(string(rs[i:j]), 64)
+			if err != nil {
+				return nil, errors.Errorf("invalid number %q at %d", string(rs[i:j]), i)
+			}
+			toks = append(toks, ftok{kind: ftNum, text: string(rs[i:j]), num: n, pos: i})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_') {
+				j++
+			}
+			toks = append(toks, ftok{kind: ftIdent, text: strings.ToUpper(string(rs[i:j])), pos: i})
+			i = j
+		default:
+			op := ""
+			if i+1 < len(rs) {
+				switch two := string(rs[i : i+2]); two {
+				case ":=", ">=", "<=", "<>", "!=", "&&", "||":
+					op = two
+				}
+			}
+			if op == "" {
+				if !strings.ContainsRune("+-*/(),;:<>=", r) {
+					return nil, errors.Errorf("unexpected character %q at %d", r, i)
+				}
+				op = string(r)
+			}
+			toks = append(toks, ftok{kind: ftOp, text: op, pos: i})
+			i += len([]rune(op))
+		}
+	}
+	toks = append(toks, ftok{kind: ftEOF, pos: len(rs)})
+	return
+}
+
+//ParseFormula parses the formula text. Unknown variables, functions and wrong number of
+//arguments are reported here so that a parsed formula can always be evaluated.
+func ParseFormula(text string) (f *Formula, e error) {
+	toks, e := lexFormula(text)
+	if e != nil {
+		return nil, errors.Wrap(e, "failed to parse formula")
+	}
+	p := &fparser{toks: toks, vars: make(map[string]bool)}
+	f = &Formula{Text: text}
+	anon := 0
+	for p.peek().kind != ftEOF {
+		if p.accept(";") {
+			continue
+		}
+		s := new(fstmt)
+		if p.peek().kind == ftIdent && p.peekAt(1).kind == ftOp &&
+			(p.peekAt(1).text == ":=" || p.peekAt(1).text == ":") {
+			s.name = p.next().text
+			s.output = p.next().text == ":"
+		} else {
+			anon++
+			s.name = fmt.Sprintf("OUT%d", anon)
+			s.output = true
+		}
+		if s.expr, e = p.parseExpr(); e != nil {
+			return nil, errors.Wrap(e, "failed to parse formula")
+		}
+		if t := p.peek(); t.kind != ftEOF && !p.accept(";") {
+			return nil, errors.Errorf("failed to parse formula: expecting ';' but got %v", t)
+		}
+		p.vars[s.name] = true
+		f.stmts = append(f.stmts, s)
+	}
+	if len(f.Outputs()) == 0 {
+		return nil, errors.New("failed to parse formula: no output is defined")
+	}
+	return
+}
+
+//MustParseFormula is like ParseFormula but panics if the formula cannot be parsed.
+func MustParseFormula(text string) *Formula {
+	f, e := ParseFormula(text)
+	if e != nil {
+		log.Panicf("%+v", e)
+	}
+	return f
+}
+
+type fparser struct {
+	toks []ftok
+	pos  int
+	//vars are the variables defined by preceding statements
+	vars map[string]bool
+}
+
+func (p *fparser) peek() ftok {
+	return p.peekAt(0)
+}
+
+func (p *fparser) peekAt(n int) ftok {
+	if p.pos+n < len(p.toks) {
+		return p.toks[p.pos+n]
+	}
+	return p.toks[len(p.toks)-1]
+}
+
+func (p *fparser) next() ftok {
+	t := p.peek()
+	if p.pos < len(p.toks)-1 {
+		p.pos++
+	}
+	return t
+}
+
+//accept consumes the next token if it's one of the given operators or keywords.
+func (p *fparser) accept(ops ...string) bool {
+	t := p.peek()
+	if t.kind != ftOp && t.kind != ftIdent {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+//binary parses a left associative chain of operands separated by the given operators.
+func (p *fparser) binary(operand func() (fnode, error), ops ...string) (n fnode, e error) {
+	if n, e = operand(); e != nil {
+		return
+	}
+	for {
+		t := p.peek()
+		if !p.accept(ops...) {
+			return
+		}
+		r, e := operand()
+		if e != nil {
+			return nil, e
+		}
+		n = &fbinary{op: t.text, l: n, r: r}
+	}
+}
+
+func (p *fparser) parseExpr() (fnode, error) {
+	return p.binary(p.parseAnd, "OR", "||")
+}
+
+func (p *fparser) parseAnd() (fnode, error) {
+	return p.binary(p.parseCmp, "AND", "&&")
+}
+
+func (p *fparser) parseCmp() (fnode, error) {
+	return p.binary(p.parseAdd, ">", "<", ">=", "<=", "=", "<>", "!=")
+}
+
+func (p *fparser) parseAdd() (fnode, error) {
+	return p.binary(p.parseMul, "+", "-")
+}
+
+func (p *fparser) parseMul() (fnode, error) {
+	return p.binary(p.parseUnary, "*", "/")
+}
+
+func (p *fparser) parseUnary() (fnode, error) {
+	if p.accept("-") {
+		n, e := p.parseUnary()
+		if e != nil {
+			return nil, e
+		}
+		return &fbinary{op: "-", l: fconst(0), r: n}, nil
+	}
+	if p.accept("+") {
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func (p *fparser) parsePrimary() (fnode, error) {
+	t := p.next()
+	switch t.kind {
+	case ftNum:
+		return fconst(t.num), nil
+	case ftIdent:
+		if p.accept("(") {
+			return p.parseCall(t)
+		}
+		if p.vars[t.text] {
+			return fvar(t.text), nil
+		}
+		if _, ok := fseries[t.text]; ok {
+			return fvar(t.text), nil
+		}
+		return nil, errors.Errorf("undefined variable %v", t)
+	case ftOp:
+		if t.text == "(" {
+			n, e := p.parseExpr()
+			if e != nil {
+				return nil, e
+			}
+			if !p.accept(")") {
+				return nil, errors.Errorf("expecting ')' but got %v", p.peek())
+			}
+			return n, nil
+		}
+	}
+	return nil, errors.Errorf("unexpected %v", t)
+}
+
+func (p *fparser) parseCall(name ftok) (fnode, error) {
+	fn, ok := ffuncs[name.text]
+	if !ok {
+		return nil, errors.Errorf("undefined function %v", name)
+	}
+	c := &fcall{name: name.text, fn: fn}
+	if !p.accept(")") {
+		for {
+			a, e := p.parseExpr()
+			if e != nil {
+				return nil, e
+			}
+			c.args = append(c.args, a)
+			if p.accept(")") {
+				break
+			}
+			if !p.accept(",") {
+				return nil, errors.Errorf("expecting ',' or ')' but got %v", p.peek())
+			}
+		}
+	}
+	if len(c.args) != fn.arity {
+		return nil, errors.Errorf("function %s expects %d arguments but got %d at %d",
+			name.text, fn.arity, len(c.args), name.pos)
+	}
+	return c, nil
+}
@@ -0,0 +1,378 @@
+package indc
+
+import (
+	"math"
+
+	"github.com/montanaflynn/stats"
+
+	"github.com/carusyte/stock/model"
+)
+
+//FormulaResult holds the output series of an evaluated formula, aligned with the input bars.
+type FormulaResult struct {
+	//Names of the outputs in the order of definition.
+	Names []string
+	//Series of the outputs, in the same order as Names.
+	Series [][]float64
+}
+
+//Get returns the output series of the given name, or nil if no such output exists.
+func (r *FormulaResult) Get(name string) []float64 {
+	for i, n := range r.Names {
+		if n == name {
+			return r.Series[i]
+		}
+	}
+	return nil
+}
+
+//Last returns the value of the last output at the last bar, or NaN if there's no bar.
+func (r *FormulaResult) Last() float64 {
+	if len(r.Series) == 0 {
+		return math.NaN()
+	}
+	s := r.Series[len(r.Series)-1]
+	if len(s) == 0 {
+		return math.NaN()
+	}
+	return s[len(s)-1]
+}
+
+//Eval evaluates the formula over the basic series of the trade data.
+func (f *Formula) Eval(td *model.TradeData) *FormulaResult {
+	return f.EvalBasic(td.Base)
+}
+
+//EvalBasic evaluates the formula over the given bars. Values not available for a bar,
+//e.g. REF beyond the first bar or into the future, are NaN. Conditions yield 1 if satisfied, otherwise 0.
+func (f *Formula) EvalBasic(src []*model.TradeDataBasic) *FormulaResult {
+	ctx := &fctx{src: src, vars: make(map[string][]float64)}
+	r := new(FormulaResult)
+	for _, s := range f.stmts {
+		v := s.expr.eval(ctx)
+		ctx.vars[s.name] = v
+		if s.output {
+			r.Names = append(r.Names, s.name)
+			r.Series = append(r.Series, v)
+		}
+	}
+	return r
+}
+
+type fctx struct {
+	src  []*model.TradeDataBasic
+	vars map[string][]float64
+}
+
+//fill creates a series of constant value.
+func (c *fctx) fill(v float64) []float64 {
+	s := make([]float64, len(c.src))
+	for i := range s {
+		s[i] = v
+	}
+	return s
+}
+
+type fnode interface {
+	eval(c *fctx) []float64
+}
+
+type fconst float64
+
+func (n fconst) eval(c *fctx) []float64 {
+	return c.fill(float64(n))
+}
+
+type fvar string
+
+func (n fvar) eval(c *fctx) []float64 {
+	if v, ok := c.vars[string(n)]; ok {
+		return v
+	}
+	f := fseries[string(n)]
+	s := make([]float64, len(c.src))
+	for i, b := range c.src {
+		s[i] = f(b)
+	}
+	c.vars[string(n)] = s
+	return s
+}
+
+//fseries maps the built-in variables to the fields of the bars.
+var fseries = map[string]func(b *model.TradeDataBasic) float64{
+	"O":      func(b *model.TradeDataBasic) float64 { return b.Open },
+	"OPEN":   func(b *model.TradeDataBasic) float64 { return b.Open },
+	"H":      func(b *model.TradeDataBasic) float64 { return b.High },
+	"HIGH":   func(b *model.TradeDataBasic) float64 { return b.High },
+	"L":      func(b *model.TradeDataBasic) float64 { return b.Low },
+	"LOW":    func(b *model.TradeDataBasic) float64 { return b.Low },
+	"C":      func(b *model.TradeDataBasic) float64 { return b.Close },
+	"CLOSE":  func(b *model.TradeDataBasic) float64 { return b.Close },
+	"V":      func(b *model.TradeDataBasic) float64 { return b.Volume.Float64 },
+	"VOL":    func(b *model.TradeDataBasic) float64 { return b.Volume.Float64 },
+	"AMO":    func(b *model.TradeDataBasic) float64 { return b.Amount.Float64 },
+	"AMOUNT": func(b *model.TradeDataBasic) float64 { return b.Amount.Float64 },
+}
+
+type fbinary struct {
+	op   string
+	l, r fnode
+}
+
+func (n *fbinary) eval(c *fctx) []float64 {
+	l, r := n.l.eval(c), n.r.eval(c)
+	s := make([]float64, len(l))
+	for i := range s {
+		a, b := l[i], r[i]
+		switch n.op {
+		case "+":
+			s[i] = a + b
+		case "-":
+			s[i] = a - b
+		case "*":
+			s[i] = a * b
+		case "/":
+			if b == 0 {
+				s[i] = math.NaN()
+			} else {
+				s[i] = a / b
+			}
+		case ">":
+			s[i] = fbool(a > b)
+		case "<":
+			s[i] = fbool(a < b)
+		case ">=":
+			s[i] = fbool(a >= b)
+		case "<=":
+			s[i] = fbool(a <= b)
+		case "=":
+			s[i] = fbool(a == b)
+		case "<>", "!=":
+			s[i] = fbool(!math.IsNaN(a) && !math.IsNaN(b) && a != b)
+		case "AND", "&&":
+			s[i] = fbool(ftrue(a) && ftrue(b))
+		case "OR", "||":
+			s[i] = fbool(ftrue(a) || ftrue(b))
+		}
+	}
+	return s
+}
+
+type fcall struct {
+	name string
+	fn   *ffunc
+	args []fnode
+}
+
+func (n *fcall) eval(c *fctx) []float64 {
+	args := make([][]float64, len(n.args))
+	for i, a := range n.args {
+		args[i] = a.eval(c)
+	}
+	return n.fn.eval(args)
+}
+
+func fbool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func ftrue(v float64) bool {
+	return v != 0 && !math.IsNaN(v)
+}
+
+//fperiod converts the period argument at the given bar into the start index of the window.
+//Non-positive periods span from the first bar.
+func fperiod(n []float64, i int) int {
+	p := int(n[i])
+	if math.IsNaN(n[i]) || p <= 0 || p > i+1 {
+		return 0
+	}
+	return i - p + 1
+}
+
+type ffunc struct {
+	arity int
+	eval  func(args [][]float64) []float64
+}
+
+//ffuncs are the functions available in formulas.
+var ffuncs = map[string]*ffunc{
+	"MA":     {2, fwindowed(MA)},
+	"STD":    {2, fwindowed(fstd)},
+	"AVEDEV": {2, fwindowed(AVEDEV)},
+	"SUM": {2, func(a [][]float64) []float64 {
+		return fmap(a[0], func(i int) float64 {
+			return SUM(a[0], i, i-fperiod(a[1], i)+1)
+		})
+	}},
+	"EMA": {2, func(a [][]float64) []float64 {
+		s := make([]float64, len(a[0]))
+		pre := math.NaN()
+		for i, x := range a[0] {
+			if math.IsNaN(pre) {
+				pre = x
+			} else if !math.IsNaN(x) {
+				pre = EMA(x, pre, a[1][i])
+			}
+			s[i] = pre
+		}
+		return s
+	}},
+	"SMA": {3, func(a [][]float64) []float64 {
+		s := make([]float64, len(a[0]))
+		pre, first := 0., true
+		for i, x := range a[0] {
+			if math.IsNaN(x) {
+				if first {
+					s[i] = math.NaN()
+				} else {
+					s[i] = pre
+				}
+				continue
+			}
+			pre = smaNext(x, pre, int(a[1][i]), int(a[2][i]), first)
+			first = false
+			s[i] = pre
+		}
+		return s
+	}},
+	"REF": {2, func(a [][]float64) []float64 {
+		return fmap(a[0], func(i int) float64 {
+			//negative periods would look ahead
+			j := i - int(a[1][i])
+			if math.IsNaN(a[1][i]) || a[1][i] < 0 || j < 0 {
+				return math.NaN()
+			}
+			return a[0][j]
+		})
+	}},
+	"HHV": {2, func(a [][]float64) []float64 {
+		return fmap(a[0], func(i int) float64 {
+			return fwindow(a[0], fperiod(a[1], i), i, math.Max)
+		})
+	}},
+	"LLV": {2, func(a [][]float64) []float64 {
+		return fmap(a[0], func(i int) float64 {
+			return fwindow(a[0], fperiod(a[1], i), i, math.Min)
+		})
+	}},
+	"COUNT": {2, func(a [][]float64) []float64 {
+		return fmap(a[0], func(i int) float64 {
+			cnt := 0.
+			for j := fperiod(a[1], i); j <= i; j++ {
+				cnt += fbool(ftrue(a[0][j]))
+			}
+			return cnt
+		})
+	}},
+	"EVERY": {2, func(a [][]float64) []float64 {
+		return fmap(a[0], func(i int) float64 {
+			for j := fperiod(a[1], i); j <= i; j++ {
+				if !ftrue(a[0][j]) {
+					return 0
+				}
+			}
+			return 1
+		})
+	}},
+	"EXIST": {2, func(a [][]float64) []float64 {
+		return fmap(a[0], func(i int) float64 {
+			for j := fperiod(a[1], i); j <= i; j++ {
+				if ftrue(a[0][j]) {
+					return 1
+				}
+			}
+			return 0
+		})
+	}},
+	"BARSLAST": {1, func(a [][]float64) []float64 {
+		last := -1
+		return fmap(a[0], func(i int) float64 {
+			if ftrue(a[0][i]) {
+				last = i
+			}
+			if last < 0 {
+				return math.NaN()
+			}
+			return float64(i - last)
+		})
+	}},
+	"CROSS": {2, func(a [][]float64) []float64 {
+		return fmap(a[0], func(i int) float64 {
+			return fbool(i > 0 && a[0][i] > a[1][i] && a[0][i-1] <= a[1][i-1])
+		})
+	}},
+	"IF": {3, func(a [][]float64) []float64 {
+		return fmap(a[0], func(i int) float64 {
+			if ftrue(a[0][i]) {
+				return a[1][i]
+			}
+			return a[2][i]
+		})
+	}},
+	"NOT": {1, func(a [][]float64) []float64 {
+		return fmap(a[0], func(i int) float64 { return fbool(!ftrue(a[0][i])) })
+	}},
+	"ABS": {1, func(a [][]float64) []float64 {
+		return fmap(a[0], func(i int) float64 { return math.Abs(a[0][i]) })
+	}},
+	"MAX": {2, func(a [][]float64) []float64 {
+		return fmap(a[0], func(i int) float64 { return math.Max(a[0][i], a[1][i]) })
+	}},
+	"MIN": {2, func(a [][]float64) []float64 {
+		return fmap(a[0], func(i int) float64 { return math.Min(a[0][i], a[1][i]) })
+	}},
+}
+
+//fwindowed adapts a moving window function of the indc package. Values of bars
+//with non-positive period or without a full window are NaN.
+func fwindowed(f func(vals []float64, curIdx, n int) float64) func(a [][]float64) []float64 {
+	return func(a [][]float64) []float64 {
+		return fmap(a[0], func(i int) float64 {
+			if math.IsNaN(a[1][i]) || int(a[1][i]) < 1 || i+1 < int(a[1][i]) {
+				return math.NaN()
+			}
+			return f(a[0], i, int(a[1][i]))
+		})
+	}
+}
+
+//fstd calculates the sample standard deviation of the window as STD of TDX does, which is NaN for a single bar.
+func fstd(vals []float64, curIdx, n int) float64 {
+	if n < 2 {
+		return math.NaN()
+	}
+	std, e := stats.StandardDeviationSample(vals[curIdx-n+1 : curIdx+1])
+	if e != nil {
+		log.Panicf("failed to calculate standard deviation: %+v", e)
+	}
+	return std
+}
+
+//fmap builds a series of the same length as src with the values returned by f for each index.
+func fmap(src []float64, f func(i int) float64) []float64 {
+	s := make([]float64, len(src))
+	for i := range s {
+		s[i] = f(i)
+	}
+	return s
+}
+
+//fwindow folds the values within [start, end] using f, ignoring NaN.
+func fwindow(src []float64, start, end int, f func(a, b float64) float64) float64 {
+	r := math.NaN()
+	for j := start; j <= end; j++ {
+		if math.IsNaN(src[j]) {
+			continue
+		}
+		if math.IsNaN(r) {
+			r = src[j]
+		} else {
+			r = f(r, src[j])
+		}
+	}
+	return r
+}
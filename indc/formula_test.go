@@ -0,0 +1,93 @@
+package indc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFormulaFunctions(t *testing.T) {
+	f, e := ParseFormula(`{reference values} MA3:MA(C,3);
+		REF1:ref(c,1);
+		UP:=C>REF(C,1);
+		CNT:COUNT(UP,3);
+		HHV(H,3);
+		LLV(L,2);
+		BARSLAST(NOT(UP) AND C<>REF(C,1));
+		CROSS(C,10.4);
+		IF(C>10.3,1,-1)`)
+	if e != nil {
+		t.Fatal(e)
+	}
+	r := f.EvalBasic(refTradeData())
+	nan := math.NaN()
+	want := map[string][]float64{
+		"MA3":  {nan, nan, 10.3, 10.2, 10.166667, 10.433333},
+		"REF1": {nan, 10.2, 10.6, 10.1, 9.9, 10.5},
+		"CNT":  {0, 1, 1, 1, 1, 2},
+		"OUT1": {10.5, 10.8, 10.8, 10.8, 10.7, 11.0},
+		"OUT2": {9.8, 9.8, 10.0, 9.7, 9.7, 9.9},
+		"OUT3": {nan, nan, 0, 0, 1, 2},
+		"OUT4": {0, 1, 0, 0, 1, 0},
+		"OUT5": {-1, 1, -1, -1, 1, 1},
+	}
+	if len(r.Names) != len(want) {
+		t.Fatalf("expected %d outputs, got %v", len(want), r.Names)
+	}
+	for n, w := range want {
+		assertSeries(t, n, r.Get(n), w)
+	}
+	if r.Get("UP") != nil {
+		t.Error("intermediate variable should not be an output")
+	}
+	if r.Last() != 1 {
+		t.Errorf("expected last value 1, got %f", r.Last())
+	}
+}
+
+func TestFormulaSTDAndREF(t *testing.T) {
+	r := MustParseFormula(`STD3:STD(C,3); STD1:STD(C,1); NEXT:REF(C,-1);`).EvalBasic(refTradeData())
+	nan := math.NaN()
+	//STD of TDX is the sample standard deviation, e.g. STD(C,3) = 0.264575 on the third bar
+	assertSeries(t, "STD3", r.Get("STD3"), []float64{nan, nan, 0.264575, 0.360555, 0.305505, 0.503322})
+	assertSeries(t, "STD1", r.Get("STD1"), []float64{nan, nan, nan, nan, nan, nan})
+	//no look-ahead
+	assertSeries(t, "NEXT", r.Get("NEXT"), []float64{nan, nan, nan, nan, nan, nan})
+}
+
+func TestFormulaKDJ(t *testing.T) {
+	f := MustParseFormula(`RSV:=(CLOSE-LLV(LOW,9))/(HHV(HIGH,9)-LLV(LOW,9))*100;
+		K:SMA(RSV,3,1);
+		D:SMA(K,3,1);
+		J:3*K-2*D;`)
+	src := randTradeData(200)
+	r := f.EvalBasic(src)
+	spec, _ := Lookup("kdj")
+	vals := spec.Calculate(src)
+	for j, n := range []string{"K", "D", "J"} {
+		s := r.Get(n)
+		for i, v := range vals {
+			if math.Abs(s[i]-v[j]) > 1e-9 {
+				t.Fatalf("%s[%d]: expected %f, got %f", n, i, v[j], s[i])
+			}
+		}
+	}
+}
+
+func TestParseFormulaError(t *testing.T) {
+	for _, txt := range []string{
+		"",
+		"X:=C;",
+		"MA(C)",
+		"FOO(C,5)",
+		"C+NOSUCHVAR",
+		"(C+1",
+		"C 1",
+		"X:=X+1;X",
+		"{unterminated C",
+		"C#2",
+	} {
+		if _, e := ParseFormula(txt); e == nil {
+			t.Errorf("expected error for formula %q", txt)
+		}
+	}
+}
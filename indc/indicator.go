@@ -21,6 +21,18 @@ func MA(vals []float64, curIdx, n int) float64 {
 	return nu / float64(n)
 }
 
+//SUM calculates the sum of the last n values up to curIdx.
+func SUM(vals []float64, curIdx, n int) float64 {
+	if curIdx >= len(vals) {
+		log.Panicf("invalid curIdx:%d, maximum:%d", curIdx, len(vals)-1)
+	}
+	sum := 0.
+	for i := int(math.Max(0, float64(curIdx-n+1))); i <= curIdx; i++ {
+		sum += vals[i]
+	}
+	return sum
+}
+
 //STD calculates standard deviation for given values.
 func STD(vals []float64, curIdx, n int) float64 {
 	if curIdx >= len(vals) {
@@ -39,12 +51,10 @@ func STD(vals []float64, curIdx, n int) float64 {
 func SMA(src []float64, n, m int) []float64 {
 	r := make([]float64, len(src))
 	for x, i := range src {
-		fm := float64(m)
-		fn := float64(n)
 		if x == 0 {
-			r[x] = fm * i / fn
+			r[x] = smaNext(i, 0, n, m, true)
 		} else {
-			r[x] = (fm*i + (fn-fm)*r[x-1]) / fn
+			r[x] = smaNext(i, r[x-1], n, m, false)
 		}
 		if math.IsNaN(r[x]) {
 			log.Printf("NaN detected in SMA, x[%d], i[%f], m[%d], n[%d], %+v", x, i, m, n, src)
@@ -54,6 +64,16 @@ func SMA(src []float64, n, m int) []float64 {
 	return r
 }
 
+//smaNext calculates the next SMA value given the previous one.
+func smaNext(x, pre float64, n, m int, first bool) float64 {
+	fm := float64(m)
+	fn := float64(n)
+	if first {
+		return fm * x / fn
+	}
+	return (fm*x + (fn-fm)*pre) / fn
+}
+
 //EMA calculates exponential moving average for given values.
 //formula: Y = [2*X + (N-1)*Y']/(N+1)
 func EMA(x, pre, n float64) float64 {
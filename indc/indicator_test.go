@@ -1,7 +1,109 @@
 package indc
 
-import "testing"
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/carusyte/stock/model"
+)
 
 func TestEMA(t *testing.T) {
 
 }
+
+func TestSpecs(t *testing.T) {
+	src := randTradeData(300)
+	specs, e := Specs()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(specs) == 0 {
+		t.Fatal("no indicator registered")
+	}
+	for _, s := range specs {
+		vals := s.Calculate(src)
+		for i, v := range vals {
+			if len(v) != len(s.Cols) {
+				t.Fatalf("%s row %d has %d values, %d columns declared", s.Name, i, len(v), len(s.Cols))
+			}
+		}
+	}
+	if _, e = Specs("kdj", "no_such_indicator"); e == nil {
+		t.Error("expected error for unregistered indicator")
+	}
+}
+
+//randTradeData generates a random walk series of trade data.
+func randTradeData(n int) []*model.TradeDataBasic {
+	r := rand.New(rand.NewSource(7))
+	src := make([]*model.TradeDataBasic, n)
+	c := 10.
+	for i := range src {
+		o := c * (1 + (r.Float64()-0.5)*0.04)
+		c = o * (1 + (r.Float64()-0.5)*0.06)
+		h := o
+		if c > h {
+			h = c
+		}
+		l := o + c - h
+		h *= 1 + r.Float64()*0.02
+		l *= 1 - r.Float64()*0.02
+		src[i] = &model.TradeDataBasic{
+			Code:   "000001",
+			Date:   fmt.Sprintf("2019-%02d-%02d", i/28%12+1, i%28+1),
+			Klid:   i,
+			Open:   o,
+			High:   h,
+			Low:    l,
+			Close:  c,
+			Volume: sql.NullFloat64{Float64: 1e6 * (1 + r.Float64()), Valid: true},
+			Amount: sql.NullFloat64{Float64: 1e7 * (1 + r.Float64()), Valid: true},
+		}
+	}
+	return src
+}
+
+//refTradeData returns a short series used to verify indicators against hand-computed values.
+func refTradeData() []*model.TradeDataBasic {
+	ohlcva := [][]float64{
+		{10, 10.5, 9.8, 10.2, 1000, 10100},
+		{10.2, 10.8, 10.1, 10.6, 1500, 15900},
+		{10.6, 10.7, 10.0, 10.1, 1200, 12240},
+		{10.1, 10.3, 9.7, 9.9, 800, 7920},
+		{9.9, 10.6, 9.9, 10.5, 2000, 20800},
+		{10.5, 11.0, 10.4, 10.9, 2500, 27000},
+	}
+	src := make([]*model.TradeDataBasic, len(ohlcva))
+	for i, v := range ohlcva {
+		src[i] = &model.TradeDataBasic{
+			Code:   "000001",
+			Date:   fmt.Sprintf("2019-01-%02d", i+2),
+			Klid:   i,
+			Open:   v[0],
+			High:   v[1],
+			Low:    v[2],
+			Close:  v[3],
+			Volume: sql.NullFloat64{Float64: v[4], Valid: true},
+			Amount: sql.NullFloat64{Float64: v[5], Valid: true},
+		}
+	}
+	return src
+}
+
+func assertSeries(t *testing.T, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: expected %d values, got %d", name, len(want), len(got))
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) && math.IsNaN(got[i]) {
+			continue
+		}
+		if math.IsNaN(got[i]) || math.Abs(got[i]-want[i]) > 1e-6 {
+			t.Errorf("%s[%d]: expected %.6f, got %.6f", name, i, want[i], got[i])
+		}
+	}
+}
@@ -6,6 +6,21 @@ import (
 	"github.com/carusyte/stock/model"
 )
 
+func init() {
+	Register(&Spec{
+		Name:   "kdj",
+		Params: []float64{9, 3, 3},
+		Cols:   []string{"KDJ_K", "KDJ_D", "KDJ_J"},
+		WarmUp: 9,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return toRows(KDJ(src, int(p[0]), int(p[1]), int(p[2])), func(idc *model.Indicator) []float64 {
+				return []float64{idc.KDJ_K, idc.KDJ_D, idc.KDJ_J}
+			})
+		},
+		Step: stepKDJ,
+	})
+}
+
 //KDJ calculates KDJ indicator for the given parameters
 func KDJ(src []*model.TradeDataBasic, n, m1, m2 int) []*model.Indicator {
 	r := make([]*model.Indicator, len(src))
@@ -15,14 +30,7 @@ func KDJ(src []*model.TradeDataBasic, n, m1, m2 int) []*model.Indicator {
 		r[i].Code = s.Code
 		r[i].Date = s.Date[:10]
 		r[i].Klid = s.Klid
-		bg := int(math.Max(float64(i-n+1), 0))
-		llv := LLV(src[bg:i+1], "Low")
-		hhv := HHV(src[bg:i+1], "High")
-		if llv != hhv {
-			rsv[i] = (s.Close - llv) / (hhv - llv) * 100
-		} else {
-			rsv[i] = 1
-		}
+		rsv[i] = kdjRSV(src, i, n)
 	}
 	a := SMA(rsv, m1, 1)
 	b := SMA(a, m2, 1)
@@ -34,6 +42,17 @@ func KDJ(src []*model.TradeDataBasic, n, m1, m2 int) []*model.Indicator {
 	return r
 }
 
+//kdjRSV calculates the raw stochastic value of the bar at curIdx.
+func kdjRSV(src []*model.TradeDataBasic, curIdx, n int) float64 {
+	bg := int(math.Max(float64(curIdx-n+1), 0))
+	llv := LLV(src[bg:curIdx+1], "Low")
+	hhv := HHV(src[bg:curIdx+1], "High")
+	if llv != hhv {
+		return (src[curIdx].Close - llv) / (hhv - llv) * 100
+	}
+	return 1
+}
+
 //DeftKDJ calculates KDJ indicator using default parameters (9,3,3)
 func DeftKDJ(src []*model.TradeDataBasic) []*model.Indicator {
 	return KDJ(src, 9, 3, 3)
@@ -4,6 +4,21 @@ import (
 	"github.com/carusyte/stock/model"
 )
 
+func init() {
+	Register(&Spec{
+		Name:   "macd",
+		Params: []float64{12, 26, 9},
+		Cols:   []string{"MACD", "MACD_diff", "MACD_dea"},
+		WarmUp: 26,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return toRows(MACD(src, p[0], p[1], p[2]), func(idc *model.Indicator) []float64 {
+				return []float64{idc.MACD, idc.MACD_diff, idc.MACD_dea}
+			})
+		},
+		Step: stepMACD,
+	})
+}
+
 //MACD calculates MACD indicator for the given parameters
 func MACD(src []*model.TradeDataBasic, nshort, nlong, m float64) []*model.Indicator {
 	r := make([]*model.Indicator, len(src))
@@ -0,0 +1,52 @@
+package indc
+
+import (
+	"github.com/carusyte/stock/model"
+)
+
+func init() {
+	Register(&Spec{
+		Name:   "mfi",
+		Params: []float64{14},
+		Cols:   []string{"MFI"},
+		WarmUp: 14,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return zipRows(MFI(src, int(p[0])))
+		},
+	})
+}
+
+//MFI calculates Money Flow Index for the given parameters.
+//formula: TYP:=(HIGH+LOW+CLOSE)/3; V1:=SUM(IF(TYP>REF(TYP,1),TYP*VOL,0),N)/SUM(IF(TYP<REF(TYP,1),TYP*VOL,0),N);
+//MFI:=100-(100/(1+V1))
+func MFI(src []*model.TradeDataBasic, n int) []float64 {
+	r := make([]float64, len(src))
+	typ := make([]float64, len(src))
+	pos := make([]float64, len(src))
+	neg := make([]float64, len(src))
+	for i, s := range src {
+		typ[i] = (s.High + s.Low + s.Close) / 3.
+		if i > 0 {
+			if typ[i] > typ[i-1] {
+				pos[i] = typ[i] * s.Volume.Float64
+			} else if typ[i] < typ[i-1] {
+				neg[i] = typ[i] * s.Volume.Float64
+			}
+		}
+		p, m := SUM(pos, i, n), SUM(neg, i, n)
+		switch {
+		case m != 0:
+			r[i] = 100. - 100./(1.+p/m)
+		case p != 0:
+			r[i] = 100.
+		default:
+			r[i] = 50.
+		}
+	}
+	return r
+}
+
+//DeftMFI calculates MFI indicator using default parameters (14)
+func DeftMFI(src []*model.TradeDataBasic) []float64 {
+	return MFI(src, 14)
+}
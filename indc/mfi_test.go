@@ -0,0 +1,7 @@
+package indc
+
+import "testing"
+
+func TestMFI(t *testing.T) {
+	assertSeries(t, "MFI", MFI(refTradeData(), 3), []float64{50, 100, 56.109726, 43.697401, 50.455729, 85.648287})
+}
@@ -0,0 +1,54 @@
+package indc
+
+import (
+	"github.com/carusyte/stock/model"
+)
+
+func init() {
+	Register(&Spec{
+		Name:   "obv",
+		Params: []float64{},
+		Cols:   []string{"OBV"},
+		WarmUp: 1,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return zipRows(OBV(src))
+		},
+		Step: func(st *State, src []*model.TradeDataBasic, p []float64) [][]float64 {
+			obv, pc := st.carried(0), st.carried(1)
+			rows := make([][]float64, len(src))
+			for i, s := range src {
+				obv = obvNext(obv, pc, s, st.Count+i == 0)
+				pc = s.Close
+				rows[i] = []float64{obv}
+			}
+			st.Vals = []float64{obv, pc}
+			return rows
+		},
+	})
+}
+
+//OBV calculates On-Balance Volume. The accumulation starts from 0 at the first bar.
+//formula: OBV:=SUM(IF(CLOSE>REF(CLOSE,1),VOL,IF(CLOSE<REF(CLOSE,1),-VOL,0)),0)
+func OBV(src []*model.TradeDataBasic) []float64 {
+	r := make([]float64, len(src))
+	obv, pc := 0., 0.
+	for i, s := range src {
+		obv = obvNext(obv, pc, s, i == 0)
+		pc = s.Close
+		r[i] = obv
+	}
+	return r
+}
+
+func obvNext(pre, pc float64, s *model.TradeDataBasic, first bool) float64 {
+	switch {
+	case first:
+		return 0
+	case s.Close > pc:
+		return pre + s.Volume.Float64
+	case s.Close < pc:
+		return pre - s.Volume.Float64
+	default:
+		return pre
+	}
+}
@@ -0,0 +1,7 @@
+package indc
+
+import "testing"
+
+func TestOBV(t *testing.T) {
+	assertSeries(t, "OBV", OBV(refTradeData()), []float64{0, 1500, 300, -500, 1500, 4000})
+}
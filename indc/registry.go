@@ -0,0 +1,114 @@
+package indc
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/carusyte/stock/model"
+	"github.com/pkg/errors"
+)
+
+//Spec declares an indicator that can be calculated and persisted without knowing its concrete type.
+type Spec struct {
+	//Name uniquely identifies the indicator. It's also used as the suffix of the storage table.
+	Name string
+	//Params are the parameters passed to Calc.
+	Params []float64
+	//Cols are the names of the output columns, in the same order as the values returned by Calc.
+	Cols []string
+	//WarmUp is the number of leading bars required before the output becomes meaningful.
+	//For indicators without Step, the output of a bar must depend only on the WarmUp bars preceding it.
+	WarmUp int
+	//Calc calculates the indicator for the given trade data. It returns one row of values per bar.
+	Calc func(src []*model.TradeDataBasic, params []float64) [][]float64
+	//Raw indicators are calculated from the non-reinstated klines regardless of the configured source,
+	//e.g. those based on the amount, which is never reinstated.
+	Raw bool
+	//Step continues the calculation from the given state and updates the carried values in it.
+	//Recursive indicators (e.g. based on SMA or EMA) must provide it, windowed ones may leave it nil.
+	Step func(st *State, src []*model.TradeDataBasic, params []float64) [][]float64
+}
+
+var (
+	specs   = make(map[string]*Spec)
+	specsMu sync.RWMutex
+)
+
+//Register adds the indicator spec to the registry. It panics if the name is already taken.
+func Register(s *Spec) {
+	specsMu.Lock()
+	defer specsMu.Unlock()
+	if s == nil || len(s.Name) == 0 || s.Calc == nil || len(s.Cols) == 0 {
+		log.Panicf("invalid indicator spec: %+v", s)
+	}
+	if _, ok := specs[s.Name]; ok {
+		log.Panicf("indicator %s already registered", s.Name)
+	}
+	specs[s.Name] = s
+}
+
+//Lookup returns the registered spec of the given name.
+func Lookup(name string) (s *Spec, ok bool) {
+	specsMu.RLock()
+	defer specsMu.RUnlock()
+	s, ok = specs[name]
+	return
+}
+
+//Specs returns the registered specs of the given names, or all registered specs sorted by name
+//if no name is specified.
+func Specs(names ...string) (r []*Spec, e error) {
+	specsMu.RLock()
+	defer specsMu.RUnlock()
+	if len(names) == 0 {
+		for _, s := range specs {
+			r = append(r, s)
+		}
+		sort.Slice(r, func(i, j int) bool {
+			return r[i].Name < r[j].Name
+		})
+		return
+	}
+	for _, n := range names {
+		s, ok := specs[n]
+		if !ok {
+			return nil, errors.Errorf("indicator %s is not registered", n)
+		}
+		r = append(r, s)
+	}
+	return
+}
+
+//Calculate runs the spec over the given trade data using its declared parameters.
+func (s *Spec) Calculate(src []*model.TradeDataBasic) [][]float64 {
+	vals := s.Calc(src, s.Params)
+	if len(vals) != len(src) {
+		log.Panicf("indicator %s returned %d rows for %d bars", s.Name, len(vals), len(src))
+	}
+	return vals
+}
+
+//toRows converts wide indicator structs into value rows using the given extractor.
+func toRows(r []*model.Indicator, f func(idc *model.Indicator) []float64) [][]float64 {
+	rows := make([][]float64, len(r))
+	for i, idc := range r {
+		rows[i] = f(idc)
+	}
+	return rows
+}
+
+//zipRows combines value series of the same length into rows.
+func zipRows(series ...[]float64) [][]float64 {
+	if len(series) == 0 {
+		return nil
+	}
+	rows := make([][]float64, len(series[0]))
+	for i := range rows {
+		row := make([]float64, len(series))
+		for j, s := range series {
+			row[j] = s[i]
+		}
+		rows[i] = row
+	}
+	return rows
+}
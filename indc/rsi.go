@@ -6,6 +6,21 @@ import (
 	"github.com/carusyte/stock/model"
 )
 
+func init() {
+	Register(&Spec{
+		Name:   "rsi",
+		Params: []float64{6, 12, 24},
+		Cols:   []string{"RSI1", "RSI2", "RSI3"},
+		WarmUp: 24,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return toRows(RSI(src, int(p[0]), int(p[1]), int(p[2])), func(idc *model.Indicator) []float64 {
+				return []float64{idc.RSI1, idc.RSI2, idc.RSI3}
+			})
+		},
+		Step: stepRSI,
+	})
+}
+
 //RSI calculates Relative Strength Indicator for the given parameters
 func RSI(src []*model.TradeDataBasic, n1, n2, n3 int) []*model.Indicator {
 	r := make([]*model.Indicator, len(src))
@@ -27,25 +42,20 @@ func RSI(src []*model.TradeDataBasic, n1, n2, n3 int) []*model.Indicator {
 	for i := range r {
 		idc := &model.Indicator{}
 		r[i] = idc
-		d1 := dens1[i]
-		d2 := dens2[i]
-		d3 := dens3[i]
-		if d1 == 0 {
-			d1 = 0.01
-		}
-		if d2 == 0 {
-			d2 = 0.01
-		}
-		if d3 == 0 {
-			d3 = 0.01
-		}
-		idc.RSI1 = math.Min(100., nums1[i]/d1*100.)
-		idc.RSI2 = math.Min(100., nums2[i]/d2*100.)
-		idc.RSI3 = math.Min(100., nums3[i]/d3*100.)
+		idc.RSI1 = rsiVal(nums1[i], dens1[i])
+		idc.RSI2 = rsiVal(nums2[i], dens2[i])
+		idc.RSI3 = rsiVal(nums3[i], dens3[i])
 	}
 	return r
 }
 
+func rsiVal(num, den float64) float64 {
+	if den == 0 {
+		den = 0.01
+	}
+	return math.Min(100., num/den*100.)
+}
+
 //DeftRSI calculates RSI indicator using default parameters (6,12,24)
 func DeftRSI(src []*model.TradeDataBasic) []*model.Indicator {
 	return RSI(src, 6, 12, 24)
@@ -0,0 +1,83 @@
+package indc
+
+import (
+	"math"
+
+	"github.com/carusyte/stock/model"
+)
+
+func init() {
+	Register(&Spec{
+		Name:   "sar",
+		Params: []float64{0.02, 0.2},
+		Cols:   []string{"SAR", "SAR_trend"},
+		WarmUp: 2,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return zipRows(SAR(src, p[0], p[1]))
+		},
+		Step: func(st *State, src []*model.TradeDataBasic, p []float64) [][]float64 {
+			bars := append(append([]*model.TradeDataBasic(nil), st.Bars...), src...)
+			off := len(st.Bars)
+			v := make([]float64, 4)
+			copy(v, st.Vals)
+			rows := make([][]float64, len(src))
+			for i := range src {
+				sarStep(bars, off+i, v, st.Count+i == 0, p[0], p[1])
+				rows[i] = []float64{v[3], v[0]}
+			}
+			st.Vals = v
+			return rows
+		},
+	})
+}
+
+//SAR calculates Parabolic Stop and Reverse for the given acceleration step and limit, returning the
+//SAR values and the trend (1 for up, -1 for down). The first bar is assumed to start an uptrend.
+func SAR(src []*model.TradeDataBasic, step, limit float64) (sar, trend []float64) {
+	sar = make([]float64, len(src))
+	trend = make([]float64, len(src))
+	v := make([]float64, 4)
+	for i := range src {
+		sarStep(src, i, v, i == 0, step, limit)
+		sar[i] = v[3]
+		trend[i] = v[0]
+	}
+	return
+}
+
+//sarStep advances the SAR state v, which holds [trend, acceleration factor, extreme point, SAR],
+//to the bar at curIdx.
+func sarStep(src []*model.TradeDataBasic, curIdx int, v []float64, first bool, step, limit float64) {
+	s := src[curIdx]
+	if first {
+		v[0], v[1], v[2], v[3] = 1, step, s.High, s.Low
+		return
+	}
+	trend, af, ep, sar := v[0], v[1], v[2], v[3]
+	sar += af * (ep - sar)
+	if trend > 0 {
+		for j := curIdx - 1; j >= 0 && j >= curIdx-2; j-- {
+			sar = math.Min(sar, src[j].Low)
+		}
+		if s.Low < sar {
+			trend, sar, ep, af = -1, ep, s.Low, step
+		} else if s.High > ep {
+			ep, af = s.High, math.Min(af+step, limit)
+		}
+	} else {
+		for j := curIdx - 1; j >= 0 && j >= curIdx-2; j-- {
+			sar = math.Max(sar, src[j].High)
+		}
+		if s.High > sar {
+			trend, sar, ep, af = 1, ep, s.High, step
+		} else if s.Low < ep {
+			ep, af = s.Low, math.Min(af+step, limit)
+		}
+	}
+	v[0], v[1], v[2], v[3] = trend, af, ep, sar
+}
+
+//DeftSAR calculates SAR indicator using default parameters (0.02,0.2)
+func DeftSAR(src []*model.TradeDataBasic) (sar, trend []float64) {
+	return SAR(src, 0.02, 0.2)
+}
@@ -0,0 +1,9 @@
+package indc
+
+import "testing"
+
+func TestSAR(t *testing.T) {
+	sar, trend := SAR(refTradeData(), 0.02, 0.2)
+	assertSeries(t, "SAR", sar, []float64{9.8, 9.8, 9.8, 10.8, 10.778, 9.7})
+	assertSeries(t, "SAR_trend", trend, []float64{1, 1, 1, -1, -1, 1})
+}
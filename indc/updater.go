@@ -0,0 +1,161 @@
+package indc
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/carusyte/stock/model"
+)
+
+//State carries whatever is needed to continue calculating an indicator from the last consumed bar.
+type State struct {
+	//Params the state is built with. A state is only valid for the same parameters.
+	Params []float64
+	//Count is the number of bars consumed so far.
+	Count int
+	//Klid of the last consumed bar.
+	Klid int
+	//Date of the last consumed bar.
+	Date string
+	//Close of the last consumed bar, used to detect revised history.
+	Close float64
+	//Bars are the trailing bars retained for window based calculation.
+	Bars []*model.TradeDataBasic
+	//Vals are the recursive values (e.g. SMA, EMA) carried forward.
+	Vals []float64
+}
+
+//NewState creates an empty state for the spec.
+func (s *Spec) NewState() *State {
+	return &State{Params: s.Params, Klid: -1}
+}
+
+//Clone returns a copy of the state which can be advanced independently.
+func (st *State) Clone() *State {
+	c := *st
+	c.Params = append([]float64(nil), st.Params...)
+	c.Bars = append([]*model.TradeDataBasic(nil), st.Bars...)
+	c.Vals = append([]float64(nil), st.Vals...)
+	return &c
+}
+
+//Valid checks whether the state can be continued by the spec with the given bar as its last consumed bar.
+func (st *State) Valid(s *Spec, last *model.TradeDataBasic) bool {
+	if st == nil || len(st.Params) != len(s.Params) {
+		return false
+	}
+	for i, p := range st.Params {
+		if p != s.Params[i] {
+			return false
+		}
+	}
+	if st.Count == 0 {
+		return true
+	}
+	return last != nil && last.Klid == st.Klid && last.Date[:10] == st.Date && last.Close == st.Close
+}
+
+//Update calculates the indicator for the bars following those already consumed by the state,
+//and advances the state past the last given bar. Feeding a series in chunks yields exactly
+//the same values as calculating it at once with Calculate.
+func (s *Spec) Update(st *State, src []*model.TradeDataBasic) (vals [][]float64) {
+	if len(src) == 0 {
+		return
+	}
+	if s.Step != nil {
+		vals = s.Step(st, src, s.Params)
+	} else {
+		all := make([]*model.TradeDataBasic, 0, len(st.Bars)+len(src))
+		all = append(all, st.Bars...)
+		all = append(all, src...)
+		vals = s.Calc(all, s.Params)[len(st.Bars):]
+	}
+	if len(vals) != len(src) {
+		log.Panicf("indicator %s returned %d rows for %d bars", s.Name, len(vals), len(src))
+	}
+	st.Bars = append(st.Bars, src...)
+	if len(st.Bars) > s.WarmUp {
+		st.Bars = append([]*model.TradeDataBasic(nil), st.Bars[len(st.Bars)-s.WarmUp:]...)
+	}
+	last := src[len(src)-1]
+	st.Count += len(src)
+	st.Klid = last.Klid
+	st.Date = last.Date[:10]
+	st.Close = last.Close
+	return
+}
+
+//MarshalState serializes the state for persistence.
+func MarshalState(st *State) ([]byte, error) {
+	return json.Marshal(st)
+}
+
+//UnmarshalState deserializes the state persisted by MarshalState.
+func UnmarshalState(b []byte) (st *State, e error) {
+	st = new(State)
+	e = json.Unmarshal(b, st)
+	return
+}
+
+//carried returns the carried value at index i, or zero for an empty state.
+func (st *State) carried(i int) float64 {
+	if i < len(st.Vals) {
+		return st.Vals[i]
+	}
+	return 0
+}
+
+//stepKDJ continues KDJ calculation from the given state.
+func stepKDJ(st *State, src []*model.TradeDataBasic, p []float64) [][]float64 {
+	n, m1, m2 := int(p[0]), int(p[1]), int(p[2])
+	bars := append(append([]*model.TradeDataBasic(nil), st.Bars...), src...)
+	off := len(st.Bars)
+	a, b := st.carried(0), st.carried(1)
+	rows := make([][]float64, len(src))
+	for i := range src {
+		first := st.Count+i == 0
+		a = smaNext(kdjRSV(bars, off+i, n), a, m1, 1, first)
+		b = smaNext(a, b, m2, 1, first)
+		rows[i] = []float64{a, b, 3*a - 2*b}
+	}
+	st.Vals = []float64{a, b}
+	return rows
+}
+
+//stepMACD continues MACD calculation from the given state.
+func stepMACD(st *State, src []*model.TradeDataBasic, p []float64) [][]float64 {
+	close, diff := st.carried(0), st.carried(1)
+	rows := make([][]float64, len(src))
+	for i, s := range src {
+		d := EMA(s.Close, close, p[0]) - EMA(s.Close, close, p[1])
+		dea := EMA(d, diff, p[2])
+		rows[i] = []float64{2. * (d - dea), d, dea}
+		close = s.Close
+		diff = d
+	}
+	st.Vals = []float64{close, diff}
+	return rows
+}
+
+//stepRSI continues RSI calculation from the given state.
+func stepRSI(st *State, src []*model.TradeDataBasic, p []float64) [][]float64 {
+	ns := []int{int(p[0]), int(p[1]), int(p[2])}
+	pc := st.carried(0)
+	nums := []float64{st.carried(1), st.carried(2), st.carried(3)}
+	dens := []float64{st.carried(4), st.carried(5), st.carried(6)}
+	rows := make([][]float64, len(src))
+	for i, s := range src {
+		first := st.Count+i == 0
+		d := s.Close - pc
+		row := make([]float64, len(ns))
+		for j, n := range ns {
+			nums[j] = smaNext(math.Max(0, d), nums[j], n, 1, first)
+			dens[j] = smaNext(math.Abs(d), dens[j], n, 1, first)
+			row[j] = rsiVal(nums[j], dens[j])
+		}
+		rows[i] = row
+		pc = s.Close
+	}
+	st.Vals = append([]float64{pc}, append(nums, dens...)...)
+	return rows
+}
@@ -0,0 +1,69 @@
+package indc
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestUpdateMatchesFullCalculation(t *testing.T) {
+	specs, e := Specs()
+	if e != nil {
+		t.Fatal(e)
+	}
+	r := rand.New(rand.NewSource(42))
+	for round := 0; round < 20; round++ {
+		src := randTradeData(50 + r.Intn(400))
+		for _, s := range specs {
+			full := s.Calculate(src)
+			st := s.NewState()
+			var incr [][]float64
+			for i := 0; i < len(src); {
+				end := i + 1 + r.Intn(30)
+				if end > len(src) {
+					end = len(src)
+				}
+				// persist and restore the state between chunks
+				b, e := MarshalState(st)
+				if e != nil {
+					t.Fatal(e)
+				}
+				if st, e = UnmarshalState(b); e != nil {
+					t.Fatal(e)
+				}
+				if i > 0 && !st.Valid(s, src[i-1]) {
+					t.Fatalf("%s state invalidated after %d bars", s.Name, i)
+				}
+				incr = append(incr, s.Update(st, src[i:end])...)
+				i = end
+			}
+			if len(incr) != len(full) {
+				t.Fatalf("%s: %d incremental rows, %d full rows", s.Name, len(incr), len(full))
+			}
+			for i := range full {
+				for j := range full[i] {
+					if full[i][j] != incr[i][j] {
+						t.Fatalf("%s[%s] mismatch at bar %d of %d: full %v, incremental %v",
+							s.Name, s.Cols[j], i, len(src), full[i][j], incr[i][j])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestStateValid(t *testing.T) {
+	src := randTradeData(30)
+	s, _ := Lookup("kdj")
+	st := s.NewState()
+	s.Update(st, src[:20])
+	if !st.Valid(s, src[19]) {
+		t.Error("state should be valid for its last bar")
+	}
+	if st.Valid(s, src[18]) {
+		t.Error("state should be invalid for a different bar")
+	}
+	st.Params = []float64{9, 3, 4}
+	if st.Valid(s, src[19]) {
+		t.Error("state should be invalid for different parameters")
+	}
+}
@@ -0,0 +1,33 @@
+package indc
+
+import (
+	"github.com/carusyte/stock/model"
+)
+
+func init() {
+	Register(&Spec{
+		Name:   "vwap",
+		Params: []float64{},
+		Cols:   []string{"VWAP"},
+		WarmUp: 0,
+		Raw:    true,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return zipRows(VWAP(src))
+		},
+	})
+}
+
+//VWAP calculates the volume weighted average price of each bar from its amount and volume.
+//Bars without volume take the close price. As amount and volume are not reinstated, the trade data
+//should not be reinstated either for the prices to be on the same basis.
+func VWAP(src []*model.TradeDataBasic) []float64 {
+	r := make([]float64, len(src))
+	for i, s := range src {
+		if !s.Volume.Valid || !s.Amount.Valid || s.Volume.Float64 <= 0 {
+			r[i] = s.Close
+			continue
+		}
+		r[i] = s.Amount.Float64 / s.Volume.Float64
+	}
+	return r
+}
@@ -0,0 +1,9 @@
+package indc
+
+import "testing"
+
+func TestVWAP(t *testing.T) {
+	src := refTradeData()
+	src[2].Volume.Valid = false
+	assertSeries(t, "VWAP", VWAP(src), []float64{10.1, 10.6, 10.1, 9.9, 10.4, 10.8})
+}
@@ -1 +1,52 @@
 package indc
+
+import (
+	"math"
+
+	"github.com/carusyte/stock/model"
+)
+
+func init() {
+	Register(&Spec{
+		Name:   "wr",
+		Params: []float64{10, 6},
+		Cols:   []string{"WR1", "WR2"},
+		WarmUp: 10,
+		Calc: func(src []*model.TradeDataBasic, p []float64) [][]float64 {
+			return toRows(WR(src, int(p[0]), int(p[1])), func(idc *model.Indicator) []float64 {
+				return []float64{idc.WR1, idc.WR2}
+			})
+		},
+	})
+}
+
+//WR calculates Williams %R indicator for the given parameters
+//formula: WR:=100*(HHV(HIGH,N)-CLOSE)/(HHV(HIGH,N)-LLV(LOW,N))
+func WR(src []*model.TradeDataBasic, n1, n2 int) []*model.Indicator {
+	r := make([]*model.Indicator, len(src))
+	for i, s := range src {
+		idc := &model.Indicator{}
+		r[i] = idc
+		idc.Code = s.Code
+		idc.Date = s.Date[:10]
+		idc.Klid = s.Klid
+		idc.WR1 = calcWR(src, i, n1)
+		idc.WR2 = calcWR(src, i, n2)
+	}
+	return r
+}
+
+func calcWR(src []*model.TradeDataBasic, curIdx, n int) float64 {
+	bg := int(math.Max(float64(curIdx-n+1), 0))
+	llv := LLV(src[bg:curIdx+1], "Low")
+	hhv := HHV(src[bg:curIdx+1], "High")
+	if hhv == llv {
+		return 0
+	}
+	return (hhv - src[curIdx].Close) / (hhv - llv) * 100.
+}
+
+//DeftWR calculates WR indicator using default parameters (10,6)
+func DeftWR(src []*model.TradeDataBasic) []*model.Indicator {
+	return WR(src, 10, 6)
+}
@@ -0,0 +1,13 @@
+package indc
+
+import "testing"
+
+func TestWR(t *testing.T) {
+	var wr1, wr2 []float64
+	for _, idc := range WR(refTradeData(), 3, 2)[1:] {
+		wr1 = append(wr1, idc.WR1)
+		wr2 = append(wr2, idc.WR2)
+	}
+	assertSeries(t, "WR1", wr1[1:], []float64{70, 81.818182, 20, 7.692308})
+	assertSeries(t, "WR2", wr2, []float64{20, 87.5, 80, 11.111111, 9.090909})
+}
@@ -45,6 +45,31 @@ const (
 	M1          CYTP = "M1"
 )
 
+//Minutes returns the length of an intraday cycle in minutes, or 0 for daily and longer cycles.
+func (c CYTP) Minutes() int {
+	switch c {
+	case M120:
+		return 120
+	case M60:
+		return 60
+	case M30:
+		return 30
+	case M15:
+		return 15
+	case M5:
+		return 5
+	case M1:
+		return 1
+	default:
+		return 0
+	}
+}
+
+//Intraday returns whether the cycle is shorter than a trading day.
+func (c CYTP) Intraday() bool {
+	return c.Minutes() > 0
+}
+
 const (
 	MarketSZ string = "SZ"
 	MarketSH string = "SH"
@@ -59,6 +84,10 @@ const (
 	KlineMaster DataSource = "kline"
 	//Index the index table
 	Index DataSource = "index"
+	//ConvBond the convertible bond kline table
+	ConvBond DataSource = "cb"
+	//FundKline the fund kline table
+	FundKline DataSource = "fund"
 	//XQ xueqiu
 	XQ DataSource = "xq"
 	//EM eastmoney
@@ -69,12 +98,18 @@ const (
 	TC DataSource = "tc"
 	//WHT Kaleidoscope
 	WHT DataSource = "wht"
+	//Local files, e.g. Tongdaxin day files or CSV
+	Local DataSource = "local"
+	//Reinstated klines derived locally from non-reinstated klines and xdxr
+	Reinstated DataSource = "reinstated"
+	//Validated klines copied from the validate source in database
+	Validated DataSource = "validated"
 )
 
 const (
-	INDICATOR_DAY        DBTab = "indicator_d"
-	INDICATOR_WEEK       DBTab = "indicator_w"
-	INDICATOR_MONTH      DBTab = "indicator_m"
+	INDICATOR_DAY        DBTab = "indicator_d_kdj"
+	INDICATOR_WEEK       DBTab = "indicator_w_kdj"
+	INDICATOR_MONTH      DBTab = "indicator_m_kdj"
 	KLINE_DAY_F          DBTab = "kline_d_f"
 	KLINE_DAY_F_LR       DBTab = "kline_d_f_lr"
 	KLINE_DAY_F_MA       DBTab = "kline_d_f_ma"
@@ -179,6 +214,8 @@ type Stock struct {
 	UTime            sql.NullString
 	// source of index
 	Source string
+	// type of fund or bond, empty for stocks and indices
+	Type string
 }
 
 func (s *Stock) String() string {
@@ -426,12 +463,42 @@ type Finance struct {
 	ConsQuickRatio sql.NullFloat64 `db:"cons_quick_ratio"`
 	//EquityRatio 产权比率
 	EquityRatio sql.NullFloat64 `db:"equity_ratio"`
+	//Period type of the report, i.e. Q1, H1, Q3 or FY 报告期类型
+	Period sql.NullString
+	//Single-quarter EPS 单季每股收益
+	EpsQ sql.NullFloat64 `db:"eps_q"`
+	//Single-quarter Net Profit (1/10 Billion) 单季净利润（亿）
+	NpQ sql.NullFloat64 `db:"np_q"`
+	//Single-quarter NP After Deduction of Non-profits (1/10 Billion) 单季扣非净利润（亿）
+	NpAdnQ sql.NullFloat64 `db:"np_adn_q"`
+	//Single-quarter Gross Revenue (1/10 Billion) 单季营业总收入（亿）
+	GrQ sql.NullFloat64 `db:"gr_q"`
+	//Trailing Twelve Months EPS 滚动每股收益
+	EpsTtm sql.NullFloat64 `db:"eps_ttm"`
+	//Trailing Twelve Months Net Profit (1/10 Billion) 滚动净利润（亿）
+	NpTtm sql.NullFloat64 `db:"np_ttm"`
+	//Trailing Twelve Months Gross Revenue (1/10 Billion) 滚动营业总收入（亿）
+	GrTtm sql.NullFloat64 `db:"gr_ttm"`
+	//Trailing Twelve Months ROE 滚动净资产收益率
+	RoeTtm sql.NullFloat64 `db:"roe_ttm"`
 	//最后更新日期
 	Udate sql.NullString
 	//最后更新时间
 	Utime sql.NullString
 }
 
+//report period types of the financial reports
+const (
+	//PeriodQ1 the first quarter report 一季报
+	PeriodQ1 = "Q1"
+	//PeriodH1 the half-year report 中报
+	PeriodH1 = "H1"
+	//PeriodQ3 the third quarter report 三季报
+	PeriodQ3 = "Q3"
+	//PeriodFY the annual report 年报
+	PeriodFY = "FY"
+)
+
 //FinReport represents the finance report page data
 type FinReport struct {
 	Code          string
@@ -624,6 +691,36 @@ func (fin *FinReport) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+//financial statements
+const (
+	//StmtBalance the balance sheet 资产负债表
+	StmtBalance = "BS"
+	//StmtIncome the income statement 利润表
+	StmtIncome = "IS"
+	//StmtCashFlow the cash flow statement 现金流量表
+	StmtCashFlow = "CF"
+)
+
+//FinStmt is a line item of the financial statements in long format. Items of the income and cash flow
+//statements are cumulative in the fiscal year, as in the reports.
+type FinStmt struct {
+	Code string
+	//报告期
+	Year string
+	//报告类型
+	Period string
+	//报表
+	Statement string
+	//科目
+	Item string
+	//金额（元）
+	Value float64
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
 //TradeDataBasic models the basic trading data such as OHLCV etc.
 type TradeDataBasic struct {
 	Code          string
@@ -1081,6 +1178,434 @@ type Indicator struct {
 	BOLL_lower_h float64
 	BOLL_lower_l float64
 	BOLL_lower_c float64
+	CCI          float64
+	DMA_dif      float64
+	DMA_difma    float64
+	ENE_mid      float64
+	ENE_mid_o    float64
+	ENE_mid_h    float64
+	ENE_mid_l    float64
+	ENE_mid_c    float64
+	ENE_upper    float64
+	ENE_upper_o  float64
+	ENE_upper_h  float64
+	ENE_upper_l  float64
+	ENE_upper_c  float64
+	ENE_lower    float64
+	ENE_lower_o  float64
+	ENE_lower_h  float64
+	ENE_lower_l  float64
+	ENE_lower_c  float64
+	WR1          float64
+	WR2          float64
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//IndicatorState persists the carried state of an indicator for incremental calculation.
+type IndicatorState struct {
+	Code      string
+	Cycle     string
+	Indicator string
+	Klid      int
+	//JSON serialized indc.State
+	State string
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//KlineDiscrepancy records a difference between the master klines and those of the validate source.
+type KlineDiscrepancy struct {
+	Code  string
+	Cycle string
+	Rtype string
+	Date  string
+	//category of the difference
+	Kind string
+	//comma separated names of the fields in difference
+	Fields string
+	//validate source
+	Src string
+	//ratio of the validate value to the master value, if consistent among the fields
+	Ratio sql.NullFloat64
+	//maximum relative deviation of the fields
+	Dev sql.NullFloat64
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//NorthHolding is the daily holding of a stock by the northbound investors of the HK Stock Connect.
+type NorthHolding struct {
+	Code string
+	Date string
+	//持股数量（股）
+	Shares float64
+	//持股市值（元）
+	MarketCap sql.NullFloat64 `db:"market_cap"`
+	//占流通股比例（%）
+	FreeRatio sql.NullFloat64 `db:"free_ratio"`
+	//占总股本比例（%）
+	TotalRatio sql.NullFloat64 `db:"total_ratio"`
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//NorthFlow is the daily northbound deal flow of the HK Stock Connect to an exchange.
+type NorthFlow struct {
+	Date string
+	//SH for Shanghai Connect, SZ for Shenzhen Connect
+	Market string
+	//成交净买额（百万元）
+	NetBuy sql.NullFloat64 `db:"net_buy"`
+	//买入成交额（百万元）
+	Buy sql.NullFloat64
+	//卖出成交额（百万元）
+	Sell sql.NullFloat64
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//NorthData holds the northbound holdings of a stock and the net flows to its exchange, in chronological order
+//unless queried otherwise.
+type NorthData struct {
+	Code    string
+	Holding []*NorthHolding
+	Flow    []*NorthFlow
+}
+
+//DragonTiger is an entry of the daily abnormal trading list (龙虎榜) of the exchanges.
+type DragonTiger struct {
+	Code string
+	Date string
+	//上榜原因
+	Reason string
+	//收盘价
+	Close sql.NullFloat64
+	//涨跌幅（%）
+	ChangeRate sql.NullFloat64 `db:"change_rate"`
+	//龙虎榜净买额（元）
+	NetBuy sql.NullFloat64 `db:"net_buy"`
+	//龙虎榜买入额（元）
+	Buy sql.NullFloat64
+	//龙虎榜卖出额（元）
+	Sell sql.NullFloat64
+	//龙虎榜成交额（元）
+	Amount sql.NullFloat64
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//DragonTigerSeat is one of the top five buy or sell seats of a Dragon-Tiger list entry.
+type DragonTigerSeat struct {
+	Code   string
+	Date   string
+	Reason string
+	//B for the top buy seats, S for the top sell seats
+	Side string
+	//rank on the side, from 1
+	Rank     int    `db:"seat_rank"`
+	SeatCode string `db:"seat_code"`
+	//买入金额（元）
+	Buy sql.NullFloat64
+	//卖出金额（元）
+	Sell sql.NullFloat64
+	//净额（元）
+	NetBuy sql.NullFloat64 `db:"net_buy"`
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//Seat is a trading seat (营业部) appearing in the Dragon-Tiger lists.
+type Seat struct {
+	Code string
+	Name string
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//SeatProfile is the performance of the stocks in the days after a seat appears as a net buyer
+//in the Dragon-Tiger lists.
+type SeatProfile struct {
+	SeatCode string `db:"seat_code"`
+	//number of trading days after the appearance
+	Days int
+	//number of appearances with subsequent klines
+	Appearances int
+	//average return (%) from the close of the appearance day
+	AvgRet float64 `db:"avg_ret"`
+	//ratio of the appearances with positive return
+	WinRate float64 `db:"win_rate"`
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//HolderNum is the shareholder count of a stock at the end of a reporting period, along with the derived
+//chip concentration metrics.
+type HolderNum struct {
+	Code string
+	//截止日期
+	Date string
+	//公告日期
+	NoticeDate sql.NullString `db:"notice_date"`
+	//股东户数
+	Holders int64
+	//户均持股数量（股）
+	AvgShares sql.NullFloat64 `db:"avg_shares"`
+	//股东户数较上期变化（%）
+	HoldersChg sql.NullFloat64 `db:"holders_chg"`
+	//户均流通股（股）
+	FloatPerHolder sql.NullFloat64 `db:"float_per_holder"`
+	//十大流通股东持股合计占流通股比例（%）
+	Top10Float sql.NullFloat64 `db:"top10_float"`
+	//十大流通股东持股合计较上期变化（百分点）
+	Top10FloatChg sql.NullFloat64 `db:"top10_float_chg"`
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//TopHolder is one of the top 10 holders or top 10 float holders of a stock at the end of a reporting period.
+type TopHolder struct {
+	Code string
+	//截止日期
+	Date string
+	//whether it's in the top 10 float holders
+	Float bool `db:"is_float"`
+	//名次, from 1
+	Rank int `db:"holder_rank"`
+	//股东名称
+	Name string
+	//持股数量（股）
+	Shares float64
+	//占总股本或流通股比例（%）
+	Ratio sql.NullFloat64
+	//股份类型
+	ShareType sql.NullString `db:"share_type"`
+	//持股较上期变化（股），null for new holders
+	Chg sql.NullFloat64
+	//new, inc, dec or same
+	Status string
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//ShareChg is a change of the share capital of a stock.
+type ShareChg struct {
+	Code string
+	//变动日期
+	Date string
+	//总股本（股）
+	TotalShares sql.NullFloat64 `db:"total_shares"`
+	//流通A股（股）
+	FloatAShares sql.NullFloat64 `db:"float_a_shares"`
+	//限售A股（股）
+	LimitAShares sql.NullFloat64 `db:"limit_a_shares"`
+	//变动原因
+	Reason sql.NullString
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//event types of the announcements
+const (
+	//EventBuyback share buyback 回购
+	EventBuyback = "buyback"
+	//EventPledge share pledge 股权质押
+	EventPledge = "pledge"
+	//EventPledgeRelease release of share pledge 解除质押
+	EventPledgeRelease = "pledge_release"
+	//EventLockupExpiry lock-up expiry 限售股解禁
+	EventLockupExpiry = "lockup_expiry"
+	//EventReduction share reduction by major holders 股东减持
+	EventReduction = "reduction"
+	//EventIncrease share increase by major holders 股东增持
+	EventIncrease = "increase"
+	//EventPreAnnounce earnings pre-announcement or flash report 业绩预告、快报
+	EventPreAnnounce = "pre_announce"
+	//EventRestructure major asset restructuring 重大资产重组
+	EventRestructure = "restructure"
+	//EventDividend dividend distribution 权益分派
+	EventDividend = "dividend"
+	//EventSuspension trading suspension or resumption 停复牌
+	EventSuspension = "suspension"
+)
+
+//Announcement is an exchange announcement (公告) of a stock, tagged with the event type by its title.
+type Announcement struct {
+	Code string
+	//公告编号
+	ArtCode string `db:"art_code"`
+	//公告日期
+	Date string
+	//标题
+	Title string
+	//公告类别
+	Category sql.NullString
+	//事件类型
+	Event sql.NullString
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//Lockup is a scheduled lock-up expiry (限售解禁) of a stock.
+type Lockup struct {
+	Code string
+	//解禁日期
+	Date string
+	//限售股类型
+	ShareType string `db:"share_type"`
+	//解禁数量（股）
+	Shares float64
+	//实际解禁数量（股）
+	AbleShares sql.NullFloat64 `db:"able_shares"`
+	//解禁市值（元）
+	MarketCap sql.NullFloat64 `db:"market_cap"`
+	//占解禁前流通股比例（%）
+	FloatRatio sql.NullFloat64 `db:"float_ratio"`
+	//占总股本比例（%）
+	TotalRatio sql.NullFloat64 `db:"total_ratio"`
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//Margin is the daily margin trading (融资融券) data of a stock.
+type Margin struct {
+	Code string
+	Date string
+	//融资余额（元）
+	FinBal sql.NullFloat64 `db:"fin_bal"`
+	//融资买入额（元）
+	FinBuy sql.NullFloat64 `db:"fin_buy"`
+	//融资偿还额（元）
+	FinRepay sql.NullFloat64 `db:"fin_repay"`
+	//融券余额（元）
+	SecBal sql.NullFloat64 `db:"sec_bal"`
+	//融券余量（股）
+	SecVol sql.NullFloat64 `db:"sec_vol"`
+	//融券卖出量（股）
+	SecSell sql.NullFloat64 `db:"sec_sell"`
+	//融券偿还量（股）
+	SecRepay sql.NullFloat64 `db:"sec_repay"`
+	//融资融券余额（元）
+	TotalBal sql.NullFloat64 `db:"total_bal"`
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//MarginLogRtn is the log returns of the daily margin trading data against the previous trading day.
+type MarginLogRtn struct {
+	Code     string
+	Date     string
+	FinBal   sql.NullFloat64 `db:"fin_bal"`
+	FinBuy   sql.NullFloat64 `db:"fin_buy"`
+	FinRepay sql.NullFloat64 `db:"fin_repay"`
+	SecBal   sql.NullFloat64 `db:"sec_bal"`
+	SecVol   sql.NullFloat64 `db:"sec_vol"`
+	SecSell  sql.NullFloat64 `db:"sec_sell"`
+	SecRepay sql.NullFloat64 `db:"sec_repay"`
+	TotalBal sql.NullFloat64 `db:"total_bal"`
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//KlineLimit annotates a non-reinstated daily bar reaching the price limit (涨跌停).
+type KlineLimit struct {
+	Code string
+	Date string
+	Klid int
+	//limit rate, e.g. 0.1 for ±10%
+	Rate float64
+	//limit-up price
+	UpPrice float64 `db:"up_price"`
+	//limit-down price
+	DownPrice float64 `db:"down_price"`
+	//closed at limit-up price
+	LimitUp bool `db:"limit_up"`
+	//closed at limit-down price
+	LimitDown bool `db:"limit_down"`
+	//open, high, low and close all at the limit price (一字板)
+	OneWord bool `db:"one_word"`
+	//high reached limit-up price but not closed there
+	TouchUp bool `db:"touch_up"`
+	//low reached limit-down price but not closed there
+	TouchDown bool `db:"touch_down"`
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//TradeCalendar is a day of the trade calendar shared by SSE and SZSE.
+type TradeCalendar struct {
+	//sequence of the day, in the format of 20060102
+	Index sql.NullInt64 `db:"index"`
+	Date  string        `db:"calendarDate"`
+	//whether the exchanges are open on the day
+	Open bool `db:"isOpen"`
+	//trading, weekend, holiday or makeup, which is not stored
+	Kind string `db:"-"`
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//StockStatus is a period in which the stock is in a special status, such as suspended or ST.
+type StockStatus struct {
+	Code string
+	//suspended, st, *st, delisting or delisted
+	Status string
+	//first day of the status
+	Start string `db:"start_date"`
+	//last day of the status, null if the status persists
+	End sql.NullString `db:"end_date"`
+	//stock name when the status is observed
+	Name sql.NullString
+	//reason of suspension
+	Reason sql.NullString
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//PipelineCkpt marks a stock completed by a stage of the data pipeline.
+type PipelineCkpt struct {
+	Stage string
+	//source of index, empty for stocks
+	Src  string
+	Code string
 	//最后更新日期
 	Udate sql.NullString
 	//最后更新时间
@@ -1509,6 +2034,8 @@ type XQKline struct {
 	//MissingAmount stores dates of kline missing basic info
 	MissingData []string
 	NumAdded    int
+	//Intraday indicates the kline is of minute cycle, whose date includes the time of the bar.
+	Intraday bool
 }
 
 //creates a map for column name -> value
@@ -1536,9 +2063,13 @@ func (x *XQKline) m2base(m map[string]interface{}) (b *TradeDataBasic, e error)
 		return b, errors.Errorf("invalid format of 'timestamp': %+v", m)
 	}
 	sec := util.ConvTimeUnit(ms, time.Millisecond, time.Second)
+	f := global.DateFormat
+	if x.Intraday {
+		f = global.MinuteFormat
+	}
 	b = &TradeDataBasic{
 		Code: x.Code,
-		Date: time.Unix(int64(sec), 0).Format(global.DateFormat),
+		Date: time.Unix(int64(sec), 0).Format(f),
 	}
 	if v, ok = m["volume"].(float64); ok {
 		b.Volume = sql.NullFloat64{Float64: v, Valid: true}
@@ -1871,6 +2402,145 @@ type IdxLst struct {
 	Src, Market, Code, Name string
 }
 
+//fund types
+const (
+	//FundETF exchange traded fund
+	FundETF = "ETF"
+	//FundLOF listed open-end fund
+	FundLOF = "LOF"
+)
+
+//Fund is a listed fund whose klines are fetched along with the stocks, in tables of its own.
+type Fund struct {
+	Code   string
+	Market string
+	Name   string
+	//ETF or LOF
+	Type string
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//FundNav is the daily net asset value of a fund.
+type FundNav struct {
+	Code string
+	Date string
+	//单位净值
+	Nav float64
+	//累计净值
+	AccNav sql.NullFloat64 `db:"acc_nav"`
+	//日增长率（%）
+	NavChg sql.NullFloat64 `db:"nav_chg"`
+	//收盘价（不复权）
+	Close sql.NullFloat64
+	//溢价率（%），负数为折价
+	Premium sql.NullFloat64
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//FundHolding is a constituent stock held by a fund at the end of a reporting period.
+type FundHolding struct {
+	Code string
+	//报告期
+	Date string
+	//股票代码
+	StockCode string `db:"stock_code"`
+	//股票名称
+	StockName string `db:"stock_name"`
+	//占净值比例（%）
+	Ratio sql.NullFloat64
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//BondCB is the type of convertible bonds
+const BondCB = "CB"
+
+//CBond is a convertible bond (可转债).
+type CBond struct {
+	Code   string
+	Market string
+	Name   string
+	//正股代码
+	StockCode string `db:"stock_code"`
+	//起息日
+	ValueDate sql.NullString `db:"value_date"`
+	//上市日期
+	ListDate sql.NullString `db:"list_date"`
+	//到期日
+	Maturity sql.NullString
+	//退市日期
+	DelistDate sql.NullString `db:"delist_date"`
+	//发行规模（亿元）
+	IssueSize sql.NullFloat64 `db:"issue_size"`
+	//初始转股价
+	InitConvPrice sql.NullFloat64 `db:"init_conv_price"`
+	//最新转股价
+	ConvPrice sql.NullFloat64 `db:"conv_price"`
+	//各年票面利率（%），逗号分隔
+	Coupons sql.NullString
+	//到期赎回价（含最后一期利息）
+	RedeemPrice sql.NullFloat64 `db:"redeem_price"`
+	//强赎触发价
+	CallTrigger sql.NullFloat64 `db:"call_trigger"`
+	//回售触发价
+	PutTrigger sql.NullFloat64 `db:"put_trigger"`
+	//赎回条款
+	CallClause sql.NullString `db:"call_clause"`
+	//回售条款
+	PutClause sql.NullString `db:"put_clause"`
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//CBondConvPrice is the conversion price of the convertible bond effective since the date.
+type CBondConvPrice struct {
+	Code string
+	//生效日期
+	Date string
+	//转股价
+	ConvPrice float64 `db:"conv_price"`
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
+//CBondValue is the daily valuation of the convertible bond.
+type CBondValue struct {
+	Code string
+	Date string
+	//收盘价
+	Close float64
+	//正股收盘价
+	StockClose sql.NullFloat64 `db:"stock_close"`
+	//转股价
+	ConvPrice sql.NullFloat64 `db:"conv_price"`
+	//转股价值
+	ConvValue sql.NullFloat64 `db:"conv_value"`
+	//转股溢价率（%）
+	ConvPremium sql.NullFloat64 `db:"conv_premium"`
+	//纯债价值
+	BondValue sql.NullFloat64 `db:"bond_value"`
+	//纯债溢价率（%）
+	BondPremium sql.NullFloat64 `db:"bond_premium"`
+	//双低值：收盘价+转股溢价率
+	DoubleLow sql.NullFloat64 `db:"double_low"`
+	//最后更新日期
+	Udate sql.NullString
+	//最后更新时间
+	Utime sql.NullString
+}
+
 // FinPredict financial prediction
 type FinPredict struct {
 	Code      string
@@ -1893,6 +2563,38 @@ type FinPredict struct {
 	Utime     sql.NullString
 }
 
+//FinPredictHist is a dated snapshot of the consensus prediction, kept for the revision history.
+type FinPredictHist struct {
+	FinPredict
+	//快照日期
+	Date string
+}
+
+//FinPredictRev is the revision of the consensus prediction of a stock for a year, derived from the
+//prediction snapshots.
+type FinPredictRev struct {
+	Code string
+	Year string
+	//最新快照日期
+	Date string
+	//最新每股收益预测均值
+	EpsAvg sql.NullFloat64
+	//近1月每股收益预测均值变化（%）
+	EpsChg1M sql.NullFloat64
+	//近3月每股收益预测均值变化（%）
+	EpsChg3M sql.NullFloat64
+	//近6月每股收益预测均值变化（%）
+	EpsChg6M sql.NullFloat64
+	//近3月净利润预测均值变化（%）
+	NpChg3M sql.NullFloat64
+	//近3月每股收益预测均值上调次数
+	EpsUps int
+	//近3月每股收益预测均值下调次数
+	EpsDowns int
+	//上下调广度，介于-1和1之间
+	Breadth sql.NullFloat64
+}
+
 //KeyPoint mapped to database table kpts.
 type KeyPoint struct {
 	UUID     string
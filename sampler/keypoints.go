@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/carusyte/stock/conf"
+	"github.com/carusyte/stock/getd"
 	"github.com/carusyte/stock/global"
 	"github.com/carusyte/stock/model"
 	"github.com/pkg/errors"
@@ -129,6 +130,8 @@ func sampKeyPoints(code string, wg *sync.WaitGroup, wf *chan int, fail chan stri
 // if resample is -1, resample all the key points.
 func KeyPoints(code string, resample, prior int) (err error) {
 	frames := conf.Args.Sampler.GraderTimeFrames
+	hist := getd.LoadStatusHist(code)
+	lims := getd.LoadLimits(code, "", "")
 	for _, frame := range frames {
 		// keep track of latest selected klid;
 		var lkp *model.KeyPoint
@@ -175,12 +178,40 @@ func KeyPoints(code string, resample, prior int) (err error) {
 		if err != nil {
 			return
 		}
+		r = filterKpts(r, klhist, frame, hist, lims)
 		chkpts[frame] <- r
 		log.Printf("%s kpts%d sampled: %d", code, frame, len(r))
 	}
 	return nil
 }
 
+//filterKpts drops the key points sampled in special status, whose time frame spans a suspension,
+//or which are untradeable for closing at limit-up price.
+func filterKpts(kpts []*model.KeyPoint, klhist []*model.Quote, frame int, hist getd.StatusHist,
+	lims map[string]*model.KlineLimit) (r []*model.KeyPoint) {
+	if len(hist) == 0 && len(lims) == 0 {
+		return kpts
+	}
+	idx := make(map[string]int, len(klhist))
+	for i, q := range klhist {
+		idx[q.Date] = i
+	}
+	for _, kp := range kpts {
+		if l, ok := lims[kp.Date]; ok && l.LimitUp || hist.Is(kp.Code, kp.Date) {
+			continue
+		}
+		end := kp.Date
+		if i, ok := idx[kp.Date]; ok && i+frame < len(klhist) {
+			end = klhist[i+frame].Date
+		}
+		if hist.During(kp.Code, kp.Date, end, getd.StatusSuspended) {
+			continue
+		}
+		r = append(r, kp)
+	}
+	return
+}
+
 // SaveKpts update existing keypoint data or insert new ones in database.
 func SaveKpts(table string, kpts ...*model.KeyPoint) (err error) {
 	if len(kpts) == 0 {
@@ -1589,6 +1589,11 @@ func getFeatQuery() (qk, qd string) {
 		var strs []string
 		cols := conf.Args.Sampler.FeatureCols
 		for _, c := range cols {
+			if isMarginCol(c) {
+				// not all stocks or days are eligible for margin trading, defaults to the mean
+				strs = append(strs, fmt.Sprintf("COALESCE((m.%[1]s-s.%[1]s_mean)/s.%[1]s_std, 0) %[1]s,", c))
+				continue
+			}
 			strs = append(strs, fmt.Sprintf("(d.%[1]s-s.%[1]s_mean)/s.%[1]s_std %[1]s,", c))
 		}
 		pkline := strings.Join(strs, " ")
@@ -1616,6 +1621,16 @@ func getFeatQuery() (qk, qd string) {
 	return qryKline, qryDate
 }
 
+//isMarginCol checks whether the feature column refers to the margin trading log returns.
+func isMarginCol(c string) bool {
+	for _, m := range getd.MarginLrCols {
+		if c == m {
+			return true
+		}
+	}
+	return false
+}
+
 func uploadToGCS(ch <-chan *FileUploadJob, wg *sync.WaitGroup, nocache, overwrite bool) {
 	defer wg.Done()
 	log.Println("gcs upload worker started")
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/carusyte/stock/getd"
 	"github.com/carusyte/stock/indc"
 	"github.com/carusyte/stock/model"
 	"github.com/carusyte/stock/util"
@@ -41,6 +42,7 @@ type BlueChip struct {
 	EpsIndAvg  []float64
 	NpPredict  []float64
 	NpIndAvg   []float64
+	EpsRev     sql.NullFloat64
 	Dars       []float64
 	DarAvg     float64
 }
@@ -115,6 +117,7 @@ func (b *BlueChip) Get(s []string, limit int, ranked bool) (r *Result) {
 
 		item.Score += ip.Score
 	}
+	markStatus(r.Items)
 	r.SetFields(b.ID(), b.Fields()...)
 	if ranked {
 		r.Sort()
@@ -134,7 +137,7 @@ func sROE(b *BlueChip, finHist []*model.Finance, wts WtScore) {
 		s    = 0.
 	)
 	for _, f := range finHist {
-		if "12" == f.Year[5:7] {
+		if f.Period.String == model.PeriodFY {
 			if f.RoeYoy.Valid {
 				ygrs = append(ygrs, f.RoeYoy.Float64)
 			} else {
@@ -197,11 +200,11 @@ func scoreRoeGr(roe float64) (s float64) {
 
 func getRoeLambda(pos float64) float64 {
 	if math.IsNaN(roeLambda) {
-		c, e := dbmap.SelectInt("select count(*) from finance where year like '%-12-%'")
+		c, e := dbmap.SelectInt("select count(*) from finance where period = 'FY'")
 		util.CheckErr(e, "failed to count annual report in finance table")
 		rank := math.Ceil(pos * float64(c))
 		roeLambda, e = dbmap.SelectFloat("select roe_yoy from finance "+
-			"where year like '%-12-%' order by roe_yoy desc limit 1 offset ?", int(rank))
+			"where period = 'FY' order by roe_yoy desc limit 1 offset ?", int(rank))
 		util.CheckErr(e, "failed to query roe lambda from finance table")
 	}
 	return roeLambda
@@ -214,6 +217,7 @@ func getRoeLambda(pos float64) float64 {
 // Immediate comparison of nearest prediction and latest annual report; (0.35)
 // Comparison between EPS average and industrial average; (0.25)
 // Trend of growing performance, including growing rate. (0.4)
+// Revision momentum of the nearest prediction, if the snapshots are available. (0.2)
 func sFinPredict(b *BlueChip, finHist []*model.Finance, wts WtScore) {
 	fps := getFinPredicts(b.Code)
 	if len(fps) == 0 {
@@ -225,7 +229,7 @@ func sFinPredict(b *BlueChip, finHist []*model.Finance, wts WtScore) {
 	epsCofa, npCofa := fiprCofa(fps)
 	// find larp
 	for _, f := range finHist {
-		if "12" == f.Year[5:7] {
+		if f.Period.String == model.PeriodFY {
 			larp = f
 			break
 		}
@@ -243,6 +247,7 @@ func sFinPredict(b *BlueChip, finHist []*model.Finance, wts WtScore) {
 	}
 	compIndustrial(fps, wts, epsCofa, npCofa, 0.25)
 	perfTrend(larp, fps, wts, epsCofa, npCofa, 0.4)
+	predictRevision(b, fps[0], wts, 0.2)
 	for _, fp := range fps {
 		if fp.EpsAvg.Valid {
 			b.EpsPredict = append(b.EpsPredict, fp.EpsAvg.Float64)
@@ -504,6 +509,39 @@ func nextPerf(larp *model.Finance, fps []*model.FinPredict, wts WtScore, epsCofa
 	}
 }
 
+// evaluation of the revision momentum of the consensus EPS prediction.
+// max: raised by >= 10% in 3 months (or 1 month if the history is shorter) and all revisions are up.
+// zero: cut by >= 10% and all revisions are down.
+func predictRevision(b *BlueChip, fp *model.FinPredict, wts WtScore, wtPortion float64) {
+	MaxRev := 10.
+	rev, ok := getd.FinPredictRevs(b.Code)[fp.Year]
+	if !ok {
+		return
+	}
+	chg := rev.EpsChg3M
+	if !chg.Valid {
+		chg = rev.EpsChg1M
+	}
+	if !chg.Valid && !rev.Breadth.Valid {
+		return
+	}
+	s, w := 0., 0.
+	if chg.Valid {
+		b.EpsRev = chg
+		s += 70. * math.Max(0, math.Min(1, (chg.Float64+MaxRev)/(2*MaxRev)))
+		w += 0.7
+	}
+	if rev.Breadth.Valid {
+		s += 30. * (rev.Breadth.Float64 + 1) / 2
+		w += 0.3
+	}
+	cf := 0.
+	if fp.EpsNum.Valid {
+		cf = cofa(float64(fp.EpsNum.Int64))
+	}
+	wts.Add("FP_REV", s/w, ExtWeightFinPredict*wtPortion*cf)
+}
+
 // calculates EPS and NP confidence factor
 func fiprCofa(fps []*model.FinPredict) (epsCofa, npCofa []float64) {
 	epsCofa = make([]float64, len(fps))
@@ -667,6 +705,7 @@ func getFinPredicts(code string) (fps []*model.FinPredict) {
 // P/E: Get max score if 0 < P/E <= 5, get 0 if P/E >= 40
 // EPS GR: Get max score if EPS_YOY is all positive and complete for 3 years, and SMA EPS_YOY >= 15;
 //         Get 0 if recent 5 avg negative growth rate is <= -80%
+// EPS_YOY is taken from the TTM EPS where available, so that reports of different periods are comparable.
 func sEps(b *BlueChip, hist []*model.Finance, wts WtScore) {
 	ZeroPE := 80.
 	MaxPE := 5.
@@ -682,8 +721,12 @@ func sEps(b *BlueChip, hist []*model.Finance, wts WtScore) {
 	pnum := .0
 	count := true
 	for i, f := range hist {
-		if f.EpsYoy.Valid {
-			grs = append(grs, f.EpsYoy.Float64)
+		gr, ok := epsTtmYoy(f, hist)
+		if !ok && f.EpsYoy.Valid {
+			gr, ok = f.EpsYoy.Float64, true
+		}
+		if ok {
+			grs = append(grs, gr)
 			if grs[i] < 0 {
 				if i < 4 {
 					ngrs = append(ngrs, grs[i])
@@ -727,6 +770,28 @@ func sEps(b *BlueChip, hist []*model.Finance, wts WtScore) {
 	return
 }
 
+//epsTtmYoy returns the growth rate (%) of the TTM EPS against the same period last year.
+func epsTtmYoy(f *model.Finance, hist []*model.Finance) (gr float64, ok bool) {
+	if !f.EpsTtm.Valid || len(f.Year) < 4 {
+		return
+	}
+	y, e := strconv.Atoi(f.Year[:4])
+	if e != nil {
+		return
+	}
+	ly := fmt.Sprintf("%d%s", y-1, f.Year[4:])
+	for _, p := range hist {
+		if p.Year != ly {
+			continue
+		}
+		if !p.EpsTtm.Valid || p.EpsTtm.Float64 == 0 {
+			return
+		}
+		return (f.EpsTtm.Float64 - p.EpsTtm.Float64) / math.Abs(p.EpsTtm.Float64) * 100., true
+	}
+	return
+}
+
 //ID the identifier of this scorer
 func (*BlueChip) ID() string {
 	return "BLUE"
@@ -736,7 +801,7 @@ func (*BlueChip) ID() string {
 func (b *BlueChip) Fields() []string {
 	return []string{"Latest Report", "PE", "ROE GR%", "EPS GR%",
 		"EPS GR AVG%", "PU", "UDPPS GR%", "UDPPS GR AVG%",
-		"EPS Predict", "EPS Industry", "NP Predict", "NP Industry",
+		"EPS Predict", "EPS Industry", "NP Predict", "NP Industry", "EPS REV%",
 		"DARS%", "DAR AVG%"}
 }
 
@@ -773,6 +838,11 @@ func (b *BlueChip) GetFieldStr(name string) string {
 		return util.SprintFa(b.NpPredict, "%.2f", "/", 4)
 	case "NP Industry":
 		return util.SprintFa(b.NpIndAvg, "%.2f", "/", 4)
+	case "EPS REV%":
+		if b.EpsRev.Valid {
+			return fmt.Sprintf("%.2f", b.EpsRev.Float64)
+		}
+		return "NaN"
 	case "DARS%":
 		return util.SprintFa(b.Dars, "%.2f", "/", 4)
 	case "DAR AVG%":
@@ -0,0 +1,164 @@
+package score
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/carusyte/stock/getd"
+	"github.com/carusyte/stock/model"
+	"github.com/pkg/errors"
+)
+
+//DragonTiger scores the stocks on the latest Dragon-Tiger list by the track records of their net buying
+//seats. The score is the win rate (%) of the stocks in the days after these seats net bought before,
+//weighted by the appearances of each seat. Seats with fewer appearances than MinAppear are ignored.
+type DragonTiger struct {
+	Code string
+	Name string
+	Date string
+	//Reasons of the list entries
+	Reason string
+	//NetBuy is the largest net buy amount (万元) among the list entries
+	NetBuy float64
+	//Seats is the number of profiled net buying seats
+	Seats int
+	//AvgRet is the weighted average return (%) after the seats appeared
+	AvgRet float64
+	//WinRate is the weighted win rate (%) after the seats appeared
+	WinRate float64
+	//Days after the appearances to evaluate, 5 if not specified.
+	Days int
+	//MinAppear is the minimum appearances of the seats to take, 10 if not specified.
+	MinAppear int
+}
+
+//GetFieldStr returns the string representation of the specified field.
+func (d *DragonTiger) GetFieldStr(name string) string {
+	switch name {
+	case "NETBUY":
+		return fmt.Sprintf("%.2f", d.NetBuy)
+	case "AVGRET":
+		return fmt.Sprintf("%.2f", d.AvgRet)
+	case "WINRATE":
+		return fmt.Sprintf("%.2f", d.WinRate)
+	case "SEATS":
+		return fmt.Sprintf("%d", d.Seats)
+	case "DATE":
+		return d.Date
+	case "REASON":
+		return d.Reason
+	default:
+		r := reflect.ValueOf(d)
+		f := reflect.Indirect(r).FieldByName(name)
+		if !f.IsValid() {
+			panic(errors.New("undefined field for DragonTiger: " + name))
+		}
+		return fmt.Sprintf("%+v", f.Interface())
+	}
+}
+
+//Get scores the specified stocks on the latest list, or all stocks on the list if none is specified.
+func (d *DragonTiger) Get(stock []string, limit int, ranked bool) (r *Result) {
+	days, minAppear := d.Days, d.MinAppear
+	if days <= 0 {
+		days = 5
+	}
+	if minAppear <= 0 {
+		minAppear = 10
+	}
+	r = new(Result)
+	r.PfIds = append(r.PfIds, d.ID())
+	date, dts := getd.LatestDragonTiger(stock...)
+	if len(dts) == 0 {
+		log.Printf("no dragon-tiger list found")
+		return
+	}
+	var codes []string
+	dmap := make(map[string][]*model.DragonTiger)
+	for _, dt := range dts {
+		if _, ok := dmap[dt.Code]; !ok {
+			codes = append(codes, dt.Code)
+		}
+		dmap[dt.Code] = append(dmap[dt.Code], dt)
+	}
+	smap := make(map[string][]*model.DragonTigerSeat)
+	var seatCodes []string
+	for _, s := range getd.DragonTigerSeats(date, getd.SeatBuy, codes...) {
+		smap[s.Code] = append(smap[s.Code], s)
+		seatCodes = append(seatCodes, s.SeatCode)
+	}
+	var profs map[string]*model.SeatProfile
+	if len(seatCodes) > 0 {
+		profs = getd.SeatProfiles(days, seatCodes...)
+	}
+	for _, s := range getd.StocksDbByCode(codes...) {
+		dt := &DragonTiger{Code: s.Code, Name: s.Name, Date: date}
+		var reasons []string
+		for i, e := range dmap[s.Code] {
+			reasons = append(reasons, e.Reason)
+			if nb := e.NetBuy.Float64 / 1e4; i == 0 || nb > dt.NetBuy {
+				dt.NetBuy = nb
+			}
+		}
+		dt.Reason = strings.Join(reasons, "; ")
+		dt.Seats, dt.AvgRet, dt.WinRate = seatRecord(smap[s.Code], profs, minAppear)
+		item := &Item{Code: s.Code, Name: s.Name, Profiles: make(map[string]*Profile)}
+		if s.Industry.Valid {
+			item.Industry = s.Industry.String
+		}
+		item.Profiles[d.ID()] = &Profile{Score: dt.WinRate, FieldHolder: dt}
+		item.Score = dt.WinRate
+		r.AddItem(item)
+	}
+	markStatus(r.Items)
+	r.SetFields(d.ID(), d.Fields()...)
+	if ranked {
+		r.Sort()
+	}
+	r.Shrink(limit)
+	return
+}
+
+//seatRecord returns the number of the profiled net buying seats, along with their average return and
+//win rate in percent weighted by appearances. A seat appearing in several entries is counted once.
+func seatRecord(seats []*model.DragonTigerSeat, profs map[string]*model.SeatProfile, minAppear int) (
+	n int, avgRet, winRate float64) {
+	seen := make(map[string]bool)
+	total := 0
+	for _, s := range seats {
+		p, ok := profs[s.SeatCode]
+		if !ok || seen[s.SeatCode] || s.NetBuy.Float64 <= 0 || p.Appearances < minAppear {
+			continue
+		}
+		seen[s.SeatCode] = true
+		n++
+		total += p.Appearances
+		avgRet += p.AvgRet * float64(p.Appearances)
+		winRate += p.WinRate * float64(p.Appearances)
+	}
+	if total == 0 {
+		return 0, 0, 0
+	}
+	return n, avgRet / float64(total), winRate / float64(total) * 100
+}
+
+//Geta gets result for all stocks on the latest list
+func (d *DragonTiger) Geta() (r *Result) {
+	return d.Get(nil, -1, false)
+}
+
+//ID for the scorer
+func (d *DragonTiger) ID() string {
+	return "DRAGON_TIGER"
+}
+
+//Fields for the scorer
+func (d *DragonTiger) Fields() []string {
+	return []string{"DATE", "REASON", "NETBUY", "SEATS", "AVGRET", "WINRATE"}
+}
+
+//Description for the scorer
+func (d *DragonTiger) Description() string {
+	return "Score stocks on the latest Dragon-Tiger list by the track records of their net buying seats."
+}
@@ -0,0 +1,37 @@
+package score
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/carusyte/stock/model"
+)
+
+func TestSeatRecord(t *testing.T) {
+	nf := func(f float64) sql.NullFloat64 { return sql.NullFloat64{Float64: f, Valid: true} }
+	seats := []*model.DragonTigerSeat{
+		{SeatCode: "a", NetBuy: nf(100)},
+		{SeatCode: "b", NetBuy: nf(50)},
+		//counted once
+		{SeatCode: "a", NetBuy: nf(80)},
+		//net selling
+		{SeatCode: "c", NetBuy: nf(-10)},
+		//too few appearances
+		{SeatCode: "d", NetBuy: nf(10)},
+		//not profiled
+		{SeatCode: "e", NetBuy: nf(10)},
+	}
+	profs := map[string]*model.SeatProfile{
+		"a": {SeatCode: "a", Appearances: 30, AvgRet: 2, WinRate: 0.6},
+		"b": {SeatCode: "b", Appearances: 10, AvgRet: -2, WinRate: 0.2},
+		"c": {SeatCode: "c", Appearances: 50, AvgRet: 5, WinRate: 0.9},
+		"d": {SeatCode: "d", Appearances: 3, AvgRet: 9, WinRate: 1},
+	}
+	n, avgRet, winRate := seatRecord(seats, profs, 10)
+	if n != 2 || avgRet != 1 || winRate != 50 {
+		t.Errorf("want 2 seats, 1%% return and 50%% win rate, got %d, %v, %v", n, avgRet, winRate)
+	}
+	if n, _, _ = seatRecord(seats, nil, 10); n != 0 {
+		t.Errorf("want no seat without profiles, got %d", n)
+	}
+}
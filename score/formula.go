@@ -0,0 +1,140 @@
+package score
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/carusyte/stock/getd"
+	"github.com/carusyte/stock/indc"
+	"github.com/carusyte/stock/model"
+	"github.com/pkg/errors"
+)
+
+//Formula scores stocks by evaluating an indicator formula in TDX/THS syntax over their klines.
+//The value of the last output at the latest bar is taken as the score, so a screening condition
+//yields 1 for matched stocks, and a formula such as IF(CROSS(MA(C,5),MA(C,10)),100,0) can be
+//used to score in the same scale as other scorers. Stocks with NaN score are excluded.
+type Formula struct {
+	Code string
+	Name string
+	//Text of the formula.
+	Text string
+	//Cycle of the klines to evaluate on, daily if not specified.
+	Cycle model.CYTP
+	//Reinstate type of the klines, forward reinstatement if not specified.
+	Reinstate model.Rtype
+	//Bars is the number of latest bars to evaluate on, all bars if not positive.
+	Bars int
+	//Screen keeps only the stocks satisfying the formula, i.e. with non-zero score.
+	Screen bool
+	//Vals are the output values at the latest bar.
+	Vals    []float64
+	outputs []string
+}
+
+//GetFieldStr returns the string representation of the specified field.
+func (f *Formula) GetFieldStr(name string) string {
+	for i, o := range f.outputs {
+		if o == name && i < len(f.Vals) {
+			return fmt.Sprintf("%.2f", f.Vals[i])
+		}
+	}
+	panic(errors.New("undefined field for Formula: " + name))
+}
+
+//Get evaluates the formula for the specified stocks, or all stocks if none is specified.
+func (f *Formula) Get(stock []string, limit int, ranked bool) (r *Result) {
+	fml, e := indc.ParseFormula(f.Text)
+	if e != nil {
+		log.Panicf("%+v", e)
+	}
+	f.outputs = fml.Outputs()
+	var stks []*model.Stock
+	if len(stock) == 0 {
+		stks = getd.StocksDb()
+	} else {
+		stks = getd.StocksDbByCode(stock...)
+	}
+	qry := getd.TrDataQry{
+		Cycle:     f.Cycle,
+		Reinstate: f.Reinstate,
+		Basic:     true,
+	}
+	if qry.Cycle == "" {
+		qry.Cycle = model.DAY
+	}
+	if qry.Reinstate == "" {
+		qry.Reinstate = model.Forward
+	}
+
+	r = new(Result)
+	r.PfIds = append(r.PfIds, f.ID())
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+	)
+	chstk := make(chan *model.Stock, JobCapacity)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range chstk {
+				fr := fml.Eval(getd.GetTrDataDB(s.Code, qry, f.Bars, false))
+				if math.IsNaN(fr.Last()) || f.Screen && fr.Last() == 0 {
+					continue
+				}
+				fh := &Formula{Code: s.Code, Name: s.Name, outputs: f.outputs}
+				for _, ser := range fr.Series {
+					fh.Vals = append(fh.Vals, ser[len(ser)-1])
+				}
+				item := &Item{Code: s.Code, Name: s.Name, Profiles: make(map[string]*Profile)}
+				if s.Industry.Valid {
+					item.Industry = s.Industry.String
+				}
+				item.Profiles[f.ID()] = &Profile{Score: fr.Last(), FieldHolder: fh}
+				item.Score = fr.Last()
+				lock.Lock()
+				r.AddItem(item)
+				lock.Unlock()
+			}
+		}()
+	}
+	for _, s := range stks {
+		chstk <- s
+	}
+	close(chstk)
+	wg.Wait()
+
+	markStatus(r.Items)
+	r.SetFields(f.ID(), f.Fields()...)
+	if ranked {
+		r.Sort()
+	}
+	r.Shrink(limit)
+	return
+}
+
+//Geta gets result for all stocks
+func (f *Formula) Geta() (r *Result) {
+	return f.Get(nil, -1, false)
+}
+
+//ID for the scorer
+func (f *Formula) ID() string {
+	return "FORMULA"
+}
+
+//Fields for the scorer, which are the outputs of the formula.
+func (f *Formula) Fields() []string {
+	if f.outputs == nil {
+		f.outputs = indc.MustParseFormula(f.Text).Outputs()
+	}
+	return f.outputs
+}
+
+//Description for the scorer
+func (f *Formula) Description() string {
+	return "Score by formula: " + f.Text
+}
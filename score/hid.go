@@ -135,6 +135,7 @@ func (h *HiD) Get(s []string, limit int, ranked bool) (r *Result) {
 		ip.Score = math.Max(0, ip.Score)
 		item.Score += ip.Score
 	}
+	markStatus(r.Items)
 	r.SetFields(h.ID(), h.Fields()...)
 	if ranked {
 		r.Sort()
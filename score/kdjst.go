@@ -60,6 +60,9 @@ func (k *KdjSt) Get(stock []string, limit int, ranked bool) (r *Result) {
 		item.Code = vri.Code
 		item.Name = vri.Name
 		item.Industry = vri.Industry
+		//status marks and comments of kdjv
+		item.Marks = vri.Marks
+		item.Comments = vri.Comments
 
 		kst := new(KdjSt)
 		kst.Code = item.Code
@@ -113,6 +113,7 @@ func (k *KdjV) Get(codes []string, limit int, ranked bool) (r *Result) {
 		}
 		items = append(items, item)
 	}
+	markStatus(items)
 	for _, idx := range idxlst {
 		item := new(Item)
 		item.Code = idx.Code
@@ -6,8 +6,13 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strings"
+	"time"
 
+	"github.com/carusyte/stock/calendar"
+	"github.com/carusyte/stock/getd"
 	"github.com/carusyte/stock/global"
+	"github.com/carusyte/stock/model"
 	"github.com/olekukonko/tablewriter"
 )
 
@@ -138,6 +143,57 @@ func (r *Result) Sort() (rr *Result) {
 	return
 }
 
+//markStatus marks the stock items currently suspended, under special treatment or delisting,
+//and comments the status with its start date, as well as the price limit reached on the latest
+//trading day.
+func markStatus(items []*Item) {
+	if len(items) == 0 {
+		return
+	}
+	codes := make([]string, len(items))
+	for i, it := range items {
+		codes[i] = it.Code
+	}
+	hist := getd.LoadStatusHist(codes...)
+	today := time.Now().Format(global.DateFormat)
+	td := today
+	if !calendar.IsTradingDay(td) {
+		td = calendar.PrevTD(td)
+	}
+	lims := getd.LimitsOn(td, codes...)
+	for _, it := range items {
+		if ps := hist.Periods(it.Code, today, today); len(ps) > 0 {
+			it.AddMark(WarnMark)
+			for _, p := range ps {
+				it.Cmtf("%s since %s", strings.ToUpper(p.Status), p.Start)
+			}
+		}
+		if l, ok := lims[it.Code]; ok {
+			it.Cmt(limitComment(l))
+		}
+	}
+}
+
+func limitComment(l *model.KlineLimit) string {
+	var c string
+	switch {
+	case l.LimitUp:
+		c = "LIMIT UP"
+	case l.LimitDown:
+		c = "LIMIT DOWN"
+	case l.TouchUp && l.TouchDown:
+		c = "TOUCHED LIMIT UP & DOWN"
+	case l.TouchUp:
+		c = "TOUCHED LIMIT UP"
+	default:
+		c = "TOUCHED LIMIT DOWN"
+	}
+	if l.OneWord {
+		c = "ONE-WORD " + c
+	}
+	return fmt.Sprintf("%s on %s", c, l.Date)
+}
+
 //Mark mark the leading n (positive) or trailing n (negative) items in the result,
 //which will be display in the "Rank" column with the specified marks.
 func (r *Result) Mark(n int, m ...mark) (rr *Result) {
@@ -2,6 +2,20 @@ CREATE DATABASE IF NOT EXISTS `secu` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLL
 
 USE `secu`;
 
+CREATE TABLE `announcement` (
+  `code` varchar(8) NOT NULL COMMENT '股票代码',
+  `art_code` varchar(30) NOT NULL COMMENT '公告编号',
+  `date` varchar(10) NOT NULL COMMENT '公告日期',
+  `title` varchar(500) NOT NULL COMMENT '标题',
+  `category` varchar(200) DEFAULT NULL COMMENT '公告类别',
+  `event` varchar(20) DEFAULT NULL COMMENT '事件类型',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`art_code`),
+  KEY `date` (`date`,`code`),
+  KEY `event` (`event`,`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Company announcements tagged with event types';
+
 CREATE TABLE `basics` (
   `code` varchar(6) NOT NULL COMMENT '股票代码',
   `name` varchar(10) DEFAULT NULL COMMENT '名称',
@@ -56,6 +70,185 @@ CREATE TABLE `basics` (
   PRIMARY KEY (`code`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
+CREATE TABLE `cb_d_n` (
+  `code` varchar(8) NOT NULL,
+  `date` varchar(20) NOT NULL,
+  `klid` int NOT NULL,
+  `open` double DEFAULT NULL,
+  `high` double DEFAULT NULL,
+  `close` double DEFAULT NULL,
+  `low` double DEFAULT NULL,
+  `volume` double DEFAULT NULL COMMENT '成交量(张)',
+  `amount` double DEFAULT NULL COMMENT '成交额(元)',
+  `xrate` double DEFAULT NULL COMMENT '换手率(%)',
+  `varate` double DEFAULT NULL COMMENT 'Closing price variation(%)',
+  `varate_h` double DEFAULT NULL COMMENT 'Highest price variation(%)',
+  `varate_o` double DEFAULT NULL COMMENT 'Opening price variation(%)',
+  `varate_l` double DEFAULT NULL COMMENT 'Lowest price variation(%)',
+  `udate` varchar(10) DEFAULT NULL COMMENT 'Last update date',
+  `utime` varchar(8) DEFAULT NULL COMMENT 'Last update time',
+  PRIMARY KEY (`code`,`klid`),
+  UNIQUE KEY `CB_D_N_IDX1` (`code`,`date`),
+  KEY `CB_D_N_DATE` (`date`,`klid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci ROW_FORMAT=COMPRESSED COMMENT='Daily Convertible Bond (Non-Reinstated)'
+/*!50100 PARTITION BY KEY (`code`)
+PARTITIONS 16 */;
+
+CREATE TABLE `cb_d_n_lr` (
+  `code` varchar(8) NOT NULL,
+  `date` varchar(20) NOT NULL,
+  `klid` int NOT NULL,
+  `amount` double DEFAULT NULL,
+  `xrate` double DEFAULT NULL,
+  `close` double DEFAULT NULL COMMENT 'Log Return (Close)',
+  `high` double DEFAULT NULL COMMENT 'Log Return (High)',
+  `high_close` double DEFAULT NULL,
+  `open` double DEFAULT NULL COMMENT 'Log Return (Open)',
+  `open_close` double DEFAULT NULL,
+  `low` double DEFAULT NULL COMMENT 'Log Return (Low)',
+  `low_close` double DEFAULT NULL,
+  `volume` double DEFAULT NULL COMMENT 'Log Return for Volume',
+  `udate` varchar(10) DEFAULT NULL COMMENT 'Last update date',
+  `utime` varchar(8) DEFAULT NULL COMMENT 'Last update time',
+  PRIMARY KEY (`code`,`klid`),
+  UNIQUE KEY `CB_D_N_LR_IDX1` (`code`,`date`),
+  KEY `CB_D_N_LR_DATE` (`date`,`klid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci ROW_FORMAT=COMPRESSED COMMENT='Daily Convertible Bond Log Return (Non-Reinstated)'
+/*!50100 PARTITION BY KEY (`code`)
+PARTITIONS 16 */;
+
+CREATE TABLE `cb_d_n_ma` (
+  `code` varchar(8) NOT NULL,
+  `date` varchar(20) NOT NULL,
+  `klid` int NOT NULL,
+  `ma5` double DEFAULT NULL,
+  `ma10` double DEFAULT NULL,
+  `ma20` double DEFAULT NULL,
+  `ma30` double DEFAULT NULL,
+  `ma60` double DEFAULT NULL,
+  `ma120` double DEFAULT NULL,
+  `ma200` double DEFAULT NULL,
+  `ma250` double DEFAULT NULL,
+  `vol5` double DEFAULT NULL,
+  `vol10` double DEFAULT NULL,
+  `vol20` double DEFAULT NULL,
+  `vol30` double DEFAULT NULL,
+  `vol60` double DEFAULT NULL,
+  `vol120` double DEFAULT NULL,
+  `vol200` double DEFAULT NULL,
+  `vol250` double DEFAULT NULL,
+  `udate` varchar(10) DEFAULT NULL COMMENT 'Last update date',
+  `utime` varchar(8) DEFAULT NULL COMMENT 'Last update time',
+  PRIMARY KEY (`code`,`klid`),
+  UNIQUE KEY `CB_D_N_MA_IDX1` (`code`,`date`),
+  KEY `CB_D_N_MA_DATE` (`date`,`klid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci ROW_FORMAT=COMPRESSED COMMENT='Daily Convertible Bond Moving Average (Non-Reinstated)'
+/*!50100 PARTITION BY KEY (`code`)
+PARTITIONS 16 */;
+
+CREATE TABLE `cb_d_n_ma_lr` (
+  `code` varchar(8) NOT NULL,
+  `date` varchar(20) NOT NULL,
+  `klid` int NOT NULL,
+  `ma5` double DEFAULT NULL,
+  `ma5_o` double DEFAULT NULL,
+  `ma5_h` double DEFAULT NULL,
+  `ma5_l` double DEFAULT NULL,
+  `ma10` double DEFAULT NULL,
+  `ma10_o` double DEFAULT NULL,
+  `ma10_h` double DEFAULT NULL,
+  `ma10_l` double DEFAULT NULL,
+  `ma20` double DEFAULT NULL,
+  `ma20_o` double DEFAULT NULL,
+  `ma20_h` double DEFAULT NULL,
+  `ma20_l` double DEFAULT NULL,
+  `ma30` double DEFAULT NULL,
+  `ma30_o` double DEFAULT NULL,
+  `ma30_h` double DEFAULT NULL,
+  `ma30_l` double DEFAULT NULL,
+  `ma60` double DEFAULT NULL,
+  `ma60_o` double DEFAULT NULL,
+  `ma60_h` double DEFAULT NULL,
+  `ma60_l` double DEFAULT NULL,
+  `ma120` double DEFAULT NULL,
+  `ma120_o` double DEFAULT NULL,
+  `ma120_h` double DEFAULT NULL,
+  `ma120_l` double DEFAULT NULL,
+  `ma200` double DEFAULT NULL,
+  `ma200_o` double DEFAULT NULL,
+  `ma200_h` double DEFAULT NULL,
+  `ma200_l` double DEFAULT NULL,
+  `ma250` double DEFAULT NULL,
+  `ma250_o` double DEFAULT NULL,
+  `ma250_h` double DEFAULT NULL,
+  `ma250_l` double DEFAULT NULL,
+  `vol5` double DEFAULT NULL,
+  `vol10` double DEFAULT NULL,
+  `vol20` double DEFAULT NULL,
+  `vol30` double DEFAULT NULL,
+  `vol60` double DEFAULT NULL,
+  `vol120` double DEFAULT NULL,
+  `vol200` double DEFAULT NULL,
+  `vol250` double DEFAULT NULL,
+  `udate` varchar(10) DEFAULT NULL COMMENT 'Last update date',
+  `utime` varchar(8) DEFAULT NULL COMMENT 'Last update time',
+  PRIMARY KEY (`code`,`klid`),
+  UNIQUE KEY `CB_D_N_MA_LR_IDX1` (`code`,`date`),
+  KEY `CB_D_N_MA_LR_DATE` (`date`,`klid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci ROW_FORMAT=COMPRESSED COMMENT='Daily Convertible Bond Moving Average Log Return (Non-Reinstated)'
+/*!50100 PARTITION BY KEY (`code`)
+PARTITIONS 16 */;
+
+CREATE TABLE `cbond` (
+  `code` varchar(8) NOT NULL COMMENT '债券代码',
+  `market` varchar(2) NOT NULL COMMENT '市场',
+  `name` varchar(20) NOT NULL COMMENT '债券简称',
+  `stock_code` varchar(8) NOT NULL COMMENT '正股代码',
+  `value_date` varchar(10) DEFAULT NULL COMMENT '起息日',
+  `list_date` varchar(10) DEFAULT NULL COMMENT '上市日期',
+  `maturity` varchar(10) DEFAULT NULL COMMENT '到期日',
+  `delist_date` varchar(10) DEFAULT NULL COMMENT '退市日期',
+  `issue_size` double DEFAULT NULL COMMENT '发行规模（亿元）',
+  `init_conv_price` double DEFAULT NULL COMMENT '初始转股价',
+  `conv_price` double DEFAULT NULL COMMENT '最新转股价',
+  `coupons` varchar(100) DEFAULT NULL COMMENT '各年票面利率（%），逗号分隔',
+  `redeem_price` double DEFAULT NULL COMMENT '到期赎回价（含最后一期利息）',
+  `call_trigger` double DEFAULT NULL COMMENT '强赎触发价',
+  `put_trigger` double DEFAULT NULL COMMENT '回售触发价',
+  `call_clause` text COMMENT '赎回条款',
+  `put_clause` text COMMENT '回售条款',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`),
+  KEY `CBOND_STOCK` (`stock_code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Convertible bonds';
+
+CREATE TABLE `cbond_conv_price` (
+  `code` varchar(8) NOT NULL COMMENT '债券代码',
+  `date` varchar(10) NOT NULL COMMENT '生效日期',
+  `conv_price` double NOT NULL COMMENT '转股价',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Conversion price reset history of convertible bonds';
+
+CREATE TABLE `cbond_value` (
+  `code` varchar(8) NOT NULL COMMENT '债券代码',
+  `date` varchar(10) NOT NULL COMMENT '交易日期',
+  `close` double NOT NULL COMMENT '收盘价',
+  `stock_close` double DEFAULT NULL COMMENT '正股收盘价',
+  `conv_price` double DEFAULT NULL COMMENT '转股价',
+  `conv_value` double DEFAULT NULL COMMENT '转股价值',
+  `conv_premium` double DEFAULT NULL COMMENT '转股溢价率（%）',
+  `bond_value` double DEFAULT NULL COMMENT '纯债价值',
+  `bond_premium` double DEFAULT NULL COMMENT '纯债溢价率（%）',
+  `double_low` double DEFAULT NULL COMMENT '双低值：收盘价+转股溢价率',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`date`),
+  KEY `CBOND_VALUE_DATE` (`date`,`double_low`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Daily conversion and pure-bond valuation of convertible bonds';
+
 CREATE TABLE `cmpool` (
   `seqno` int NOT NULL AUTO_INCREMENT,
   `code` varchar(8) NOT NULL,
@@ -63,6 +256,39 @@ CREATE TABLE `cmpool` (
   PRIMARY KEY (`seqno`,`code`)
 ) ENGINE=InnoDB AUTO_INCREMENT=1277 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
+CREATE TABLE `dragon_tiger` (
+  `code` varchar(8) NOT NULL,
+  `date` varchar(10) NOT NULL,
+  `reason` varchar(128) NOT NULL COMMENT '上榜原因',
+  `close` double DEFAULT NULL COMMENT '收盘价',
+  `change_rate` double DEFAULT NULL COMMENT '涨跌幅（%）',
+  `net_buy` double DEFAULT NULL COMMENT '龙虎榜净买额（元）',
+  `buy` double DEFAULT NULL COMMENT '龙虎榜买入额（元）',
+  `sell` double DEFAULT NULL COMMENT '龙虎榜卖出额（元）',
+  `amount` double DEFAULT NULL COMMENT '龙虎榜成交额（元）',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`date`,`reason`),
+  KEY `date` (`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Daily abnormal trading lists (Dragon-Tiger)';
+
+CREATE TABLE `dragon_tiger_seat` (
+  `code` varchar(8) NOT NULL,
+  `date` varchar(10) NOT NULL,
+  `reason` varchar(128) NOT NULL COMMENT '上榜原因',
+  `side` varchar(1) NOT NULL COMMENT 'B: 买入前五, S: 卖出前五',
+  `seat_rank` int NOT NULL COMMENT '排名',
+  `seat_code` varchar(64) NOT NULL COMMENT '营业部代码',
+  `buy` double DEFAULT NULL COMMENT '买入金额（元）',
+  `sell` double DEFAULT NULL COMMENT '卖出金额（元）',
+  `net_buy` double DEFAULT NULL COMMENT '净额（元）',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`date`,`reason`,`side`,`seat_rank`),
+  KEY `seat` (`seat_code`,`date`),
+  KEY `date` (`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Top buy and sell seats of the Dragon-Tiger lists';
+
 CREATE TABLE `em_d_b` (
   `code` varchar(8) NOT NULL,
   `date` varchar(20) NOT NULL,
@@ -1320,9 +1546,19 @@ CREATE TABLE `finance` (
   `quick_ratio` float DEFAULT NULL COMMENT '速动比率',
   `cons_quick_ratio` float DEFAULT NULL COMMENT '保守速动比率',
   `equity_ratio` float DEFAULT NULL COMMENT '产权比率',
+  `period` varchar(2) DEFAULT NULL COMMENT '报告期类型: Q1, H1, Q3, FY',
+  `eps_q` double DEFAULT NULL COMMENT '单季每股收益(元)',
+  `np_q` double DEFAULT NULL COMMENT '单季净利润(亿)',
+  `np_adn_q` double DEFAULT NULL COMMENT '单季扣非净利润(亿)',
+  `gr_q` double DEFAULT NULL COMMENT '单季营业总收入(亿)',
+  `eps_ttm` double DEFAULT NULL COMMENT '滚动每股收益(元)',
+  `np_ttm` double DEFAULT NULL COMMENT '滚动净利润(亿)',
+  `gr_ttm` double DEFAULT NULL COMMENT '滚动营业总收入(亿)',
+  `roe_ttm` double DEFAULT NULL COMMENT '滚动净资产收益率',
   `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
   `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
-  PRIMARY KEY (`code`,`year`)
+  PRIMARY KEY (`code`,`year`),
+  KEY `period` (`period`,`code`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='财务信息';
 
 CREATE TABLE `fin_predict` (
@@ -1347,6 +1583,42 @@ CREATE TABLE `fin_predict` (
   PRIMARY KEY (`code`,`year`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='业绩预测简表';
 
+CREATE TABLE `fin_predict_hist` (
+  `code` varchar(8) NOT NULL COMMENT '股票代码',
+  `year` varchar(4) NOT NULL COMMENT '年份',
+  `date` varchar(10) NOT NULL COMMENT '快照日期',
+  `eps_num` int DEFAULT NULL COMMENT '每股收益预测机构数',
+  `eps_min` double DEFAULT NULL COMMENT '每股收益最小值',
+  `eps_avg` double DEFAULT NULL COMMENT '每股收益平均值',
+  `eps_max` double DEFAULT NULL COMMENT '每股收益最大值',
+  `eps_ind_avg` double DEFAULT NULL COMMENT '每股收益行业平均',
+  `eps_up_rt` double DEFAULT NULL COMMENT 'EPS预测上调机构占比',
+  `eps_dn_rt` double DEFAULT NULL COMMENT 'EPS预测下调机构占比',
+  `np_num` int DEFAULT NULL COMMENT '净利润预测机构数',
+  `np_min` double DEFAULT NULL COMMENT '净利润最小值 (亿元）',
+  `np_avg` double DEFAULT NULL COMMENT '净利润平均值 (亿元）',
+  `np_max` double DEFAULT NULL COMMENT '净利润最大值 (亿元）',
+  `np_ind_avg` double DEFAULT NULL COMMENT '净利润行业平均值 (亿元）',
+  `np_up_rt` double DEFAULT NULL COMMENT '净利润预测上调机构占比',
+  `np_dn_rt` double DEFAULT NULL COMMENT '净利润预测下调机构占比',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`year`,`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Dated snapshots of the consensus prediction';
+
+CREATE TABLE `fin_stmt` (
+  `code` varchar(8) NOT NULL COMMENT '股票代码',
+  `year` varchar(10) NOT NULL COMMENT '报告期',
+  `period` varchar(2) NOT NULL COMMENT '报告类型',
+  `statement` varchar(2) NOT NULL COMMENT '报表：BS资产负债表，IS利润表，CF现金流量表',
+  `item` varchar(40) NOT NULL COMMENT '科目',
+  `value` double NOT NULL COMMENT '金额（元），利润表和现金流量表为年初至报告期末累计',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`year`,`statement`,`item`),
+  KEY `item` (`item`,`year`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Financial statement line items in long format';
+
 CREATE TABLE `fs_stats` (
   `method` varchar(45) NOT NULL,
   `tab` varchar(45) NOT NULL,
@@ -1359,6 +1631,41 @@ CREATE TABLE `fs_stats` (
   PRIMARY KEY (`method`,`tab`,`fields`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Feature Scaling Statistics';
 
+CREATE TABLE `fund` (
+  `code` varchar(8) NOT NULL COMMENT '基金代码',
+  `market` varchar(2) NOT NULL COMMENT '市场',
+  `name` varchar(50) NOT NULL COMMENT '基金名称',
+  `type` varchar(10) NOT NULL COMMENT '基金类型：ETF, LOF',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='基金列表';
+
+CREATE TABLE `fund_holding` (
+  `code` varchar(8) NOT NULL COMMENT '基金代码',
+  `date` varchar(10) NOT NULL COMMENT '报告期',
+  `stock_code` varchar(8) NOT NULL COMMENT '股票代码',
+  `stock_name` varchar(20) DEFAULT NULL COMMENT '股票名称',
+  `ratio` double DEFAULT NULL COMMENT '占净值比例（%）',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`date`,`stock_code`),
+  KEY `stock_code` (`stock_code`,`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Fund constituent holdings by reporting period';
+
+CREATE TABLE `fund_nav` (
+  `code` varchar(8) NOT NULL COMMENT '基金代码',
+  `date` varchar(10) NOT NULL COMMENT '净值日期',
+  `nav` double NOT NULL COMMENT '单位净值',
+  `acc_nav` double DEFAULT NULL COMMENT '累计净值',
+  `nav_chg` double DEFAULT NULL COMMENT '日增长率（%）',
+  `close` double DEFAULT NULL COMMENT '收盘价（不复权）',
+  `premium` double DEFAULT NULL COMMENT '溢价率（%），负数为折价',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Daily fund NAV with premium or discount of the close';
+
 CREATE TABLE `grader_stats` (
   `grader` varchar(20) NOT NULL,
   `frame` int NOT NULL,
@@ -1371,6 +1678,21 @@ CREATE TABLE `grader_stats` (
   PRIMARY KEY (`grader`,`frame`,`score`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
+CREATE TABLE `holder_num` (
+  `code` varchar(8) NOT NULL,
+  `date` varchar(10) NOT NULL COMMENT '截止日期',
+  `notice_date` varchar(10) DEFAULT NULL COMMENT '公告日期',
+  `holders` bigint NOT NULL COMMENT '股东户数',
+  `avg_shares` double DEFAULT NULL COMMENT '户均持股数量（股）',
+  `holders_chg` double DEFAULT NULL COMMENT '股东户数较上期变化（%）',
+  `float_per_holder` double DEFAULT NULL COMMENT '户均流通股（股）',
+  `top10_float` double DEFAULT NULL COMMENT '十大流通股东持股合计占流通股比例（%）',
+  `top10_float_chg` double DEFAULT NULL COMMENT '十大流通股东持股合计较上期变化（百分点）',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Shareholder count history and chip concentration metrics';
+
 CREATE TABLE `idxlst` (
   `src` varchar(20) NOT NULL COMMENT '来源',
   `code` varchar(20) NOT NULL COMMENT '代码',
@@ -1797,113 +2119,19 @@ CREATE TABLE `index_w_n_ma_lr` (
 /*!50100 PARTITION BY KEY (`code`)
 PARTITIONS 16 */;
 
-CREATE TABLE `indicator_d` (
-  `Code` varchar(8) NOT NULL,
-  `Date` varchar(10) NOT NULL,
-  `Klid` int NOT NULL,
-  `KDJ_K` double DEFAULT NULL,
-  `KDJ_D` double DEFAULT NULL,
-  `KDJ_J` double DEFAULT NULL,
-  `MACD` double DEFAULT NULL,
-  `MACD_diff` double DEFAULT NULL,
-  `MACD_dea` double DEFAULT NULL,
-  `RSI1` double DEFAULT NULL,
-  `RSI2` double DEFAULT NULL,
-  `RSI3` double DEFAULT NULL,
-  `BIAS1` double DEFAULT NULL,
-  `BIAS2` double DEFAULT NULL,
-  `BIAS3` double DEFAULT NULL,
-  `BOLL_lower` double DEFAULT NULL,
-  `BOLL_lower_o` double DEFAULT NULL,
-  `BOLL_lower_h` double DEFAULT NULL,
-  `BOLL_lower_l` double DEFAULT NULL,
-  `BOLL_lower_c` double DEFAULT NULL,
-  `BOLL_mid` double DEFAULT NULL,
-  `BOLL_mid_o` double DEFAULT NULL,
-  `BOLL_mid_h` double DEFAULT NULL,
-  `BOLL_mid_l` double DEFAULT NULL,
-  `BOLL_mid_c` double DEFAULT NULL,
-  `BOLL_upper` double DEFAULT NULL,
-  `BOLL_upper_o` double DEFAULT NULL,
-  `BOLL_upper_h` double DEFAULT NULL,
-  `BOLL_upper_l` double DEFAULT NULL,
-  `BOLL_upper_c` double DEFAULT NULL,
-  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
-  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
-  PRIMARY KEY (`Code`,`Klid`)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
-
-CREATE TABLE `indicator_m` (
-  `Code` varchar(8) NOT NULL,
-  `Date` varchar(10) NOT NULL,
-  `Klid` int NOT NULL,
-  `KDJ_K` double DEFAULT NULL,
-  `KDJ_D` double DEFAULT NULL,
-  `KDJ_J` double DEFAULT NULL,
-  `MACD` double DEFAULT NULL,
-  `MACD_diff` double DEFAULT NULL,
-  `MACD_dea` double DEFAULT NULL,
-  `RSI1` double DEFAULT NULL,
-  `RSI2` double DEFAULT NULL,
-  `RSI3` double DEFAULT NULL,
-  `BIAS1` double DEFAULT NULL,
-  `BIAS2` double DEFAULT NULL,
-  `BIAS3` double DEFAULT NULL,
-  `BOLL_lower` double DEFAULT NULL,
-  `BOLL_lower_o` double DEFAULT NULL,
-  `BOLL_lower_h` double DEFAULT NULL,
-  `BOLL_lower_l` double DEFAULT NULL,
-  `BOLL_lower_c` double DEFAULT NULL,
-  `BOLL_mid` double DEFAULT NULL,
-  `BOLL_mid_o` double DEFAULT NULL,
-  `BOLL_mid_h` double DEFAULT NULL,
-  `BOLL_mid_l` double DEFAULT NULL,
-  `BOLL_mid_c` double DEFAULT NULL,
-  `BOLL_upper` double DEFAULT NULL,
-  `BOLL_upper_o` double DEFAULT NULL,
-  `BOLL_upper_h` double DEFAULT NULL,
-  `BOLL_upper_l` double DEFAULT NULL,
-  `BOLL_upper_c` double DEFAULT NULL,
+CREATE TABLE `indicator_state` (
+  `code` varchar(8) NOT NULL,
+  `cycle` varchar(4) NOT NULL COMMENT '周期',
+  `indicator` varchar(20) NOT NULL COMMENT '指标名称',
+  `klid` int NOT NULL COMMENT '最后计入状态的K线ID',
+  `state` mediumtext NOT NULL COMMENT '指标状态（JSON）',
   `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
   `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
-  PRIMARY KEY (`Code`,`Klid`)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+  PRIMARY KEY (`code`,`cycle`,`indicator`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Carried indicator states for incremental calculation';
 
-CREATE TABLE `indicator_w` (
-  `Code` varchar(8) NOT NULL,
-  `Date` varchar(10) NOT NULL,
-  `Klid` int NOT NULL,
-  `KDJ_K` double DEFAULT NULL,
-  `KDJ_D` double DEFAULT NULL,
-  `KDJ_J` double DEFAULT NULL,
-  `MACD` double DEFAULT NULL,
-  `MACD_diff` double DEFAULT NULL,
-  `MACD_dea` double DEFAULT NULL,
-  `RSI1` double DEFAULT NULL,
-  `RSI2` double DEFAULT NULL,
-  `RSI3` double DEFAULT NULL,
-  `BIAS1` double DEFAULT NULL,
-  `BIAS2` double DEFAULT NULL,
-  `BIAS3` double DEFAULT NULL,
-  `BOLL_lower` double DEFAULT NULL,
-  `BOLL_lower_o` double DEFAULT NULL,
-  `BOLL_lower_h` double DEFAULT NULL,
-  `BOLL_lower_l` double DEFAULT NULL,
-  `BOLL_lower_c` double DEFAULT NULL,
-  `BOLL_mid` double DEFAULT NULL,
-  `BOLL_mid_o` double DEFAULT NULL,
-  `BOLL_mid_h` double DEFAULT NULL,
-  `BOLL_mid_l` double DEFAULT NULL,
-  `BOLL_mid_c` double DEFAULT NULL,
-  `BOLL_upper` double DEFAULT NULL,
-  `BOLL_upper_o` double DEFAULT NULL,
-  `BOLL_upper_h` double DEFAULT NULL,
-  `BOLL_upper_l` double DEFAULT NULL,
-  `BOLL_upper_c` double DEFAULT NULL,
-  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
-  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
-  PRIMARY KEY (`Code`,`Klid`)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+-- indicator_<cycle>_<name> tables (e.g. indicator_d_kdj) are created on demand by getd,
+-- with columns declared by the registered indicator specs in package indc.
 
 CREATE TABLE `kdj_feat_dat` (
   `fid` varchar(50) NOT NULL COMMENT '特征ID',
@@ -1970,6 +2198,8 @@ CREATE TABLE `kline_60m` (
   PRIMARY KEY (`code`,`klid`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='60分钟K线（前复权）';
 
+-- intraday kline tables (e.g. kline_m60_f, kline_m60_f_lr) are created on demand by getd,
+-- sharing the structure of their daily counterparts.
 CREATE TABLE `kline_d_b` (
   `code` varchar(8) NOT NULL,
   `date` varchar(20) NOT NULL,
@@ -2369,6 +2599,48 @@ CREATE TABLE `kline_d_n_ma_lr` (
 /*!50100 PARTITION BY KEY (`code`)
 PARTITIONS 1024 */;
 
+CREATE TABLE `kline_discrepancy` (
+  `code` varchar(8) NOT NULL,
+  `cycle` varchar(4) NOT NULL COMMENT '周期',
+  `rtype` varchar(10) NOT NULL COMMENT '复权类型',
+  `date` varchar(10) NOT NULL,
+  `kind` varchar(15) NOT NULL COMMENT '差异类型：missing, extra, gap, split, price, volume_scale, volume, xrate',
+  `fields` varchar(60) DEFAULT NULL COMMENT '存在差异的字段',
+  `src` varchar(10) DEFAULT NULL COMMENT '校验数据源',
+  `ratio` double DEFAULT NULL COMMENT '校验值与主数据值之比',
+  `dev` double DEFAULT NULL COMMENT '最大相对偏差',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`cycle`,`rtype`,`date`,`kind`),
+  KEY `kind` (`kind`,`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Differences between master klines and validate source';
+
+CREATE TABLE `kline_limit` (
+  `code` varchar(8) NOT NULL,
+  `date` varchar(10) NOT NULL,
+  `klid` int NOT NULL,
+  `rate` double NOT NULL COMMENT '涨跌幅限制',
+  `up_price` double NOT NULL COMMENT '涨停价',
+  `down_price` double NOT NULL COMMENT '跌停价',
+  `limit_up` tinyint(1) NOT NULL COMMENT '收盘涨停',
+  `limit_down` tinyint(1) NOT NULL COMMENT '收盘跌停',
+  `one_word` tinyint(1) NOT NULL COMMENT '一字板',
+  `touch_up` tinyint(1) NOT NULL COMMENT '盘中触及涨停未封板',
+  `touch_down` tinyint(1) NOT NULL COMMENT '盘中触及跌停未封板',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`date`),
+  KEY `date` (`date`,`limit_up`,`limit_down`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Daily bars reaching the price limit';
+
+CREATE TABLE `kline_limit_scan` (
+  `code` varchar(8) NOT NULL,
+  `date` varchar(10) NOT NULL COMMENT '最后扫描的交易日',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Last daily bar scanned for price limits';
+
 CREATE TABLE `kline_m_b` (
   `code` varchar(8) NOT NULL,
   `date` varchar(20) NOT NULL,
@@ -3167,6 +3439,44 @@ CREATE TABLE `kline_w_n_ma_lr` (
 /*!50100 PARTITION BY KEY (`code`)
 PARTITIONS 1024 */;
 
+-- fund klines share the layout of the stock klines
+CREATE TABLE `fund_d_n` LIKE `kline_d_n`;
+CREATE TABLE `fund_d_n_lr` LIKE `kline_d_n_lr`;
+CREATE TABLE `fund_d_n_ma` LIKE `kline_d_n_ma`;
+CREATE TABLE `fund_d_n_ma_lr` LIKE `kline_d_n_ma_lr`;
+CREATE TABLE `fund_d_b` LIKE `kline_d_b`;
+CREATE TABLE `fund_d_b_lr` LIKE `kline_d_b_lr`;
+CREATE TABLE `fund_d_b_ma` LIKE `kline_d_b_ma`;
+CREATE TABLE `fund_d_b_ma_lr` LIKE `kline_d_b_ma_lr`;
+CREATE TABLE `fund_d_f` LIKE `kline_d_f`;
+CREATE TABLE `fund_d_f_lr` LIKE `kline_d_f_lr`;
+CREATE TABLE `fund_d_f_ma` LIKE `kline_d_f_ma`;
+CREATE TABLE `fund_d_f_ma_lr` LIKE `kline_d_f_ma_lr`;
+CREATE TABLE `fund_w_n` LIKE `kline_w_n`;
+CREATE TABLE `fund_w_n_lr` LIKE `kline_w_n_lr`;
+CREATE TABLE `fund_w_n_ma` LIKE `kline_w_n_ma`;
+CREATE TABLE `fund_w_n_ma_lr` LIKE `kline_w_n_ma_lr`;
+CREATE TABLE `fund_w_b` LIKE `kline_w_b`;
+CREATE TABLE `fund_w_b_lr` LIKE `kline_w_b_lr`;
+CREATE TABLE `fund_w_b_ma` LIKE `kline_w_b_ma`;
+CREATE TABLE `fund_w_b_ma_lr` LIKE `kline_w_b_ma_lr`;
+CREATE TABLE `fund_w_f` LIKE `kline_w_f`;
+CREATE TABLE `fund_w_f_lr` LIKE `kline_w_f_lr`;
+CREATE TABLE `fund_w_f_ma` LIKE `kline_w_f_ma`;
+CREATE TABLE `fund_w_f_ma_lr` LIKE `kline_w_f_ma_lr`;
+CREATE TABLE `fund_m_n` LIKE `kline_m_n`;
+CREATE TABLE `fund_m_n_lr` LIKE `kline_m_n_lr`;
+CREATE TABLE `fund_m_n_ma` LIKE `kline_m_n_ma`;
+CREATE TABLE `fund_m_n_ma_lr` LIKE `kline_m_n_ma_lr`;
+CREATE TABLE `fund_m_b` LIKE `kline_m_b`;
+CREATE TABLE `fund_m_b_lr` LIKE `kline_m_b_lr`;
+CREATE TABLE `fund_m_b_ma` LIKE `kline_m_b_ma`;
+CREATE TABLE `fund_m_b_ma_lr` LIKE `kline_m_b_ma_lr`;
+CREATE TABLE `fund_m_f` LIKE `kline_m_f`;
+CREATE TABLE `fund_m_f_lr` LIKE `kline_m_f_lr`;
+CREATE TABLE `fund_m_f_ma` LIKE `kline_m_f_ma`;
+CREATE TABLE `fund_m_f_ma_lr` LIKE `kline_m_f_ma_lr`;
+
 CREATE TABLE `kpts10` (
   `uuid` varchar(50) NOT NULL,
   `code` varchar(8) NOT NULL,
@@ -3293,6 +3603,79 @@ CREATE TABLE `kpts60` (
   KEY `kpts60_lr_rema` (`rema_lr`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='60-days key points';
 
+CREATE TABLE `lockup` (
+  `code` varchar(8) NOT NULL COMMENT '股票代码',
+  `date` varchar(10) NOT NULL COMMENT '解禁日期',
+  `share_type` varchar(50) NOT NULL COMMENT '限售股类型',
+  `shares` double NOT NULL COMMENT '解禁数量（股）',
+  `able_shares` double DEFAULT NULL COMMENT '实际解禁数量（股）',
+  `market_cap` double DEFAULT NULL COMMENT '解禁市值（元）',
+  `float_ratio` double DEFAULT NULL COMMENT '占解禁前流通股比例（%）',
+  `total_ratio` double DEFAULT NULL COMMENT '占总股本比例（%）',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`date`,`share_type`),
+  KEY `date` (`date`,`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Lock-up expiry calendar';
+
+CREATE TABLE `margin` (
+  `code` varchar(8) NOT NULL,
+  `date` varchar(10) NOT NULL,
+  `fin_bal` double DEFAULT NULL COMMENT '融资余额（元）',
+  `fin_buy` double DEFAULT NULL COMMENT '融资买入额（元）',
+  `fin_repay` double DEFAULT NULL COMMENT '融资偿还额（元）',
+  `sec_bal` double DEFAULT NULL COMMENT '融券余额（元）',
+  `sec_vol` double DEFAULT NULL COMMENT '融券余量（股）',
+  `sec_sell` double DEFAULT NULL COMMENT '融券卖出量（股）',
+  `sec_repay` double DEFAULT NULL COMMENT '融券偿还量（股）',
+  `total_bal` double DEFAULT NULL COMMENT '融资融券余额（元）',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`date`),
+  KEY `date` (`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Daily margin trading of stocks';
+
+CREATE TABLE `margin_lr` (
+  `code` varchar(8) NOT NULL,
+  `date` varchar(10) NOT NULL,
+  `fin_bal` double DEFAULT NULL COMMENT 'Log Return (融资余额)',
+  `fin_buy` double DEFAULT NULL COMMENT 'Log Return (融资买入额)',
+  `fin_repay` double DEFAULT NULL COMMENT 'Log Return (融资偿还额)',
+  `sec_bal` double DEFAULT NULL COMMENT 'Log Return (融券余额)',
+  `sec_vol` double DEFAULT NULL COMMENT 'Log Return (融券余量)',
+  `sec_sell` double DEFAULT NULL COMMENT 'Log Return (融券卖出量)',
+  `sec_repay` double DEFAULT NULL COMMENT 'Log Return (融券偿还量)',
+  `total_bal` double DEFAULT NULL COMMENT 'Log Return (融资融券余额)',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`date`),
+  KEY `date` (`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Daily margin trading log return of stocks';
+
+CREATE TABLE `north_flow` (
+  `date` varchar(10) NOT NULL,
+  `market` varchar(2) NOT NULL COMMENT 'SH: 沪股通, SZ: 深股通',
+  `net_buy` double DEFAULT NULL COMMENT '成交净买额（百万元）',
+  `buy` double DEFAULT NULL COMMENT '买入成交额（百万元）',
+  `sell` double DEFAULT NULL COMMENT '卖出成交额（百万元）',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`date`,`market`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Northbound deal flows of HK Stock Connect';
+
+CREATE TABLE `north_holding` (
+  `code` varchar(8) NOT NULL,
+  `date` varchar(10) NOT NULL,
+  `shares` double NOT NULL COMMENT '持股数量（股）',
+  `market_cap` double DEFAULT NULL COMMENT '持股市值（元）',
+  `free_ratio` double DEFAULT NULL COMMENT '占流通股比例（%）',
+  `total_ratio` double DEFAULT NULL COMMENT '占总股本比例（%）',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`date`),
+  KEY `date` (`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Northbound holdings of HK Stock Connect';
+
 CREATE TABLE `params` (
   `id` int NOT NULL AUTO_INCREMENT,
   `section` varchar(45) NOT NULL,
@@ -3304,6 +3687,15 @@ CREATE TABLE `params` (
   KEY `params_idx_01` (`section`,`param`)
 ) ENGINE=InnoDB AUTO_INCREMENT=4 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
+CREATE TABLE `pipeline_ckpt` (
+  `stage` varchar(20) NOT NULL COMMENT '数据流程阶段',
+  `src` varchar(10) NOT NULL DEFAULT '' COMMENT '指数数据源，股票为空',
+  `code` varchar(8) NOT NULL COMMENT '代码，全局阶段为*',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`stage`,`src`,`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Checkpoints of the data pipeline stages';
+
 CREATE TABLE `proxy_list` (
   `source` varchar(20) NOT NULL,
   `host` varchar(15) NOT NULL,
@@ -3322,6 +3714,37 @@ CREATE TABLE `proxy_list` (
   KEY `status` (`status`,`fail`,`host`,`port`,`type`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
+CREATE TABLE `seat` (
+  `code` varchar(64) NOT NULL COMMENT '营业部代码',
+  `name` varchar(128) NOT NULL COMMENT '营业部名称',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Trading seats of the Dragon-Tiger lists';
+
+CREATE TABLE `seat_profile` (
+  `seat_code` varchar(64) NOT NULL COMMENT '营业部代码',
+  `days` int NOT NULL COMMENT '上榜后交易日数',
+  `appearances` int NOT NULL COMMENT '净买入上榜次数',
+  `avg_ret` double NOT NULL COMMENT '平均收益率（%）',
+  `win_rate` double NOT NULL COMMENT '上涨概率',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`seat_code`,`days`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Subsequent returns after the seats net buying on the Dragon-Tiger lists';
+
+CREATE TABLE `share_chg` (
+  `code` varchar(8) NOT NULL,
+  `date` varchar(10) NOT NULL COMMENT '变动日期',
+  `total_shares` double DEFAULT NULL COMMENT '总股本（股）',
+  `float_a_shares` double DEFAULT NULL COMMENT '流通A股（股）',
+  `limit_a_shares` double DEFAULT NULL COMMENT '限售A股（股）',
+  `reason` varchar(256) DEFAULT NULL COMMENT '变动原因',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Share capital change history';
+
 CREATE TABLE `stats` (
   `code` varchar(6) NOT NULL,
   `start` varchar(20) DEFAULT NULL,
@@ -3330,6 +3753,19 @@ CREATE TABLE `stats` (
   PRIMARY KEY (`code`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
+CREATE TABLE `stock_status` (
+  `code` varchar(8) NOT NULL,
+  `status` varchar(10) NOT NULL COMMENT '状态：suspended, st, *st, delisting, delisted',
+  `start_date` varchar(10) NOT NULL COMMENT '起始日期',
+  `end_date` varchar(10) DEFAULT NULL COMMENT '截止日期，为空表示持续中',
+  `name` varchar(20) DEFAULT NULL COMMENT '证券简称',
+  `reason` varchar(200) DEFAULT NULL COMMENT '停牌原因',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`status`,`start_date`),
+  KEY `end_date` (`end_date`,`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='History of suspension, ST and delisting status';
+
 CREATE TABLE `stockrel` (
   `code` varchar(6) NOT NULL,
   `date` varchar(20) NOT NULL,
@@ -3351,13 +3787,30 @@ CREATE TABLE `stockrel` (
 /*!50100 PARTITION BY LINEAR KEY (`code`,klid)
 PARTITIONS 512 */;
 
+CREATE TABLE `top_holder` (
+  `code` varchar(8) NOT NULL,
+  `date` varchar(10) NOT NULL COMMENT '截止日期',
+  `is_float` tinyint(1) NOT NULL COMMENT '1: 十大流通股东, 0: 十大股东',
+  `holder_rank` int NOT NULL COMMENT '名次',
+  `name` varchar(256) NOT NULL COMMENT '股东名称',
+  `shares` double NOT NULL COMMENT '持股数量（股）',
+  `ratio` double DEFAULT NULL COMMENT '占总股本或流通股比例（%）',
+  `share_type` varchar(64) DEFAULT NULL COMMENT '股份类型',
+  `chg` double DEFAULT NULL COMMENT '持股较上期变化（股），新进为空',
+  `status` varchar(10) NOT NULL COMMENT 'new: 新进, inc: 增持, dec: 减持, same: 不变',
+  `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
+  `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`code`,`date`,`is_float`,`holder_rank`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Top 10 holders and top 10 float holders';
+
 CREATE TABLE `tradecal` (
   `index` bigint DEFAULT NULL,
   `calendarDate` date DEFAULT NULL,
   `isOpen` int DEFAULT NULL,
   `udate` varchar(10) DEFAULT NULL COMMENT '更新日期',
   `utime` varchar(8) DEFAULT NULL COMMENT '更新时间',
-  KEY `ix_tradecal_index` (`index`)
+  KEY `ix_tradecal_index` (`index`),
+  KEY `ix_tradecal_date` (`calendarDate`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
 CREATE TABLE `user_agents` (
@@ -0,0 +1,27 @@
+/*
+-- Upgrades an existing finance table with the report period type, single-quarter and TTM columns.
+-- The period is derived from the month of the report end date, so that queries by period work right away.
+-- The single-quarter and TTM values are filled in when the finance stage re-parses the finance pages.
+*/
+ALTER TABLE `secu`.`finance`
+  ADD COLUMN `period` varchar(2) DEFAULT NULL COMMENT '报告期类型: Q1, H1, Q3, FY' AFTER `equity_ratio`,
+  ADD COLUMN `eps_q` double DEFAULT NULL COMMENT '单季每股收益(元)' AFTER `period`,
+  ADD COLUMN `np_q` double DEFAULT NULL COMMENT '单季净利润(亿)' AFTER `eps_q`,
+  ADD COLUMN `np_adn_q` double DEFAULT NULL COMMENT '单季扣非净利润(亿)' AFTER `np_q`,
+  ADD COLUMN `gr_q` double DEFAULT NULL COMMENT '单季营业总收入(亿)' AFTER `np_adn_q`,
+  ADD COLUMN `eps_ttm` double DEFAULT NULL COMMENT '滚动每股收益(元)' AFTER `gr_q`,
+  ADD COLUMN `np_ttm` double DEFAULT NULL COMMENT '滚动净利润(亿)' AFTER `eps_ttm`,
+  ADD COLUMN `gr_ttm` double DEFAULT NULL COMMENT '滚动营业总收入(亿)' AFTER `np_ttm`,
+  ADD COLUMN `roe_ttm` double DEFAULT NULL COMMENT '滚动净资产收益率' AFTER `gr_ttm`,
+  ADD KEY `period` (`period`,`code`);
+
+UPDATE `secu`.`finance`
+SET
+    `period` = CASE SUBSTR(`year`, 6, 2)
+        WHEN '03' THEN 'Q1'
+        WHEN '06' THEN 'H1'
+        WHEN '09' THEN 'Q3'
+        WHEN '12' THEN 'FY'
+    END
+WHERE
+    `period` IS NULL;
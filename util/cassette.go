@@ -0,0 +1,178 @@
+package util
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/carusyte/stock/conf"
+	"github.com/pkg/errors"
+)
+
+//cassette is a recorded HTTP request/response pair.
+type cassette struct {
+	Method string
+	//URL is the normalized request URL
+	URL string
+	//Request is the request body
+	Request []byte `json:",omitempty"`
+	Status  int
+	Header  http.Header
+	Body    []byte
+}
+
+var (
+	//cassetteSeq counts the requests served or recorded for each cassette key, so that
+	//repeated requests with the same key, e.g. paging with ignored parameters, replay in order.
+	cassetteSeq = make(map[string]int)
+	cassetteMu  sync.Mutex
+)
+
+//ResetCassettes restarts the sequence of the cassettes, so that subsequent requests
+//replay from the first recorded response.
+func ResetCassettes() {
+	cassetteMu.Lock()
+	defer cassetteMu.Unlock()
+	cassetteSeq = make(map[string]int)
+}
+
+//normalizeURL sorts the query parameters and strips the volatile ones configured by
+//network.cassette.ignore_params.
+func normalizeURL(link string) (string, error) {
+	u, e := url.Parse(link)
+	if e != nil {
+		return "", errors.WithStack(e)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	q := u.Query()
+	for _, p := range conf.Args.Network.Cassette.IgnoreParams {
+		q.Del(p)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+//cassetteKey returns the key of the request, and the normalized URL.
+func cassetteKey(method, link string, body []byte) (key, nurl string, e error) {
+	if nurl, e = normalizeURL(link); e != nil {
+		return
+	}
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\n%s\n", method, nurl)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nurl, nil
+}
+
+//cassetteFile returns the path of the cassette file for the key at the given sequence.
+//Files are grouped by host for ease of browsing.
+func cassetteFile(nurl, key string, seq int) string {
+	host := "_"
+	if u, e := url.Parse(nurl); e == nil && u.Host != "" {
+		host = strings.Replace(u.Host, ":", "_", -1)
+	}
+	return filepath.Join(conf.Args.Network.Cassette.Path, host, fmt.Sprintf("%s_%d.json", key, seq))
+}
+
+//nextSeq returns the sequence number of the next request with the key.
+func nextSeq(key string) int {
+	cassetteMu.Lock()
+	defer cassetteMu.Unlock()
+	n := cassetteSeq[key]
+	cassetteSeq[key] = n + 1
+	return n
+}
+
+//replayHTTP serves the recorded response for the request if the replay mode is on, in which
+//case ok is true. The last recorded response is served again when the recorded sequence is exhausted.
+func replayHTTP(method, link string, body []byte) (res *http.Response, ok bool, e error) {
+	if conf.Args.Network.Cassette.Mode != conf.CassetteReplay {
+		return nil, false, nil
+	}
+	key, nurl, e := cassetteKey(method, link, body)
+	if e != nil {
+		return nil, true, e
+	}
+	var data []byte
+	for i := nextSeq(key); i >= 0; i-- {
+		if data, e = ioutil.ReadFile(cassetteFile(nurl, key, i)); e == nil {
+			break
+		}
+	}
+	if e != nil {
+		return nil, true, errors.Errorf("no cassette recorded for %s %s", method, nurl)
+	}
+	c := new(cassette)
+	if e = json.Unmarshal(data, c); e != nil {
+		return nil, true, errors.Wrapf(e, "invalid cassette for %s %s", method, nurl)
+	}
+	log.Debugf("replaying cassette for %s %s", method, nurl)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", c.Status, http.StatusText(c.Status)),
+		StatusCode:    c.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        c.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+	}, true, nil
+}
+
+//recordHTTP saves the response to a cassette if the record mode is on. The original response body
+//is consumed and closed, and a response with the same body is returned for the caller to read.
+//An error is returned only if the original body can't be read. Failing to save the cassette is
+//not fatal to the caller.
+func recordHTTP(method, link string, body []byte, res *http.Response) (*http.Response, error) {
+	if conf.Args.Network.Cassette.Mode != conf.CassetteRecord || res == nil {
+		return res, nil
+	}
+	data, e := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if e != nil {
+		return res, errors.WithStack(e)
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(data))
+	key, nurl, e := cassetteKey(method, link, body)
+	if e != nil {
+		log.Warnf("failed to record cassette for %s %s: %+v", method, link, e)
+		return res, nil
+	}
+	file := cassetteFile(nurl, key, nextSeq(key))
+	c := &cassette{
+		Method:  method,
+		URL:     nurl,
+		Request: body,
+		Status:  res.StatusCode,
+		Header:  res.Header,
+		Body:    data,
+	}
+	if e = saveCassette(file, c); e != nil {
+		log.Warnf("failed to record cassette for %s %s: %+v", method, link, e)
+	}
+	return res, nil
+}
+
+func saveCassette(file string, c *cassette) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if e := enc.Encode(c); e != nil {
+		return errors.WithStack(e)
+	}
+	if e := os.MkdirAll(filepath.Dir(file), 0755); e != nil {
+		return errors.WithStack(e)
+	}
+	return errors.WithStack(ioutil.WriteFile(file, buf.Bytes(), 0644))
+}
@@ -0,0 +1,127 @@
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/carusyte/stock/conf"
+)
+
+//withCassettes sets the cassette mode and directory until the returned function is called.
+func withCassettes(mode, path string) func() {
+	c := conf.Args.Network.Cassette
+	conf.Args.Network.Cassette.Mode = mode
+	conf.Args.Network.Cassette.Path = path
+	conf.Args.Network.Cassette.IgnoreParams = []string{"_"}
+	ResetCassettes()
+	return func() {
+		conf.Args.Network.Cassette = c
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	defer withCassettes("", "")()
+	for _, c := range [][2]string{
+		{"HTTP://Example.com/a?b=2&a=1", "http://example.com/a?a=1&b=2"},
+		{"http://example.com/a?&a=1&_=1579449600000#top", "http://example.com/a?a=1"},
+		{"http://example.com/a?symbol=SH600104&begin=1579449600000&_=1579449600000",
+			"http://example.com/a?begin=1579449600000&symbol=SH600104"},
+	} {
+		got, e := normalizeURL(c[0])
+		if e != nil {
+			t.Fatalf("%+v", e)
+		}
+		if got != c[1] {
+			t.Errorf("normalizeURL(%s): want %s, got %s", c[0], c[1], got)
+		}
+	}
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	dir, e := ioutil.TempDir("", "cassettes")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Hit", fmt.Sprint(hits))
+		fmt.Fprintf(w, "%s %s #%d %s", r.Method, r.URL.Query().Get("page"), hits, body)
+	}))
+
+	restore := withCassettes(conf.CassetteRecord, dir)
+	var recorded []string
+	for _, link := range []string{
+		srv.URL + "/k?page=1&_=100",
+		srv.URL + "/k?page=1&_=200",
+		srv.URL + "/k?page=2",
+	} {
+		body, e := HttpGetBytes(link)
+		if e != nil {
+			t.Fatalf("%+v", e)
+		}
+		recorded = append(recorded, string(body))
+	}
+	res, e := HTTPGet(srv.URL+"/k?page=3", nil, nil)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	recorded = append(recorded, string(body))
+	post, e := HTTPPostJSON(srv.URL+"/p", nil, map[string]string{"code": "600104"})
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	restore()
+	srv.Close()
+
+	defer withCassettes(conf.CassetteReplay, dir)()
+	//same key replays in recorded order, then repeats the last one
+	for i, link := range []string{
+		srv.URL + "/k?_=300&page=1",
+		srv.URL + "/k?page=1",
+		srv.URL + "/k?page=1",
+	} {
+		want := recorded[0]
+		if i > 0 {
+			want = recorded[1]
+		}
+		body, e := HttpGetBytes(link)
+		if e != nil {
+			t.Fatalf("%+v", e)
+		}
+		if string(body) != want {
+			t.Errorf("%s: want %q, got %q", link, want, body)
+		}
+	}
+	res, e = HTTPGetResponse(srv.URL+"/k?page=3", nil, false, false, false)
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	body, _ = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != recorded[3] || res.StatusCode != http.StatusOK || res.Header.Get("X-Hit") != "4" {
+		t.Errorf("unexpected replayed response: %d %+v %q", res.StatusCode, res.Header, body)
+	}
+	replayed, e := HTTPPostJSON(srv.URL+"/p", nil, map[string]string{"code": "600104"})
+	if e != nil {
+		t.Fatalf("%+v", e)
+	}
+	if string(replayed) != string(post) {
+		t.Errorf("post: want %q, got %q", post, replayed)
+	}
+	if _, e = HTTPPostJSON(srv.URL+"/p", nil, map[string]string{"code": "000001"}); e == nil {
+		t.Error("want error for request not recorded")
+	}
+	//the data selected by begin differs, so it must not replay the other cassette
+	if _, e = HTTPGet(srv.URL+"/k?page=2&begin=100", nil, nil); e == nil {
+		t.Error("want error for request with different begin")
+	}
+}
@@ -27,6 +27,9 @@ var PROXY_ADDR string
 //HTTPGet initiates HTTP get request and returns its response
 func HTTPGet(link string, headers map[string]string,
 	px *Proxy, cookies ...*http.Cookie) (res *http.Response, e error) {
+	if r, ok, err := replayHTTP(http.MethodGet, link, nil); ok {
+		return r, err
+	}
 	host := ""
 	r := regexp.MustCompile(`//([^/]*)/`).FindStringSubmatch(link)
 	if len(r) > 0 {
@@ -121,8 +124,11 @@ func HTTPGet(link string, headers map[string]string,
 			repeat.FullJitterBackoff(200*time.Millisecond).WithMaxDelay(2*time.Second).Set(),
 		),
 	)
-
-	return
+	if e != nil {
+		return
+	}
+	//only the final response is recorded
+	return recordHTTP(http.MethodGet, link, nil, res)
 }
 
 //HTTPGetResponse initiates HTTP get request and returns its response
@@ -131,6 +137,9 @@ func HTTPGetResponse(link string, headers map[string]string,
 	if useMasterProxy && rotateProxy {
 		log.Panic("can't useMasterProxy and rotateProxy at the same time.")
 	}
+	if r, ok, err := replayHTTP(http.MethodGet, link, nil); ok {
+		return r, err
+	}
 
 	host := ""
 	r := regexp.MustCompile(`//([^/]*)/`).FindStringSubmatch(link)
@@ -256,7 +265,7 @@ func HTTPGetResponse(link string, headers map[string]string,
 			time.Sleep(time.Millisecond * time.Duration(500+rand.Intn(300)))
 		} else {
 			UpdateProxyScore(prx, true)
-			return
+			return recordHTTP(http.MethodGet, link, nil, res)
 		}
 	}
 	return
@@ -267,6 +276,9 @@ func HttpGetResp(link string) (res *http.Response, e error) {
 }
 
 func HttpGetRespUsingHeaders(link string, headers map[string]string) (res *http.Response, e error) {
+	if r, ok, err := replayHTTP(http.MethodGet, link, nil); ok {
+		return r, err
+	}
 	host := ""
 	r := regexp.MustCompile(`//([^/]*)/`).FindStringSubmatch(link)
 	if len(r) > 0 {
@@ -335,7 +347,7 @@ func HttpGetRespUsingHeaders(link string, headers map[string]string) (res *http.
 				time.Sleep(time.Millisecond * 500)
 			}
 		} else {
-			return
+			return recordHTTP(http.MethodGet, link, nil, res)
 		}
 	}
 	return
@@ -393,6 +405,15 @@ func HTTPPostJSON(link string, headers, params map[string]string) (body []byte,
 			(*resBody).Close()
 		}
 	}()
+	if jsonParams, err := json.Marshal(params); err == nil {
+		if res, ok, err := replayHTTP(http.MethodPost, link, jsonParams); ok {
+			if err != nil {
+				return nil, err
+			}
+			defer res.Body.Close()
+			return ioutil.ReadAll(res.Body)
+		}
+	}
 
 	var client *http.Client
 	//determine if we must use a proxy
@@ -447,7 +468,10 @@ func HTTPPostJSON(link string, headers, params map[string]string) (body []byte,
 			time.Sleep(time.Millisecond * 500)
 		} else {
 			resBody = &res.Body
-			body, err = ioutil.ReadAll(res.Body)
+			if res, err = recordHTTP(http.MethodPost, link, jsonParams, res); err == nil {
+				resBody = &res.Body
+				body, err = ioutil.ReadAll(res.Body)
+			}
 			if err != nil {
 				//handle "read: connection reset by peer" error by retrying
 				if i >= RETRY {